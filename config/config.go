@@ -2,21 +2,38 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	App        AppConfig
-	Server     ServerConfig
-	Database   DatabaseConfig
-	JWT        JWTConfig
-	Loan       LoanConfig
-	CoinGecko  CoinGeckoConfig
-	Log        LogConfig
-	Redis      RedisConfig      `mapstructure:"redis"`
-	Blockchain BlockchainConfig `mapstructure:"blockchain"`
+	App            AppConfig
+	Server         ServerConfig
+	Database       DatabaseConfig
+	JWT            JWTConfig
+	Loan           LoanConfig
+	CoinGecko      CoinGeckoConfig
+	Log            LogConfig
+	Redis          RedisConfig          `mapstructure:"redis"`
+	Blockchain     BlockchainConfig     `mapstructure:"blockchain"`
+	PriceStream    PriceStreamConfig    `mapstructure:"price_stream"`
+	DepositWatcher DepositWatcherConfig `mapstructure:"deposit_watcher"`
+	Confirmations  ConfirmationConfig   `mapstructure:"confirmation_tracker"`
+	HDWallet       HDWalletConfig       `mapstructure:"hd_wallet"`
+	KeyManagement  KeyManagementConfig  `mapstructure:"key_management"`
+	Withdrawals    WithdrawalConfig     `mapstructure:"withdrawals"`
+	AddressBook    AddressBookConfig    `mapstructure:"withdrawal_addresses"`
+	Reconciliation ReconciliationConfig `mapstructure:"reconciliation"`
+	Stablecoins    StablecoinConfig     `mapstructure:"stablecoins"`
+	MFA            MFAConfig            `mapstructure:"mfa"`
+	LoginThrottle  LoginThrottleConfig  `mapstructure:"login_throttle"`
+	RateLimit      RateLimitConfig      `mapstructure:"rate_limit"`
+	Approvals      ApprovalsConfig      `mapstructure:"approvals"`
+	APIKeys        APIKeysConfig        `mapstructure:"api_keys"`
+	Email          EmailConfig          `mapstructure:"email"`
 }
 
 type AppConfig struct {
@@ -32,6 +49,9 @@ type ServerConfig struct {
 	WriteTimeout   time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout    time.Duration `mapstructure:"idle_timeout"`
 	AllowedOrigins string        `mapstructure:"allowed_origins"`
+	// TrustedProxies are the load balancers, by IP or CIDR, whose
+	// X-Forwarded-For header is believed. Empty trusts none.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 type DatabaseConfig struct {
@@ -44,9 +64,65 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
+	// Secret is the legacy HS256 secret. It only signs when no keys are
+	// configured, which production refuses. Outside production the tokens it
+	// signed verify for as long as it is set; in production only until
+	// LegacySecretUntil.
 	Secret             string        `mapstructure:"secret"`
 	AccessTokenExpiry  time.Duration `mapstructure:"access_token_expiry"`
 	RefreshTokenExpiry time.Duration `mapstructure:"refresh_token_expiry"`
+	// LegacySecretUntil is the RFC 3339 cut-off after which production
+	// rejects HS256 tokens. Empty rejects them outright.
+	LegacySecretUntil string `mapstructure:"legacy_secret_until"`
+	// CutoffCacheTTL is how long a user's log-out-everywhere cutoff is
+	// cached. It bounds how late a revocation applies if the cache can't be
+	// invalidated, or on other instances when there is no Redis.
+	CutoffCacheTTL time.Duration `mapstructure:"cutoff_cache_ttl"`
+	// Keys are the asymmetric signing keys. The most recently activated key
+	// signs; every key verifies until it retires and is published in the
+	// JWKS, so a rotation is scheduled by adding the next key ahead of its
+	// active_from.
+	Keys []JWTKeyConfig `mapstructure:"keys"`
+}
+
+// JWTKeyConfig is an Ed25519 or RSA private key in PKCS#8 PEM form, given
+// inline or as a file.
+type JWTKeyConfig struct {
+	ID             string `mapstructure:"id"`
+	PrivateKey     string `mapstructure:"private_key"`
+	PrivateKeyFile string `mapstructure:"private_key_file"`
+	// ActiveFrom and RetireAt are RFC 3339 times; empty means always active
+	// and never retired.
+	ActiveFrom string `mapstructure:"active_from"`
+	RetireAt   string `mapstructure:"retire_at"`
+}
+
+// hasActiveKey reports whether a key is past its active_from and before its
+// retire_at at now. Malformed times count as inactive.
+func (c *JWTConfig) hasActiveKey(now time.Time) bool {
+	for _, key := range c.Keys {
+		if key.ActiveFrom != "" {
+			from, err := time.Parse(time.RFC3339, key.ActiveFrom)
+			if err != nil || from.After(now) {
+				continue
+			}
+		}
+		if key.RetireAt != "" {
+			retire, err := time.Parse(time.RFC3339, key.RetireAt)
+			if err != nil || !now.Before(retire) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// defaultJWTSecrets are the placeholder secrets shipped in the sample config
+// and formerly as the default.
+var defaultJWTSecrets = map[string]bool{
+	"your-secret-key-change-in-production":           true,
+	"your-super-secret-jwt-key-change-in-production": true,
 }
 
 type LoanConfig struct {
@@ -58,6 +134,8 @@ type LoanConfig struct {
 	RepaymentFrequencyDays int     `mapstructure:"repayment_frequency_days"`
 	GracePeriodDays        int     `mapstructure:"grace_period_days"`
 	PenaltyAPR             float64 `mapstructure:"penalty_apr"`
+	// Platform fee as a fraction of the loan amount; 0 disables it
+	OriginationFeeRate float64 `mapstructure:"origination_fee_rate"`
 }
 
 type RedisConfig struct {
@@ -73,8 +151,14 @@ func (c *RedisConfig) Address() string {
 }
 
 type CoinGeckoConfig struct {
-	APIKey  string `mapstructure:"api_key"`
-	BaseURL string `mapstructure:"base_url"`
+	APIKey           string        `mapstructure:"api_key"`
+	BaseURL          string        `mapstructure:"base_url"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+	MaxRetries       int           `mapstructure:"max_retries"`
+	RetryBaseDelay   time.Duration `mapstructure:"retry_base_delay"`
+	RetryMaxDelay    time.Duration `mapstructure:"retry_max_delay"`
+	BreakerThreshold int           `mapstructure:"breaker_threshold"`
+	BreakerCooldown  time.Duration `mapstructure:"breaker_cooldown"`
 }
 
 type LogConfig struct {
@@ -82,9 +166,248 @@ type LogConfig struct {
 }
 
 type BlockchainConfig struct {
-	EthereumRPC      string `mapstructure:"ethereum_rpc"`
-	MinConfirmations int    `mapstructure:"min_confirmations"`
-	UseNoopVerifier  bool   `mapstructure:"use_noop_verifier"`
+	UseNoopVerifier bool `mapstructure:"use_noop_verifier"`
+
+	// EVM networks keyed by name; a network without an RPC URL is disabled
+	Networks map[string]NetworkConfig `mapstructure:"networks"`
+
+	// Fraction of the quoted amount a deposit may fall short by
+	UnderpaymentTolerance float64 `mapstructure:"underpayment_tolerance"`
+
+	// Esplora-compatible REST endpoint used to verify BTC deposits
+	BitcoinAPIURL           string `mapstructure:"bitcoin_api_url"`
+	BitcoinMinConfirmations int    `mapstructure:"bitcoin_min_confirmations"`
+}
+
+// NetworkConfig describes an EVM-compatible chain deposits are accepted on.
+type NetworkConfig struct {
+	RPCURL           string                 `mapstructure:"rpc_url"`
+	ChainID          int64                  `mapstructure:"chain_id"`
+	NativeSymbol     string                 `mapstructure:"native_symbol"`
+	MinConfirmations int                    `mapstructure:"min_confirmations"`
+	Tokens           map[string]TokenConfig `mapstructure:"tokens"`
+}
+
+// TokenConfig describes an ERC-20 collateral asset, keyed by symbol.
+type TokenConfig struct {
+	Contract string `mapstructure:"contract"`
+	Decimals uint8  `mapstructure:"decimals"`
+}
+
+// HDWalletConfig holds the account-level extended public keys deposit
+// addresses are derived from. Private keys never reach the server.
+type HDWalletConfig struct {
+	// Accounts keyed by asset symbol; an asset without an xpub has no deposit addresses
+	Accounts map[string]HDAccountConfig `mapstructure:"accounts"`
+	// Bitcoin address encoding: mainnet, testnet or regtest
+	BitcoinNetwork string `mapstructure:"bitcoin_network"`
+}
+
+// HDAccountConfig is an account xpub and the path it was exported at,
+// e.g. m/84'/0'/0' for BTC or m/44'/60'/0' for ETH.
+type HDAccountConfig struct {
+	XPub string `mapstructure:"xpub"`
+	Path string `mapstructure:"path"`
+}
+
+// KeyManagementConfig selects how hot wallet private keys are encrypted at
+// rest. The local provider wraps per-row data keys with AES-256-GCM master
+// keys; any other provider must be registered with pkg/keymanager.
+type KeyManagementConfig struct {
+	Provider string `mapstructure:"provider"`
+	// Master key new data keys are wrapped under
+	ActiveKeyID string `mapstructure:"active_key_id"`
+	// Base64 32-byte master keys keyed by id; keep retired keys until rotation has re-wrapped their rows
+	MasterKeys map[string]string `mapstructure:"master_keys"`
+	// Single master key for active_key_id, usually supplied as LOANEE_MASTER_KEY
+	MasterKey string `mapstructure:"master_key"`
+}
+
+type DepositWatcherConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	PollInterval     time.Duration `mapstructure:"poll_interval"`
+	MaxBlocksPerScan uint64        `mapstructure:"max_blocks_per_scan"`
+}
+
+// ConfirmationConfig controls how long locked collateral is re-checked for
+// reorganisations before its loan may be disbursed.
+type ConfirmationConfig struct {
+	Enabled              bool             `mapstructure:"enabled"`
+	PollInterval         time.Duration    `mapstructure:"poll_interval"`
+	DefaultFinalityDepth int64            `mapstructure:"default_finality_depth"`
+	FinalityDepth        map[string]int64 `mapstructure:"finality_depth"`
+}
+
+// Depth returns the confirmation count after which a deposit of asset is
+// final, falling back to the default for assets without an entry.
+func (c *ConfirmationConfig) Depth(asset string) int64 {
+	for symbol, depth := range c.FinalityDepth {
+		if strings.EqualFold(symbol, asset) && depth > 0 {
+			return depth
+		}
+	}
+	if c.DefaultFinalityDepth <= 0 {
+		return 12
+	}
+	return c.DefaultFinalityDepth
+}
+
+// WithdrawalConfig controls the worker that returns released collateral
+// on-chain from the hot wallets.
+type WithdrawalConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Replace a transaction with a higher fee after it sits unmined this long
+	BumpAfter   time.Duration `mapstructure:"bump_after"`
+	BumpPercent int64         `mapstructure:"bump_percent"`
+	// Ceiling on the fee cap of any withdrawal; 0 means unbounded
+	MaxFeePerGasGwei int64 `mapstructure:"max_fee_per_gas_gwei"`
+	// Signed transactions per withdrawal, fee bumps included
+	MaxAttempts int `mapstructure:"max_attempts"`
+}
+
+// AddressBookConfig controls how new withdrawal addresses are whitelisted.
+type AddressBookConfig struct {
+	// Wait between confirming an address and sending funds to it
+	CoolingOff time.Duration `mapstructure:"cooling_off"`
+	// Lifetime of the emailed confirmation token
+	ConfirmationTTL time.Duration `mapstructure:"confirmation_ttl"`
+}
+
+// ReconciliationConfig controls the worker that syncs on-chain wallet
+// balances and compares custody against outstanding collateral.
+type ReconciliationConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+	// Relative difference between custody and the books tolerated before a
+	// discrepancy is raised
+	Tolerance float64 `mapstructure:"tolerance"`
+}
+
+// StablecoinConfig sets the peg rules for stablecoin collateral. While an
+// asset trades outside its band, new loans against it are suspended and its
+// collateral is valued with the haircut.
+type StablecoinConfig struct {
+	Assets   []string `mapstructure:"assets"`
+	Currency string   `mapstructure:"currency"`
+	Peg      float64  `mapstructure:"peg"`
+	// Relative deviation from the peg tolerated, e.g. 0.02 for ±2%
+	Band          float64       `mapstructure:"band"`
+	Haircut       float64       `mapstructure:"haircut"`
+	CheckInterval time.Duration `mapstructure:"check_interval"`
+}
+
+// MFAConfig controls TOTP two-factor authentication. Secrets are sealed by
+// the key manager, so enrollment is unavailable without a master key.
+type MFAConfig struct {
+	// Issuer is the account name authenticator apps display
+	Issuer string `mapstructure:"issuer"`
+	// ChallengeTTL bounds how long a login may wait for its second factor
+	ChallengeTTL time.Duration `mapstructure:"challenge_ttl"`
+	// StepUpTTL is how long one step-up verification covers sensitive actions
+	StepUpTTL time.Duration `mapstructure:"step_up_ttl"`
+	// Skew is how many 30s steps either side of now a code is accepted for
+	Skew          int `mapstructure:"skew"`
+	RecoveryCodes int `mapstructure:"recovery_codes"`
+	// RequireForSensitiveActions refuses sensitive actions to users who have
+	// not enrolled, instead of letting them through without step-up
+	RequireForSensitiveActions bool `mapstructure:"require_for_sensitive_actions"`
+}
+
+// LoginThrottleConfig limits guesses at passwords, second factors,
+// verification codes and reset tokens, per account and per IP.
+type LoginThrottleConfig struct {
+	AccountMaxFailures int           `mapstructure:"account_max_failures"`
+	AccountWindow      time.Duration `mapstructure:"account_window"`
+	AccountLockout     time.Duration `mapstructure:"account_lockout"`
+	IPMaxFailures      int           `mapstructure:"ip_max_failures"`
+	IPWindow           time.Duration `mapstructure:"ip_window"`
+	IPLockout          time.Duration `mapstructure:"ip_lockout"`
+	// Failures tolerated before each further one doubles the wait, from
+	// BaseDelay up to MaxDelay
+	FreeFailures int           `mapstructure:"free_failures"`
+	BaseDelay    time.Duration `mapstructure:"base_delay"`
+	MaxDelay     time.Duration `mapstructure:"max_delay"`
+}
+
+// RateLimitConfig caps request rates per user, API key or client IP. Each
+// route group has its own limit; a group without one is not limited. The ip
+// group limits each client IP before authentication on every route that
+// needs it.
+type RateLimitConfig struct {
+	Enabled bool                     `mapstructure:"enabled"`
+	Groups  map[string]RateLimitRule `mapstructure:"groups"`
+}
+
+// RateLimitRule allows Requests per Window, which may arrive in one burst.
+type RateLimitRule struct {
+	Requests int           `mapstructure:"requests"`
+	Window   time.Duration `mapstructure:"window"`
+}
+
+// ApprovalsConfig puts high-value admin actions under maker-checker control.
+// Thresholds maps an action (loan_approve, loan_disburse, collateral_release)
+// to the value, per fiat currency, from which a second administrator must
+// approve it. An action without thresholds never needs one; one valued in a
+// currency it has no threshold for always does.
+type ApprovalsConfig struct {
+	Enabled    bool                          `mapstructure:"enabled"`
+	TTL        time.Duration                 `mapstructure:"ttl"`
+	Thresholds map[string]map[string]float64 `mapstructure:"thresholds"`
+}
+
+// APIKeysConfig bounds the keys partners' backends authenticate with.
+type APIKeysConfig struct {
+	MaxPerUser int `mapstructure:"max_per_user"`
+	// DefaultTTL applies when a key is created without an expiry; MaxTTL
+	// caps the expiry a key may ask for.
+	DefaultTTL time.Duration `mapstructure:"default_ttl"`
+	MaxTTL     time.Duration `mapstructure:"max_ttl"`
+	// LastUsedInterval limits how often a key's last use is written back.
+	LastUsedInterval time.Duration `mapstructure:"last_used_interval"`
+}
+
+// EmailConfig selects how queued emails are delivered. Driver "smtp" sends
+// them; "file" writes them to FileDir and "memory" keeps them in process,
+// for development and tests.
+type EmailConfig struct {
+	Driver string `mapstructure:"driver"`
+	// From is the sender, optionally with a display name.
+	From string `mapstructure:"from"`
+	// LinkBaseURL is the frontend that links in emails point to.
+	LinkBaseURL string            `mapstructure:"link_base_url"`
+	FileDir     string            `mapstructure:"file_dir"`
+	SMTP        SMTPConfig        `mapstructure:"smtp"`
+	Outbox      EmailOutboxConfig `mapstructure:"outbox"`
+}
+
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	// ImplicitTLS connects over TLS from the start (port 465); otherwise
+	// STARTTLS is required.
+	ImplicitTLS bool          `mapstructure:"implicit_tls"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+}
+
+// EmailOutboxConfig tunes the dispatcher that delivers queued emails.
+type EmailOutboxConfig struct {
+	PollInterval   time.Duration `mapstructure:"poll_interval"`
+	BatchSize      int           `mapstructure:"batch_size"`
+	MaxAttempts    int           `mapstructure:"max_attempts"`
+	RetryBaseDelay time.Duration `mapstructure:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `mapstructure:"retry_max_delay"`
+}
+
+type PriceStreamConfig struct {
+	PollInterval          time.Duration `mapstructure:"poll_interval"`
+	HeartbeatInterval     time.Duration `mapstructure:"heartbeat_interval"`
+	Currencies            []string      `mapstructure:"currencies"`
+	MaxConnections        int           `mapstructure:"max_connections"`
+	MaxConnectionsPerUser int           `mapstructure:"max_connections_per_user"`
+	SubscriberBuffer      int           `mapstructure:"subscriber_buffer"`
 }
 
 func Load() (*Config, error) {
@@ -113,9 +436,54 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// validate refuses malformed settings, and settings that are unsafe to run
+// in production.
+func (c *Config) validate() error {
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("server.trusted_proxies: %q is not an IP address or CIDR range", proxy)
+			}
+		}
+	}
+
+	// There is no default secret, so a deployment using only keys need not
+	// set one; with neither, no token could be signed.
+	if c.JWT.Secret == "" && len(c.JWT.Keys) == 0 {
+		return fmt.Errorf("jwt: configure jwt.keys or, outside production, jwt.secret")
+	}
+
+	if c.App.Environment != "production" {
+		return nil
+	}
+
+	if defaultJWTSecrets[c.JWT.Secret] {
+		return fmt.Errorf("jwt.secret is still the default placeholder; set a real secret or remove it")
+	}
+	if len(c.JWT.Keys) == 0 {
+		return fmt.Errorf("jwt.keys must configure at least one asymmetric signing key in production")
+	}
+	if !c.JWT.hasActiveKey(time.Now()) {
+		return fmt.Errorf("jwt.keys has no key active now; check active_from and retire_at")
+	}
+	if c.JWT.LegacySecretUntil != "" {
+		if _, err := time.Parse(time.RFC3339, c.JWT.LegacySecretUntil); err != nil {
+			return fmt.Errorf("jwt.legacy_secret_until is not an RFC 3339 time: %w", err)
+		}
+	}
+	if c.Email.Driver != "smtp" {
+		return fmt.Errorf("email.driver must be smtp in production, got %q", c.Email.Driver)
+	}
+	return nil
+}
+
 func setDefaults() {
 	// App defaults
 	viper.SetDefault("app.name", "loanee")
@@ -146,9 +514,9 @@ func setDefaults() {
 	viper.SetDefault("redis.db", 0)
 
 	// JWT defaults
-	viper.SetDefault("jwt.secret", "your-secret-key-change-in-production")
 	viper.SetDefault("jwt.access_token_expiry", 15*time.Minute)
 	viper.SetDefault("jwt.refresh_token_expiry", 7*24*time.Hour)
+	viper.SetDefault("jwt.cutoff_cache_ttl", 30*time.Second)
 
 	// Loan defaults
 	viper.SetDefault("loan.default_ltv", 0.7)
@@ -159,17 +527,193 @@ func setDefaults() {
 	viper.SetDefault("loan.repayment_frequency_days", 30)
 	viper.SetDefault("loan.grace_period_days", 3)
 	viper.SetDefault("loan.penalty_apr", 15.0)
+	viper.SetDefault("loan.origination_fee_rate", 0.0)
 
 	// CoinGecko defaults
 	viper.SetDefault("coingecko.base_url", "https://api.coingecko.com/api/v3")
+	viper.SetDefault("coingecko.timeout", 10*time.Second)
+	viper.SetDefault("coingecko.max_retries", 3)
+	viper.SetDefault("coingecko.retry_base_delay", 250*time.Millisecond)
+	viper.SetDefault("coingecko.retry_max_delay", 5*time.Second)
+	viper.SetDefault("coingecko.breaker_threshold", 5)
+	viper.SetDefault("coingecko.breaker_cooldown", 30*time.Second)
 
 	// Log defaults
 	viper.SetDefault("log.level", "info")
 
 	// Blockchain defaults
-	viper.SetDefault("blockchain.ethereum_rpc", "")
-	viper.SetDefault("blockchain.min_confirmations", 3)
 	viper.SetDefault("blockchain.use_noop_verifier", true)
+	viper.SetDefault("blockchain.underpayment_tolerance", 0.001)
+	viper.SetDefault("blockchain.bitcoin_api_url", "")
+	viper.SetDefault("blockchain.bitcoin_min_confirmations", 2)
+	viper.SetDefault("blockchain.networks", map[string]interface{}{
+		"ethereum": map[string]interface{}{
+			"rpc_url":           "",
+			"chain_id":          1,
+			"native_symbol":     "ETH",
+			"min_confirmations": 3,
+			"tokens": map[string]interface{}{
+				"usdt": map[string]interface{}{
+					"contract": "0xdAC17F958D2ee523a2206206994597C13D831ec7",
+					"decimals": 6,
+				},
+			},
+		},
+		"bsc": map[string]interface{}{
+			"rpc_url":           "",
+			"chain_id":          56,
+			"native_symbol":     "BNB",
+			"min_confirmations": 15,
+			"tokens": map[string]interface{}{
+				"usdt": map[string]interface{}{
+					"contract": "0x55d398326f99059fF775485246999027B3197955",
+					"decimals": 18,
+				},
+			},
+		},
+		"polygon": map[string]interface{}{
+			"rpc_url":           "",
+			"chain_id":          137,
+			"native_symbol":     "MATIC",
+			"min_confirmations": 64,
+		},
+		"arbitrum": map[string]interface{}{
+			"rpc_url":           "",
+			"chain_id":          42161,
+			"native_symbol":     "ETH",
+			"min_confirmations": 20,
+			"tokens": map[string]interface{}{
+				"usdt": map[string]interface{}{
+					"contract": "0xFd086bC7CD5C481DCC9C85ebE478A1C0b69FCbb9",
+					"decimals": 6,
+				},
+			},
+		},
+	})
+
+	// Price stream defaults
+	viper.SetDefault("price_stream.poll_interval", 15*time.Second)
+	viper.SetDefault("price_stream.heartbeat_interval", 20*time.Second)
+	viper.SetDefault("price_stream.currencies", []string{"USD", "NGN"})
+	viper.SetDefault("price_stream.max_connections", 1000)
+	viper.SetDefault("price_stream.max_connections_per_user", 3)
+	viper.SetDefault("price_stream.subscriber_buffer", 4)
+
+	// Deposit watcher defaults
+	viper.SetDefault("deposit_watcher.enabled", true)
+	viper.SetDefault("deposit_watcher.poll_interval", 15*time.Second)
+	viper.SetDefault("deposit_watcher.max_blocks_per_scan", 100)
+
+	// HD wallet defaults: one hardened account per asset so deposit
+	// addresses never repeat across assets
+	viper.SetDefault("hd_wallet.bitcoin_network", "mainnet")
+	viper.SetDefault("hd_wallet.accounts", map[string]interface{}{
+		"btc":   map[string]interface{}{"xpub": "", "path": "m/84'/0'/0'"},
+		"eth":   map[string]interface{}{"xpub": "", "path": "m/44'/60'/0'"},
+		"usdt":  map[string]interface{}{"xpub": "", "path": "m/44'/60'/1'"},
+		"bnb":   map[string]interface{}{"xpub": "", "path": "m/44'/60'/2'"},
+		"matic": map[string]interface{}{"xpub": "", "path": "m/44'/60'/3'"},
+	})
+
+	// Key management defaults
+	viper.SetDefault("key_management.provider", "local")
+	viper.SetDefault("key_management.active_key_id", "local-1")
+	viper.SetDefault("key_management.master_key", "")
+	_ = viper.BindEnv("key_management.master_key", "LOANEE_MASTER_KEY")
+
+	// Withdrawal defaults
+	viper.SetDefault("withdrawals.enabled", true)
+	viper.SetDefault("withdrawals.poll_interval", 15*time.Second)
+	viper.SetDefault("withdrawals.bump_after", 5*time.Minute)
+	viper.SetDefault("withdrawals.bump_percent", 20)
+	viper.SetDefault("withdrawals.max_fee_per_gas_gwei", 500)
+	viper.SetDefault("withdrawals.max_attempts", 5)
+
+	// Withdrawal address book defaults
+	viper.SetDefault("withdrawal_addresses.cooling_off", 24*time.Hour)
+	viper.SetDefault("withdrawal_addresses.confirmation_ttl", time.Hour)
+
+	// Reconciliation defaults
+	viper.SetDefault("reconciliation.enabled", true)
+	viper.SetDefault("reconciliation.interval", 10*time.Minute)
+	viper.SetDefault("reconciliation.tolerance", 0.001)
+
+	// Stablecoin peg defaults
+	viper.SetDefault("stablecoins.assets", []string{"USDT"})
+	viper.SetDefault("stablecoins.currency", "USD")
+	viper.SetDefault("stablecoins.peg", 1.0)
+	viper.SetDefault("stablecoins.band", 0.02)
+	viper.SetDefault("stablecoins.haircut", 0.1)
+	viper.SetDefault("stablecoins.check_interval", time.Minute)
+
+	// MFA defaults
+	viper.SetDefault("mfa.issuer", "Loanee")
+	viper.SetDefault("mfa.challenge_ttl", 5*time.Minute)
+	viper.SetDefault("mfa.step_up_ttl", 5*time.Minute)
+	viper.SetDefault("mfa.skew", 1)
+	viper.SetDefault("mfa.recovery_codes", 10)
+	viper.SetDefault("mfa.require_for_sensitive_actions", false)
+
+	// Login throttle defaults
+	viper.SetDefault("login_throttle.account_max_failures", 5)
+	viper.SetDefault("login_throttle.account_window", 15*time.Minute)
+	viper.SetDefault("login_throttle.account_lockout", 15*time.Minute)
+	viper.SetDefault("login_throttle.ip_max_failures", 50)
+	viper.SetDefault("login_throttle.ip_window", 15*time.Minute)
+	viper.SetDefault("login_throttle.ip_lockout", 15*time.Minute)
+	viper.SetDefault("login_throttle.free_failures", 2)
+	viper.SetDefault("login_throttle.base_delay", time.Second)
+	viper.SetDefault("login_throttle.max_delay", 30*time.Second)
+
+	// Rate limit defaults
+	viper.SetDefault("rate_limit.enabled", true)
+	viper.SetDefault("rate_limit.groups.auth.requests", 20)
+	viper.SetDefault("rate_limit.groups.auth.window", time.Minute)
+	viper.SetDefault("rate_limit.groups.api.requests", 120)
+	viper.SetDefault("rate_limit.groups.api.window", time.Minute)
+	viper.SetDefault("rate_limit.groups.admin.requests", 300)
+	viper.SetDefault("rate_limit.groups.admin.window", time.Minute)
+	viper.SetDefault("rate_limit.groups.ip.requests", 600)
+	viper.SetDefault("rate_limit.groups.ip.window", time.Minute)
+
+	// Dual control defaults
+	viper.SetDefault("approvals.enabled", true)
+	viper.SetDefault("approvals.ttl", 24*time.Hour)
+	for _, action := range []string{"loan_approve", "loan_disburse", "collateral_release"} {
+		viper.SetDefault("approvals.thresholds."+action+".ngn", 5000000)
+		viper.SetDefault("approvals.thresholds."+action+".usd", 3500)
+	}
+
+	// API key defaults
+	viper.SetDefault("api_keys.max_per_user", 10)
+	viper.SetDefault("api_keys.default_ttl", 90*24*time.Hour)
+	viper.SetDefault("api_keys.max_ttl", 365*24*time.Hour)
+	viper.SetDefault("api_keys.last_used_interval", time.Minute)
+
+	// Email defaults
+	viper.SetDefault("email.driver", "file")
+	viper.SetDefault("email.from", "Loanee <no-reply@loanee.local>")
+	viper.SetDefault("email.link_base_url", "http://localhost:3000")
+	viper.SetDefault("email.file_dir", "tmp/mail")
+	viper.SetDefault("email.smtp.port", 587)
+	viper.SetDefault("email.smtp.timeout", 30*time.Second)
+	viper.SetDefault("email.outbox.poll_interval", 5*time.Second)
+	viper.SetDefault("email.outbox.batch_size", 20)
+	viper.SetDefault("email.outbox.max_attempts", 8)
+	viper.SetDefault("email.outbox.retry_base_delay", 30*time.Second)
+	viper.SetDefault("email.outbox.retry_max_delay", time.Hour)
+
+	// Confirmation tracker defaults
+	viper.SetDefault("confirmation_tracker.enabled", true)
+	viper.SetDefault("confirmation_tracker.poll_interval", 30*time.Second)
+	viper.SetDefault("confirmation_tracker.default_finality_depth", 12)
+	viper.SetDefault("confirmation_tracker.finality_depth", map[string]interface{}{
+		"btc":   6,
+		"eth":   64,
+		"usdt":  64,
+		"bnb":   15,
+		"matic": 128,
+	})
 }
 
 func (c *DatabaseConfig) DSN() string {
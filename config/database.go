@@ -12,7 +12,10 @@ import (
 func NewDatabase(cfg *Config) (*gorm.DB, error) {
 	dsn := cfg.Database.DSN()
 
-	gormConfig := &gorm.Config{}
+	gormConfig := &gorm.Config{
+		// Surface unique violations as gorm.ErrDuplicatedKey
+		TranslateError: true,
+	}
 
 	// Set logger based on environment
 	if cfg.App.Environment == "development" {
@@ -0,0 +1,127 @@
+package addressbook
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/thoraf20/loanee/internal/wallet"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+// BitcoinNetwork is the network name BTC withdrawal addresses are kept under.
+const BitcoinNetwork = "bitcoin"
+
+// Chains validates withdrawal addresses against the networks collateral can
+// be returned on.
+type Chains struct {
+	bitcoin *chaincfg.Params
+	// evm maps each EVM network to the asset symbols it carries.
+	evm map[string]map[string]struct{}
+}
+
+// NewChains builds the validator from the configured Bitcoin network and the
+// assets carried by each EVM network.
+func NewChains(bitcoinNetwork string, evmAssets map[string][]string) (*Chains, error) {
+	params, err := wallet.BitcoinParams(bitcoinNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	evm := make(map[string]map[string]struct{}, len(evmAssets))
+	for network, assets := range evmAssets {
+		carried := make(map[string]struct{}, len(assets))
+		for _, asset := range assets {
+			carried[strings.ToUpper(asset)] = struct{}{}
+		}
+		evm[strings.ToLower(network)] = carried
+	}
+	return &Chains{bitcoin: params, evm: evm}, nil
+}
+
+// Normalize checks that address can receive asset on network and returns the
+// network and the address in canonical form. An empty network is resolved
+// when only one network carries the asset. Mixed-case EVM addresses must
+// carry a valid EIP-55 checksum.
+func (c *Chains) Normalize(asset, network, address string) (string, string, error) {
+	asset = strings.ToUpper(strings.TrimSpace(asset))
+	network = strings.ToLower(strings.TrimSpace(network))
+	address = strings.TrimSpace(address)
+
+	if asset == "BTC" {
+		if network != "" && network != BitcoinNetwork {
+			return "", "", fmt.Errorf("%w: BTC is only withdrawn on %s", e.ErrWithdrawalAddressInvalid, BitcoinNetwork)
+		}
+		canonical, err := c.bitcoinAddress(address)
+		if err != nil {
+			return "", "", err
+		}
+		return BitcoinNetwork, canonical, nil
+	}
+
+	network, err := c.evmNetwork(asset, network)
+	if err != nil {
+		return "", "", err
+	}
+	canonical, err := evmAddress(address)
+	if err != nil {
+		return "", "", err
+	}
+	return network, canonical, nil
+}
+
+func (c *Chains) bitcoinAddress(address string) (string, error) {
+	decoded, err := btcutil.DecodeAddress(address, c.bitcoin)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", e.ErrWithdrawalAddressInvalid, err)
+	}
+	if !decoded.IsForNet(c.bitcoin) {
+		return "", fmt.Errorf("%w: address is not for bitcoin %s", e.ErrWithdrawalAddressInvalid, c.bitcoin.Name)
+	}
+	return decoded.EncodeAddress(), nil
+}
+
+func (c *Chains) evmNetwork(asset, network string) (string, error) {
+	if network != "" {
+		if _, ok := c.evm[network][asset]; !ok {
+			return "", fmt.Errorf("%w: %s is not withdrawn on %q", e.ErrWithdrawalAddressInvalid, asset, network)
+		}
+		return network, nil
+	}
+
+	var candidates []string
+	for name, assets := range c.evm {
+		if _, ok := assets[asset]; ok {
+			candidates = append(candidates, name)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("%w: %s is not withdrawn on any network", e.ErrWithdrawalAddressInvalid, asset)
+	case 1:
+		return candidates[0], nil
+	default:
+		sort.Strings(candidates)
+		return "", fmt.Errorf("%w: network is required for %s (one of %s)", e.ErrWithdrawalAddressInvalid, asset, strings.Join(candidates, ", "))
+	}
+}
+
+func evmAddress(address string) (string, error) {
+	if !common.IsHexAddress(address) || !strings.HasPrefix(address, "0x") {
+		return "", fmt.Errorf("%w: not a 0x-prefixed 20-byte hex address", e.ErrWithdrawalAddressInvalid)
+	}
+	parsed := common.HexToAddress(address)
+	if parsed == (common.Address{}) {
+		return "", fmt.Errorf("%w: zero address", e.ErrWithdrawalAddressInvalid)
+	}
+
+	digits := address[2:]
+	mixedCase := strings.ToLower(digits) != digits && strings.ToUpper(digits) != digits
+	if mixedCase && parsed.Hex() != address {
+		return "", fmt.Errorf("%w: checksum mismatch", e.ErrWithdrawalAddressInvalid)
+	}
+	return parsed.Hex(), nil
+}
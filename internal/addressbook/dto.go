@@ -0,0 +1,13 @@
+package addressbook
+
+type AddRequest struct {
+	AssetSymbol string `json:"asset_symbol" validate:"required,oneof=BTC ETH USDT BNB MATIC"`
+	// Network is required for assets offered on several networks.
+	Network string `json:"network,omitempty"`
+	Address string `json:"address" validate:"required"`
+	Label   string `json:"label,omitempty" validate:"max=100"`
+}
+
+type ConfirmRequest struct {
+	Token string `json:"token" validate:"required"`
+}
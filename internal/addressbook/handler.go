@@ -0,0 +1,125 @@
+package addressbook
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/validator"
+)
+
+type Handler struct {
+	service   *Service
+	validator *validator.Validator
+	logger    zerolog.Logger
+}
+
+func NewHandler(service *Service, validator *validator.Validator, logger zerolog.Logger) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator,
+		logger:    logger.With().Str("component", "addressbook_handler").Logger(),
+	}
+}
+
+func (h *Handler) ListMine(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	addresses, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Msg("failed to list withdrawal addresses")
+		utils.InternalServerError(c, "failed to fetch withdrawal addresses", err.Error())
+		return
+	}
+
+	utils.OK(c, "withdrawal addresses retrieved", addresses)
+}
+
+func (h *Handler) Add(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var payload AddRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.BadRequest(c, "invalid payload", err.Error())
+		return
+	}
+	if err := h.validator.Validate(&payload); err != nil {
+		utils.BadRequest(c, "validation failed", err.Error())
+		return
+	}
+
+	address, err := h.service.Add(c.Request.Context(), userID, payload)
+	if err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Msg("failed to add withdrawal address")
+		respondError(c, "failed to add withdrawal address", err)
+		return
+	}
+
+	utils.Created(c, "withdrawal address added, check your email to confirm it", address)
+}
+
+func (h *Handler) Confirm(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var payload ConfirmRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.BadRequest(c, "invalid payload", err.Error())
+		return
+	}
+	if err := h.validator.Validate(&payload); err != nil {
+		utils.BadRequest(c, "validation failed", err.Error())
+		return
+	}
+
+	address, err := h.service.Confirm(c.Request.Context(), userID, payload.Token)
+	if err != nil {
+		h.logger.Warn().Err(err).Any("user_id", userID).Msg("failed to confirm withdrawal address")
+		respondError(c, "failed to confirm withdrawal address", err)
+		return
+	}
+
+	utils.OK(c, "withdrawal address confirmed", address)
+}
+
+func (h *Handler) Revoke(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	addressID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid withdrawal address id", err.Error())
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), userID, addressID); err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Any("address_id", addressID).Msg("failed to revoke withdrawal address")
+		respondError(c, "failed to revoke withdrawal address", err)
+		return
+	}
+
+	utils.NoContent(c)
+}
+
+func respondError(c *gin.Context, message string, err error) {
+	if appErr := e.GetAppError(err); appErr != nil {
+		utils.Error(c, appErr.StatusCode, message, err.Error())
+		return
+	}
+	utils.InternalServerError(c, message, err.Error())
+}
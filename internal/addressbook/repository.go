@@ -0,0 +1,95 @@
+package addressbook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, address *models.WithdrawalAddress) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.WithdrawalAddress, error)
+	// Find looks up an entry by its natural key, revoked entries included.
+	Find(ctx context.Context, userID uuid.UUID, asset, network, address string) (*models.WithdrawalAddress, error)
+	GetByConfirmationHash(ctx context.Context, hash string) (*models.WithdrawalAddress, error)
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.WithdrawalAddress, error)
+	Update(ctx context.Context, address *models.WithdrawalAddress) error
+}
+
+type repository struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewRepository(db *gorm.DB, logger zerolog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, address *models.WithdrawalAddress) error {
+	now := time.Now()
+	if address.ID == uuid.Nil {
+		address.ID = uuid.New()
+	}
+	address.CreatedAt = now
+	address.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(address).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return e.ErrWithdrawalAddressExists
+		}
+		return fmt.Errorf("failed to create withdrawal address: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*models.WithdrawalAddress, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *repository) Find(ctx context.Context, userID uuid.UUID, asset, network, address string) (*models.WithdrawalAddress, error) {
+	return r.first(ctx, "user_id = ? AND asset_symbol = ? AND network = ? AND address = ?", userID, asset, network, address)
+}
+
+func (r *repository) GetByConfirmationHash(ctx context.Context, hash string) (*models.WithdrawalAddress, error) {
+	return r.first(ctx, "confirmation_hash = ?", hash)
+}
+
+func (r *repository) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.WithdrawalAddress, error) {
+	var addresses []models.WithdrawalAddress
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status <> ?", userID, models.AddressRevoked).
+		Order("created_at ASC").
+		Find(&addresses).Error; err != nil {
+		return nil, fmt.Errorf("failed to list withdrawal addresses: %w", err)
+	}
+	return addresses, nil
+}
+
+func (r *repository) Update(ctx context.Context, address *models.WithdrawalAddress) error {
+	address.UpdatedAt = time.Now()
+	if err := r.db.WithContext(ctx).Save(address).Error; err != nil {
+		return fmt.Errorf("failed to update withdrawal address: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) first(ctx context.Context, query string, args ...interface{}) (*models.WithdrawalAddress, error) {
+	var address models.WithdrawalAddress
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&address).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch withdrawal address: %w", err)
+	}
+	return &address, nil
+}
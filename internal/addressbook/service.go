@@ -0,0 +1,213 @@
+package addressbook
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+// ConfirmationSender delivers the token that confirms a new address to the
+// account's email.
+type ConfirmationSender interface {
+	SendAddressConfirmation(ctx context.Context, userID uuid.UUID, address *models.WithdrawalAddress, token string) error
+}
+
+type Options struct {
+	// CoolingOff is how long a confirmed address waits before it can
+	// receive funds.
+	CoolingOff time.Duration
+	// ConfirmationTTL bounds how long an emailed token stays valid.
+	ConfirmationTTL time.Duration
+}
+
+// Service keeps each borrower's withdrawal address book. A new address must
+// be confirmed from the account's email and then sits out a cooling-off
+// period before released collateral may be sent to it.
+type Service struct {
+	repo   Repository
+	chains *Chains
+	sender ConfirmationSender
+	opts   Options
+	now    func() time.Time
+	logger zerolog.Logger
+}
+
+func NewService(repo Repository, chains *Chains, sender ConfirmationSender, opts Options, logger zerolog.Logger) *Service {
+	if opts.ConfirmationTTL <= 0 {
+		opts.ConfirmationTTL = time.Hour
+	}
+
+	return &Service{
+		repo:   repo,
+		chains: chains,
+		sender: sender,
+		opts:   opts,
+		now:    time.Now,
+		logger: logger.With().Str("component", "addressbook_service").Logger(),
+	}
+}
+
+func (s *Service) List(ctx context.Context, userID uuid.UUID) ([]models.WithdrawalAddress, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// Add registers an address and emails a confirmation token. Adding an
+// address that is still unconfirmed or was revoked issues a fresh token.
+func (s *Service) Add(ctx context.Context, userID uuid.UUID, req AddRequest) (*models.WithdrawalAddress, error) {
+	asset := strings.ToUpper(req.AssetSymbol)
+	network, address, err := s.chains.Normalize(asset, req.Network, req.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	token, hash, err := newConfirmationToken()
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := s.now().Add(s.opts.ConfirmationTTL)
+
+	entry, err := s.repo.Find(ctx, userID, asset, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil && entry.Status == models.AddressConfirmed {
+		return nil, e.ErrWithdrawalAddressExists
+	}
+
+	if entry == nil {
+		entry = &models.WithdrawalAddress{
+			ID:          uuid.New(),
+			UserID:      userID,
+			AssetSymbol: asset,
+			Network:     network,
+			Address:     address,
+		}
+	}
+	entry.Label = strings.TrimSpace(req.Label)
+	entry.Status = models.AddressPendingConfirmation
+	entry.ConfirmationHash = &hash
+	entry.ConfirmationExpiresAt = &expiresAt
+	entry.ConfirmedAt = nil
+	entry.UsableFrom = nil
+	entry.RevokedAt = nil
+
+	if entry.CreatedAt.IsZero() {
+		err = s.repo.Create(ctx, entry)
+	} else {
+		err = s.repo.Update(ctx, entry)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.sender.SendAddressConfirmation(ctx, userID, entry, token); err != nil {
+		return nil, fmt.Errorf("failed to send address confirmation: %w", err)
+	}
+
+	s.logger.Info().
+		Any("user_id", userID).
+		Str("address_id", entry.ID.String()).
+		Str("asset", asset).
+		Str("network", network).
+		Msg("Withdrawal address added, awaiting confirmation")
+	return entry, nil
+}
+
+// Confirm accepts an emailed token and starts the cooling-off period.
+func (s *Service) Confirm(ctx context.Context, userID uuid.UUID, token string) (*models.WithdrawalAddress, error) {
+	entry, err := s.repo.GetByConfirmationHash(ctx, hashToken(strings.TrimSpace(token)))
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if entry == nil || entry.UserID != userID || entry.Status != models.AddressPendingConfirmation ||
+		entry.ConfirmationExpiresAt == nil || now.After(*entry.ConfirmationExpiresAt) {
+		return nil, e.ErrWithdrawalAddressConfirmation
+	}
+
+	usableFrom := now.Add(s.opts.CoolingOff)
+	entry.Status = models.AddressConfirmed
+	entry.ConfirmedAt = &now
+	entry.UsableFrom = &usableFrom
+	entry.ConfirmationHash = nil
+	entry.ConfirmationExpiresAt = nil
+	if err := s.repo.Update(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().
+		Any("user_id", userID).
+		Str("address_id", entry.ID.String()).
+		Time("usable_from", usableFrom).
+		Msg("Withdrawal address confirmed")
+	return entry, nil
+}
+
+// Revoke removes an address from the book. Re-adding it later starts the
+// confirmation and cooling-off over.
+func (s *Service) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	entry, err := s.owned(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	now := s.now()
+	entry.Status = models.AddressRevoked
+	entry.RevokedAt = &now
+	entry.ConfirmationHash = nil
+	entry.ConfirmationExpiresAt = nil
+	if err := s.repo.Update(ctx, entry); err != nil {
+		return err
+	}
+
+	s.logger.Info().Any("user_id", userID).Str("address_id", id.String()).Msg("Withdrawal address revoked")
+	return nil
+}
+
+// Usable returns the user's address if it may receive funds now.
+func (s *Service) Usable(ctx context.Context, userID, id uuid.UUID) (*models.WithdrawalAddress, error) {
+	entry, err := s.owned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.Usable(s.now()) {
+		return nil, e.ErrWithdrawalAddressNotUsable
+	}
+	return entry, nil
+}
+
+func (s *Service) owned(ctx context.Context, userID, id uuid.UUID) (*models.WithdrawalAddress, error) {
+	entry, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil || entry.UserID != userID || entry.Status == models.AddressRevoked {
+		return nil, e.ErrWithdrawalAddressNotFound
+	}
+	return entry, nil
+}
+
+// newConfirmationToken returns a random token and the hash stored in its
+// place, so a database leak does not expose usable tokens.
+func newConfirmationToken() (string, string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,217 @@
+package addressbook
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+func TestNormalizeValidatesPerChain(t *testing.T) {
+	chains := testChains(t)
+
+	network, address, err := chains.Normalize("btc", "", "BC1QCR8TE4KR609GCAWUTMRZA0J4XV80JY8Z306FYU")
+	require.NoError(t, err)
+	require.Equal(t, BitcoinNetwork, network)
+	require.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", address)
+
+	network, address, err = chains.Normalize("ETH", "", "0x9858effd232b4033e47d90003d41ec34ecaeda94")
+	require.NoError(t, err)
+	require.Equal(t, "ethereum", network)
+	require.Equal(t, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", address)
+
+	network, _, err = chains.Normalize("USDT", "BSC", "0x9858EfFD232B4033E47d90003D41EC34EcaEda94")
+	require.NoError(t, err)
+	require.Equal(t, "bsc", network)
+
+	for _, tc := range []struct{ asset, network, address string }{
+		// One flipped letter case breaks the EIP-55 checksum.
+		{"ETH", "ethereum", "0x9858eFFD232B4033E47d90003D41EC34EcaEda94"},
+		{"ETH", "ethereum", "0x0000000000000000000000000000000000000000"},
+		{"ETH", "ethereum", "9858effd232b4033e47d90003d41ec34ecaeda94"},
+		{"ETH", "bsc", "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"},
+		// USDT is offered on two networks, so one must be named.
+		{"USDT", "", "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"},
+		// Testnet and malformed bech32 addresses.
+		{"BTC", "", "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx"},
+		{"BTC", "", "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyv"},
+		{"BTC", "ethereum", "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"},
+	} {
+		_, _, err := chains.Normalize(tc.asset, tc.network, tc.address)
+		require.ErrorIs(t, err, e.ErrWithdrawalAddressInvalid, "%s %s %s", tc.asset, tc.network, tc.address)
+	}
+}
+
+func TestAddressUsableAfterConfirmationAndCoolingOff(t *testing.T) {
+	service, sender := newTestService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	address, err := service.Add(ctx, userID, AddRequest{AssetSymbol: "eth", Address: "0x9858effd232b4033e47d90003d41ec34ecaeda94", Label: "ledger"})
+	require.NoError(t, err)
+	require.Equal(t, models.AddressPendingConfirmation, address.Status)
+	require.NotEmpty(t, sender.token)
+	require.NotContains(t, *address.ConfirmationHash, sender.token)
+
+	_, err = service.Usable(ctx, userID, address.ID)
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressNotUsable)
+
+	// Tokens are bound to the account that added the address.
+	_, err = service.Confirm(ctx, uuid.New(), sender.token)
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressConfirmation)
+
+	confirmed, err := service.Confirm(ctx, userID, sender.token)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(24*time.Hour), *confirmed.UsableFrom)
+	_, err = service.Confirm(ctx, userID, sender.token)
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressConfirmation)
+
+	_, err = service.Usable(ctx, userID, address.ID)
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressNotUsable)
+
+	now = now.Add(24 * time.Hour)
+	usable, err := service.Usable(ctx, userID, address.ID)
+	require.NoError(t, err)
+	require.Equal(t, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", usable.Address)
+
+	_, err = service.Add(ctx, userID, AddRequest{AssetSymbol: "ETH", Address: usable.Address})
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressExists)
+}
+
+func TestExpiredConfirmationIsRejected(t *testing.T) {
+	service, sender := newTestService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	_, err := service.Add(ctx, userID, AddRequest{AssetSymbol: "BTC", Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"})
+	require.NoError(t, err)
+	stale := sender.token
+
+	now = now.Add(2 * time.Hour)
+	_, err = service.Confirm(ctx, userID, stale)
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressConfirmation)
+
+	// Adding it again issues a fresh token and retires the old one.
+	_, err = service.Add(ctx, userID, AddRequest{AssetSymbol: "BTC", Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"})
+	require.NoError(t, err)
+	require.NotEqual(t, stale, sender.token)
+	_, err = service.Confirm(ctx, userID, sender.token)
+	require.NoError(t, err)
+}
+
+func TestRevokedAddressStartsOver(t *testing.T) {
+	service, sender := newTestService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	request := AddRequest{AssetSymbol: "USDT", Network: "ethereum", Address: "0x9858EfFD232B4033E47d90003D41EC34EcaEda94"}
+	address, err := service.Add(ctx, userID, request)
+	require.NoError(t, err)
+	_, err = service.Confirm(ctx, userID, sender.token)
+	require.NoError(t, err)
+	now = now.Add(48 * time.Hour)
+
+	require.ErrorIs(t, service.Revoke(ctx, uuid.New(), address.ID), e.ErrWithdrawalAddressNotFound)
+	require.NoError(t, service.Revoke(ctx, userID, address.ID))
+	_, err = service.Usable(ctx, userID, address.ID)
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressNotFound)
+
+	readded, err := service.Add(ctx, userID, request)
+	require.NoError(t, err)
+	require.Equal(t, address.ID, readded.ID)
+	require.Equal(t, models.AddressPendingConfirmation, readded.Status)
+	require.Nil(t, readded.UsableFrom)
+}
+
+func newTestService(t *testing.T) (*Service, *recordingSender) {
+	t.Helper()
+	sender := &recordingSender{}
+	service := NewService(&memoryRepo{}, testChains(t), sender, Options{CoolingOff: 24 * time.Hour, ConfirmationTTL: time.Hour}, zerolog.Nop())
+	return service, sender
+}
+
+func testChains(t *testing.T) *Chains {
+	t.Helper()
+	chains, err := NewChains("mainnet", map[string][]string{
+		"ethereum": {"ETH", "USDT"},
+		"bsc":      {"BNB", "USDT"},
+	})
+	require.NoError(t, err)
+	return chains
+}
+
+type recordingSender struct {
+	token string
+}
+
+func (r *recordingSender) SendAddressConfirmation(ctx context.Context, userID uuid.UUID, address *models.WithdrawalAddress, token string) error {
+	r.token = token
+	return nil
+}
+
+type memoryRepo struct {
+	addresses []models.WithdrawalAddress
+}
+
+func (m *memoryRepo) Create(ctx context.Context, address *models.WithdrawalAddress) error {
+	address.CreatedAt = time.Now()
+	m.addresses = append(m.addresses, *address)
+	return nil
+}
+
+func (m *memoryRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.WithdrawalAddress, error) {
+	return m.find(func(a models.WithdrawalAddress) bool { return a.ID == id })
+}
+
+func (m *memoryRepo) Find(ctx context.Context, userID uuid.UUID, asset, network, address string) (*models.WithdrawalAddress, error) {
+	return m.find(func(a models.WithdrawalAddress) bool {
+		return a.UserID == userID && a.AssetSymbol == asset && a.Network == network && a.Address == address
+	})
+}
+
+func (m *memoryRepo) GetByConfirmationHash(ctx context.Context, hash string) (*models.WithdrawalAddress, error) {
+	return m.find(func(a models.WithdrawalAddress) bool {
+		return a.ConfirmationHash != nil && strings.EqualFold(*a.ConfirmationHash, hash)
+	})
+}
+
+func (m *memoryRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.WithdrawalAddress, error) {
+	var result []models.WithdrawalAddress
+	for _, address := range m.addresses {
+		if address.UserID == userID && address.Status != models.AddressRevoked {
+			result = append(result, address)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryRepo) Update(ctx context.Context, address *models.WithdrawalAddress) error {
+	for i := range m.addresses {
+		if m.addresses[i].ID == address.ID {
+			m.addresses[i] = *address
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) find(match func(models.WithdrawalAddress) bool) (*models.WithdrawalAddress, error) {
+	for _, address := range m.addresses {
+		if match(address) {
+			copy := address
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
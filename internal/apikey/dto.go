@@ -0,0 +1,22 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/thoraf20/loanee/internal/models"
+)
+
+type CreateRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+	// AllowedIPs are addresses or CIDR ranges the key may be used from.
+	AllowedIPs []string `json:"allowed_ips" validate:"max=20"`
+	// ExpiresAt defaults to the configured lifetime when omitted.
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateResponse struct {
+	// Key is shown only in this response; it cannot be recovered later.
+	Key    string         `json:"key"`
+	APIKey *models.APIKey `json:"api_key"`
+}
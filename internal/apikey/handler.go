@@ -0,0 +1,143 @@
+package apikey
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/validator"
+)
+
+type Handler struct {
+	service   *Service
+	validator *validator.Validator
+	logger    zerolog.Logger
+}
+
+func NewHandler(service *Service, validator *validator.Validator, logger zerolog.Logger) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator,
+		logger:    logger.With().Str("component", "apikey_handler").Logger(),
+	}
+}
+
+func (h *Handler) ListMine(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	keys, err := h.service.List(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Msg("failed to list API keys")
+		utils.InternalServerError(c, "failed to fetch API keys", err.Error())
+		return
+	}
+
+	utils.OK(c, "API keys retrieved", keys)
+}
+
+// Create issues a key. The key itself is in this response only.
+func (h *Handler) Create(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var payload CreateRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.BadRequest(c, "invalid payload", err.Error())
+		return
+	}
+	if err := h.validator.Validate(&payload); err != nil {
+		utils.BadRequest(c, "validation failed", err.Error())
+		return
+	}
+
+	created, err := h.service.Create(c.Request.Context(), userID, payload)
+	if err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Msg("failed to create API key")
+		respondError(c, "failed to create API key", err)
+		return
+	}
+
+	utils.Created(c, "API key created, store it now as it will not be shown again", created)
+}
+
+func (h *Handler) Revoke(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid API key id", err.Error())
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), userID, keyID); err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Any("api_key_id", keyID).Msg("failed to revoke API key")
+		respondError(c, "failed to revoke API key", err)
+		return
+	}
+
+	utils.NoContent(c)
+}
+
+// AdminList returns API keys, optionally filtered by ?user_id.
+func (h *Handler) AdminList(c *gin.Context) {
+	var userID *uuid.UUID
+	if raw := c.Query("user_id"); raw != "" {
+		parsed, err := uuid.Parse(raw)
+		if err != nil {
+			utils.BadRequest(c, "invalid user id", err.Error())
+			return
+		}
+		userID = &parsed
+	}
+
+	keys, err := h.service.AdminList(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list API keys")
+		utils.InternalServerError(c, "failed to fetch API keys", err.Error())
+		return
+	}
+
+	utils.OK(c, "API keys retrieved", keys)
+}
+
+func (h *Handler) AdminRevoke(c *gin.Context) {
+	actorID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid API key id", err.Error())
+		return
+	}
+
+	if err := h.service.AdminRevoke(c.Request.Context(), actorID, keyID); err != nil {
+		h.logger.Error().Err(err).Any("actor_id", actorID).Any("api_key_id", keyID).Msg("failed to revoke API key")
+		respondError(c, "failed to revoke API key", err)
+		return
+	}
+
+	utils.NoContent(c)
+}
+
+func respondError(c *gin.Context, message string, err error) {
+	if appErr := e.GetAppError(err); appErr != nil {
+		utils.Error(c, appErr.StatusCode, message, err.Error())
+		return
+	}
+	utils.InternalServerError(c, message, err.Error())
+}
@@ -0,0 +1,114 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	// List returns keys newest first, every user's when userID is nil.
+	List(ctx context.Context, userID *uuid.UUID) ([]models.APIKey, error)
+	// CountActive counts the user's keys that are neither revoked nor
+	// expired at now.
+	CountActive(ctx context.Context, userID uuid.UUID, now time.Time) (int64, error)
+	// Revoke marks an unrevoked key revoked and reports whether it did.
+	Revoke(ctx context.Context, id, by uuid.UUID, at time.Time) (bool, error)
+	TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time, ip string) error
+}
+
+type repository struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewRepository(db *gorm.DB, logger zerolog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, key *models.APIKey) error {
+	now := time.Now()
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	key.CreatedAt = now
+	key.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(key).Error; err != nil {
+		return fmt.Errorf("failed to create API key: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*models.APIKey, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *repository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	return r.first(ctx, "prefix = ?", prefix)
+}
+
+func (r *repository) List(ctx context.Context, userID *uuid.UUID) ([]models.APIKey, error) {
+	query := r.db.WithContext(ctx).Order("created_at DESC")
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var keys []models.APIKey
+	if err := query.Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *repository) CountActive(ctx context.Context, userID uuid.UUID, now time.Time) (int64, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", userID, now).
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	return count, nil
+}
+
+func (r *repository) Revoke(ctx context.Context, id, by uuid.UUID, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": at, "revoked_by": by, "updated_at": at})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time, ip string) error {
+	if err := r.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": at, "last_used_ip": ip}).Error; err != nil {
+		return fmt.Errorf("failed to record API key use: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) first(ctx context.Context, query string, args ...interface{}) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+	return &key, nil
+}
@@ -0,0 +1,337 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/internal/rbac"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+// keyPrefix starts every key, so leaked keys are easy to recognise and scan
+// for. A key reads lk_<id>_<secret>; the id part is stored as Prefix.
+const keyPrefix = "lk_"
+
+// Scopes are the permissions a key may carry. Admin permissions are left
+// out: those actions need an interactive session.
+var Scopes = []string{rbac.PermOwnLoansRead, rbac.PermOwnRepaymentsCreate}
+
+// PermissionChecker reports whether a user's role grants a permission.
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userID uuid.UUID, permission string) (bool, error)
+}
+
+type Options struct {
+	MaxPerUser int
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+	// LastUsedInterval limits how often a key's last use is written back.
+	LastUsedInterval time.Duration
+}
+
+// Service issues, authenticates and revokes API keys.
+type Service struct {
+	repo   Repository
+	perms  PermissionChecker
+	opts   Options
+	now    func() time.Time
+	logger zerolog.Logger
+}
+
+func NewService(repo Repository, perms PermissionChecker, opts Options, logger zerolog.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		perms:  perms,
+		opts:   opts,
+		now:    time.Now,
+		logger: logger.With().Str("component", "apikey_service").Logger(),
+	}
+}
+
+// Create issues a key for userID. Every scope must be one keys may carry and
+// granted to the user's role.
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, req CreateRequest) (*CreateResponse, error) {
+	scopes, err := s.checkScopes(ctx, userID, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedIPs, err := normalizeAllowedIPs(req.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	expiresAt, err := s.expiry(now, req.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.opts.MaxPerUser > 0 {
+		count, err := s.repo.CountActive(ctx, userID, now)
+		if err != nil {
+			return nil, err
+		}
+		if count >= int64(s.opts.MaxPerUser) {
+			return nil, e.ErrAPIKeyLimitReached
+		}
+	}
+
+	raw, prefix, secretHash, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+
+	key := &models.APIKey{
+		UserID:     userID,
+		Name:       strings.TrimSpace(req.Name),
+		Prefix:     prefix,
+		SecretHash: secretHash,
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("user_id", userID.String()).
+		Str("api_key_id", key.ID.String()).
+		Strs("scopes", scopes).
+		Msg("API key created")
+
+	return &CreateResponse{Key: raw, APIKey: key}, nil
+}
+
+// Authenticate resolves a presented key. Unknown, revoked and expired keys
+// all fail the same way, so a caller learns nothing about which it was.
+func (s *Service) Authenticate(ctx context.Context, raw, ip string) (*models.APIKey, error) {
+	prefix, secret, ok := parseKey(raw)
+	if !ok {
+		return nil, e.ErrAPIKeyInvalid
+	}
+
+	key, err := s.repo.GetByPrefix(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, e.ErrAPIKeyInvalid
+	}
+
+	now := s.now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+		return nil, e.ErrAPIKeyInvalid
+	}
+	if !ipAllowed(key.AllowedIPs, ip) {
+		s.logger.Warn().
+			Str("api_key_id", key.ID.String()).
+			Str("ip", ip).
+			Msg("API key used from an address outside its allow-list")
+		return nil, e.ErrAPIKeyIPNotAllowed
+	}
+
+	// Recording every use would write on every request
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= s.opts.LastUsedInterval {
+		if err := s.repo.TouchLastUsed(ctx, key.ID, now, ip); err != nil {
+			s.logger.Error().Err(err).Str("api_key_id", key.ID.String()).Msg("failed to record API key use")
+		} else {
+			key.LastUsedAt = &now
+			key.LastUsedIP = ip
+		}
+	}
+
+	return key, nil
+}
+
+// List returns the user's keys, revoked and expired ones included.
+func (s *Service) List(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	return s.repo.List(ctx, &userID)
+}
+
+// Revoke revokes one of the user's own keys.
+func (s *Service) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key == nil || key.UserID != userID {
+		return e.ErrAPIKeyNotFound
+	}
+	return s.revoke(ctx, key, userID)
+}
+
+// AdminList returns every user's keys, or one user's when userID is set.
+func (s *Service) AdminList(ctx context.Context, userID *uuid.UUID) ([]models.APIKey, error) {
+	return s.repo.List(ctx, userID)
+}
+
+// AdminRevoke revokes any user's key.
+func (s *Service) AdminRevoke(ctx context.Context, actorID, id uuid.UUID) error {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return e.ErrAPIKeyNotFound
+	}
+	return s.revoke(ctx, key, actorID)
+}
+
+// revoke is idempotent: revoking a revoked key succeeds.
+func (s *Service) revoke(ctx context.Context, key *models.APIKey, by uuid.UUID) error {
+	revoked, err := s.repo.Revoke(ctx, key.ID, by, s.now())
+	if err != nil {
+		return err
+	}
+	if revoked {
+		s.logger.Info().
+			Str("api_key_id", key.ID.String()).
+			Str("user_id", key.UserID.String()).
+			Str("revoked_by", by.String()).
+			Msg("API key revoked")
+	}
+	return nil
+}
+
+func (s *Service) checkScopes(ctx context.Context, userID uuid.UUID, requested []string) ([]string, error) {
+	seen := make(map[string]bool, len(requested))
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+
+		if !isScope(scope) {
+			return nil, e.ErrAPIKeyScopeNotAllowed
+		}
+		granted, err := s.perms.HasPermission(ctx, userID, scope)
+		if err != nil {
+			return nil, err
+		}
+		if !granted {
+			return nil, e.ErrAPIKeyScopeNotAllowed
+		}
+		scopes = append(scopes, scope)
+	}
+
+	if len(scopes) == 0 {
+		return nil, e.ErrAPIKeyScopeNotAllowed
+	}
+	return scopes, nil
+}
+
+func (s *Service) expiry(now time.Time, requested *time.Time) (*time.Time, error) {
+	if requested == nil {
+		if s.opts.DefaultTTL <= 0 {
+			return nil, nil
+		}
+		expiresAt := now.Add(s.opts.DefaultTTL)
+		return &expiresAt, nil
+	}
+
+	if !requested.After(now) {
+		return nil, e.NewBadRequestError("expires_at must be in the future")
+	}
+	if s.opts.MaxTTL > 0 && requested.Sub(now) > s.opts.MaxTTL {
+		return nil, e.NewBadRequestError(fmt.Sprintf("expires_at may be at most %s away", s.opts.MaxTTL))
+	}
+	expiresAt := requested.UTC()
+	return &expiresAt, nil
+}
+
+func isScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeAllowedIPs(entries []string) ([]string, error) {
+	var allowed []string
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if ip := net.ParseIP(entry); ip != nil {
+			allowed = append(allowed, ip.String())
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, e.NewBadRequestError(fmt.Sprintf("invalid IP address or CIDR range %q", entry))
+		}
+		allowed = append(allowed, network.String())
+	}
+	return allowed, nil
+}
+
+func ipAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	client := net.ParseIP(ip)
+	if client == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(client) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(client) {
+			return true
+		}
+	}
+	return false
+}
+
+// newKey returns a new key, the prefix it is looked up by and the hash of
+// its secret. The secret has 256 bits of entropy, so a plain hash is enough.
+func newKey() (raw, prefix, secretHash string, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	prefix = keyPrefix + hex.EncodeToString(id)
+	secretHex := hex.EncodeToString(secret)
+	return prefix + "_" + secretHex, prefix, hashSecret(secretHex), nil
+}
+
+func parseKey(raw string) (prefix, secret string, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(raw), keyPrefix)
+	if !found {
+		return "", "", false
+	}
+	id, secret, found := strings.Cut(rest, "_")
+	if !found || id == "" || secret == "" {
+		return "", "", false
+	}
+	return keyPrefix + id, secret, true
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,194 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/internal/rbac"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+func TestKeyAuthenticatesUntilRevoked(t *testing.T) {
+	service, repo := newTestService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	created, err := service.Create(ctx, userID, CreateRequest{
+		Name:       "partner",
+		Scopes:     []string{rbac.PermOwnLoansRead, rbac.PermOwnLoansRead},
+		AllowedIPs: []string{"10.0.0.0/8", "192.0.2.7"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{rbac.PermOwnLoansRead}, created.APIKey.Scopes)
+	require.NotContains(t, created.APIKey.SecretHash, created.Key)
+	require.Equal(t, service.now().Add(time.Hour), *created.APIKey.ExpiresAt)
+
+	key, err := service.Authenticate(ctx, created.Key, "10.1.2.3")
+	require.NoError(t, err)
+	require.Equal(t, userID, key.UserID)
+	require.Equal(t, "10.1.2.3", repo.keys[0].LastUsedIP)
+
+	_, err = service.Authenticate(ctx, created.Key, "192.0.2.7")
+	require.NoError(t, err)
+	_, err = service.Authenticate(ctx, created.Key, "192.0.2.8")
+	require.ErrorIs(t, err, e.ErrAPIKeyIPNotAllowed)
+
+	// A key with its secret altered is unknown
+	_, err = service.Authenticate(ctx, created.Key+"0", "10.1.2.3")
+	require.ErrorIs(t, err, e.ErrAPIKeyInvalid)
+	_, err = service.Authenticate(ctx, "not-a-key", "10.1.2.3")
+	require.ErrorIs(t, err, e.ErrAPIKeyInvalid)
+
+	require.ErrorIs(t, service.Revoke(ctx, uuid.New(), key.ID), e.ErrAPIKeyNotFound)
+	require.NoError(t, service.Revoke(ctx, userID, key.ID))
+	require.NoError(t, service.Revoke(ctx, userID, key.ID))
+	_, err = service.Authenticate(ctx, created.Key, "10.1.2.3")
+	require.ErrorIs(t, err, e.ErrAPIKeyInvalid)
+}
+
+func TestKeyExpires(t *testing.T) {
+	service, _ := newTestService()
+	ctx := context.Background()
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	expiresAt := now.Add(30 * time.Minute)
+	created, err := service.Create(ctx, uuid.New(), CreateRequest{Name: "short", Scopes: []string{rbac.PermOwnRepaymentsCreate}, ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+
+	_, err = service.Authenticate(ctx, created.Key, "10.0.0.1")
+	require.NoError(t, err)
+
+	now = now.Add(30 * time.Minute)
+	_, err = service.Authenticate(ctx, created.Key, "10.0.0.1")
+	require.ErrorIs(t, err, e.ErrAPIKeyInvalid)
+
+	tooLate := now.Add(3 * time.Hour)
+	_, err = service.Create(ctx, uuid.New(), CreateRequest{Name: "long", Scopes: []string{rbac.PermOwnLoansRead}, ExpiresAt: &tooLate})
+	require.Error(t, err)
+}
+
+func TestScopesAndLimitsAreEnforced(t *testing.T) {
+	service, _ := newTestService()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// Admin permissions are never key scopes, even for an admin
+	_, err := service.Create(ctx, userID, CreateRequest{Name: "admin", Scopes: []string{rbac.PermLoansApprove}})
+	require.ErrorIs(t, err, e.ErrAPIKeyScopeNotAllowed)
+
+	service.perms = denyAll{}
+	_, err = service.Create(ctx, userID, CreateRequest{Name: "denied", Scopes: []string{rbac.PermOwnLoansRead}})
+	require.ErrorIs(t, err, e.ErrAPIKeyScopeNotAllowed)
+	service.perms = allowAll{}
+
+	_, err = service.Create(ctx, userID, CreateRequest{Name: "bad ip", Scopes: []string{rbac.PermOwnLoansRead}, AllowedIPs: []string{"10.0.0.0/33"}})
+	require.Error(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = service.Create(ctx, userID, CreateRequest{Name: "key", Scopes: []string{rbac.PermOwnLoansRead}})
+		require.NoError(t, err)
+	}
+	_, err = service.Create(ctx, userID, CreateRequest{Name: "third", Scopes: []string{rbac.PermOwnLoansRead}})
+	require.ErrorIs(t, err, e.ErrAPIKeyLimitReached)
+}
+
+func newTestService() (*Service, *memoryRepo) {
+	repo := &memoryRepo{}
+	service := NewService(repo, allowAll{}, Options{
+		MaxPerUser:       2,
+		DefaultTTL:       time.Hour,
+		MaxTTL:           2 * time.Hour,
+		LastUsedInterval: time.Minute,
+	}, zerolog.Nop())
+	now := time.Now()
+	service.now = func() time.Time { return now }
+	return service, repo
+}
+
+type allowAll struct{}
+
+func (allowAll) HasPermission(ctx context.Context, userID uuid.UUID, permission string) (bool, error) {
+	return true, nil
+}
+
+type denyAll struct{}
+
+func (denyAll) HasPermission(ctx context.Context, userID uuid.UUID, permission string) (bool, error) {
+	return false, nil
+}
+
+type memoryRepo struct {
+	keys []models.APIKey
+}
+
+func (m *memoryRepo) Create(ctx context.Context, key *models.APIKey) error {
+	key.ID = uuid.New()
+	key.CreatedAt = time.Now()
+	m.keys = append(m.keys, *key)
+	return nil
+}
+
+func (m *memoryRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.APIKey, error) {
+	return m.find(func(k models.APIKey) bool { return k.ID == id })
+}
+
+func (m *memoryRepo) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	return m.find(func(k models.APIKey) bool { return k.Prefix == prefix })
+}
+
+func (m *memoryRepo) List(ctx context.Context, userID *uuid.UUID) ([]models.APIKey, error) {
+	var result []models.APIKey
+	for _, key := range m.keys {
+		if userID == nil || key.UserID == *userID {
+			result = append(result, key)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryRepo) CountActive(ctx context.Context, userID uuid.UUID, now time.Time) (int64, error) {
+	var count int64
+	for _, key := range m.keys {
+		if key.UserID == userID && key.RevokedAt == nil && (key.ExpiresAt == nil || key.ExpiresAt.After(now)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *memoryRepo) Revoke(ctx context.Context, id, by uuid.UUID, at time.Time) (bool, error) {
+	for i := range m.keys {
+		if m.keys[i].ID == id && m.keys[i].RevokedAt == nil {
+			m.keys[i].RevokedAt = &at
+			m.keys[i].RevokedBy = &by
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryRepo) TouchLastUsed(ctx context.Context, id uuid.UUID, at time.Time, ip string) error {
+	for i := range m.keys {
+		if m.keys[i].ID == id {
+			m.keys[i].LastUsedAt = &at
+			m.keys[i].LastUsedIP = ip
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) find(match func(models.APIKey) bool) (*models.APIKey, error) {
+	for _, key := range m.keys {
+		if match(key) {
+			copy := key
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
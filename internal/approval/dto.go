@@ -0,0 +1,27 @@
+package approval
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/thoraf20/loanee/internal/models"
+)
+
+// Proposal asks for an action to run, subject to dual control.
+type Proposal struct {
+	Action     string
+	ResourceID uuid.UUID
+	// Amount is passed through to the action, zero if it takes none.
+	Amount     float64
+	ProposedBy uuid.UUID
+	Note       string
+}
+
+type DecisionRequest struct {
+	Note string `json:"note" validate:"max=500"`
+}
+
+type ApproveResponse struct {
+	Approval *models.Approval `json:"approval"`
+	// Result is what the action itself returned.
+	Result interface{} `json:"result"`
+}
@@ -0,0 +1,110 @@
+package approval
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/validator"
+)
+
+type Handler struct {
+	service   *Service
+	validator *validator.Validator
+	logger    zerolog.Logger
+}
+
+func NewHandler(service *Service, validator *validator.Validator, logger zerolog.Logger) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator,
+		logger:    logger.With().Str("component", "approval_handler").Logger(),
+	}
+}
+
+// AdminList returns approvals, optionally filtered by ?status=.
+func (h *Handler) AdminList(c *gin.Context) {
+	status := models.ApprovalStatus(c.Query("status"))
+
+	approvals, err := h.service.List(c.Request.Context(), status)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list approvals")
+		utils.InternalServerError(c, "failed to fetch approvals", err.Error())
+		return
+	}
+
+	utils.OK(c, "approvals retrieved", approvals)
+}
+
+// AdminApprove confirms a pending approval, running its action.
+func (h *Handler) AdminApprove(c *gin.Context) {
+	adminID, approvalID, payload, ok := h.decision(c)
+	if !ok {
+		return
+	}
+
+	approval, result, err := h.service.Approve(c.Request.Context(), approvalID, adminID, payload.Note)
+	if err != nil {
+		h.logger.Error().Err(err).Any("approval_id", approvalID).Any("admin_id", adminID).Msg("failed to approve action")
+		respondError(c, "failed to approve action", err)
+		return
+	}
+
+	utils.OK(c, "action approved", ApproveResponse{Approval: approval, Result: result})
+}
+
+// AdminReject declines a pending approval.
+func (h *Handler) AdminReject(c *gin.Context) {
+	adminID, approvalID, payload, ok := h.decision(c)
+	if !ok {
+		return
+	}
+
+	approval, err := h.service.Reject(c.Request.Context(), approvalID, adminID, payload.Note)
+	if err != nil {
+		h.logger.Error().Err(err).Any("approval_id", approvalID).Any("admin_id", adminID).Msg("failed to reject action")
+		respondError(c, "failed to reject action", err)
+		return
+	}
+
+	utils.OK(c, "action rejected", approval)
+}
+
+// decision reads the deciding admin, the approval ID and the optional note.
+func (h *Handler) decision(c *gin.Context) (uuid.UUID, uuid.UUID, DecisionRequest, bool) {
+	var payload DecisionRequest
+
+	adminID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return uuid.Nil, uuid.Nil, payload, false
+	}
+
+	approvalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid approval id", err.Error())
+		return uuid.Nil, uuid.Nil, payload, false
+	}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			utils.BadRequest(c, "invalid payload", err.Error())
+			return uuid.Nil, uuid.Nil, payload, false
+		}
+		if err := h.validator.Validate(&payload); err != nil {
+			utils.BadRequest(c, "validation failed", err.Error())
+			return uuid.Nil, uuid.Nil, payload, false
+		}
+	}
+	return adminID, approvalID, payload, true
+}
+
+func respondError(c *gin.Context, message string, err error) {
+	if appErr := e.GetAppError(err); appErr != nil {
+		utils.Error(c, appErr.StatusCode, message, err.Error())
+		return
+	}
+	utils.InternalServerError(c, message, err.Error())
+}
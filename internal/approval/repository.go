@@ -0,0 +1,121 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+type Repository interface {
+	// Create fails with ErrApprovalPending when the action already has a
+	// pending approval for the resource.
+	Create(ctx context.Context, approval *models.Approval) error
+	// GetByID returns nil when the approval does not exist.
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Approval, error)
+	// FindPending returns nil when no approval of the action is pending for
+	// the resource.
+	FindPending(ctx context.Context, action string, resourceID uuid.UUID) (*models.Approval, error)
+	// List returns approvals newest first, all of them when status is empty.
+	List(ctx context.Context, status models.ApprovalStatus) ([]models.Approval, error)
+	// Transition saves approval's status and decision if it is still in
+	// status from, reporting false when another request moved it first.
+	Transition(ctx context.Context, approval *models.Approval, from models.ApprovalStatus) (bool, error)
+	// ExpirePending marks pending approvals past their expiry as expired.
+	ExpirePending(ctx context.Context, now time.Time) (int64, error)
+}
+
+type repository struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewRepository(db *gorm.DB, logger zerolog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, approval *models.Approval) error {
+	now := time.Now()
+	if approval.ID == uuid.Nil {
+		approval.ID = uuid.New()
+	}
+	approval.CreatedAt = now
+	approval.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(approval).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return e.ErrApprovalPending
+		}
+		return fmt.Errorf("failed to create approval: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Approval, error) {
+	return r.first(ctx, "id = ?", id)
+}
+
+func (r *repository) FindPending(ctx context.Context, action string, resourceID uuid.UUID) (*models.Approval, error) {
+	return r.first(ctx, "action = ? AND resource_id = ? AND status = ?", action, resourceID, models.ApprovalPending)
+}
+
+func (r *repository) List(ctx context.Context, status models.ApprovalStatus) ([]models.Approval, error) {
+	query := r.db.WithContext(ctx).Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var approvals []models.Approval
+	if err := query.Find(&approvals).Error; err != nil {
+		return nil, fmt.Errorf("failed to list approvals: %w", err)
+	}
+	return approvals, nil
+}
+
+func (r *repository) Transition(ctx context.Context, approval *models.Approval, from models.ApprovalStatus) (bool, error) {
+	approval.UpdatedAt = time.Now()
+	result := r.db.WithContext(ctx).
+		Model(&models.Approval{}).
+		Where("id = ? AND status = ?", approval.ID, from).
+		Select("status", "decided_by", "decided_at", "decision_note", "executed_at", "error", "updated_at").
+		Updates(approval)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update approval: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) ExpirePending(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.Approval{}).
+		Where("status = ? AND expires_at <= ?", models.ApprovalPending, now).
+		Updates(map[string]interface{}{
+			"status":     models.ApprovalExpired,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to expire approvals: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *repository) first(ctx context.Context, query string, args ...interface{}) (*models.Approval, error) {
+	var approval models.Approval
+	if err := r.db.WithContext(ctx).Where(query, args...).First(&approval).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get approval: %w", err)
+	}
+	return &approval, nil
+}
@@ -0,0 +1,303 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+// Actions under dual control. They double as the keys of the configured
+// thresholds.
+const (
+	ActionLoanApprove       = "loan_approve"
+	ActionLoanDisburse      = "loan_disburse"
+	ActionCollateralRelease = "collateral_release"
+)
+
+// PermissionChecker reports whether a user's role grants a permission.
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userID uuid.UUID, permission string) (bool, error)
+}
+
+// Value is what an action is worth, in the currency it is denominated in.
+type Value struct {
+	Amount   float64
+	Currency string
+}
+
+// Action is an admin operation that may need a second administrator.
+type Action struct {
+	Name string
+	// Permission is what the approving administrator must hold.
+	Permission string
+	// Validate, when set, checks the action's preconditions when it is
+	// proposed, so an action that could not run is never held for approval.
+	Validate func(ctx context.Context, resourceID uuid.UUID, amount float64) error
+	// Value is weighed against the action's threshold in its currency.
+	Value func(ctx context.Context, resourceID uuid.UUID, amount float64) (Value, error)
+	// Execute performs the action, returning what the API responds with.
+	Execute func(ctx context.Context, resourceID uuid.UUID, amount float64) (interface{}, error)
+}
+
+type Options struct {
+	Enabled bool
+	// TTL is how long a proposal waits for approval before it expires.
+	TTL time.Duration
+	// Thresholds maps an action to the value, per currency, from which it
+	// needs approval. Actions without thresholds never do; a controlled
+	// action valued in a currency without a threshold always does.
+	Thresholds map[string]map[string]float64
+}
+
+// Service runs high-value admin actions under maker-checker control: above
+// its threshold an action is held as a pending approval, and only runs once
+// a different administrator with the action's permission approves it.
+type Service struct {
+	repo        Repository
+	permissions PermissionChecker
+	actions     map[string]Action
+	opts        Options
+	now         func() time.Time
+	logger      zerolog.Logger
+}
+
+func NewService(repo Repository, permissions PermissionChecker, opts Options, logger zerolog.Logger) *Service {
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+	// Currencies are matched upper-case; config keys arrive lower-cased.
+	thresholds := make(map[string]map[string]float64, len(opts.Thresholds))
+	for action, byCurrency := range opts.Thresholds {
+		thresholds[action] = make(map[string]float64, len(byCurrency))
+		for currency, threshold := range byCurrency {
+			thresholds[action][strings.ToUpper(currency)] = threshold
+		}
+	}
+	opts.Thresholds = thresholds
+
+	return &Service{
+		repo:        repo,
+		permissions: permissions,
+		actions:     make(map[string]Action),
+		opts:        opts,
+		now:         time.Now,
+		logger:      logger.With().Str("component", "approval_service").Logger(),
+	}
+}
+
+// Register makes an action available to Submit.
+func (s *Service) Register(action Action) {
+	s.actions[action.Name] = action
+}
+
+// Submit runs the proposed action at once when it is under its threshold.
+// Otherwise it records a pending approval, returned in place of a result.
+func (s *Service) Submit(ctx context.Context, p Proposal) (interface{}, *models.Approval, error) {
+	action, ok := s.actions[p.Action]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown approval action %q", p.Action)
+	}
+	if action.Validate != nil {
+		if err := action.Validate(ctx, p.ResourceID, p.Amount); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	thresholds, controlled := s.opts.Thresholds[p.Action]
+	if !s.opts.Enabled || !controlled {
+		result, err := action.Execute(ctx, p.ResourceID, p.Amount)
+		return result, nil, err
+	}
+
+	value, err := action.Value(ctx, p.ResourceID, p.Amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	value.Currency = strings.ToUpper(value.Currency)
+	if threshold, ok := thresholds[value.Currency]; ok && value.Amount < threshold {
+		result, err := action.Execute(ctx, p.ResourceID, p.Amount)
+		return result, nil, err
+	}
+
+	existing, err := s.repo.FindPending(ctx, p.Action, p.ResourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing != nil {
+		if !s.expire(ctx, existing) {
+			return nil, nil, e.ErrApprovalPending
+		}
+	}
+
+	now := s.now()
+	approval := &models.Approval{
+		ID:           uuid.New(),
+		Action:       p.Action,
+		ResourceID:   p.ResourceID,
+		Amount:       p.Amount,
+		Value:        value.Amount,
+		Currency:     value.Currency,
+		Status:       models.ApprovalPending,
+		ProposedBy:   p.ProposedBy,
+		ProposalNote: p.Note,
+		ExpiresAt:    now.Add(s.opts.TTL),
+	}
+	if err := s.repo.Create(ctx, approval); err != nil {
+		return nil, nil, err
+	}
+
+	s.logger.Info().
+		Any("approval_id", approval.ID).
+		Str("action", p.Action).
+		Any("resource_id", p.ResourceID).
+		Float64("value", value.Amount).
+		Str("currency", value.Currency).
+		Any("proposed_by", p.ProposedBy).
+		Msg("Action held for second approval")
+	return nil, approval, nil
+}
+
+// Approve confirms a pending approval and runs its action. If the action
+// fails the approval is marked failed and the action must be proposed again.
+func (s *Service) Approve(ctx context.Context, id, approverID uuid.UUID, note string) (*models.Approval, interface{}, error) {
+	approval, action, err := s.decidable(ctx, id, approverID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if approval.ProposedBy == approverID {
+		return nil, nil, e.ErrSelfApproval
+	}
+
+	now := s.now()
+	approval.Status = models.ApprovalApproved
+	approval.DecidedBy = &approverID
+	approval.DecidedAt = &now
+	approval.DecisionNote = note
+	if err := s.transition(ctx, approval, models.ApprovalPending); err != nil {
+		return nil, nil, err
+	}
+
+	result, execErr := action.Execute(ctx, approval.ResourceID, approval.Amount)
+
+	executedAt := s.now()
+	approval.ExecutedAt = &executedAt
+	approval.Status = models.ApprovalExecuted
+	if execErr != nil {
+		approval.Status = models.ApprovalFailed
+		approval.Error = truncate(execErr.Error(), 500)
+	}
+	if _, err := s.repo.Transition(ctx, approval, models.ApprovalApproved); err != nil {
+		s.logger.Error().Err(err).Any("approval_id", approval.ID).Msg("Failed to record approval outcome")
+	}
+
+	s.logger.Info().
+		Any("approval_id", approval.ID).
+		Str("action", approval.Action).
+		Any("proposed_by", approval.ProposedBy).
+		Any("approved_by", approverID).
+		Str("status", string(approval.Status)).
+		Msg("Approval decided")
+	return approval, result, execErr
+}
+
+// Reject declines a pending approval; its action never runs.
+func (s *Service) Reject(ctx context.Context, id, deciderID uuid.UUID, note string) (*models.Approval, error) {
+	approval, _, err := s.decidable(ctx, id, deciderID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	approval.Status = models.ApprovalRejected
+	approval.DecidedBy = &deciderID
+	approval.DecidedAt = &now
+	approval.DecisionNote = note
+	if err := s.transition(ctx, approval, models.ApprovalPending); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().
+		Any("approval_id", approval.ID).
+		Str("action", approval.Action).
+		Any("rejected_by", deciderID).
+		Msg("Approval rejected")
+	return approval, nil
+}
+
+// List returns approvals, newest first, after expiring stale ones.
+func (s *Service) List(ctx context.Context, status models.ApprovalStatus) ([]models.Approval, error) {
+	if _, err := s.repo.ExpirePending(ctx, s.now()); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, status)
+}
+
+// decidable loads a pending, unexpired approval that decider may decide.
+func (s *Service) decidable(ctx context.Context, id, deciderID uuid.UUID) (*models.Approval, Action, error) {
+	approval, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, Action{}, err
+	}
+	if approval == nil {
+		return nil, Action{}, e.ErrApprovalNotFound
+	}
+	if approval.Status != models.ApprovalPending {
+		return nil, Action{}, e.ErrApprovalNotPending
+	}
+	if s.expire(ctx, approval) {
+		return nil, Action{}, e.ErrApprovalExpired
+	}
+
+	action, ok := s.actions[approval.Action]
+	if !ok {
+		return nil, Action{}, fmt.Errorf("unknown approval action %q", approval.Action)
+	}
+
+	allowed, err := s.permissions.HasPermission(ctx, deciderID, action.Permission)
+	if err != nil {
+		return nil, Action{}, err
+	}
+	if !allowed {
+		return nil, Action{}, e.ErrPermissionDenied
+	}
+	return approval, action, nil
+}
+
+// expire marks a pending approval expired if its time is up, reporting
+// whether it has expired.
+func (s *Service) expire(ctx context.Context, approval *models.Approval) bool {
+	if s.now().Before(approval.ExpiresAt) {
+		return false
+	}
+
+	approval.Status = models.ApprovalExpired
+	if _, err := s.repo.Transition(ctx, approval, models.ApprovalPending); err != nil {
+		s.logger.Warn().Err(err).Any("approval_id", approval.ID).Msg("Failed to expire approval")
+	}
+	return true
+}
+
+func (s *Service) transition(ctx context.Context, approval *models.Approval, from models.ApprovalStatus) error {
+	moved, err := s.repo.Transition(ctx, approval, from)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return e.ErrApprovalNotPending
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
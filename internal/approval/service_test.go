@@ -0,0 +1,237 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+func TestHighValueActionNeedsSecondAdmin(t *testing.T) {
+	svc, _, runs := newTestService(t)
+	ctx := context.Background()
+	maker, checker, clerk := uuid.New(), uuid.New(), uuid.New()
+	svc.permissions = fakePermissions{checker: true, maker: true}
+
+	// Under the threshold the action runs straight away.
+	result, pending, err := svc.Submit(ctx, Proposal{Action: ActionLoanApprove, ResourceID: uuid.New(), Amount: 999, ProposedBy: maker})
+	require.NoError(t, err)
+	require.Nil(t, pending)
+	require.Equal(t, "done", result)
+	require.Equal(t, 1, *runs)
+
+	loanID := uuid.New()
+	result, pending, err = svc.Submit(ctx, Proposal{Action: ActionLoanApprove, ResourceID: loanID, Amount: 1000, ProposedBy: maker, Note: "big one"})
+	require.NoError(t, err)
+	require.Nil(t, result)
+	require.Equal(t, models.ApprovalPending, pending.Status)
+	require.Equal(t, 1, *runs)
+
+	_, _, err = svc.Submit(ctx, Proposal{Action: ActionLoanApprove, ResourceID: loanID, Amount: 1000, ProposedBy: maker})
+	require.ErrorIs(t, err, e.ErrApprovalPending)
+
+	_, _, err = svc.Approve(ctx, pending.ID, maker, "")
+	require.ErrorIs(t, err, e.ErrSelfApproval)
+
+	_, _, err = svc.Approve(ctx, pending.ID, clerk, "")
+	require.ErrorIs(t, err, e.ErrPermissionDenied)
+
+	approved, result, err := svc.Approve(ctx, pending.ID, checker, "checked")
+	require.NoError(t, err)
+	require.Equal(t, "done", result)
+	require.Equal(t, models.ApprovalExecuted, approved.Status)
+	require.Equal(t, maker, approved.ProposedBy)
+	require.Equal(t, checker, *approved.DecidedBy)
+	require.NotNil(t, approved.ExecutedAt)
+	require.Equal(t, 2, *runs)
+
+	// Approving twice does not run the action again.
+	_, _, err = svc.Approve(ctx, pending.ID, checker, "")
+	require.ErrorIs(t, err, e.ErrApprovalNotPending)
+	require.Equal(t, 2, *runs)
+}
+
+func TestApprovalExpiresAndRecordsFailures(t *testing.T) {
+	svc, repo, _ := newTestService(t)
+	ctx := context.Background()
+	maker, checker := uuid.New(), uuid.New()
+	svc.permissions = fakePermissions{checker: true}
+
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	loanID := uuid.New()
+	_, pending, err := svc.Submit(ctx, Proposal{Action: ActionLoanApprove, ResourceID: loanID, Amount: 5000, ProposedBy: maker})
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Hour)
+	_, _, err = svc.Approve(ctx, pending.ID, checker, "")
+	require.ErrorIs(t, err, e.ErrApprovalExpired)
+	require.Equal(t, models.ApprovalExpired, repo.approvals[pending.ID].Status)
+
+	// Once expired the action can be proposed again.
+	_, pending, err = svc.Submit(ctx, Proposal{Action: ActionLoanApprove, ResourceID: loanID, Amount: 5000, ProposedBy: maker})
+	require.NoError(t, err)
+
+	svc.actions[ActionLoanApprove] = Action{
+		Name:       ActionLoanApprove,
+		Permission: "loans:approve",
+		Execute: func(ctx context.Context, id uuid.UUID, amount float64) (interface{}, error) {
+			return nil, errors.New("loan not found")
+		},
+	}
+	approved, _, err := svc.Approve(ctx, pending.ID, checker, "")
+	require.Error(t, err)
+	require.Equal(t, models.ApprovalFailed, approved.Status)
+	require.Equal(t, "loan not found", repo.approvals[pending.ID].Error)
+}
+
+func TestThresholdsApplyPerCurrency(t *testing.T) {
+	svc, _, runs := newTestService(t)
+	ctx := context.Background()
+
+	currency := "usd"
+	action := svc.actions[ActionLoanApprove]
+	action.Value = func(ctx context.Context, id uuid.UUID, amount float64) (Value, error) {
+		return Value{Amount: amount, Currency: currency}, nil
+	}
+	svc.Register(action)
+
+	// 999 is under the NGN threshold but not the USD one.
+	_, pending, err := svc.Submit(ctx, Proposal{Action: ActionLoanApprove, ResourceID: uuid.New(), Amount: 999, ProposedBy: uuid.New()})
+	require.NoError(t, err)
+	require.Equal(t, "USD", pending.Currency)
+	require.Equal(t, 999.0, pending.Value)
+
+	_, pending, err = svc.Submit(ctx, Proposal{Action: ActionLoanApprove, ResourceID: uuid.New(), Amount: 9, ProposedBy: uuid.New()})
+	require.NoError(t, err)
+	require.Nil(t, pending)
+	require.Equal(t, 1, *runs)
+
+	// A currency without a threshold is always held.
+	currency = "EUR"
+	_, pending, err = svc.Submit(ctx, Proposal{Action: ActionLoanApprove, ResourceID: uuid.New(), Amount: 1, ProposedBy: uuid.New()})
+	require.NoError(t, err)
+	require.NotNil(t, pending)
+	require.Equal(t, 1, *runs)
+}
+
+func TestSubmitChecksPreconditionsBeforeHolding(t *testing.T) {
+	svc, repo, runs := newTestService(t)
+	ctx := context.Background()
+
+	action := svc.actions[ActionLoanApprove]
+	action.Validate = func(ctx context.Context, id uuid.UUID, amount float64) error {
+		return e.ErrLoanNotApproved
+	}
+	svc.Register(action)
+
+	result, pending, err := svc.Submit(ctx, Proposal{Action: ActionLoanApprove, ResourceID: uuid.New(), Amount: 5000, ProposedBy: uuid.New()})
+	require.ErrorIs(t, err, e.ErrLoanNotApproved)
+	require.Nil(t, result)
+	require.Nil(t, pending)
+	require.Empty(t, repo.approvals)
+	require.Zero(t, *runs)
+}
+
+func newTestService(t *testing.T) (*Service, *memoryRepo, *int) {
+	t.Helper()
+
+	repo := &memoryRepo{approvals: make(map[uuid.UUID]*models.Approval)}
+	svc := NewService(repo, fakePermissions{}, Options{
+		Enabled:    true,
+		TTL:        time.Hour,
+		Thresholds: map[string]map[string]float64{ActionLoanApprove: {"ngn": 1000, "usd": 10}},
+	}, zerolog.Nop())
+
+	runs := 0
+	svc.Register(Action{
+		Name:       ActionLoanApprove,
+		Permission: "loans:approve",
+		Value: func(ctx context.Context, id uuid.UUID, amount float64) (Value, error) {
+			return Value{Amount: amount, Currency: "NGN"}, nil
+		},
+		Execute: func(ctx context.Context, id uuid.UUID, amount float64) (interface{}, error) {
+			runs++
+			return "done", nil
+		},
+	})
+	return svc, repo, &runs
+}
+
+// fakePermissions grants every permission to the listed users.
+type fakePermissions map[uuid.UUID]bool
+
+func (f fakePermissions) HasPermission(ctx context.Context, userID uuid.UUID, permission string) (bool, error) {
+	return f[userID], nil
+}
+
+type memoryRepo struct {
+	approvals map[uuid.UUID]*models.Approval
+}
+
+func (m *memoryRepo) Create(ctx context.Context, approval *models.Approval) error {
+	if existing, _ := m.FindPending(ctx, approval.Action, approval.ResourceID); existing != nil {
+		return e.ErrApprovalPending
+	}
+	stored := *approval
+	m.approvals[approval.ID] = &stored
+	return nil
+}
+
+func (m *memoryRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Approval, error) {
+	approval, ok := m.approvals[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *approval
+	return &copied, nil
+}
+
+func (m *memoryRepo) FindPending(ctx context.Context, action string, resourceID uuid.UUID) (*models.Approval, error) {
+	for _, approval := range m.approvals {
+		if approval.Action == action && approval.ResourceID == resourceID && approval.Status == models.ApprovalPending {
+			copied := *approval
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRepo) List(ctx context.Context, status models.ApprovalStatus) ([]models.Approval, error) {
+	var approvals []models.Approval
+	for _, approval := range m.approvals {
+		if status == "" || approval.Status == status {
+			approvals = append(approvals, *approval)
+		}
+	}
+	return approvals, nil
+}
+
+func (m *memoryRepo) Transition(ctx context.Context, approval *models.Approval, from models.ApprovalStatus) (bool, error) {
+	stored, ok := m.approvals[approval.ID]
+	if !ok || stored.Status != from {
+		return false, nil
+	}
+	copied := *approval
+	m.approvals[approval.ID] = &copied
+	return true, nil
+}
+
+func (m *memoryRepo) ExpirePending(ctx context.Context, now time.Time) (int64, error) {
+	var expired int64
+	for _, approval := range m.approvals {
+		if approval.Status == models.ApprovalPending && !now.Before(approval.ExpiresAt) {
+			approval.Status = models.ApprovalExpired
+			expired++
+		}
+	}
+	return expired, nil
+}
@@ -1,6 +1,9 @@
 package auth
 
-import "github.com/thoraf20/loanee/internal/user"
+import (
+	"github.com/google/uuid"
+	"github.com/thoraf20/loanee/internal/user"
+)
 
 type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
@@ -18,12 +21,33 @@ type RegisterResponse struct {
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// Device is an optional client-supplied label for the session, e.g.
+	// "Ada's iPhone".
+	Device string `json:"device" binding:"omitempty,max=100"`
 }
 
+// LoginResponse carries either the session's tokens or, when MFARequired is
+// set, only the MFA challenge token to complete the login with.
 type LoginResponse struct {
-	User         *user.User `json:"user"`
-	AccessToken  string     `json:"access_token"`
-	RefreshToken string     `json:"refresh_token"`
+	User         *user.User `json:"user,omitempty"`
+	SessionID    *uuid.UUID `json:"session_id,omitempty"`
+	AccessToken  string     `json:"access_token,omitempty"`
+	RefreshToken string     `json:"refresh_token,omitempty"`
+	MFARequired  bool       `json:"mfa_required,omitempty"`
+	MFAToken     string     `json:"mfa_token,omitempty"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code is a current TOTP code or an unused recovery code
+	Code   string `json:"code" binding:"required"`
+	Device string `json:"device" binding:"omitempty,max=100"`
+}
+
+// ClientInfo describes where a login or refresh request came from.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
 }
 
 type VerifyEmailRequest struct {
@@ -59,7 +83,12 @@ type RefreshTokenRequest struct {
 }
 
 type RefreshTokenResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required"`
 }
 
 type LogoutRequest struct {
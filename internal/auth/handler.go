@@ -1,11 +1,15 @@
 package auth
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
 	"github.com/thoraf20/loanee/pkg/response"
 	"github.com/thoraf20/loanee/pkg/validator"
 )
@@ -79,16 +83,79 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.Login(c.Request.Context(), &req)
+	resp, err := h.service.Login(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
-		h.logger.Error().Err(err).Any("user", &req).Msg("Failed to login user")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login user"})
+		h.logger.Error().Err(err).Str("email", req.Email).Msg("Failed to login user")
+		respondError(c, "failed to login user", err)
+		return
+	}
+
+	if resp.MFARequired {
+		utils.OK(c, "two-factor authentication required", resp)
+		return
+	}
+
+	utils.OK(c, "login successful", resp)
+}
+
+// LoginMFA godoc
+// @Summary Complete two-factor login
+// @Description Exchange the MFA challenge token from login and a TOTP or recovery code for tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body LoginMFARequest true "Two-factor login request"
+// @Success 200 {object} LoginResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/login/mfa [post]
+func (h *Handler) LoginMFA(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.HandleValidationError(c, err)
+		return
+	}
+
+	resp, err := h.service.CompleteMFALogin(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to complete two-factor login")
+		respondError(c, "failed to complete login", err)
 		return
 	}
 
 	utils.OK(c, "login successful", resp)
 }
 
+// RefreshToken godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token. Each refresh token is single-use; reusing one revokes every session from its login.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh token request"
+// @Success 200 {object} RefreshTokenResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/refresh [post]
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.HandleValidationError(c, err)
+		return
+	}
+
+	resp, err := h.service.Refresh(c.Request.Context(), &req, clientInfo(c))
+	if err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to refresh token")
+		if appErr := e.GetAppError(err); appErr != nil {
+			utils.Error(c, appErr.StatusCode, "failed to refresh token", err.Error())
+			return
+		}
+		utils.InternalServerError(c, "failed to refresh token", err.Error())
+		return
+	}
+
+	utils.OK(c, "token refreshed", resp)
+}
+
 // VerifyEmail godoc
 // @Summary Verify user email
 // @Description Verify user's email address with verification code
@@ -111,10 +178,10 @@ func (h *Handler) VerifyEmail(c *gin.Context) {
 		return
 	}
 
-	resp, err := h.service.VerifyEmail(c.Request.Context(), &req)
+	resp, err := h.service.VerifyEmail(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
 		h.logger.Error().Err(err).Any("user", &req).Msg("Failed to verify user")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify user"})
+		respondError(c, "failed to verify user", err)
 		return
 	}
 
@@ -143,10 +210,10 @@ func (h *Handler) ResendVerificationCode(c *gin.Context) {
 		return
 	}
 
-	err := h.service.ResendVerificationCode(c.Request.Context(), &req)
+	err := h.service.ResendVerificationCode(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
 		h.logger.Error().Err(err).Any("user", &req).Msg("Operation failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Operation failed"})
+		respondError(c, "operation failed", err)
 		return
 	}
 
@@ -175,10 +242,10 @@ func (h *Handler) ForgotPassword(c *gin.Context) {
 		return
 	}
 
-	err := h.service.ForgotPassword(c.Request.Context(), &req)
+	err := h.service.ForgotPassword(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
 		h.logger.Error().Err(err).Any("user", &req).Msg("Operation failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Operation failed"})
+		respondError(c, "operation failed", err)
 		return
 	}
 
@@ -207,16 +274,42 @@ func (h *Handler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	err := h.service.ResetPassword(c.Request.Context(), &req)
+	err := h.service.ResetPassword(c.Request.Context(), &req, clientInfo(c))
 	if err != nil {
 		h.logger.Error().Err(err).Any("user", &req).Msg("Operation failed")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Operation failed"})
+		respondError(c, "operation failed", err)
 		return
 	}
 
 	utils.OK(c, "", "password reset successful")
 }
 
+// UnlockAccount godoc
+// @Summary Unlock account
+// @Description Lift a login lockout early with the token emailed when it began
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body UnlockAccountRequest true "Unlock account request"
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/unlock [post]
+func (h *Handler) UnlockAccount(c *gin.Context) {
+	var req UnlockAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.HandleValidationError(c, err)
+		return
+	}
+
+	if err := h.service.UnlockAccount(c.Request.Context(), &req); err != nil {
+		h.logger.Warn().Err(err).Msg("Failed to unlock account")
+		respondError(c, "failed to unlock account", err)
+		return
+	}
+
+	utils.OK(c, "account unlocked", nil)
+}
+
 // Logout godoc
 // @Summary Logout user
 // @Description Logout user and invalidate tokens
@@ -254,4 +347,114 @@ func (h *Handler) Logout(c *gin.Context) {
 	}
 
 	utils.OK(c, "Logged out successfully", nil)
-}
\ No newline at end of file
+}
+
+// LogoutAllDevices godoc
+// @Summary Logout all devices
+// @Description Revoke every session and reject all tokens issued to the user so far
+// @Tags auth
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/logout-all [post]
+func (h *Handler) LogoutAllDevices(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "unauthorized")
+		return
+	}
+
+	if err := h.service.LogoutAllDevices(c.Request.Context(), userID); err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Msg("Failed to logout all devices")
+		utils.InternalServerError(c, "failed to logout all devices", err.Error())
+		return
+	}
+
+	utils.OK(c, "Logged out of all devices", nil)
+}
+
+// ListSessions godoc
+// @Summary List sessions
+// @Description List the devices the user is signed in on
+// @Tags auth
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} SuccessResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /auth/sessions [get]
+func (h *Handler) ListSessions(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "unauthorized")
+		return
+	}
+
+	sessions, err := h.service.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Msg("Failed to list sessions")
+		utils.InternalServerError(c, "failed to list sessions", err.Error())
+		return
+	}
+
+	utils.OK(c, "sessions retrieved", sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Sign one device out; its refresh token stops working immediately
+// @Tags auth
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Session ID"
+// @Success 200 {object} SuccessResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /auth/sessions/{id} [delete]
+func (h *Handler) RevokeSession(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "unauthorized")
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid session id", err.Error())
+		return
+	}
+
+	if err := h.service.RevokeSession(c.Request.Context(), userID, sessionID); err != nil {
+		h.logger.Error().Err(err).Any("session_id", sessionID).Msg("Failed to revoke session")
+		if appErr := e.GetAppError(err); appErr != nil {
+			utils.Error(c, appErr.StatusCode, "failed to revoke session", err.Error())
+			return
+		}
+		utils.InternalServerError(c, "failed to revoke session", err.Error())
+		return
+	}
+
+	utils.OK(c, "session revoked", nil)
+}
+
+func clientInfo(c *gin.Context) ClientInfo {
+	return ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// respondError maps service errors to their status, telling throttled
+// clients when to retry.
+func respondError(c *gin.Context, message string, err error) {
+	appErr := e.GetAppError(err)
+	if appErr == nil {
+		utils.InternalServerError(c, message, err.Error())
+		return
+	}
+
+	if appErr.RetryAfter > 0 {
+		seconds := int64(math.Ceil(appErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+	utils.Error(c, appErr.StatusCode, message, err.Error())
+}
@@ -10,6 +10,7 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"github.com/thoraf20/loanee/internal/models"
 	"github.com/thoraf20/loanee/internal/user"
 	e "github.com/thoraf20/loanee/pkg/error"
 )
@@ -23,13 +24,30 @@ type Repository interface {
 	UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error
 
 	MarkEmailAsVerified(ctx context.Context, userID uuid.UUID) error
-	SaveVerificationCode(ctx context.Context, userID uuid.UUID, code string, expiresAt time.Time) error
+	// SaveVerificationCode replaces the user's unused codes and queues mail,
+	// which carries the code, in the same transaction.
+	SaveVerificationCode(ctx context.Context, userID uuid.UUID, code string, expiresAt time.Time, mail *models.OutboxEmail) error
 	GetVerificationCode(ctx context.Context, userID uuid.UUID) (*user.VerificationCode, error)
 	InvalidateVerificationCode(ctx context.Context, codeID uuid.UUID, code string) error
 
-	SavePasswordResetToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time) error
+	// SavePasswordResetToken replaces the user's unused tokens and queues
+	// mail, which carries the token, in the same transaction.
+	SavePasswordResetToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time, mail *models.OutboxEmail) error
 	GetPasswordResetToken(ctx context.Context, token string) (*user.PasswordResetToken, error)
 	InvalidatePasswordResetToken(ctx context.Context, token string) error
+
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, id uuid.UUID) (*models.RefreshToken, error)
+	ConsumeRefreshToken(ctx context.Context, id, replacedBy uuid.UUID) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+
+	CreateSession(ctx context.Context, session *models.Session) error
+	TouchSession(ctx context.Context, id, refreshTokenID uuid.UUID, ipAddress string) error
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error)
+	GetSession(ctx context.Context, userID, id uuid.UUID) (*models.Session, error)
+	RevokeAllSessions(ctx context.Context, userID uuid.UUID, at time.Time) error
+	// GetTokensValidAfter reads only the user's token cutoff
+	GetTokensValidAfter(ctx context.Context, userID uuid.UUID) (*time.Time, error)
 }
 
 type repository struct {
@@ -152,7 +170,7 @@ func (r *repository) MarkEmailAsVerified(ctx context.Context, userID uuid.UUID)
 }
 
 // SaveVerificationCode saves a verification code for email verification
-func (r *repository) SaveVerificationCode(ctx context.Context, userID uuid.UUID, code string, expiresAt time.Time) error {
+func (r *repository) SaveVerificationCode(ctx context.Context, userID uuid.UUID, code string, expiresAt time.Time, mail *models.OutboxEmail) error {
 	// Hash the code before storing
 	hashedCode, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
 	if err != nil {
@@ -167,13 +185,22 @@ func (r *repository) SaveVerificationCode(ctx context.Context, userID uuid.UUID,
 		Used:      false,
 	}
 
-	if err := r.db.WithContext(ctx).
-		Where("user_id = ? AND used = ?", userID, false).
-		Delete(&user.VerificationCode{}).Error; err != nil {
-		r.logger.Warn().Err(err).Any("user_id", userID).Msg("Failed to delete old verification codes")
-	}
-
-	if err := r.db.WithContext(ctx).Create(verificationCode).Error; err != nil {
+	// The email is only sent once the code it carries is committed
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("user_id = ? AND used = ?", userID, false).
+			Delete(&user.VerificationCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(verificationCode).Error; err != nil {
+			return err
+		}
+		if mail != nil {
+			return tx.Create(mail).Error
+		}
+		return nil
+	})
+	if err != nil {
 		r.logger.Error().Err(err).Any("user_id", userID).Msg("Failed to save verification code")
 		return e.NewDatabaseError("failed to save verification code", err)
 	}
@@ -232,7 +259,7 @@ func (r *repository) InvalidateVerificationCode(ctx context.Context, userID uuid
 }
 
 // SavePasswordResetToken saves a password reset token
-func (r *repository) SavePasswordResetToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time) error {
+func (r *repository) SavePasswordResetToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time, mail *models.OutboxEmail) error {
 	// Hash the token before storing
 	hashedToken, err := bcrypt.GenerateFromPassword([]byte(token), bcrypt.DefaultCost)
 	if err != nil {
@@ -247,13 +274,22 @@ func (r *repository) SavePasswordResetToken(ctx context.Context, userID uuid.UUI
 		Used:      false,
 	}
 
-	if err := r.db.WithContext(ctx).
-		Where("user_id = ? AND used = ?", userID, false).
-		Delete(&user.PasswordResetToken{}).Error; err != nil {
-		r.logger.Warn().Err(err).Any("user_id", userID).Msg("Failed to delete old reset tokens")
-	}
-
-	if err := r.db.WithContext(ctx).Create(resetToken).Error; err != nil {
+	// The email is only sent once the token it carries is committed
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("user_id = ? AND used = ?", userID, false).
+			Delete(&user.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(resetToken).Error; err != nil {
+			return err
+		}
+		if mail != nil {
+			return tx.Create(mail).Error
+		}
+		return nil
+	})
+	if err != nil {
 		r.logger.Error().Err(err).Any("user_id", userID).Msg("Failed to save reset token")
 		return e.NewDatabaseError("failed to save reset token", err)
 	}
@@ -360,4 +396,178 @@ func (r *repository) CleanupExpiredPasswordResetTokens(ctx context.Context) erro
 
 	r.logger.Info().Int64("deleted_count", result.RowsAffected).Msg("Cleaned up expired password reset tokens")
 	return nil
-}
\ No newline at end of file
+}
+
+// CreateRefreshToken records an issued refresh token
+func (r *repository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	token.CreatedAt = time.Now()
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		r.logger.Error().Err(err).Any("user_id", token.UserID).Msg("Failed to save refresh token")
+		return e.NewDatabaseError("failed to save refresh token", err)
+	}
+	return nil
+}
+
+// GetRefreshToken retrieves a refresh token by its jti
+func (r *repository) GetRefreshToken(ctx context.Context, id uuid.UUID) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.WithContext(ctx).First(&token, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, e.NewDatabaseError("failed to get refresh token", err)
+	}
+	return &token, nil
+}
+
+// ConsumeRefreshToken marks a refresh token used, recording its replacement.
+// It reports false when the token was already used or revoked, so two
+// concurrent refreshes with the same token cannot both succeed.
+func (r *repository) ConsumeRefreshToken(ctx context.Context, id, replacedBy uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"used_at":     time.Now(),
+			"replaced_by": replacedBy,
+		})
+
+	if result.Error != nil {
+		return false, e.NewDatabaseError("failed to consume refresh token", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeRefreshTokenFamily revokes every token descended from one login,
+// along with the session that login started
+func (r *repository) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	now := time.Now()
+	var revoked int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		revoked = result.RowsAffected
+
+		return tx.Model(&models.Session{}).
+			Where("id = ? AND revoked_at IS NULL", familyID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Any("family_id", familyID).Msg("Failed to revoke refresh token family")
+		return e.NewDatabaseError("failed to revoke refresh tokens", err)
+	}
+
+	r.logger.Info().Any("family_id", familyID).Int64("revoked", revoked).Msg("Refresh token family revoked")
+	return nil
+}
+
+// CreateSession records a new signed-in device
+func (r *repository) CreateSession(ctx context.Context, session *models.Session) error {
+	if err := r.db.WithContext(ctx).Create(session).Error; err != nil {
+		r.logger.Error().Err(err).Any("user_id", session.UserID).Msg("Failed to create session")
+		return e.NewDatabaseError("failed to create session", err)
+	}
+	return nil
+}
+
+// TouchSession records a refresh on a session: its current refresh token,
+// the address it came from and when
+func (r *repository) TouchSession(ctx context.Context, id, refreshTokenID uuid.UUID, ipAddress string) error {
+	updates := map[string]interface{}{
+		"refresh_token_id": refreshTokenID,
+		"last_seen_at":     time.Now(),
+	}
+	if ipAddress != "" {
+		updates["ip_address"] = ipAddress
+	}
+
+	if err := r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ?", id).
+		Updates(updates).Error; err != nil {
+		return e.NewDatabaseError("failed to update session", err)
+	}
+	return nil
+}
+
+// ListSessions returns a user's sessions that have not been revoked, most
+// recently used first
+func (r *repository) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	var sessions []models.Session
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, e.NewDatabaseError("failed to list sessions", err)
+	}
+	return sessions, nil
+}
+
+// GetSession retrieves one of a user's sessions
+func (r *repository) GetSession(ctx context.Context, userID, id uuid.UUID) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, e.ErrSessionNotFound
+		}
+		return nil, e.NewDatabaseError("failed to get session", err)
+	}
+	return &session, nil
+}
+
+// GetTokensValidAfter selects only tokens_valid_after, which is read on
+// every authenticated request the cutoff cache misses
+func (r *repository) GetTokensValidAfter(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	var rows []struct {
+		TokensValidAfter *time.Time
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&user.User{}).
+		Select("tokens_valid_after").
+		Where("id = ?", userID).
+		Limit(1).
+		Find(&rows).Error; err != nil {
+		r.logger.Error().Err(err).Any("user_id", userID).Msg("Failed to get token cutoff")
+		return nil, e.NewDatabaseError("failed to get token cutoff", err)
+	}
+	if len(rows) == 0 {
+		return nil, e.ErrUserNotFound
+	}
+	return rows[0].TokensValidAfter, nil
+}
+
+// RevokeAllSessions logs a user out everywhere: every session and refresh
+// token is revoked and access tokens issued up to at stop being accepted
+func (r *repository) RevokeAllSessions(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user.User{}).
+			Where("id = ?", userID).
+			Update("tokens_valid_after", at).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", at).Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Any("user_id", userID).Msg("Failed to revoke all sessions")
+		return e.NewDatabaseError("failed to revoke sessions", err)
+	}
+
+	r.logger.Info().Any("user_id", userID).Msg("All sessions revoked")
+	return nil
+}
@@ -7,20 +7,52 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/thoraf20/loanee/config"
+	"github.com/thoraf20/loanee/internal/email"
+	"github.com/thoraf20/loanee/internal/models"
 	"github.com/thoraf20/loanee/internal/user"
 	jwt "github.com/thoraf20/loanee/internal/utils"
 	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/throttle"
 	"github.com/thoraf20/loanee/pkg/tokenblacklist"
+	"github.com/thoraf20/loanee/pkg/tokencutoff"
 )
 
+// Throttling scopes. Each endpoint counts failures separately, so failed
+// logins do not also block email verification.
+const (
+	scopeLogin          = "login"
+	scopeMFA            = "mfa"
+	scopeVerifyEmail    = "verify_email"
+	scopeResendCode     = "resend_code"
+	scopeForgotPassword = "forgot_password"
+	scopeResetPassword  = "reset_password"
+)
+
+// Lifetimes of emailed verification codes and reset tokens
+const (
+	verificationCodeTTL = 24 * time.Hour
+	resetTokenTTL       = time.Hour
+)
+
+// MFA is the second factor checked at login for users who enabled it.
+type MFA interface {
+	Enabled(ctx context.Context, userID uuid.UUID) (bool, error)
+	Verify(ctx context.Context, userID uuid.UUID, code string) error
+}
+
 type Service struct {
 	repo       Repository
 	jwtManager *jwt.Manager
 	tokenBlacklist tokenblacklist.Blacklist
+	cutoffs    tokencutoff.Cache
+	mfa        MFA
+	limiter    *throttle.Limiter
+	emails     *email.Service
 	config     *config.Config
 	logger     zerolog.Logger
 }
@@ -29,6 +61,10 @@ func NewService(
 	repo Repository,
 	jwtManager *jwt.Manager,
 	tokenBlacklist tokenblacklist.Blacklist,
+	cutoffs tokencutoff.Cache,
+	mfa MFA,
+	limiter *throttle.Limiter,
+	emails *email.Service,
 	config *config.Config,
 	logger zerolog.Logger,
 ) *Service {
@@ -36,6 +72,10 @@ func NewService(
 		repo:       repo,
 		jwtManager: jwtManager,
 		tokenBlacklist: tokenBlacklist,
+		cutoffs:    cutoffs,
+		mfa:        mfa,
+		limiter:    limiter,
+		emails:     emails,
 		config:     config,
 		logger:     logger,
 	}
@@ -82,23 +122,14 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*Register
 		s.logger.Error().Err(err).Msg("Failed to generate verification code")
 		// Don't fail registration if verification code generation fails
 	} else {
-		// Save verification code
-		expiresAt := time.Now().Add(24 * time.Hour)
-		if err := s.repo.SaveVerificationCode(ctx, newUser.ID, verificationCode, expiresAt); err != nil {
+		if err := s.saveVerificationCode(ctx, newUser, verificationCode); err != nil {
 			s.logger.Error().Err(err).Msg("Failed to save verification code")
-		} else {
-			// TODO: Send verification email
-			s.logger.Info().
-				Any("user_id", newUser.ID).
-				Str("code", verificationCode).
-				Msg("Verification code generated (TODO: send email)")
 		}
 	}
 
 	s.logger.Info().
 		Any("user_id", newUser.ID).
 		Str("email", newUser.Email).
-		Str("code", verificationCode).
 		Msg("User registered successfully")
 
 	return &RegisterResponse{
@@ -107,14 +138,22 @@ func (s *Service) Register(ctx context.Context, req *RegisterRequest) (*Register
 	}, nil
 }
 
-// Login authenticates a user and returns tokens
-func (s *Service) Login(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+// Login authenticates a user, records the session for the device they signed
+// in from and returns tokens. Users with two-factor authentication get an MFA
+// challenge token instead, to be completed by CompleteMFALogin.
+func (s *Service) Login(ctx context.Context, req *LoginRequest, client ClientInfo) (*LoginResponse, error) {
 	s.logger.Info().Str("email", req.Email).Msg("User login attempt")
 
+	attempt := throttle.Attempt{Scope: scopeLogin, Account: req.Email, IP: client.IPAddress}
+	if err := s.limiter.Check(ctx, attempt); err != nil {
+		s.logger.Warn().Str("email", req.Email).Str("ip", client.IPAddress).Msg("Login throttled")
+		return nil, err
+	}
+
 	user, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		if appErr := e.GetAppError(err); appErr != nil && appErr.Code == e.CodeNotFound {
-			return nil, e.ErrInvalidCredentials
+			return nil, s.failAttempt(ctx, attempt, nil, e.ErrInvalidCredentials)
 		}
 		return nil, err
 	}
@@ -124,43 +163,139 @@ func (s *Service) Login(ctx context.Context, req *LoginRequest) (*LoginResponse,
 			Any("user_id", user.ID).
 			Str("email", req.Email).
 			Msg("Invalid password attempt")
-		return nil, e.ErrInvalidCredentials
+		return nil, s.failAttempt(ctx, attempt, user, e.ErrInvalidCredentials)
 	}
+	s.limiter.Succeed(ctx, attempt)
 
 	// Check if email is verified (optional based on your requirements)
 	if !user.IsVerified  && s.config.App.RequireEmailVerification {
 		return nil, e.ErrEmailNotVerified
 	}
 
-	accessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(
-		user.ID,
-		user.Email,
-		user.FirstName+" "+user.LastName,
-		user.Role,
-	)
+	if s.mfa != nil {
+		enabled, err := s.mfa.Enabled(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if enabled {
+			challenge, err := s.jwtManager.GenerateMFAChallengeToken(user.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			s.logger.Info().
+				Any("user_id", user.ID).
+				Msg("Password accepted, awaiting second factor")
+
+			return &LoginResponse{
+				MFARequired: true,
+				MFAToken:    challenge,
+			}, nil
+		}
+	}
+
+	return s.startSession(ctx, user, req.Device, client)
+}
+
+// CompleteMFALogin finishes a login that returned an MFA challenge, given a
+// TOTP or recovery code
+func (s *Service) CompleteMFALogin(ctx context.Context, req *LoginMFARequest, client ClientInfo) (*LoginResponse, error) {
+	claims, err := s.jwtManager.ValidateMFAChallengeToken(req.MFAToken)
 	if err != nil {
-		s.logger.Error().Err(err).Any("user_id", user.ID).Msg("Failed to generate tokens")
 		return nil, err
 	}
+	if s.mfa == nil {
+		return nil, e.ErrMFAUnavailable
+	}
+
+	attempt := throttle.Attempt{Scope: scopeMFA, Account: claims.UserID.String(), IP: client.IPAddress}
+	if err := s.limiter.Check(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfa.Verify(ctx, user.ID, req.Code); err != nil {
+		s.logger.Warn().
+			Any("user_id", user.ID).
+			Err(err).
+			Msg("Invalid second factor at login")
+		if appErr := e.GetAppError(err); appErr != nil && appErr.Code == e.CodeInvalidCredentials {
+			return nil, s.failAttempt(ctx, attempt, user, err)
+		}
+		return nil, err
+	}
+	s.limiter.Succeed(ctx, attempt)
+
+	return s.startSession(ctx, user, req.Device, client)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Each refresh token can be used once; presenting one that was already
+// used revokes every token in its family, since either the client or an
+// attacker is holding a stolen copy.
+func (s *Service) Refresh(ctx context.Context, req *RefreshTokenRequest, client ClientInfo) (*RefreshTokenResponse, error) {
+	claims, err := s.jwtManager.ValidateRefreshToken(req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, e.ErrTokenInvalid.WithDetail("reason", "invalid token id")
+	}
+
+	record, err := s.repo.GetRefreshToken(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.UserID != claims.UserID {
+		return nil, e.ErrTokenInvalid.WithDetail("reason", "unknown refresh token")
+	}
+	if record.RevokedAt != nil {
+		return nil, e.ErrTokenInvalid.WithDetail("reason", "token has been revoked")
+	}
+	if record.UsedAt != nil {
+		return nil, s.revokeReusedFamily(ctx, record)
+	}
+
+	// Reload the user so the new access token carries their current role
+	user, err := s.repo.GetUserByID(ctx, record.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, refreshID, err := s.issueTokens(ctx, user, record.FamilyID, &record.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.TouchSession(ctx, record.FamilyID, refreshID, client.IPAddress); err != nil {
+		s.logger.Warn().Err(err).Any("session_id", record.FamilyID).Msg("Failed to update session")
+	}
 
 	s.logger.Info().
 		Any("user_id", user.ID).
-		Str("email", user.Email).
-		Msg("User logged in successfully")
+		Any("family_id", record.FamilyID).
+		Msg("Refresh token rotated")
 
-	// Remove password from response
-	user.Password = ""
-
-	return &LoginResponse{
-		User:         user,
+	return &RefreshTokenResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}, nil
 }
 
-func (s *Service) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (*VerifyEmailResponse, error) {
+func (s *Service) VerifyEmail(ctx context.Context, req *VerifyEmailRequest, client ClientInfo) (*VerifyEmailResponse, error) {
 	s.logger.Info().Str("email", req.Email).Msg("Verifying email")
 
+	attempt := throttle.Attempt{Scope: scopeVerifyEmail, Account: req.Email, IP: client.IPAddress}
+	if err := s.limiter.Check(ctx, attempt); err != nil {
+		return nil, err
+	}
+
 	// Get user by email
 	user, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
@@ -181,7 +316,7 @@ func (s *Service) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (*Ve
 		s.logger.Warn().
 			Any("user_id", user.ID).
 			Msg("Invalid verification code attempt")
-		return nil, e.ErrVerificationCodeInvalid
+		return nil, s.failAttempt(ctx, attempt, nil, e.ErrVerificationCodeInvalid)
 	}
 
 	// Check expiration
@@ -199,6 +334,7 @@ func (s *Service) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (*Ve
 	if err := s.repo.InvalidateVerificationCode(ctx, verificationCode.ID, req.Code); err != nil {
 		s.logger.Warn().Err(err).Msg("Failed to invalidate verification code")
 	}
+	s.limiter.Succeed(ctx, attempt)
 
 	s.logger.Info().
 		Any("user_id", user.ID).
@@ -212,9 +348,16 @@ func (s *Service) VerifyEmail(ctx context.Context, req *VerifyEmailRequest) (*Ve
 }
 
 // ResendVerificationCode generates and sends a new verification code
-func (s *Service) ResendVerificationCode(ctx context.Context, req *ResendCodeRequest) error {
+func (s *Service) ResendVerificationCode(ctx context.Context, req *ResendCodeRequest, client ClientInfo) error {
 	s.logger.Info().Str("email", req.Email).Msg("Resending verification code")
 
+	// Every send counts, so the endpoint cannot be used to flood an inbox
+	attempt := throttle.Attempt{Scope: scopeResendCode, Account: req.Email, IP: client.IPAddress}
+	if err := s.limiter.Check(ctx, attempt); err != nil {
+		return err
+	}
+	s.limiter.Fail(ctx, attempt)
+
 	// Get user by email
 	user, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
@@ -233,25 +376,26 @@ func (s *Service) ResendVerificationCode(ctx context.Context, req *ResendCodeReq
 		return e.NewInternalError("failed to generate verification code", err)
 	}
 
-	// Save verification code
-	expiresAt := time.Now().Add(24 * time.Hour)
-	if err := s.repo.SaveVerificationCode(ctx, user.ID, verificationCode, expiresAt); err != nil {
+	if err := s.saveVerificationCode(ctx, user, verificationCode); err != nil {
 		return err
 	}
 
-	// TODO: Send verification email
-	s.logger.Info().
-		Any("user_id", user.ID).
-		Str("code", verificationCode).
-		Msg("Verification code generated (TODO: send email)")
-
+	s.logger.Info().Any("user_id", user.ID).Msg("Verification code resent")
 	return nil
 }
 
 // ForgotPassword initiates password reset process
-func (s *Service) ForgotPassword(ctx context.Context, req *ForgotPasswordRequest) error {
+func (s *Service) ForgotPassword(ctx context.Context, req *ForgotPasswordRequest, client ClientInfo) error {
 	s.logger.Info().Str("email", req.Email).Msg("Password reset requested")
 
+	// Every request counts, whether or not the account exists, so the
+	// throttle reveals nothing about which emails are registered
+	attempt := throttle.Attempt{Scope: scopeForgotPassword, Account: req.Email, IP: client.IPAddress}
+	if err := s.limiter.Check(ctx, attempt); err != nil {
+		return err
+	}
+	s.limiter.Fail(ctx, attempt)
+
 	user, err := s.repo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		if appErr := e.GetAppError(err); appErr != nil && appErr.Code == e.CodeNotFound {
@@ -267,35 +411,47 @@ func (s *Service) ForgotPassword(ctx context.Context, req *ForgotPasswordRequest
 		return e.NewInternalError("failed to generate reset token", err)
 	}
 
-	// Save reset token
-	expiresAt := time.Now().Add(1 * time.Hour)
-	if err := s.repo.SavePasswordResetToken(ctx, user.ID, resetToken, expiresAt); err != nil {
-		return err
+	mail, err := s.emails.Compose(user.Email, email.TemplatePasswordReset, map[string]interface{}{
+		"Name":      user.FirstName,
+		"Link":      s.emails.Link("/reset-password", resetToken),
+		"ExpiresIn": resetTokenTTL,
+	})
+	if err != nil {
+		return e.NewInternalError("failed to render password reset email", err)
 	}
 
-	// TODO: Send password reset email
-	s.logger.Info().
-		Any("user_id", user.ID).
-		Str("token", resetToken).
-		Msg("Password reset token generated (TODO: send email)")
+	// Save reset token, queueing its email in the same transaction
+	if err := s.repo.SavePasswordResetToken(ctx, user.ID, resetToken, time.Now().Add(resetTokenTTL), mail); err != nil {
+		return err
+	}
 
+	s.logger.Info().Any("user_id", user.ID).Msg("Password reset email queued")
 	return nil
 }
 
-func (s *Service) ResetPassword(ctx context.Context, req *ResetPasswordRequest) error {
+func (s *Service) ResetPassword(ctx context.Context, req *ResetPasswordRequest, client ClientInfo) error {
 	s.logger.Info().Msg("Password reset attempt")
 
+	// The token is the only identifier, so guesses are counted per IP
+	attempt := throttle.Attempt{Scope: scopeResetPassword, IP: client.IPAddress}
+	if err := s.limiter.Check(ctx, attempt); err != nil {
+		return err
+	}
+
 	resetToken, err := s.repo.GetPasswordResetToken(ctx, req.Token)
 	if err != nil {
+		if appErr := e.GetAppError(err); appErr != nil && appErr.Code == e.CodeNotFound {
+			return s.failAttempt(ctx, attempt, nil, err)
+		}
 		return err
 	}
 
 	if time.Now().After(resetToken.ExpiresAt) {
-		return e.ErrPasswordResetTokenNotFound.WithDetail("reason", "token expired")
+		return s.failAttempt(ctx, attempt, nil, e.ErrPasswordResetTokenNotFound.WithDetail("reason", "token expired"))
 	}
 
 	if resetToken.Used {
-		return e.ErrPasswordResetTokenNotFound.WithDetail("reason", "token already used")
+		return s.failAttempt(ctx, attempt, nil, e.ErrPasswordResetTokenNotFound.WithDetail("reason", "token already used"))
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
@@ -308,6 +464,11 @@ func (s *Service) ResetPassword(ctx context.Context, req *ResetPasswordRequest)
 		return err
 	}
 
+	// Whoever knew the old password may still be signed in
+	if err := s.revokeAllSessions(ctx, resetToken.UserID); err != nil {
+		return err
+	}
+
 	if err := s.repo.InvalidatePasswordResetToken(ctx, req.Token); err != nil {
 		s.logger.Warn().Err(err).Msg("Failed to invalidate reset token")
 	}
@@ -324,14 +485,14 @@ func (s *Service) Logout(ctx context.Context, accessToken, refreshToken string)
 	s.logger.Info().Msg("User logout initiated")
 
 	// Validate and extract claims from access token
-	accessClaims, err := s.jwtManager.ValidateToken(accessToken)
+	accessClaims, err := s.jwtManager.ValidateAccessToken(accessToken)
 	if err != nil {
 		// Token might be invalid or expired, but we still try to blacklist
 		s.logger.Warn().Err(err).Msg("Invalid access token during logout")
 	}
 
 	// Validate and extract claims from refresh token
-	refreshClaims, err := s.jwtManager.ValidateToken(refreshToken)
+	refreshClaims, err := s.jwtManager.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		s.logger.Warn().Err(err).Msg("Invalid refresh token during logout")
 	}
@@ -344,39 +505,132 @@ func (s *Service) Logout(ctx context.Context, accessToken, refreshToken string)
 		}
 	}
 
-	// Blacklist refresh token
+	// Revoke the refresh token's family so no rotation of it survives logout
 	if refreshClaims != nil {
-		if err := s.tokenBlacklist.Add(ctx, refreshToken, refreshClaims.ExpiresAt.Time); err != nil {
-			s.logger.Error().Err(err).Msg("Failed to blacklist refresh token")
-			return err
+		if tokenID, err := uuid.Parse(refreshClaims.ID); err == nil {
+			record, err := s.repo.GetRefreshToken(ctx, tokenID)
+			if err != nil {
+				return err
+			}
+			if record != nil && record.UserID == refreshClaims.UserID {
+				if err := s.repo.RevokeRefreshTokenFamily(ctx, record.FamilyID); err != nil {
+					return err
+				}
+			}
 		}
 	}
 
-	s.logger.Info().
-		Any("user_id", accessClaims.UserID).
-		Msg("User logged out successfully")
+	if accessClaims != nil {
+		s.logger.Info().
+			Any("user_id", accessClaims.UserID).
+			Msg("User logged out successfully")
+	}
+
+	return nil
+}
+
+// LogoutAllDevices logs the user out everywhere: every session and refresh
+// token is revoked, and access tokens issued until now are rejected by
+// TokensValidAfter.
+func (s *Service) LogoutAllDevices(ctx context.Context, userID uuid.UUID) error {
+	s.logger.Info().Any("user_id", userID).Msg("Logout all devices initiated")
+
+	return s.revokeAllSessions(ctx, userID)
+}
 
+// ListSessions returns the user's sessions that can still be refreshed
+func (s *Service) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	sessions, err := s.repo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A session whose last refresh token has expired can never be used again
+	now := time.Now()
+	active := make([]models.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if session.LastSeenAt.Add(s.config.JWT.RefreshTokenExpiry).After(now) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+// RevokeSession signs one of the user's devices out by revoking its refresh
+// token family. Access tokens already issued to it lapse on their own expiry.
+func (s *Service) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	session, err := s.repo.GetSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	if session.RevokedAt != nil {
+		return e.ErrSessionNotFound
+	}
+
+	if err := s.repo.RevokeRefreshTokenFamily(ctx, session.ID); err != nil {
+		return err
+	}
+
+	s.logger.Info().Any("user_id", userID).Any("session_id", sessionID).Msg("Session revoked")
 	return nil
 }
 
-// LogoutAllDevices logs out user from all devices (blacklist all active tokens)
-func (s *Service) LogoutAllDevices(ctx context.Context, userID uint) error {
-	s.logger.Info().Uint("user_id", userID).Msg("Logout all devices initiated")
+// TokensValidAfter returns the time at or before which the user's tokens
+// were revoked by logging out everywhere, nil if they never have. It runs on
+// every authenticated request, so the cutoff is cached for a short while.
+func (s *Service) TokensValidAfter(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	cutoff, found, err := s.cutoffs.Get(ctx, userID)
+	if err != nil {
+		s.logger.Warn().Err(err).Any("user_id", userID).Msg("Failed to read cached token cutoff")
+	} else if found {
+		return cutoff, nil
+	}
 
-	// TODO: If you track user sessions in database, you can blacklist all their tokens
-	// For now, this would require storing session tokens in database
-	// which is beyond the scope of this implementation
+	cutoff, err = s.repo.GetTokensValidAfter(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cutoffs.Set(ctx, userID, cutoff); err != nil {
+		s.logger.Warn().Err(err).Any("user_id", userID).Msg("Failed to cache token cutoff")
+	}
+	return cutoff, nil
+}
 
-	// Alternative: Set a "logged_out_at" timestamp on user record
-	// and reject any tokens issued before that timestamp
+// revokeAllSessions moves the user's token cutoff to now and drops the
+// cached one, so every instance picks up the new cutoff on its next request.
+func (s *Service) revokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := s.repo.RevokeAllSessions(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	if err := s.cutoffs.Invalidate(ctx, userID); err != nil {
+		return e.NewInternalError("failed to invalidate token cutoff", err)
+	}
+	return nil
+}
 
+// UnlockAccount lifts a lockout using the token emailed when it began
+func (s *Service) UnlockAccount(ctx context.Context, req *UnlockAccountRequest) error {
+	claims, err := s.jwtManager.ValidateUnlockToken(req.Token)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	s.limiter.Unlock(ctx, scopeLogin, user.Email)
+	s.limiter.Unlock(ctx, scopeMFA, user.ID.String())
+
+	s.logger.Info().Any("user_id", user.ID).Msg("Account unlocked from email")
 	return nil
 }
 
 // ValidateToken validates a JWT token and checks if it's blacklisted
 func (s *Service) ValidateToken(tokenString string) (*user.User, error) {
 	// First validate the token structure and signature
-	claims, err := s.jwtManager.ValidateToken(tokenString)
+	claims, err := s.jwtManager.ValidateAccessToken(tokenString)
 	if err != nil {
 		return nil, err
 	}
@@ -398,6 +652,10 @@ func (s *Service) ValidateToken(tokenString string) (*user.User, error) {
 		return nil, err
 	}
 
+	if jwt.IssuedBefore(claims, user.TokensValidAfter) {
+		return nil, e.ErrTokenInvalid.WithDetail("reason", "token has been revoked")
+	}
+
 	// Remove password from response
 	user.Password = ""
 
@@ -406,6 +664,158 @@ func (s *Service) ValidateToken(tokenString string) (*user.User, error) {
 
 // Helper functions
 
+// startSession issues a fully authenticated user their tokens and records
+// the session. Every login starts a new refresh token family, which is also
+// the session's ID.
+func (s *Service) startSession(ctx context.Context, user *user.User, device string, client ClientInfo) (*LoginResponse, error) {
+	sessionID := uuid.New()
+	accessToken, refreshToken, refreshID, err := s.issueTokens(ctx, user, sessionID, nil)
+	if err != nil {
+		s.logger.Error().Err(err).Any("user_id", user.ID).Msg("Failed to generate tokens")
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.repo.CreateSession(ctx, &models.Session{
+		ID:             sessionID,
+		UserID:         user.ID,
+		RefreshTokenID: refreshID,
+		Device:         device,
+		IPAddress:      client.IPAddress,
+		UserAgent:      client.UserAgent,
+		CreatedAt:      now,
+		LastSeenAt:     now,
+	}); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().
+		Any("user_id", user.ID).
+		Str("email", user.Email).
+		Msg("User logged in successfully")
+
+	// Remove password from response
+	user.Password = ""
+
+	return &LoginResponse{
+		User:         user,
+		SessionID:    &sessionID,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// issueTokens signs an access token for user and a refresh token in familyID,
+// recording the refresh token server-side and returning its jti. When
+// replacing is set, that token is consumed in favour of the new one; losing
+// that race to a concurrent refresh counts as reuse.
+func (s *Service) issueTokens(ctx context.Context, user *user.User, familyID uuid.UUID, replacing *uuid.UUID) (string, string, uuid.UUID, error) {
+	refreshToken, refreshClaims, err := s.jwtManager.GenerateRefreshToken(user.ID)
+	if err != nil {
+		return "", "", uuid.Nil, err
+	}
+
+	record := &models.RefreshToken{
+		ID:        uuid.MustParse(refreshClaims.ID),
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		ExpiresAt: refreshClaims.ExpiresAt.Time,
+	}
+
+	if replacing != nil {
+		consumed, err := s.repo.ConsumeRefreshToken(ctx, *replacing, record.ID)
+		if err != nil {
+			return "", "", uuid.Nil, err
+		}
+		if !consumed {
+			return "", "", uuid.Nil, s.revokeReusedFamily(ctx, &models.RefreshToken{ID: *replacing, UserID: user.ID, FamilyID: familyID})
+		}
+	}
+
+	if err := s.repo.CreateRefreshToken(ctx, record); err != nil {
+		return "", "", uuid.Nil, err
+	}
+
+	accessToken, err := s.jwtManager.GenerateAccessToken(
+		user.ID,
+		user.Email,
+		user.FirstName+" "+user.LastName,
+		user.Role,
+	)
+	if err != nil {
+		return "", "", uuid.Nil, err
+	}
+
+	return accessToken, refreshToken, record.ID, nil
+}
+
+// revokeReusedFamily handles a refresh token presented after it was already
+// used by revoking its whole family.
+func (s *Service) revokeReusedFamily(ctx context.Context, record *models.RefreshToken) error {
+	s.logger.Warn().
+		Any("user_id", record.UserID).
+		Any("family_id", record.FamilyID).
+		Any("token_id", record.ID).
+		Msg("Refresh token reuse detected, revoking token family")
+
+	if err := s.repo.RevokeRefreshTokenFamily(ctx, record.FamilyID); err != nil {
+		return err
+	}
+	return e.ErrRefreshTokenReused
+}
+
+// failAttempt records a failed attempt and returns err, or the lockout it
+// triggered. A locked-out user is emailed a link to unlock their account.
+func (s *Service) failAttempt(ctx context.Context, attempt throttle.Attempt, user *user.User, err error) error {
+	if !s.limiter.Fail(ctx, attempt) {
+		return err
+	}
+
+	if user != nil {
+		s.sendUnlockLink(ctx, user)
+	}
+	return e.NewAccountLockedError(s.config.LoginThrottle.AccountLockout)
+}
+
+// sendUnlockLink emails the user a token that lifts their lockout early
+func (s *Service) sendUnlockLink(ctx context.Context, user *user.User) {
+	token, err := s.jwtManager.GenerateUnlockToken(user.ID)
+	if err != nil {
+		s.logger.Error().Err(err).Any("user_id", user.ID).Msg("Failed to generate unlock token")
+		return
+	}
+
+	mail, err := s.emails.Compose(user.Email, email.TemplateAccountUnlock, map[string]interface{}{
+		"Name":      user.FirstName,
+		"Link":      s.emails.Link("/unlock", token),
+		"ExpiresIn": s.config.LoginThrottle.AccountLockout,
+	})
+	if err == nil {
+		err = s.emails.Queue(ctx, mail)
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Any("user_id", user.ID).Msg("Failed to queue account unlock email")
+		return
+	}
+
+	s.logger.Info().Any("user_id", user.ID).Msg("Account unlock email queued")
+}
+
+// saveVerificationCode stores a new code for user, queueing its email in the
+// same transaction
+func (s *Service) saveVerificationCode(ctx context.Context, user *user.User, code string) error {
+	mail, err := s.emails.Compose(user.Email, email.TemplateVerification, map[string]interface{}{
+		"Name":      user.FirstName,
+		"Code":      code,
+		"ExpiresIn": verificationCodeTTL,
+	})
+	if err != nil {
+		return e.NewInternalError("failed to render verification email", err)
+	}
+
+	return s.repo.SaveVerificationCode(ctx, user.ID, code, time.Now().Add(verificationCodeTTL), mail)
+}
+
 // generateVerificationCode generates a random 6-digit verification code
 func (s *Service) generateVerificationCode() (string, error) {
 	// Generate 6-digit code
@@ -0,0 +1,500 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/thoraf20/loanee/config"
+	"github.com/thoraf20/loanee/internal/email"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/internal/user"
+	jwt "github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/throttle"
+	"github.com/thoraf20/loanee/pkg/tokenblacklist"
+	"github.com/thoraf20/loanee/pkg/tokencutoff"
+)
+
+func TestRefreshRotatesAndRevokesFamilyOnReuse(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+
+	login, err := svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "password123"}, ClientInfo{})
+	require.NoError(t, err)
+
+	// An access token is not accepted as a refresh token.
+	_, err = svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: login.AccessToken}, ClientInfo{})
+	require.Error(t, err)
+
+	first, err := svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: login.RefreshToken}, ClientInfo{})
+	require.NoError(t, err)
+	require.NotEqual(t, login.RefreshToken, first.RefreshToken)
+
+	// Nor is a refresh token accepted as an access token.
+	_, err = svc.ValidateToken(first.RefreshToken)
+	require.Error(t, err)
+	_, err = svc.ValidateToken(first.AccessToken)
+	require.NoError(t, err)
+
+	second, err := svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: first.RefreshToken}, ClientInfo{})
+	require.NoError(t, err)
+
+	// Replaying the login's token revokes the whole family, including the
+	// latest rotation.
+	_, err = svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: login.RefreshToken}, ClientInfo{})
+	require.True(t, errors.Is(err, e.ErrRefreshTokenReused))
+
+	_, err = svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: second.RefreshToken}, ClientInfo{})
+	require.Error(t, err)
+	for _, token := range repo.tokens {
+		require.NotNil(t, token.RevokedAt)
+	}
+
+	// A new login starts a fresh family.
+	again, err := svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "password123"}, ClientInfo{})
+	require.NoError(t, err)
+	_, err = svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: again.RefreshToken}, ClientInfo{})
+	require.NoError(t, err)
+}
+
+func TestLogoutRevokesRefreshFamily(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+
+	login, err := svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "password123"}, ClientInfo{})
+	require.NoError(t, err)
+	rotated, err := svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: login.RefreshToken}, ClientInfo{})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Logout(ctx, rotated.AccessToken, rotated.RefreshToken))
+
+	_, err = svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: rotated.RefreshToken}, ClientInfo{})
+	require.Error(t, err)
+	_, err = svc.ValidateToken(rotated.AccessToken)
+	require.Error(t, err)
+}
+
+func TestSessionsAndLogoutEverywhere(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+	userID := repo.user.ID
+
+	phone, err := svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "password123", Device: "phone"}, ClientInfo{IPAddress: "10.0.0.1", UserAgent: "app/1.0"})
+	require.NoError(t, err)
+	laptop, err := svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "password123", Device: "laptop"}, ClientInfo{IPAddress: "10.0.0.2"})
+	require.NoError(t, err)
+
+	_, err = svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: phone.RefreshToken}, ClientInfo{IPAddress: "10.0.0.3"})
+	require.NoError(t, err)
+
+	sessions, err := svc.ListSessions(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.Equal(t, "10.0.0.3", repo.sessions[*phone.SessionID].IPAddress)
+	require.Equal(t, "app/1.0", repo.sessions[*phone.SessionID].UserAgent)
+
+	// Revoking one session only signs out that device.
+	require.NoError(t, svc.RevokeSession(ctx, userID, *laptop.SessionID))
+	_, err = svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: laptop.RefreshToken}, ClientInfo{})
+	require.Error(t, err)
+	require.True(t, errors.Is(svc.RevokeSession(ctx, userID, *laptop.SessionID), e.ErrSessionNotFound))
+	require.True(t, errors.Is(svc.RevokeSession(ctx, uuid.New(), *phone.SessionID), e.ErrSessionNotFound))
+
+	sessions, err = svc.ListSessions(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, *phone.SessionID, sessions[0].ID)
+
+	// Logging out everywhere rejects every token issued so far.
+	_, err = svc.ValidateToken(phone.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, svc.LogoutAllDevices(ctx, userID))
+	_, err = svc.ValidateToken(phone.AccessToken)
+	require.Error(t, err)
+
+	sessions, err = svc.ListSessions(ctx, userID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+
+	cutoff, err := svc.TokensValidAfter(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, cutoff)
+}
+
+func TestTokenCutoffIsCachedUntilRevoked(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+	userID := repo.user.ID
+
+	for i := 0; i < 3; i++ {
+		cutoff, err := svc.TokensValidAfter(ctx, userID)
+		require.NoError(t, err)
+		require.Nil(t, cutoff)
+	}
+	require.Equal(t, 1, repo.cutoffReads)
+
+	// Logging out everywhere drops the cached cutoff.
+	require.NoError(t, svc.LogoutAllDevices(ctx, userID))
+	cutoff, err := svc.TokensValidAfter(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, cutoff)
+	require.Equal(t, 2, repo.cutoffReads)
+}
+
+func TestResetPasswordLogsOutEverywhere(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+	userID := repo.user.ID
+
+	login, err := svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "password123"}, ClientInfo{})
+	require.NoError(t, err)
+	cutoff, err := svc.TokensValidAfter(ctx, userID)
+	require.NoError(t, err)
+	require.Nil(t, cutoff)
+
+	repo.resetToken = "reset-token"
+	require.NoError(t, svc.ResetPassword(ctx, &ResetPasswordRequest{Token: "reset-token", NewPassword: "new-password123"}, ClientInfo{}))
+
+	cutoff, err = svc.TokensValidAfter(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, cutoff)
+	_, err = svc.Refresh(ctx, &RefreshTokenRequest{RefreshToken: login.RefreshToken}, ClientInfo{})
+	require.Error(t, err)
+}
+
+func TestLoginWithMFARequiresSecondFactor(t *testing.T) {
+	svc, _ := newTestService(t)
+	svc.mfa = &fakeMFA{code: "123456"}
+	ctx := context.Background()
+
+	login, err := svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "password123"}, ClientInfo{})
+	require.NoError(t, err)
+	require.True(t, login.MFARequired)
+	require.Empty(t, login.AccessToken)
+	require.Empty(t, login.RefreshToken)
+
+	// The challenge token is not an access token.
+	_, err = svc.ValidateToken(login.MFAToken)
+	require.Error(t, err)
+
+	_, err = svc.CompleteMFALogin(ctx, &LoginMFARequest{MFAToken: login.MFAToken, Code: "000000"}, ClientInfo{})
+	require.True(t, errors.Is(err, e.ErrMFACodeInvalid))
+
+	done, err := svc.CompleteMFALogin(ctx, &LoginMFARequest{MFAToken: login.MFAToken, Code: "123456"}, ClientInfo{})
+	require.NoError(t, err)
+	require.NotEmpty(t, done.RefreshToken)
+	_, err = svc.ValidateToken(done.AccessToken)
+	require.NoError(t, err)
+}
+
+func TestLoginLocksOutAfterRepeatedFailures(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+	client := ClientInfo{IPAddress: "203.0.113.7"}
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "wrong"}, client)
+		require.True(t, errors.Is(err, e.ErrInvalidCredentials))
+	}
+
+	_, err := svc.Login(ctx, &LoginRequest{Email: "ADA@example.com", Password: "wrong"}, client)
+	appErr := e.GetAppError(err)
+	require.NotNil(t, appErr)
+	require.Equal(t, e.CodeAccountLocked, appErr.Code)
+	require.Positive(t, appErr.RetryAfter)
+
+	// The right password is refused while the lockout lasts.
+	_, err = svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "password123"}, client)
+	require.Equal(t, e.CodeAccountLocked, e.GetAppError(err).Code)
+
+	// Another scope is unaffected.
+	require.NoError(t, svc.limiter.Check(ctx, throttle.Attempt{Scope: scopeForgotPassword, Account: "ada@example.com"}))
+
+	// The emailed unlock token lifts the lockout early.
+	token, err := svc.jwtManager.GenerateUnlockToken(repo.user.ID)
+	require.NoError(t, err)
+	require.NoError(t, svc.UnlockAccount(ctx, &UnlockAccountRequest{Token: token}))
+
+	_, err = svc.Login(ctx, &LoginRequest{Email: "ada@example.com", Password: "password123"}, client)
+	require.NoError(t, err)
+}
+
+func TestForgotPasswordQueuesResetEmailWithToken(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+
+	require.NoError(t, svc.ForgotPassword(ctx, &ForgotPasswordRequest{Email: "ada@example.com"}, ClientInfo{}))
+
+	// The email is handed to the repository with the token, to be stored in
+	// one transaction
+	require.NotEmpty(t, repo.resetToken)
+	require.NotNil(t, repo.resetMail)
+	require.Equal(t, "ada@example.com", repo.resetMail.Recipient)
+	require.Equal(t, models.OutboxPending, repo.resetMail.Status)
+	require.Contains(t, repo.resetMail.TextBody, "https://app.example.com/reset-password?token="+repo.resetToken)
+	require.Contains(t, repo.resetMail.HTMLBody, "Hi Ada,")
+	require.Empty(t, repo.outbox.mails)
+
+	// Unknown emails are not revealed and get nothing
+	repo.resetMail = nil
+	require.NoError(t, svc.ForgotPassword(ctx, &ForgotPasswordRequest{Email: "nobody@example.com"}, ClientInfo{}))
+	require.Nil(t, repo.resetMail)
+}
+
+func newTestService(t *testing.T) (*Service, *memoryRepo) {
+	t.Helper()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := &memoryRepo{
+		user: &user.User{
+			ID:         uuid.New(),
+			Email:      "ada@example.com",
+			Password:   string(hashed),
+			FirstName:  "Ada",
+			LastName:   "Lovelace",
+			Role:       "user",
+			IsVerified: true,
+		},
+		tokens:   make(map[uuid.UUID]*models.RefreshToken),
+		sessions: make(map[uuid.UUID]*models.Session),
+		outbox:   &memoryOutbox{},
+	}
+
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+	cfg.JWT.AccessTokenExpiry = time.Minute
+	cfg.JWT.RefreshTokenExpiry = time.Hour
+	cfg.LoginThrottle.AccountLockout = time.Minute
+
+	// No delays, so only the lockout after three failures is exercised
+	limiter := throttle.NewLimiter(throttle.NewMemoryStore(), throttle.Options{
+		Account: throttle.Rule{MaxFailures: 3, Window: time.Minute, Lockout: time.Minute},
+		IP:      throttle.Rule{MaxFailures: 100, Window: time.Minute, Lockout: time.Minute},
+	}, zerolog.Nop())
+
+	manager, err := jwt.NewManager(cfg)
+	require.NoError(t, err)
+
+	templates, err := email.LoadTemplates()
+	require.NoError(t, err)
+	emails := email.NewService(repo.outbox, templates, nil, email.Options{AppName: "Loanee", LinkBaseURL: "https://app.example.com/"}, zerolog.Nop())
+
+	svc := NewService(repo, manager, tokenblacklist.NewMemoryBlacklist(zerolog.Nop()), tokencutoff.NewMemoryCache(time.Minute), nil, limiter, emails, cfg, zerolog.Nop())
+	return svc, repo
+}
+
+// memoryOutbox records queued emails; the embedded Repository is nil.
+type memoryOutbox struct {
+	email.Repository
+	mails []models.OutboxEmail
+}
+
+func (m *memoryOutbox) Create(ctx context.Context, mail *models.OutboxEmail) error {
+	m.mails = append(m.mails, *mail)
+	return nil
+}
+
+type fakeMFA struct {
+	code string
+}
+
+func (f *fakeMFA) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeMFA) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	if code != f.code {
+		return e.ErrMFACodeInvalid
+	}
+	return nil
+}
+
+// memoryRepo backs the refresh token and session flows; the embedded
+// Repository is nil, so any other method panics if a test reaches it.
+type memoryRepo struct {
+	Repository
+
+	mu       sync.Mutex
+	user     *user.User
+	tokens   map[uuid.UUID]*models.RefreshToken
+	sessions map[uuid.UUID]*models.Session
+	outbox   *memoryOutbox
+
+	resetToken string
+	resetMail  *models.OutboxEmail
+
+	cutoffReads int
+}
+
+func (m *memoryRepo) GetTokensValidAfter(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cutoffReads++
+	if userID != m.user.ID {
+		return nil, e.ErrUserNotFound
+	}
+	return m.user.TokensValidAfter, nil
+}
+
+func (m *memoryRepo) GetPasswordResetToken(ctx context.Context, token string) (*user.PasswordResetToken, error) {
+	if token == "" || token != m.resetToken {
+		return nil, e.ErrPasswordResetTokenNotFound
+	}
+	return &user.PasswordResetToken{UserID: m.user.ID, Token: token, ExpiresAt: time.Now().Add(time.Hour)}, nil
+}
+
+func (m *memoryRepo) UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.user.Password = hashedPassword
+	return nil
+}
+
+func (m *memoryRepo) InvalidatePasswordResetToken(ctx context.Context, token string) error {
+	m.resetToken = ""
+	return nil
+}
+
+func (m *memoryRepo) SavePasswordResetToken(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time, mail *models.OutboxEmail) error {
+	m.resetToken = token
+	m.resetMail = mail
+	return nil
+}
+
+func (m *memoryRepo) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if email != m.user.Email {
+		return nil, e.ErrUserNotFound
+	}
+	u := *m.user
+	return &u, nil
+}
+
+func (m *memoryRepo) GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id != m.user.ID {
+		return nil, e.ErrUserNotFound
+	}
+	u := *m.user
+	return &u, nil
+}
+
+func (m *memoryRepo) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *token
+	m.tokens[token.ID] = &stored
+	return nil
+}
+
+func (m *memoryRepo) GetRefreshToken(ctx context.Context, id uuid.UUID) (*models.RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.tokens[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *token
+	return &copied, nil
+}
+
+func (m *memoryRepo) ConsumeRefreshToken(ctx context.Context, id, replacedBy uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.tokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	token.ReplacedBy = &replacedBy
+	return true, nil
+}
+
+func (m *memoryRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, token := range m.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	if session, ok := m.sessions[familyID]; ok && session.RevokedAt == nil {
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+func (m *memoryRepo) CreateSession(ctx context.Context, session *models.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *session
+	m.sessions[session.ID] = &stored
+	return nil
+}
+
+func (m *memoryRepo) TouchSession(ctx context.Context, id, refreshTokenID uuid.UUID, ipAddress string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if session, ok := m.sessions[id]; ok {
+		session.RefreshTokenID = refreshTokenID
+		session.LastSeenAt = time.Now()
+		if ipAddress != "" {
+			session.IPAddress = ipAddress
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []models.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *memoryRepo) GetSession(ctx context.Context, userID, id uuid.UUID) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[id]
+	if !ok || session.UserID != userID {
+		return nil, e.ErrSessionNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (m *memoryRepo) RevokeAllSessions(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.user.TokensValidAfter = &at
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &at
+		}
+	}
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+		}
+	}
+	return nil
+}
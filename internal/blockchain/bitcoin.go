@@ -0,0 +1,269 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const satoshisPerBitcoin = 100_000_000
+
+var errNotFound = errors.New("not found")
+
+// BitcoinVerifier verifies BTC deposits against an Esplora-compatible REST
+// API (Blockstream, mempool.space or a self-hosted electrs instance).
+type BitcoinVerifier struct {
+	baseURL          string
+	client           *http.Client
+	minConfirmations int64
+}
+
+// NewBitcoinVerifier returns a verifier that queries the Esplora API at baseURL.
+func NewBitcoinVerifier(baseURL string, minConfirmations int) *BitcoinVerifier {
+	return &BitcoinVerifier{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		minConfirmations: int64(minConfirmations),
+	}
+}
+
+// Assets lists the symbols this verifier handles.
+func (v *BitcoinVerifier) Assets() []string {
+	return []string{"BTC"}
+}
+
+type esploraTx struct {
+	TxID   string          `json:"txid"`
+	Vin    []esploraInput  `json:"vin"`
+	Vout   []esploraOutput `json:"vout"`
+	Status struct {
+		Confirmed   bool   `json:"confirmed"`
+		BlockHeight int64  `json:"block_height"`
+		BlockHash   string `json:"block_hash"`
+	} `json:"status"`
+}
+
+type esploraInput struct {
+	Prevout *esploraOutput `json:"prevout"`
+}
+
+type esploraOutput struct {
+	Address string `json:"scriptpubkey_address"`
+	Value   int64  `json:"value"`
+}
+
+// VerifyTransaction sums the outputs paying the expected deposit address and
+// checks the total and the confirmation depth. When a sender is expected, at
+// least one input must spend from that address.
+func (v *BitcoinVerifier) VerifyTransaction(ctx context.Context, exp Expectation) (bool, *TransactionData, error) {
+	if !strings.EqualFold(exp.AssetSymbol, "BTC") {
+		return false, nil, fmt.Errorf("asset %s is not supported by the bitcoin verifier", exp.AssetSymbol)
+	}
+	if exp.Recipient == "" {
+		return false, nil, errors.New("a deposit address is required to verify BTC deposits")
+	}
+
+	var tx esploraTx
+	if err := v.getJSON(ctx, "/tx/"+strings.TrimPrefix(exp.TxHash, "0x"), &tx); err != nil {
+		if errors.Is(err, errNotFound) {
+			return false, nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, exp.TxHash)
+		}
+		return false, nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if !tx.Status.Confirmed {
+		return false, nil, fmt.Errorf("%w: transaction is still pending", ErrInsufficientConfirmations)
+	}
+
+	tip, err := v.tipHeight(ctx)
+	if err != nil {
+		return false, nil, fmt.Errorf("could not fetch chain tip: %w", err)
+	}
+
+	// Bitcoin counts the including block as the first confirmation.
+	confirmations := tip - tx.Status.BlockHeight + 1
+	if confirmations < v.minConfirmations {
+		return false, nil, fmt.Errorf("%w: transaction has only %d confirmations", ErrInsufficientConfirmations, confirmations)
+	}
+
+	from := ""
+	for _, in := range tx.Vin {
+		if in.Prevout == nil {
+			continue
+		}
+		if exp.Sender == "" || in.Prevout.Address == exp.Sender {
+			from = in.Prevout.Address
+			break
+		}
+	}
+	if exp.Sender != "" && from == "" {
+		return false, nil, fmt.Errorf("transaction does not spend from %s", exp.Sender)
+	}
+
+	var received int64
+	for _, out := range tx.Vout {
+		if out.Address == exp.Recipient {
+			received += out.Value
+		}
+	}
+	if received == 0 {
+		return false, nil, fmt.Errorf("transaction has no output paying %s", exp.Recipient)
+	}
+
+	if received < int64(math.Round(exp.MinimumAmount()*satoshisPerBitcoin)) {
+		return false, nil, fmt.Errorf(
+			"transaction pays %.8f BTC, expected %.8f BTC",
+			float64(received)/satoshisPerBitcoin, exp.Amount,
+		)
+	}
+
+	return true, &TransactionData{
+		Hash:          tx.TxID,
+		From:          from,
+		To:            exp.Recipient,
+		Amount:        float64(received) / satoshisPerBitcoin,
+		Confirmations: confirmations,
+		BlockNumber:   uint64(tx.Status.BlockHeight),
+		BlockHash:     tx.Status.BlockHash,
+	}, nil
+}
+
+// Chain implements Scanner.
+func (v *BitcoinVerifier) Chain() string {
+	return "bitcoin"
+}
+
+// Balance implements BalanceReader with the address's confirmed funds.
+func (v *BitcoinVerifier) Balance(ctx context.Context, asset, address string) (float64, error) {
+	if !strings.EqualFold(asset, "BTC") {
+		return 0, fmt.Errorf("asset %s is not supported by the bitcoin verifier", asset)
+	}
+
+	var stats struct {
+		ChainStats struct {
+			Funded int64 `json:"funded_txo_sum"`
+			Spent  int64 `json:"spent_txo_sum"`
+		} `json:"chain_stats"`
+	}
+	if err := v.getJSON(ctx, "/address/"+address, &stats); err != nil {
+		return 0, fmt.Errorf("failed to fetch balance for %s: %w", address, err)
+	}
+	return float64(stats.ChainStats.Funded-stats.ChainStats.Spent) / satoshisPerBitcoin, nil
+}
+
+// LatestBlock implements Scanner.
+func (v *BitcoinVerifier) LatestBlock(ctx context.Context) (uint64, error) {
+	height, err := v.tipHeight(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not fetch chain tip: %w", err)
+	}
+	return uint64(height), nil
+}
+
+// ScanDeposits implements Scanner by walking each address's confirmed
+// history, newest first, until it drops below the range.
+func (v *BitcoinVerifier) ScanDeposits(ctx context.Context, from, to uint64, addresses []string) ([]Deposit, error) {
+	var deposits []Deposit
+	for _, address := range addresses {
+		path := "/address/" + address + "/txs/chain"
+		for {
+			var page []esploraTx
+			if err := v.getJSON(ctx, path, &page); err != nil {
+				return nil, fmt.Errorf("failed to fetch transactions for %s: %w", address, err)
+			}
+
+			for _, tx := range page {
+				height := uint64(tx.Status.BlockHeight)
+				if height < from || height > to {
+					continue
+				}
+
+				var received int64
+				for _, out := range tx.Vout {
+					if out.Address == address {
+						received += out.Value
+					}
+				}
+				if received == 0 {
+					continue
+				}
+
+				sender := ""
+				if len(tx.Vin) > 0 && tx.Vin[0].Prevout != nil {
+					sender = tx.Vin[0].Prevout.Address
+				}
+				deposits = append(deposits, Deposit{
+					TxHash:      tx.TxID,
+					AssetSymbol: "BTC",
+					From:        sender,
+					To:          address,
+					Amount:      float64(received) / satoshisPerBitcoin,
+					BlockNumber: height,
+				})
+			}
+
+			// Esplora pages confirmed history in chunks of 25.
+			if len(page) < 25 || uint64(page[len(page)-1].Status.BlockHeight) < from {
+				break
+			}
+			path = "/address/" + address + "/txs/chain/" + page[len(page)-1].TxID
+		}
+	}
+	return deposits, nil
+}
+
+func (v *BitcoinVerifier) tipHeight(ctx context.Context) (int64, error) {
+	body, err := v.get(ctx, "/blocks/tip/height")
+	if err != nil {
+		return 0, err
+	}
+	height, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid tip height: %w", err)
+	}
+	return height, nil
+}
+
+func (v *BitcoinVerifier) getJSON(ctx context.Context, path string, out interface{}) error {
+	body, err := v.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (v *BitcoinVerifier) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.baseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bitcoin API returned status %d", resp.StatusCode)
+	}
+	return body, nil
+}
@@ -0,0 +1,197 @@
+package blockchain
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const btcDepositAddress = "bc1qdeposit0000000000000000000000000000000"
+
+// esploraStub is an httptest stand-in for an Esplora node serving a fixed
+// set of transactions and chain tip.
+type esploraStub struct {
+	tip      int64
+	txs      map[string]esploraTx
+	feeRates map[string]float64
+}
+
+func (s *esploraStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/blocks/tip/height" {
+		_, _ = w.Write([]byte(strconv.FormatInt(s.tip, 10)))
+		return
+	}
+
+	if r.URL.Path == "/fee-estimates" {
+		_ = json.NewEncoder(w).Encode(s.feeRates)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/address/") {
+		address := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/address/"), "/txs/chain")
+		history := []esploraTx{}
+		for _, tx := range s.txs {
+			for _, out := range tx.Vout {
+				if out.Address == address && tx.Status.Confirmed {
+					history = append(history, tx)
+					break
+				}
+			}
+		}
+		sort.Slice(history, func(i, j int) bool {
+			return history[i].Status.BlockHeight > history[j].Status.BlockHeight
+		})
+		_ = json.NewEncoder(w).Encode(history)
+		return
+	}
+
+	tx, ok := s.txs[r.URL.Path[len("/tx/"):]]
+	if !ok {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(tx)
+}
+
+func newBitcoinVerifier(t *testing.T, stub *esploraStub, minConfirmations int) *BitcoinVerifier {
+	t.Helper()
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return NewBitcoinVerifier(server.URL, minConfirmations)
+}
+
+func bitcoinTx(txid string, height int64, outputs ...esploraOutput) esploraTx {
+	tx := esploraTx{TxID: txid, Vout: outputs}
+	tx.Vin = []esploraInput{{Prevout: &esploraOutput{Address: "bc1qsender", Value: 1_000_000_000}}}
+	if height > 0 {
+		tx.Status.Confirmed = true
+		tx.Status.BlockHeight = height
+	}
+	return tx
+}
+
+func TestBitcoinVerifyTransaction(t *testing.T) {
+	stub := &esploraStub{
+		tip: 102,
+		txs: map[string]esploraTx{
+			"paid": bitcoinTx("paid", 100,
+				esploraOutput{Address: btcDepositAddress, Value: 30_000_000},
+				esploraOutput{Address: "bc1qchange", Value: 5_000_000},
+				esploraOutput{Address: btcDepositAddress, Value: 20_000_000},
+			),
+			"short":   bitcoinTx("short", 100, esploraOutput{Address: btcDepositAddress, Value: 49_000_000}),
+			"shallow": bitcoinTx("shallow", 102, esploraOutput{Address: btcDepositAddress, Value: 50_000_000}),
+			"mempool": bitcoinTx("mempool", 0, esploraOutput{Address: btcDepositAddress, Value: 50_000_000}),
+			"elsewhere": bitcoinTx("elsewhere", 100,
+				esploraOutput{Address: "bc1qsomeoneelse", Value: 50_000_000},
+			),
+		},
+	}
+	verifier := newBitcoinVerifier(t, stub, 3)
+
+	expect := func(txid string) Expectation {
+		return Expectation{TxHash: txid, AssetSymbol: "BTC", Amount: 0.5, Recipient: btcDepositAddress}
+	}
+
+	valid, data, err := verifier.VerifyTransaction(context.Background(), expect("paid"))
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Equal(t, 0.5, data.Amount)
+	require.Equal(t, int64(3), data.Confirmations)
+	require.Equal(t, "bc1qsender", data.From)
+	require.Equal(t, btcDepositAddress, data.To)
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), expect("short"))
+	require.ErrorContains(t, err, "expected 0.50000000 BTC")
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), expect("shallow"))
+	require.ErrorContains(t, err, "only 1 confirmations")
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), expect("mempool"))
+	require.ErrorContains(t, err, "still pending")
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), expect("elsewhere"))
+	require.ErrorContains(t, err, "no output paying")
+
+	tolerant := expect("short")
+	tolerant.Tolerance = 0.02
+	valid, _, err = verifier.VerifyTransaction(context.Background(), tolerant)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	fromSender := expect("paid")
+	fromSender.Sender = "bc1qsender"
+	_, _, err = verifier.VerifyTransaction(context.Background(), fromSender)
+	require.NoError(t, err)
+
+	fromSender.Sender = "bc1qsomeoneelse"
+	_, _, err = verifier.VerifyTransaction(context.Background(), fromSender)
+	require.ErrorContains(t, err, "does not spend from")
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), expect("missing"))
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestBitcoinScanDeposits(t *testing.T) {
+	stub := &esploraStub{
+		tip: 110,
+		txs: map[string]esploraTx{
+			"old":     bitcoinTx("old", 90, esploraOutput{Address: btcDepositAddress, Value: 10_000_000}),
+			"inrange": bitcoinTx("inrange", 105, esploraOutput{Address: btcDepositAddress, Value: 25_000_000}),
+			"mempool": bitcoinTx("mempool", 0, esploraOutput{Address: btcDepositAddress, Value: 50_000_000}),
+			"other":   bitcoinTx("other", 105, esploraOutput{Address: "bc1qsomeoneelse", Value: 50_000_000}),
+		},
+	}
+	verifier := newBitcoinVerifier(t, stub, 1)
+
+	head, err := verifier.LatestBlock(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(110), head)
+
+	deposits, err := verifier.ScanDeposits(context.Background(), 100, head, []string{btcDepositAddress})
+	require.NoError(t, err)
+	require.Equal(t, []Deposit{{
+		TxHash:      "inrange",
+		AssetSymbol: "BTC",
+		From:        "bc1qsender",
+		To:          btcDepositAddress,
+		Amount:      0.25,
+		BlockNumber: 105,
+	}}, deposits)
+}
+
+func TestRegistryDispatchesByAsset(t *testing.T) {
+	stub := &esploraStub{
+		tip: 10,
+		txs: map[string]esploraTx{
+			"paid": bitcoinTx("paid", 5, esploraOutput{Address: btcDepositAddress, Value: 100_000_000}),
+		},
+	}
+
+	registry := NewRegistry()
+	btc := newBitcoinVerifier(t, stub, 1)
+	registry.Register(btc.Chain(), btc, btc.Assets()...)
+	registry.Register("ethereum", NewNoopVerifier(), "ETH")
+
+	require.Equal(t, []string{"BTC", "ETH"}, registry.Assets())
+
+	valid, data, err := registry.VerifyTransaction(context.Background(), Expectation{
+		TxHash: "paid", AssetSymbol: "btc", Amount: 1, Recipient: btcDepositAddress,
+	})
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Equal(t, int64(6), data.Confirmations)
+
+	// Assets without a verifier are rejected instead of falling through to another chain.
+	_, _, err = registry.VerifyTransaction(context.Background(), Expectation{
+		TxHash: "paid", AssetSymbol: "SOL", Amount: 1,
+	})
+	require.ErrorIs(t, err, ErrUnsupportedAsset)
+}
@@ -0,0 +1,86 @@
+package blockchain
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TransferEventTopic is keccak256("Transfer(address,address,uint256)").
+var TransferEventTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+var (
+	transferSelector  = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+	balanceOfSelector = crypto.Keccak256([]byte("balanceOf(address)"))[:4]
+)
+
+// Token describes an ERC-20 asset accepted as collateral.
+type Token struct {
+	Symbol   string
+	Contract common.Address
+	Decimals uint8
+}
+
+// ToBaseUnits converts a human-readable amount into the token's smallest unit,
+// rounding to the nearest unit.
+func (t Token) ToBaseUnits(amount float64) *big.Int {
+	scaled := new(big.Float).SetPrec(256).SetFloat64(amount)
+	scaled.Mul(scaled, new(big.Float).SetInt(t.unit()))
+	scaled.Add(scaled, big.NewFloat(0.5))
+	result, _ := scaled.Int(nil)
+	return result
+}
+
+// FromBaseUnits converts an amount in the token's smallest unit to a decimal value.
+func (t Token) FromBaseUnits(amount *big.Int) *big.Float {
+	return new(big.Float).SetPrec(256).Quo(
+		new(big.Float).SetPrec(256).SetInt(amount),
+		new(big.Float).SetPrec(256).SetInt(t.unit()),
+	)
+}
+
+func (t Token) unit() *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Decimals)), nil)
+}
+
+// TransferCalldata encodes an ERC-20 transfer(to, amount) call.
+func TransferCalldata(to common.Address, amount *big.Int) []byte {
+	data := append([]byte{}, transferSelector...)
+	data = append(data, common.LeftPadBytes(to.Bytes(), 32)...)
+	return append(data, common.LeftPadBytes(amount.Bytes(), 32)...)
+}
+
+// BalanceOfCalldata encodes an ERC-20 balanceOf(owner) call.
+func BalanceOfCalldata(owner common.Address) []byte {
+	data := append([]byte{}, balanceOfSelector...)
+	return append(data, common.LeftPadBytes(owner.Bytes(), 32)...)
+}
+
+// Transfer is a decoded ERC-20 Transfer event.
+type Transfer struct {
+	From  common.Address
+	To    common.Address
+	Value *big.Int
+}
+
+// DecodeTransfers extracts the Transfer events emitted by contract from a
+// receipt's logs. Logs that do not follow the standard layout are skipped.
+func DecodeTransfers(logs []*types.Log, contract common.Address) []Transfer {
+	var transfers []Transfer
+	for _, entry := range logs {
+		if entry == nil || entry.Removed || entry.Address != contract {
+			continue
+		}
+		if len(entry.Topics) != 3 || entry.Topics[0] != TransferEventTopic || len(entry.Data) != 32 {
+			continue
+		}
+		transfers = append(transfers, Transfer{
+			From:  common.BytesToAddress(entry.Topics[1].Bytes()),
+			To:    common.BytesToAddress(entry.Topics[2].Bytes()),
+			Value: new(big.Int).SetBytes(entry.Data),
+		})
+	}
+	return transfers
+}
@@ -0,0 +1,449 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/rs/zerolog/log"
+)
+
+// EVMClient is the subset of the Ethereum JSON-RPC API the verifier relies
+// on. Both *ethclient.Client and the go-ethereum simulated backend satisfy it.
+type EVMClient interface {
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+}
+
+// EVMNetwork describes an EVM-compatible chain deposits are accepted on.
+type EVMNetwork struct {
+	Name             string
+	ChainID          int64
+	NativeSymbol     string
+	MinConfirmations int
+	Tokens           []Token
+	// MaxFeePerGas caps the fee cap transfers are quoted with, matching the
+	// withdrawal worker's limit; nil leaves it unbounded.
+	MaxFeePerGas *big.Int
+}
+
+// EVMVerifier verifies and scans deposits on a single EVM network.
+type EVMVerifier struct {
+	client           EVMClient
+	network          string
+	chainID          *big.Int
+	minConfirmations int64
+	nativeSymbol     string
+	tokens           map[string]Token
+	maxFeePerGas     *big.Int
+}
+
+// NewEVMVerifier dials an RPC endpoint and checks it serves the configured
+// chain, so a mislabelled URL cannot verify deposits for the wrong network.
+func NewEVMVerifier(ctx context.Context, rpcURL string, network EVMNetwork) (*EVMVerifier, error) {
+	client, err := ethclient.DialContext(ctx, rpcURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s RPC: %w", network.Name, err)
+	}
+
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to fetch %s chain id: %w", network.Name, err)
+	}
+	if network.ChainID != 0 && chainID.Int64() != network.ChainID {
+		client.Close()
+		return nil, fmt.Errorf("%s RPC serves chain %d, expected %d", network.Name, chainID.Int64(), network.ChainID)
+	}
+	network.ChainID = chainID.Int64()
+
+	return NewEVMVerifierWithClient(client, network), nil
+}
+
+// NewEVMVerifierWithClient builds a verifier around an existing client.
+func NewEVMVerifierWithClient(client EVMClient, network EVMNetwork) *EVMVerifier {
+	bySymbol := make(map[string]Token, len(network.Tokens))
+	for _, token := range network.Tokens {
+		bySymbol[strings.ToUpper(token.Symbol)] = token
+	}
+
+	var chainID *big.Int
+	if network.ChainID != 0 {
+		chainID = big.NewInt(network.ChainID)
+	}
+
+	return &EVMVerifier{
+		client:           client,
+		network:          network.Name,
+		chainID:          chainID,
+		minConfirmations: int64(network.MinConfirmations),
+		nativeSymbol:     strings.ToUpper(network.NativeSymbol),
+		tokens:           bySymbol,
+		maxFeePerGas:     network.MaxFeePerGas,
+	}
+}
+
+// Assets lists the native asset and every configured token symbol.
+func (v *EVMVerifier) Assets() []string {
+	assets := []string{v.nativeSymbol}
+	for symbol := range v.tokens {
+		assets = append(assets, symbol)
+	}
+	return assets
+}
+
+// VerifyTransaction checks that a transaction exists on-chain, succeeded, has the
+// required confirmations and carries the expected amount. Native coin deposits
+// are read from the transaction value; token deposits from ERC-20 Transfer logs.
+func (v *EVMVerifier) VerifyTransaction(ctx context.Context, exp Expectation) (bool, *TransactionData, error) {
+	symbol := strings.ToUpper(exp.AssetSymbol)
+	token, isToken := v.tokens[symbol]
+	if !isToken && symbol != v.nativeSymbol {
+		return false, nil, fmt.Errorf("asset %s is not supported on %s", exp.AssetSymbol, v.network)
+	}
+	if exp.Recipient == "" {
+		return false, nil, errors.New("a deposit address is required to verify deposits")
+	}
+
+	hash := common.HexToHash(exp.TxHash)
+	tx, isPending, err := v.client.TransactionByHash(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil, fmt.Errorf("%w: %s", ErrTransactionNotFound, exp.TxHash)
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to fetch transaction: %w", err)
+	}
+	if isPending {
+		return false, nil, fmt.Errorf("%w: transaction is still pending", ErrInsufficientConfirmations)
+	}
+
+	receipt, err := v.client.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil, fmt.Errorf("%w: no receipt for %s", ErrTransactionNotFound, exp.TxHash)
+	}
+	if err != nil {
+		return false, nil, fmt.Errorf("could not fetch transaction receipt: %w", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return false, nil, ErrTransactionFailed
+	}
+
+	blockHeader, err := v.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, nil, fmt.Errorf("could not fetch latest block header: %w", err)
+	}
+
+	currentBlock := blockHeader.Number.Int64()
+	txBlock := receipt.BlockNumber.Int64()
+	confirmations := currentBlock - txBlock
+
+	if confirmations < v.minConfirmations {
+		log.Warn().
+			Int64("confirmations", confirmations).
+			Int64("required", v.minConfirmations).
+			Msg("transaction does not have enough confirmations")
+		return false, nil, fmt.Errorf("%w: transaction has only %d confirmations", ErrInsufficientConfirmations, confirmations)
+	}
+
+	from, err := v.getSenderAddress(ctx, tx)
+	if err != nil {
+		log.Warn().Err(err).Msg("could not determine sender address")
+	}
+	if exp.Sender != "" && !strings.EqualFold(from, exp.Sender) {
+		return false, nil, fmt.Errorf("transaction was not sent from %s", exp.Sender)
+	}
+
+	txData := &TransactionData{
+		Hash:          exp.TxHash,
+		From:          from,
+		Confirmations: confirmations,
+		BlockNumber:   receipt.BlockNumber.Uint64(),
+		BlockHash:     receipt.BlockHash.Hex(),
+	}
+
+	if isToken {
+		if err := v.verifyTokenTransfer(receipt, token, exp, txData); err != nil {
+			return false, nil, err
+		}
+		return true, txData, nil
+	}
+
+	to := ""
+	if tx.To() != nil {
+		to = tx.To().Hex()
+	}
+	if !strings.EqualFold(to, exp.Recipient) {
+		return false, nil, fmt.Errorf("transaction was not sent to %s", exp.Recipient)
+	}
+
+	native := Token{Symbol: v.nativeSymbol, Decimals: 18}
+	if tx.Value().Cmp(native.ToBaseUnits(exp.MinimumAmount())) < 0 {
+		return false, nil, fmt.Errorf(
+			"transaction value %s %s is below expected %.6f %s",
+			native.FromBaseUnits(tx.Value()).Text('f', 6), native.Symbol, exp.Amount, native.Symbol,
+		)
+	}
+
+	txData.To = to
+	txData.Amount, _ = native.FromBaseUnits(tx.Value()).Float64()
+	return true, txData, nil
+}
+
+// verifyTokenTransfer sums the ERC-20 transfers emitted by the token contract
+// from the expected sender to the expected recipient and checks the total
+// against the expected amount.
+func (v *EVMVerifier) verifyTokenTransfer(receipt *types.Receipt, token Token, exp Expectation, txData *TransactionData) error {
+	transfers := DecodeTransfers(receipt.Logs, token.Contract)
+	if len(transfers) == 0 {
+		return fmt.Errorf("transaction contains no %s transfer", token.Symbol)
+	}
+
+	total := new(big.Int)
+	recipient := ""
+	for _, transfer := range transfers {
+		if !strings.EqualFold(transfer.To.Hex(), exp.Recipient) {
+			continue
+		}
+		if exp.Sender != "" && !strings.EqualFold(transfer.From.Hex(), exp.Sender) {
+			continue
+		}
+		if recipient == "" {
+			recipient = transfer.To.Hex()
+		}
+		total.Add(total, transfer.Value)
+	}
+	if recipient == "" {
+		return fmt.Errorf("transaction contains no %s transfer to %s", token.Symbol, exp.Recipient)
+	}
+
+	expected := token.ToBaseUnits(exp.Amount)
+	if total.Cmp(token.ToBaseUnits(exp.MinimumAmount())) < 0 {
+		return fmt.Errorf(
+			"transferred %s %s does not cover expected %s %s",
+			token.FromBaseUnits(total).Text('f', int(token.Decimals)), token.Symbol,
+			token.FromBaseUnits(expected).Text('f', int(token.Decimals)), token.Symbol,
+		)
+	}
+
+	txData.To = recipient
+	txData.Amount, _ = token.FromBaseUnits(total).Float64()
+	return nil
+}
+
+// Chain implements Scanner.
+func (v *EVMVerifier) Chain() string {
+	return v.network
+}
+
+// Balance implements BalanceReader. It reads at the newest block that has
+// the network's confirmation depth, so unconfirmed transfers are not counted.
+func (v *EVMVerifier) Balance(ctx context.Context, asset, address string) (float64, error) {
+	if !common.IsHexAddress(address) {
+		return 0, fmt.Errorf("invalid address %q", address)
+	}
+	owner := common.HexToAddress(address)
+
+	head, err := v.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("could not fetch latest block header: %w", err)
+	}
+	block := head.Number
+	if v.minConfirmations > 1 {
+		block = new(big.Int).Sub(head.Number, big.NewInt(v.minConfirmations-1))
+		if block.Sign() < 0 {
+			block.SetInt64(0)
+		}
+	}
+
+	if strings.EqualFold(asset, v.nativeSymbol) {
+		wei, err := v.client.BalanceAt(ctx, owner, block)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s balance: %w", v.nativeSymbol, err)
+		}
+		native := Token{Symbol: v.nativeSymbol, Decimals: 18}
+		amount, _ := native.FromBaseUnits(wei).Float64()
+		return amount, nil
+	}
+
+	token, ok := v.tokens[strings.ToUpper(asset)]
+	if !ok {
+		return 0, fmt.Errorf("asset %s is not supported on %s", asset, v.network)
+	}
+	out, err := v.client.CallContract(ctx, ethereum.CallMsg{
+		To:   &token.Contract,
+		Data: BalanceOfCalldata(owner),
+	}, block)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s balance: %w", token.Symbol, err)
+	}
+	if len(out) != 32 {
+		return 0, fmt.Errorf("unexpected balanceOf response from %s", token.Contract.Hex())
+	}
+	amount, _ := token.FromBaseUnits(new(big.Int).SetBytes(out)).Float64()
+	return amount, nil
+}
+
+// LatestBlock implements Scanner.
+func (v *EVMVerifier) LatestBlock(ctx context.Context) (uint64, error) {
+	header, err := v.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("could not fetch latest block header: %w", err)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// ScanDeposits implements Scanner. Native deposits are read from block bodies
+// and token deposits from Transfer logs; transfers that reverted are skipped.
+func (v *EVMVerifier) ScanDeposits(ctx context.Context, from, to uint64, addresses []string) ([]Deposit, error) {
+	watched := make(map[common.Address]struct{}, len(addresses))
+	topics := make([]common.Hash, 0, len(addresses))
+	for _, address := range addresses {
+		if !common.IsHexAddress(address) {
+			continue
+		}
+		addr := common.HexToAddress(address)
+		watched[addr] = struct{}{}
+		topics = append(topics, common.BytesToHash(addr.Bytes()))
+	}
+	if len(watched) == 0 {
+		return nil, nil
+	}
+
+	deposits, err := v.scanNativeDeposits(ctx, from, to, watched)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.tokens) == 0 {
+		return deposits, nil
+	}
+
+	byContract := make(map[common.Address]Token, len(v.tokens))
+	contracts := make([]common.Address, 0, len(v.tokens))
+	for _, token := range v.tokens {
+		byContract[token.Contract] = token
+		contracts = append(contracts, token.Contract)
+	}
+
+	logs, err := v.client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: contracts,
+		Topics:    [][]common.Hash{{TransferEventTopic}, nil, topics},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter transfer logs: %w", err)
+	}
+
+	// A single transaction may split a deposit across several transfers.
+	index := make(map[string]int)
+	for i := range logs {
+		entry := &logs[i]
+		token := byContract[entry.Address]
+		for _, transfer := range DecodeTransfers([]*types.Log{entry}, token.Contract) {
+			key := entry.TxHash.Hex() + token.Symbol + transfer.To.Hex()
+			if at, ok := index[key]; ok {
+				total := new(big.Float).Add(big.NewFloat(deposits[at].Amount), token.FromBaseUnits(transfer.Value))
+				deposits[at].Amount, _ = total.Float64()
+				continue
+			}
+			amount, _ := token.FromBaseUnits(transfer.Value).Float64()
+			index[key] = len(deposits)
+			deposits = append(deposits, Deposit{
+				TxHash:      entry.TxHash.Hex(),
+				AssetSymbol: token.Symbol,
+				From:        transfer.From.Hex(),
+				To:          transfer.To.Hex(),
+				Amount:      amount,
+				BlockNumber: entry.BlockNumber,
+			})
+		}
+	}
+
+	return deposits, nil
+}
+
+func (v *EVMVerifier) scanNativeDeposits(ctx context.Context, from, to uint64, watched map[common.Address]struct{}) ([]Deposit, error) {
+	signer, err := v.signer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	native := Token{Symbol: v.nativeSymbol, Decimals: 18}
+
+	var deposits []Deposit
+	for number := from; number <= to; number++ {
+		block, err := v.client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch block %d: %w", number, err)
+		}
+
+		for _, tx := range block.Transactions() {
+			if tx.To() == nil || tx.Value().Sign() == 0 {
+				continue
+			}
+			if _, ok := watched[*tx.To()]; !ok {
+				continue
+			}
+
+			receipt, err := v.client.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				return nil, fmt.Errorf("could not fetch transaction receipt: %w", err)
+			}
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				continue
+			}
+
+			sender, err := types.Sender(signer, tx)
+			if err != nil {
+				log.Warn().Err(err).Str("tx_hash", tx.Hash().Hex()).Msg("could not determine sender address")
+			}
+			amount, _ := native.FromBaseUnits(tx.Value()).Float64()
+			deposits = append(deposits, Deposit{
+				TxHash:      tx.Hash().Hex(),
+				AssetSymbol: native.Symbol,
+				From:        sender.Hex(),
+				To:          tx.To().Hex(),
+				Amount:      amount,
+				BlockNumber: number,
+			})
+		}
+	}
+	return deposits, nil
+}
+
+func (v *EVMVerifier) getSenderAddress(ctx context.Context, tx *types.Transaction) (string, error) {
+	signer, err := v.signer(ctx)
+	if err != nil {
+		return "", err
+	}
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return "", err
+	}
+	return from.Hex(), nil
+}
+
+// signer uses the configured chain ID, asking the node only when none was set.
+func (v *EVMVerifier) signer(ctx context.Context) (types.Signer, error) {
+	chainID := v.chainID
+	if chainID == nil {
+		var err error
+		chainID, err = v.client.ChainID(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch chain id: %w", err)
+		}
+	}
+	return types.LatestSignerForChainID(chainID), nil
+}
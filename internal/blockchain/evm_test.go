@@ -0,0 +1,446 @@
+package blockchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	tokenContract  = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	revertContract = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	depositAddress = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+)
+
+// simulatedChainID is the chain ID of the go-ethereum simulated backend.
+const simulatedChainID = 1337
+
+func TestVerifyNativeTransfer(t *testing.T) {
+	chain := newTestChain(t)
+
+	hash := chain.send(t, depositAddress, big.NewInt(params.Ether/2), nil, 21000)
+	chain.mine(3)
+
+	verifier := chain.verifier(3)
+	valid, data, err := verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "ETH",
+		Amount:      0.5,
+		Recipient:   depositAddress.Hex(),
+	})
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Equal(t, chain.sender.Hex(), data.From)
+	require.Equal(t, 0.5, data.Amount)
+}
+
+func TestVerifyTokenTransfer(t *testing.T) {
+	chain := newTestChain(t)
+
+	// 250.5 USDT with 6 decimals
+	hash := chain.send(t, tokenContract, big.NewInt(0), TransferCalldata(depositAddress, big.NewInt(250_500_000)), 100000)
+	chain.mine(1)
+
+	verifier := chain.verifier(1)
+	valid, data, err := verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "usdt",
+		Amount:      250.5,
+		Recipient:   depositAddress.Hex(),
+	})
+	require.NoError(t, err)
+	require.True(t, valid)
+	require.Equal(t, depositAddress.Hex(), data.To)
+	require.Equal(t, 250.5, data.Amount)
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "USDT",
+		Amount:      300,
+		Recipient:   depositAddress.Hex(),
+	})
+	require.ErrorContains(t, err, "does not cover expected")
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "USDT",
+		Amount:      250.5,
+		Recipient:   chain.sender.Hex(),
+	})
+	require.ErrorContains(t, err, "no USDT transfer to")
+}
+
+func TestVerifyRejectsZeroValueContractCallForToken(t *testing.T) {
+	chain := newTestChain(t)
+
+	// A plain call with no Transfer event must not count as a token deposit.
+	hash := chain.send(t, depositAddress, big.NewInt(0), nil, 21000)
+	chain.mine(1)
+
+	_, _, err := chain.verifier(1).VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "USDT",
+		Amount:      10,
+		Recipient:   depositAddress.Hex(),
+	})
+	require.ErrorContains(t, err, "contains no USDT transfer")
+}
+
+func TestVerifyRejectsFailedReceipt(t *testing.T) {
+	chain := newTestChain(t)
+
+	hash := chain.send(t, revertContract, big.NewInt(params.Ether), nil, 100000)
+	chain.mine(1)
+
+	_, _, err := chain.verifier(1).VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "ETH",
+		Amount:      1,
+		Recipient:   revertContract.Hex(),
+	})
+	require.ErrorContains(t, err, "failed on-chain")
+}
+
+func TestVerifyRequiresConfirmations(t *testing.T) {
+	chain := newTestChain(t)
+
+	hash := chain.send(t, depositAddress, big.NewInt(params.Ether), nil, 21000)
+
+	_, _, err := chain.verifier(5).VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "ETH",
+		Amount:      1,
+		Recipient:   depositAddress.Hex(),
+	})
+	require.ErrorContains(t, err, "confirmations")
+}
+
+func TestVerifyNativeUnderpayment(t *testing.T) {
+	chain := newTestChain(t)
+
+	// 0.999 ETH against an expected 1 ETH
+	hash := chain.send(t, depositAddress, big.NewInt(params.Ether-params.Ether/1000), nil, 21000)
+	chain.mine(1)
+	verifier := chain.verifier(1)
+
+	_, _, err := verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "ETH",
+		Amount:      1,
+		Recipient:   depositAddress.Hex(),
+	})
+	require.ErrorContains(t, err, "below expected 1.000000 ETH")
+
+	valid, _, err := verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "ETH",
+		Amount:      1,
+		Recipient:   depositAddress.Hex(),
+		Tolerance:   0.001,
+	})
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "ETH",
+		Amount:      0.5,
+		Recipient:   tokenContract.Hex(),
+	})
+	require.ErrorContains(t, err, "not sent to")
+}
+
+func TestVerifyBindsSender(t *testing.T) {
+	chain := newTestChain(t)
+
+	native := chain.send(t, depositAddress, big.NewInt(params.Ether), nil, 21000)
+	token := chain.send(t, tokenContract, big.NewInt(0), TransferCalldata(depositAddress, big.NewInt(10_000_000)), 100000)
+	chain.mine(1)
+	verifier := chain.verifier(1)
+	stranger := common.HexToAddress("0x00000000000000000000000000000000000000dd")
+
+	valid, _, err := verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      native.Hex(),
+		AssetSymbol: "ETH",
+		Amount:      1,
+		Recipient:   depositAddress.Hex(),
+		Sender:      strings.ToLower(chain.sender.Hex()),
+	})
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      native.Hex(),
+		AssetSymbol: "ETH",
+		Amount:      1,
+		Recipient:   depositAddress.Hex(),
+		Sender:      stranger.Hex(),
+	})
+	require.ErrorContains(t, err, "not sent from")
+
+	_, _, err = verifier.VerifyTransaction(context.Background(), Expectation{
+		TxHash:      token.Hex(),
+		AssetSymbol: "USDT",
+		Amount:      10,
+		Recipient:   depositAddress.Hex(),
+		Sender:      stranger.Hex(),
+	})
+	require.ErrorContains(t, err, "not sent from")
+}
+
+func TestVerifyDetectsReorg(t *testing.T) {
+	chain := newTestChain(t)
+	ctx := context.Background()
+	client := chain.backend.Client()
+
+	parent, err := client.HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+
+	hash := chain.send(t, depositAddress, big.NewInt(params.Ether), nil, 21000)
+	expectation := Expectation{
+		TxHash:      hash.Hex(),
+		AssetSymbol: "ETH",
+		Amount:      1,
+		Recipient:   depositAddress.Hex(),
+	}
+
+	_, data, err := chain.verifier(0).VerifyTransaction(ctx, expectation)
+	require.NoError(t, err)
+	require.Equal(t, parent.Number.Uint64()+1, data.BlockNumber)
+	included := data.BlockHash
+
+	// A longer side chain drops the including block; the deposit is mined
+	// again from the pool, in a block with a different hash.
+	require.NoError(t, chain.backend.Fork(parent.Hash()))
+	chain.mine(3)
+
+	_, data, err = chain.verifier(0).VerifyTransaction(ctx, expectation)
+	require.NoError(t, err)
+	require.NotEqual(t, included, data.BlockHash)
+
+	// Another side chain double-spends the nonce, so the deposit vanishes.
+	require.NoError(t, chain.backend.Fork(parent.Hash()))
+	chain.nonce = 0
+	chain.sendWithTip(t, common.HexToAddress("0x00000000000000000000000000000000000000dd"), big.NewInt(params.Ether), 10*params.GWei)
+	chain.mine(4)
+
+	_, _, err = chain.verifier(0).VerifyTransaction(ctx, expectation)
+	require.ErrorIs(t, err, ErrTransactionNotFound)
+}
+
+func TestRegistryRoutesByNetwork(t *testing.T) {
+	chain := newTestChain(t)
+
+	hash := chain.send(t, depositAddress, big.NewInt(params.Ether), nil, 21000)
+	chain.mine(1)
+
+	// The same simulated chain stands in for a second network whose native
+	// coin is BNB and whose USDT contract differs.
+	ethereum := chain.verifier(1)
+	bsc := chain.network(EVMNetwork{
+		Name:             "bsc",
+		NativeSymbol:     "BNB",
+		MinConfirmations: 1,
+		Tokens:           []Token{{Symbol: "USDT", Contract: revertContract, Decimals: 18}},
+	})
+
+	registry := NewRegistry()
+	registry.Register(ethereum.Chain(), ethereum, ethereum.Assets()...)
+	registry.Register(bsc.Chain(), bsc, bsc.Assets()...)
+
+	require.Equal(t, []string{"BNB", "ETH", "USDT"}, registry.Assets())
+	require.Equal(t, []string{"ethereum", "bsc"}, registry.Networks("USDT"))
+	network, ok := registry.DefaultNetwork("usdt")
+	require.True(t, ok)
+	require.Equal(t, "ethereum", network)
+
+	exp := Expectation{TxHash: hash.Hex(), AssetSymbol: "BNB", Amount: 1, Recipient: depositAddress.Hex()}
+	valid, _, err := registry.VerifyTransaction(context.Background(), exp)
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	// ETH is only accepted on the networks that list it.
+	exp.AssetSymbol = "ETH"
+	exp.Network = "bsc"
+	_, _, err = registry.VerifyTransaction(context.Background(), exp)
+	require.ErrorIs(t, err, ErrUnsupportedAsset)
+
+	exp.Network = ""
+	valid, _, err = registry.VerifyTransaction(context.Background(), exp)
+	require.NoError(t, err)
+	require.True(t, valid)
+}
+
+func TestScanDeposits(t *testing.T) {
+	chain := newTestChain(t)
+	verifier := chain.verifier(1)
+	ctx := context.Background()
+
+	start, err := verifier.LatestBlock(ctx)
+	require.NoError(t, err)
+
+	native := chain.send(t, depositAddress, big.NewInt(params.Ether), nil, 21000)
+	token := chain.send(t, tokenContract, big.NewInt(0), TransferCalldata(depositAddress, big.NewInt(5_000_000)), 100000)
+	chain.send(t, revertContract, big.NewInt(params.Ether), nil, 100000)
+	chain.send(t, common.HexToAddress("0x00000000000000000000000000000000000000dd"), big.NewInt(params.Ether), nil, 21000)
+
+	head, err := verifier.LatestBlock(ctx)
+	require.NoError(t, err)
+	require.Equal(t, start+4, head)
+
+	deposits, err := verifier.ScanDeposits(ctx, start+1, head, []string{
+		strings.ToLower(depositAddress.Hex()),
+		revertContract.Hex(),
+		"not-an-address",
+	})
+	require.NoError(t, err)
+	require.Len(t, deposits, 2)
+
+	require.Equal(t, native.Hex(), deposits[0].TxHash)
+	require.Equal(t, "ETH", deposits[0].AssetSymbol)
+	require.Equal(t, 1.0, deposits[0].Amount)
+	require.Equal(t, chain.sender.Hex(), deposits[0].From)
+	require.Equal(t, start+1, deposits[0].BlockNumber)
+
+	require.Equal(t, token.Hex(), deposits[1].TxHash)
+	require.Equal(t, "USDT", deposits[1].AssetSymbol)
+	require.Equal(t, 5.0, deposits[1].Amount)
+	require.Equal(t, depositAddress.Hex(), deposits[1].To)
+
+	// Ranges outside the deposits find nothing.
+	deposits, err = verifier.ScanDeposits(ctx, start+3, head, []string{depositAddress.Hex()})
+	require.NoError(t, err)
+	require.Empty(t, deposits)
+}
+
+func TestBalanceHonoursConfirmations(t *testing.T) {
+	chain := newTestChain(t)
+	ctx := context.Background()
+
+	chain.send(t, depositAddress, big.NewInt(params.Ether/2), nil, 21000)
+	verifier := chain.verifier(3)
+
+	balance, err := verifier.Balance(ctx, "ETH", depositAddress.Hex())
+	require.NoError(t, err)
+	require.Zero(t, balance)
+
+	chain.mine(2)
+	balance, err = verifier.Balance(ctx, "eth", depositAddress.Hex())
+	require.NoError(t, err)
+	require.Equal(t, 0.5, balance)
+
+	_, err = verifier.Balance(ctx, "BTC", depositAddress.Hex())
+	require.Error(t, err)
+}
+
+func TestTokenBaseUnits(t *testing.T) {
+	token := Token{Symbol: "USDT", Decimals: 6}
+	require.Equal(t, "100500000", token.ToBaseUnits(100.5).String())
+	require.Equal(t, "1", token.ToBaseUnits(0.000001).String())
+
+	value, _ := token.FromBaseUnits(big.NewInt(1_234_567)).Float64()
+	require.Equal(t, 1.234567, value)
+}
+
+type testChain struct {
+	backend *simulated.Backend
+	key     *ecdsa.PrivateKey
+	sender  common.Address
+	nonce   uint64
+}
+
+func newTestChain(t *testing.T) *testChain {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+
+	balance := new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		sender:         {Balance: balance},
+		tokenContract:  {Code: transferEmitterCode()},
+		revertContract: {Code: common.FromHex("0x60006000fd")},
+	})
+	t.Cleanup(func() { backend.Close() })
+
+	return &testChain{backend: backend, key: key, sender: sender}
+}
+
+func (c *testChain) verifier(minConfirmations int) *EVMVerifier {
+	return c.network(EVMNetwork{
+		Name:             "ethereum",
+		NativeSymbol:     "ETH",
+		MinConfirmations: minConfirmations,
+		Tokens:           []Token{{Symbol: "USDT", Contract: tokenContract, Decimals: 6}},
+	})
+}
+
+func (c *testChain) network(network EVMNetwork) *EVMVerifier {
+	network.ChainID = simulatedChainID
+	return NewEVMVerifierWithClient(c.backend.Client(), network)
+}
+
+func (c *testChain) send(t *testing.T, to common.Address, value *big.Int, data []byte, gas uint64) common.Hash {
+	t.Helper()
+	return c.sendTx(t, to, value, data, gas, params.GWei)
+}
+
+// sendWithTip sends a plain transfer with a custom priority fee, e.g. to
+// replace a pooled transaction with the same nonce.
+func (c *testChain) sendWithTip(t *testing.T, to common.Address, value *big.Int, tip int64) common.Hash {
+	t.Helper()
+	return c.sendTx(t, to, value, nil, 21000, tip)
+}
+
+func (c *testChain) sendTx(t *testing.T, to common.Address, value *big.Int, data []byte, gas uint64, tip int64) common.Hash {
+	t.Helper()
+	ctx := context.Background()
+	client := c.backend.Client()
+
+	chainID, err := client.ChainID(ctx)
+	require.NoError(t, err)
+	head, err := client.HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+
+	tx, err := types.SignNewTx(c.key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID:   chainID,
+		Nonce:     c.nonce,
+		GasTipCap: big.NewInt(tip),
+		GasFeeCap: new(big.Int).Add(head.BaseFee, big.NewInt(2*tip)),
+		Gas:       gas,
+		To:        &to,
+		Value:     value,
+		Data:      data,
+	})
+	require.NoError(t, err)
+	require.NoError(t, client.SendTransaction(ctx, tx))
+	c.nonce++
+
+	c.backend.Commit()
+	return tx.Hash()
+}
+
+func (c *testChain) mine(blocks int) {
+	for i := 0; i < blocks; i++ {
+		c.backend.Commit()
+	}
+}
+
+// transferEmitterCode is runtime bytecode for a minimal token stand-in: any
+// call with (address to, uint256 amount) calldata emits
+// Transfer(msg.sender, to, amount).
+func transferEmitterCode() []byte {
+	code := common.FromHex("0x602435600052600435337f")
+	code = append(code, TransferEventTopic.Bytes()...)
+	return append(code, common.FromHex("0x60206000a300")...)
+}
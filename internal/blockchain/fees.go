@@ -0,0 +1,145 @@
+package blockchain
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// NativeTransferGas is the gas used by a plain coin transfer.
+	NativeTransferGas = 21_000
+	// TokenTransferGas covers an ERC-20 transfer with headroom; USDT needs
+	// about 63k. A fixed limit keeps the reserved fee predictable.
+	TokenTransferGas = 100_000
+
+	// bitcoinTransferVBytes sizes a one-input, two-output P2WPKH transaction.
+	bitcoinTransferVBytes = 141
+	// bitcoinFeeTarget is the confirmation target, in blocks, fee rates are
+	// quoted for.
+	bitcoinFeeTarget = "3"
+)
+
+// FeeEstimate is the network fee for one transfer of an asset, paid in the
+// chain's native coin.
+type FeeEstimate struct {
+	Network string `json:"network"`
+	// FeeAsset is the coin the fee is paid in, which differs from the
+	// transferred asset for tokens.
+	FeeAsset  string  `json:"fee_asset"`
+	FeeAmount float64 `json:"fee_amount"`
+
+	// EIP-1559 pricing on EVM networks.
+	BaseFeeGwei     float64 `json:"base_fee_gwei,omitempty"`
+	PriorityFeeGwei float64 `json:"priority_fee_gwei,omitempty"`
+	GasLimit        uint64  `json:"gas_limit,omitempty"`
+
+	// Fee rate and size on Bitcoin.
+	SatPerVByte float64 `json:"sat_per_vbyte,omitempty"`
+	VBytes      int64   `json:"vbytes,omitempty"`
+}
+
+// FeeEstimator quotes the current network fee for transferring an asset.
+type FeeEstimator interface {
+	EstimateFee(ctx context.Context, asset string) (*FeeEstimate, error)
+}
+
+// FeeCaps returns the EIP-1559 priority fee and fee cap a transfer is sent
+// with: twice the base fee plus the tip, so the transaction stays includable
+// through a few blocks of rising base fees, limited to maxFeePerGas when set.
+// Withdrawals reserve gas times the fee cap, so quotes use the same caps.
+func FeeCaps(baseFee, tip, maxFeePerGas *big.Int) (*big.Int, *big.Int) {
+	tip = new(big.Int).Set(tip)
+	feeCap := new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tip)
+	if maxFeePerGas != nil && feeCap.Cmp(maxFeePerGas) > 0 {
+		feeCap.Set(maxFeePerGas)
+		if tip.Cmp(feeCap) > 0 {
+			tip.Set(feeCap)
+		}
+	}
+	return tip, feeCap
+}
+
+// EstimateFee implements FeeEstimator with the fee a withdrawal reserves:
+// the gas limit times the fee cap FeeCaps derives from the latest base fee
+// and the node's suggested priority fee.
+func (v *EVMVerifier) EstimateFee(ctx context.Context, asset string) (*FeeEstimate, error) {
+	symbol := strings.ToUpper(asset)
+	gas := uint64(NativeTransferGas)
+	if symbol != v.nativeSymbol {
+		if _, ok := v.tokens[symbol]; !ok {
+			return nil, fmt.Errorf("asset %s is not supported on %s", asset, v.network)
+		}
+		gas = TokenTransferGas
+	}
+
+	head, err := v.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch latest block header: %w", err)
+	}
+	tip, err := v.client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to suggest gas tip: %w", err)
+	}
+	baseFee := new(big.Int)
+	if head.BaseFee != nil {
+		baseFee.Set(head.BaseFee)
+	}
+
+	_, feeCap := FeeCaps(baseFee, tip, v.maxFeePerGas)
+	native := Token{Symbol: v.nativeSymbol, Decimals: 18}
+	amount, _ := native.FromBaseUnits(feeCap.Mul(feeCap, new(big.Int).SetUint64(gas))).Float64()
+	return &FeeEstimate{
+		Network:         v.network,
+		FeeAsset:        v.nativeSymbol,
+		FeeAmount:       amount,
+		BaseFeeGwei:     toGwei(baseFee),
+		PriorityFeeGwei: toGwei(tip),
+		GasLimit:        gas,
+	}, nil
+}
+
+// EstimateFee implements FeeEstimator with the node's fee rate for the
+// confirmation target applied to a typical single-input transfer.
+func (v *BitcoinVerifier) EstimateFee(ctx context.Context, asset string) (*FeeEstimate, error) {
+	if !strings.EqualFold(asset, "BTC") {
+		return nil, fmt.Errorf("asset %s is not supported by the bitcoin verifier", asset)
+	}
+
+	var rates map[string]float64
+	if err := v.getJSON(ctx, "/fee-estimates", &rates); err != nil {
+		return nil, fmt.Errorf("failed to fetch fee estimates: %w", err)
+	}
+	rate, ok := rates[bitcoinFeeTarget]
+	if !ok || rate <= 0 {
+		return nil, fmt.Errorf("no fee estimate for a %s-block target", bitcoinFeeTarget)
+	}
+
+	return &FeeEstimate{
+		Network:     v.Chain(),
+		FeeAsset:    "BTC",
+		FeeAmount:   rate * bitcoinTransferVBytes / satoshisPerBitcoin,
+		SatPerVByte: rate,
+		VBytes:      bitcoinTransferVBytes,
+	}, nil
+}
+
+// EstimateFee routes to the verifier registered for an asset on network,
+// the asset's default network when empty.
+func (r *Registry) EstimateFee(ctx context.Context, network, asset string) (*FeeEstimate, error) {
+	verifier, ok := r.Lookup(network, asset)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAsset, asset)
+	}
+	estimator, ok := verifier.(FeeEstimator)
+	if !ok {
+		return nil, fmt.Errorf("fees cannot be estimated for %s", asset)
+	}
+	return estimator.EstimateFee(ctx, asset)
+}
+
+func toGwei(wei *big.Int) float64 {
+	gwei, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e9)).Float64()
+	return gwei
+}
@@ -0,0 +1,66 @@
+package blockchain
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEVMEstimateFee(t *testing.T) {
+	chain := newTestChain(t)
+	verifier := chain.verifier(1)
+
+	native, err := verifier.EstimateFee(context.Background(), "eth")
+	require.NoError(t, err)
+	require.Equal(t, "ETH", native.FeeAsset)
+	require.Equal(t, uint64(NativeTransferGas), native.GasLimit)
+	require.Positive(t, native.BaseFeeGwei)
+	perGas := (2*native.BaseFeeGwei + native.PriorityFeeGwei) * 1e-9
+	require.InDelta(t, perGas*NativeTransferGas, native.FeeAmount, 1e-12)
+
+	// Token transfers are paid in the native coin with a larger gas limit.
+	token, err := verifier.EstimateFee(context.Background(), "USDT")
+	require.NoError(t, err)
+	require.Equal(t, "ETH", token.FeeAsset)
+	require.Equal(t, uint64(TokenTransferGas), token.GasLimit)
+	require.Greater(t, token.FeeAmount, native.FeeAmount)
+
+	_, err = verifier.EstimateFee(context.Background(), "BTC")
+	require.Error(t, err)
+}
+
+func TestFeeCaps(t *testing.T) {
+	tip, feeCap := FeeCaps(big.NewInt(100), big.NewInt(5), nil)
+	require.Equal(t, int64(5), tip.Int64())
+	require.Equal(t, int64(205), feeCap.Int64())
+
+	// The limit bounds the cap, and the tip with it.
+	tip, feeCap = FeeCaps(big.NewInt(100), big.NewInt(5), big.NewInt(150))
+	require.Equal(t, int64(5), tip.Int64())
+	require.Equal(t, int64(150), feeCap.Int64())
+	tip, feeCap = FeeCaps(big.NewInt(0), big.NewInt(300), big.NewInt(150))
+	require.Equal(t, int64(150), tip.Int64())
+	require.Equal(t, int64(150), feeCap.Int64())
+}
+
+func TestRegistryEstimatesBitcoinFee(t *testing.T) {
+	stub := &esploraStub{feeRates: map[string]float64{"1": 20, "3": 10, "6": 5}}
+	registry := NewRegistry()
+	btc := newBitcoinVerifier(t, stub, 1)
+	registry.Register(btc.Chain(), btc, btc.Assets()...)
+	registry.Register("ethereum", NewNoopVerifier(), "ETH")
+
+	fee, err := registry.EstimateFee(context.Background(), "", "BTC")
+	require.NoError(t, err)
+	require.Equal(t, "bitcoin", fee.Network)
+	require.Equal(t, 10.0, fee.SatPerVByte)
+	require.Equal(t, int64(141), fee.VBytes)
+	require.InDelta(t, 0.0000141, fee.FeeAmount, 1e-12)
+
+	_, err = registry.EstimateFee(context.Background(), "", "ETH")
+	require.Error(t, err)
+	_, err = registry.EstimateFee(context.Background(), "", "SOL")
+	require.ErrorIs(t, err, ErrUnsupportedAsset)
+}
@@ -0,0 +1,114 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedAsset is returned when no verifier is registered for an asset.
+var ErrUnsupportedAsset = errors.New("no verifier registered for asset")
+
+// Registry dispatches verification to the verifier registered for each
+// network and asset, so every chain is checked by a backend that actually
+// understands it. An asset offered on several networks (USDT on Ethereum and
+// BSC, say) resolves to the first network registered for it unless the
+// expectation names one.
+type Registry struct {
+	mu        sync.RWMutex
+	verifiers map[string]Verifier
+	defaults  map[string]string
+	networks  map[string][]string
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		verifiers: make(map[string]Verifier),
+		defaults:  make(map[string]string),
+		networks:  make(map[string][]string),
+	}
+}
+
+// Register routes the given asset symbols on network to verifier.
+func (r *Registry) Register(network string, verifier Verifier, assets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	network = strings.ToLower(network)
+	for _, asset := range assets {
+		asset = strings.ToUpper(asset)
+		key := registryKey(network, asset)
+		if _, ok := r.verifiers[key]; !ok {
+			r.networks[asset] = append(r.networks[asset], network)
+		}
+		r.verifiers[key] = verifier
+		if _, ok := r.defaults[asset]; !ok {
+			r.defaults[asset] = network
+		}
+	}
+}
+
+// Lookup returns the verifier registered for an asset on network. An empty
+// network selects the asset's default network.
+func (r *Registry) Lookup(network, asset string) (Verifier, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	asset = strings.ToUpper(asset)
+	if network == "" {
+		network = r.defaults[asset]
+	}
+	verifier, ok := r.verifiers[registryKey(strings.ToLower(network), asset)]
+	return verifier, ok
+}
+
+// DefaultNetwork returns the network an asset resolves to when none is named.
+func (r *Registry) DefaultNetwork(asset string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	network, ok := r.defaults[strings.ToUpper(asset)]
+	return network, ok
+}
+
+// Networks lists the networks an asset can be deposited on, default first.
+func (r *Registry) Networks(asset string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]string(nil), r.networks[strings.ToUpper(asset)]...)
+}
+
+// Assets lists the registered asset symbols in alphabetical order.
+func (r *Registry) Assets() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	assets := make([]string, 0, len(r.defaults))
+	for asset := range r.defaults {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+// VerifyTransaction implements Verifier by delegating on the expected network
+// and asset.
+func (r *Registry) VerifyTransaction(ctx context.Context, exp Expectation) (bool, *TransactionData, error) {
+	verifier, ok := r.Lookup(exp.Network, exp.AssetSymbol)
+	if !ok {
+		if exp.Network != "" {
+			return false, nil, fmt.Errorf("%w: %s on %s", ErrUnsupportedAsset, exp.AssetSymbol, exp.Network)
+		}
+		return false, nil, fmt.Errorf("%w: %s", ErrUnsupportedAsset, exp.AssetSymbol)
+	}
+	return verifier.VerifyTransaction(ctx, exp)
+}
+
+func registryKey(network, asset string) string {
+	return network + "/" + asset
+}
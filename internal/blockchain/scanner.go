@@ -0,0 +1,49 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrTransactionNotFound is returned when the node does not know a
+// transaction, for example because a reorganisation dropped it.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// ErrTransactionFailed is returned when a transaction was mined but reverted.
+var ErrTransactionFailed = errors.New("transaction failed on-chain")
+
+// ErrInsufficientConfirmations is returned when a transaction is valid but not
+// yet buried deep enough to be trusted.
+var ErrInsufficientConfirmations = errors.New("insufficient confirmations")
+
+// Deposit is an incoming transfer to a watched address observed on-chain.
+type Deposit struct {
+	TxHash      string
+	AssetSymbol string
+	From        string
+	To          string
+	Amount      float64
+	BlockNumber uint64
+}
+
+// Scanner finds deposits to a set of addresses within a block range.
+type Scanner interface {
+	// Chain names the chain, used to persist scan progress.
+	Chain() string
+	// Assets lists the symbols the scanner reports deposits for.
+	Assets() []string
+	// LatestBlock returns the current chain height.
+	LatestBlock(ctx context.Context) (uint64, error)
+	// ScanDeposits returns successful transfers to any of addresses in the
+	// inclusive block range [from, to].
+	ScanDeposits(ctx context.Context, from, to uint64, addresses []string) ([]Deposit, error)
+}
+
+// BalanceReader reads confirmed on-chain balances on one chain.
+type BalanceReader interface {
+	Chain() string
+	Assets() []string
+	// Balance returns how much of asset address holds once the chain's
+	// confirmation depth is applied.
+	Balance(ctx context.Context, asset, address string) (float64, error)
+}
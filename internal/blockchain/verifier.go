@@ -0,0 +1,98 @@
+package blockchain
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ErrInvalidTxHash is returned for a transaction hash that is not well formed
+// for its chain.
+var ErrInvalidTxHash = errors.New("invalid transaction hash")
+
+// TransactionData captures basic on-chain transaction metadata.
+type TransactionData struct {
+	Hash          string
+	From          string
+	To            string
+	Amount        float64
+	Confirmations int64
+	// BlockNumber and BlockHash identify the block that included the
+	// transaction, so later checks can notice it being reorganised away.
+	BlockNumber uint64
+	BlockHash   string
+}
+
+// Expectation describes the deposit a transaction is expected to carry.
+type Expectation struct {
+	TxHash      string
+	AssetSymbol string
+	// Network names the chain the deposit was made on. Empty selects the
+	// asset's default network.
+	Network string
+	Amount  float64
+	// Recipient is the platform deposit address the funds must be paid to.
+	Recipient string
+	// Sender, when set, is the address the funds must be paid from.
+	Sender string
+	// Tolerance is the fraction of Amount a deposit may fall short by, so
+	// rounding between the quoted and the sent amount does not fail a lock.
+	Tolerance float64
+}
+
+// MinimumAmount is the smallest deposit that satisfies the expectation.
+func (e Expectation) MinimumAmount() float64 {
+	tolerance := math.Min(math.Max(e.Tolerance, 0), 1)
+	return e.Amount * (1 - tolerance)
+}
+
+// Verifier defines the behaviour required to verify a blockchain transaction.
+type Verifier interface {
+	VerifyTransaction(ctx context.Context, exp Expectation) (bool, *TransactionData, error)
+}
+
+// NoopVerifier accepts every transaction and reports it as final. Useful for
+// local development.
+type NoopVerifier struct{}
+
+func NewNoopVerifier() Verifier {
+	return &NoopVerifier{}
+}
+
+func (n *NoopVerifier) VerifyTransaction(ctx context.Context, exp Expectation) (bool, *TransactionData, error) {
+	return true, &TransactionData{
+		Hash:          exp.TxHash,
+		To:            exp.Recipient,
+		Amount:        exp.Amount,
+		Confirmations: math.MaxInt64,
+	}, nil
+}
+
+// CanonicalTxHash returns the one spelling a transaction hash is stored
+// under, so the same deposit cannot be resubmitted with different casing,
+// padding or prefix. Bitcoin txids are exactly 64 lowercase hex characters;
+// EVM hashes take the 0x-prefixed form common.Hash prints.
+func CanonicalTxHash(asset, hash string) (string, error) {
+	hash = strings.TrimSpace(hash)
+	digits := strings.ToLower(hash)
+	if strings.HasPrefix(digits, "0x") {
+		digits = digits[2:]
+	}
+	if digits == "" || len(digits) > 2*common.HashLength {
+		return "", ErrInvalidTxHash
+	}
+	if strings.Trim(digits, "0123456789abcdef") != "" {
+		return "", ErrInvalidTxHash
+	}
+
+	if strings.EqualFold(asset, "BTC") {
+		if len(digits) != 2*common.HashLength {
+			return "", ErrInvalidTxHash
+		}
+		return digits, nil
+	}
+	return common.HexToHash(digits).Hex(), nil
+}
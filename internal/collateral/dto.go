@@ -1,9 +1,13 @@
 package collateral
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"github.com/thoraf20/loanee/internal/blockchain"
+	"github.com/thoraf20/loanee/internal/models"
+)
 
 // Supported fiat and asset codes. Keeping it small for now – extend as needed.
-var SupportedAssets = []string{"BTC", "ETH", "USDT"}
+var SupportedAssets = []string{"BTC", "ETH", "USDT", "BNB", "MATIC"}
 
 type PreviewQuery struct {
 	LoanAmount   float64 `form:"loan_amount" binding:"required,gt=0"`
@@ -19,6 +23,29 @@ type PreviewItem struct {
 	RequiredValue  float64 `json:"required_value"`
 	RequiredAmount float64 `json:"required_amount"`
 	Status         string  `json:"status"`
+	// Haircut is taken off a depegged stablecoin's price, raising the
+	// required amount; new loans against it are Suspended meanwhile.
+	Haircut   float64 `json:"haircut,omitempty"`
+	Suspended bool    `json:"suspended,omitempty"`
+	// NetworkFee is the cost of one transfer on the asset's default network:
+	// paid by the borrower's wallet on deposit and deducted on release.
+	NetworkFee     *NetworkFee     `json:"network_fee,omitempty"`
+	OriginationFee *OriginationFee `json:"origination_fee,omitempty"`
+}
+
+// NetworkFee is an estimated on-chain fee for one transfer of the collateral
+// asset, restated in the asset itself and in fiat.
+type NetworkFee struct {
+	blockchain.FeeEstimate
+	AssetAmount float64 `json:"asset_amount"`
+	FiatAmount  float64 `json:"fiat_amount"`
+}
+
+// OriginationFee is the platform fee charged on the loan amount.
+type OriginationFee struct {
+	Rate        float64 `json:"rate"`
+	AssetAmount float64 `json:"asset_amount"`
+	FiatAmount  float64 `json:"fiat_amount"`
 }
 
 type PreviewResponse struct {
@@ -28,21 +55,51 @@ type PreviewResponse struct {
 }
 
 type CreateRequest struct {
-	LoanAmount   float64   `json:"loan_amount" validate:"required,gt=0"`
-	FiatCurrency string    `json:"fiat_currency" validate:"required,oneof=USD NGN"`
-	AssetSymbol  string    `json:"asset_symbol" validate:"required,oneof=BTC ETH USDT"`
-	UserID       uuid.UUID `json:"-"`
+	LoanAmount   float64 `json:"loan_amount" validate:"required,gt=0"`
+	FiatCurrency string  `json:"fiat_currency" validate:"required,oneof=USD NGN"`
+	AssetSymbol  string  `json:"asset_symbol" validate:"required,oneof=BTC ETH USDT BNB MATIC"`
+	// Network selects the chain for assets offered on several, e.g. USDT on
+	// "bsc". Empty uses the asset's default network.
+	Network string    `json:"network,omitempty"`
+	UserID  uuid.UUID `json:"-"`
 }
 
 type LockRequest struct {
-	AssetSymbol   string  `json:"asset_symbol" validate:"required,oneof=BTC ETH USDT"`
+	AssetSymbol   string  `json:"asset_symbol" validate:"required,oneof=BTC ETH USDT BNB MATIC"`
+	Network       string  `json:"network,omitempty"`
 	TxHash        string  `json:"tx_hash" validate:"required"`
 	Amount        float64 `json:"amount" validate:"required,gt=0"`
-	WalletAddress string  `json:"wallet_address"`
+	WalletAddress string  `json:"wallet_address" validate:"required"`
 	FiatCurrency  string  `json:"fiat_currency" validate:"required,oneof=USD NGN"`
 }
 
+// ReleaseRequest names the confirmed withdrawal address released collateral
+// is sent to.
+type ReleaseRequest struct {
+	WithdrawalAddressID uuid.UUID `json:"withdrawal_address_id" validate:"required"`
+}
+
+// ReleaseResponse is a release request with the network fee expected to be
+// deducted from the returned collateral.
+type ReleaseResponse struct {
+	*models.Collateral
+	NetworkFee         *NetworkFee `json:"network_fee,omitempty"`
+	EstimatedNetAmount float64     `json:"estimated_net_amount,omitempty"`
+}
+
 type VerifyRequest struct {
 	CollateralID    uuid.UUID `json:"collateral_id" validate:"required"`
 	TransactionHash string    `json:"transaction_hash" validate:"required"`
 }
+
+// LTVUpdate reports the live loan-to-value ratio of a single collateral.
+type LTVUpdate struct {
+	CollateralID uuid.UUID `json:"collateral_id"`
+	AssetSymbol  string    `json:"asset"`
+	FiatCurrency string    `json:"fiat_currency"`
+	AssetPrice   float64   `json:"asset_price"`
+	AssetValue   float64   `json:"asset_value"`
+	LoanAmount   float64   `json:"loan_amount"`
+	CurrentLTV   float64   `json:"current_ltv"`
+	TargetLTV    float64   `json:"target_ltv"`
+}
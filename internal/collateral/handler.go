@@ -2,23 +2,37 @@ package collateral
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/approval"
+	"github.com/thoraf20/loanee/internal/pricefeed"
 	"github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
 	"github.com/thoraf20/loanee/pkg/validator"
 )
 
 type Handler struct {
 	service   *Service
+	prices    *pricefeed.Hub
+	heartbeat time.Duration
+	approvals *approval.Service
 	validator *validator.Validator
 	logger    zerolog.Logger
 }
 
-func NewHandler(service *Service, validator *validator.Validator, logger zerolog.Logger) *Handler {
+func NewHandler(service *Service, prices *pricefeed.Hub, heartbeat time.Duration, approvals *approval.Service, validator *validator.Validator, logger zerolog.Logger) *Handler {
+	if heartbeat <= 0 {
+		heartbeat = 20 * time.Second
+	}
+
 	return &Handler{
 		service:   service,
+		prices:    prices,
+		heartbeat: heartbeat,
+		approvals: approvals,
 		validator: validator,
 		// Use a component-specific logger to make filtering easier.
 		logger: logger.With().Str("component", "collateral_handler").Logger(),
@@ -35,7 +49,7 @@ func (h *Handler) Preview(c *gin.Context) {
 	result, err := h.service.PreviewCollateral(c.Request.Context(), query.LoanAmount, query.FiatCurrency)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to preview collateral")
-		utils.InternalServerError(c, "failed to preview collateral", err.Error())
+		respondError(c, "failed to preview collateral", err)
 		return
 	}
 
@@ -64,7 +78,7 @@ func (h *Handler) CreateRequest(c *gin.Context) {
 	result, err := h.service.CreateCollateralRequest(c.Request.Context(), payload)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to create collateral request")
-		utils.InternalServerError(c, "failed to create collateral request", err.Error())
+		respondError(c, "failed to create collateral request", err)
 		return
 	}
 
@@ -92,7 +106,7 @@ func (h *Handler) Lock(c *gin.Context) {
 	collateral, err := h.service.LockCollateral(c.Request.Context(), userID, payload)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to lock collateral")
-		utils.InternalServerError(c, "failed to lock collateral", err.Error())
+		respondError(c, "failed to lock collateral", err)
 		return
 	}
 
@@ -116,6 +130,63 @@ func (h *Handler) ListMine(c *gin.Context) {
 	utils.Success(c, http.StatusOK, "collaterals retrieved", collaterals)
 }
 
+// StreamLTV pushes server-sent events whenever the loan-to-value ratio of
+// one of the caller's locked collaterals changes with the market.
+func (h *Handler) StreamLTV(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	sub, err := h.prices.Subscribe(userID)
+	if err != nil {
+		pricefeed.RejectSubscription(c, err)
+		return
+	}
+	defer sub.Close()
+
+	pricefeed.OpenStream(c)
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	lastSent := make(map[uuid.UUID]float64)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if !pricefeed.Heartbeat(c) {
+				return
+			}
+		case update, open := <-sub.Updates():
+			if !open {
+				return
+			}
+
+			ltvs, err := h.service.CurrentLTVs(ctx, userID, update.Currency, update.Prices)
+			if err != nil {
+				h.logger.Warn().Err(err).Any("user_id", userID).Msg("failed to compute collateral LTV")
+				continue
+			}
+
+			sent := false
+			for _, ltv := range ltvs {
+				if prev, seen := lastSent[ltv.CollateralID]; seen && prev == ltv.CurrentLTV {
+					continue
+				}
+				lastSent[ltv.CollateralID] = ltv.CurrentLTV
+				c.SSEvent("ltv", ltv)
+				sent = true
+			}
+			if sent {
+				c.Writer.Flush()
+			}
+		}
+	}
+}
+
 func (h *Handler) RequestRelease(c *gin.Context) {
 	userID, ok := utils.UserIDFromGin(c)
 	if !ok {
@@ -129,14 +200,24 @@ func (h *Handler) RequestRelease(c *gin.Context) {
 		return
 	}
 
-	collateral, err := h.service.RequestRelease(c.Request.Context(), userID, collateralID)
+	var payload ReleaseRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.BadRequest(c, "invalid payload", err.Error())
+		return
+	}
+	if err := h.validator.Validate(&payload); err != nil {
+		utils.BadRequest(c, "validation failed", err.Error())
+		return
+	}
+
+	release, err := h.service.RequestRelease(c.Request.Context(), userID, collateralID, payload)
 	if err != nil {
 		h.logger.Error().Err(err).Any("user_id", userID).Any("collateral_id", collateralID).Msg("failed to request release")
-		utils.InternalServerError(c, "failed to request release", err.Error())
+		respondError(c, "failed to request release", err)
 		return
 	}
 
-	utils.OK(c, "collateral release requested", collateral)
+	utils.OK(c, "collateral release requested", release)
 }
 
 func (h *Handler) AdminList(c *gin.Context) {
@@ -151,16 +232,30 @@ func (h *Handler) AdminList(c *gin.Context) {
 }
 
 func (h *Handler) AdminApproveRelease(c *gin.Context) {
+	adminID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
 	collateralID, ok := parseUUIDParam(c, "id")
 	if !ok {
 		utils.BadRequest(c, "invalid collateral id", nil)
 		return
 	}
 
-	collateral, err := h.service.ApproveRelease(c.Request.Context(), collateralID)
+	collateral, pending, err := h.approvals.Submit(c.Request.Context(), approval.Proposal{
+		Action:     approval.ActionCollateralRelease,
+		ResourceID: collateralID,
+		ProposedBy: adminID,
+	})
 	if err != nil {
 		h.logger.Error().Err(err).Any("collateral_id", collateralID).Msg("failed to approve release")
-		utils.InternalServerError(c, "failed to approve release", err.Error())
+		respondError(c, "failed to approve release", err)
+		return
+	}
+	if pending != nil {
+		utils.Success(c, http.StatusAccepted, "collateral release awaiting a second administrator", pending)
 		return
 	}
 
@@ -196,6 +291,15 @@ func (h *Handler) AdminRejectRelease(c *gin.Context) {
 	utils.OK(c, "collateral release rejected", collateral)
 }
 
+// respondError uses the status carried by an AppError, falling back to 500.
+func respondError(c *gin.Context, message string, err error) {
+	if appErr := e.GetAppError(err); appErr != nil {
+		utils.Error(c, appErr.StatusCode, message, err.Error())
+		return
+	}
+	utils.InternalServerError(c, message, err.Error())
+}
+
 func parseUUIDParam(c *gin.Context, param string) (uuid.UUID, bool) {
 	value := c.Param(param)
 	id, err := uuid.Parse(value)
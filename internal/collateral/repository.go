@@ -2,20 +2,24 @@ package collateral
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
 	"gorm.io/gorm"
 )
 
 type Repository interface {
 	Create(ctx context.Context, collateral *models.Collateral) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Collateral, error)
+	GetByTxHash(ctx context.Context, txHash string) (*models.Collateral, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.Collateral, error)
 	ListAll(ctx context.Context) ([]models.Collateral, error)
+	ListByStatus(ctx context.Context, statuses ...models.CollateralStatus) ([]models.Collateral, error)
 	Update(ctx context.Context, collateral *models.Collateral) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status models.CollateralStatus) error
 	UpdateTxInfo(ctx context.Context, id uuid.UUID, txHash, walletAddress string, status models.CollateralStatus) error
@@ -40,6 +44,9 @@ func (r *repository) Create(ctx context.Context, collateral *models.Collateral)
 	collateral.UpdatedAt = now
 
 	if err := r.db.WithContext(ctx).Create(collateral).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return fmt.Errorf("%w: %w", e.ErrCollateralTxAlreadyUsed, err)
+		}
 		r.logger.Error().Err(err).Msg("failed to create collateral record")
 		return fmt.Errorf("failed to create collateral: %w", err)
 	}
@@ -58,6 +65,18 @@ func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Collate
 	return &collateral, nil
 }
 
+func (r *repository) GetByTxHash(ctx context.Context, txHash string) (*models.Collateral, error) {
+	var collateral models.Collateral
+	if err := r.db.WithContext(ctx).First(&collateral, "tx_hash = ?", txHash).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		r.logger.Error().Err(err).Str("tx_hash", txHash).Msg("failed to fetch collateral by tx hash")
+		return nil, fmt.Errorf("failed to fetch collateral: %w", err)
+	}
+	return &collateral, nil
+}
+
 func (r *repository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.Collateral, error) {
 	var collaterals []models.Collateral
 	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&collaterals).Error; err != nil {
@@ -75,6 +94,17 @@ func (r *repository) ListAll(ctx context.Context) ([]models.Collateral, error) {
 	return collaterals, nil
 }
 
+func (r *repository) ListByStatus(ctx context.Context, statuses ...models.CollateralStatus) ([]models.Collateral, error) {
+	var collaterals []models.Collateral
+	if err := r.db.WithContext(ctx).
+		Where("status IN ?", statuses).
+		Order("created_at ASC").
+		Find(&collaterals).Error; err != nil {
+		return nil, fmt.Errorf("failed to list collaterals by status: %w", err)
+	}
+	return collaterals, nil
+}
+
 func (r *repository) Update(ctx context.Context, collateral *models.Collateral) error {
 	collateral.UpdatedAt = time.Now()
 	if err := r.db.WithContext(ctx).Save(collateral).Error; err != nil {
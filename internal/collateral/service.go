@@ -15,22 +15,74 @@ import (
 	"github.com/thoraf20/loanee/internal/loan"
 	"github.com/thoraf20/loanee/internal/models"
 	"github.com/thoraf20/loanee/internal/pricing"
+	"github.com/thoraf20/loanee/internal/stablecoin"
+	e "github.com/thoraf20/loanee/pkg/error"
 )
 
+// DepositAddresses resolves the platform deposit address assigned to a user.
+type DepositAddresses interface {
+	GetOrCreatePrimary(ctx context.Context, userID uuid.UUID, asset string) (*models.Wallet, error)
+}
+
+// ReleaseAddresses resolves a borrower's whitelisted withdrawal address,
+// failing unless it is confirmed and past its cooling-off period.
+type ReleaseAddresses interface {
+	Usable(ctx context.Context, userID, id uuid.UUID) (*models.WithdrawalAddress, error)
+}
+
+// DefaultNetworks resolves the network an asset is deposited on when none is
+// named. The blockchain registry implements it.
+type DefaultNetworks interface {
+	DefaultNetwork(asset string) (string, bool)
+}
+
+// Withdrawals returns approved collateral to its owner on-chain.
+type Withdrawals interface {
+	Supports(asset, network string) bool
+	Request(ctx context.Context, collateral *models.Collateral, destination *models.WithdrawalAddress) (*models.Withdrawal, error)
+}
+
+// NetworkFees quotes the on-chain fee for transferring an asset on a
+// network, the asset's default network when empty.
+type NetworkFees interface {
+	EstimateFee(ctx context.Context, network, asset string) (*blockchain.FeeEstimate, error)
+}
+
+// Pegs guards stablecoin collateral: Check reports whether an asset holds
+// its peg (nil for non-stablecoins) and Haircut the discount applied to it.
+type Pegs interface {
+	Check(ctx context.Context, asset string) (*stablecoin.Status, error)
+	Haircut(asset string) float64
+}
+
 type Service struct {
 	repo        Repository
 	pricing     pricing.Provider
 	verifier    blockchain.Verifier
+	fees        NetworkFees
+	pegs        Pegs
+	wallets     DepositAddresses
+	addresses   ReleaseAddresses
+	withdrawals Withdrawals
 	loanService *loan.Service
 	cfg         *config.Config
 	logger      zerolog.Logger
 }
 
-func NewService(repo Repository, pricing pricing.Provider, verifier blockchain.Verifier, loanService *loan.Service, cfg *config.Config, logger zerolog.Logger) *Service {
+// NewService builds the collateral service. withdrawals may be nil, in which
+// case approving a release marks it released without moving funds; fees may
+// be nil, in which case quotes carry no network fee; pegs may be nil to
+// treat stablecoins like any other asset.
+func NewService(repo Repository, pricing pricing.Provider, verifier blockchain.Verifier, fees NetworkFees, pegs Pegs, wallets DepositAddresses, addresses ReleaseAddresses, withdrawals Withdrawals, loanService *loan.Service, cfg *config.Config, logger zerolog.Logger) *Service {
 	return &Service{
 		repo:        repo,
 		pricing:     pricing,
 		verifier:    verifier,
+		fees:        fees,
+		pegs:        pegs,
+		wallets:     wallets,
+		addresses:   addresses,
+		withdrawals: withdrawals,
 		loanService: loanService,
 		cfg:         cfg,
 		logger:      logger,
@@ -43,7 +95,7 @@ func (s *Service) PreviewCollateral(ctx context.Context, loanAmount float64, fia
 		return nil, fmt.Errorf("loan amount must be positive")
 	}
 
-	prices, err := s.pricing.GetPrices(SupportedAssets, fiat)
+	prices, err := s.pricing.GetPrices(ctx, SupportedAssets, fiat)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch prices: %w", err)
 	}
@@ -56,7 +108,18 @@ func (s *Service) PreviewCollateral(ctx context.Context, loanAmount float64, fia
 	requiredValue := loanAmount / ltv
 	previews := make([]PreviewItem, 0, len(prices))
 	for symbol, price := range prices {
-		requiredAmount := requiredValue / price
+		// A stablecoin whose peg cannot be confirmed is shown as suspended,
+		// matching what creating the request would do.
+		peg, err := s.checkPeg(ctx, symbol)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("asset", symbol).Msg("failed to check stablecoin peg")
+		}
+		haircut := 0.0
+		if peg != nil {
+			haircut = peg.Haircut
+		}
+
+		requiredAmount := requiredValue / (price * (1 - haircut))
 		previews = append(previews, PreviewItem{
 			AssetSymbol:    symbol,
 			FiatCurrency:   strings.ToUpper(fiat),
@@ -66,6 +129,10 @@ func (s *Service) PreviewCollateral(ctx context.Context, loanAmount float64, fia
 			RequiredValue:  roundTo(requiredValue, 2),
 			RequiredAmount: roundTo(requiredAmount, 8),
 			Status:         string(models.StatusPreview),
+			Haircut:        haircut,
+			Suspended:      err != nil || (peg != nil && peg.Depegged),
+			NetworkFee:     s.networkFee(ctx, "", symbol, fiat, prices),
+			OriginationFee: s.originationFee(loanAmount, price),
 		})
 	}
 
@@ -77,7 +144,15 @@ func (s *Service) PreviewCollateral(ctx context.Context, loanAmount float64, fia
 }
 
 func (s *Service) CreateCollateralRequest(ctx context.Context, req CreateRequest) (*models.Collateral, error) {
-	price, err := s.pricing.GetPrice(req.AssetSymbol, req.FiatCurrency)
+	peg, err := s.checkPeg(ctx, req.AssetSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if peg != nil && peg.Depegged {
+		return nil, fmt.Errorf("%w: %s at %.4f %s", e.ErrStablecoinDepegged, peg.Asset, peg.Price, peg.Currency)
+	}
+
+	price, err := s.pricing.GetPrice(ctx, req.AssetSymbol, req.FiatCurrency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch %s price: %w", req.AssetSymbol, err)
 	}
@@ -95,6 +170,7 @@ func (s *Service) CreateCollateralRequest(ctx context.Context, req CreateRequest
 		ID:            uuid.New(),
 		UserID:        req.UserID,
 		AssetSymbol:   req.AssetSymbol,
+		Network:       strings.ToLower(req.Network),
 		AssetAmount:   requiredAmount,
 		AssetValue:    price * requiredAmount,
 		RequiredValue: requiredValue,
@@ -120,21 +196,64 @@ func (s *Service) CreateCollateralRequest(ctx context.Context, req CreateRequest
 }
 
 func (s *Service) LockCollateral(ctx context.Context, userID uuid.UUID, req LockRequest) (*models.Collateral, error) {
+	req.Network = strings.ToLower(strings.TrimSpace(req.Network))
+	if req.WalletAddress == "" {
+		return nil, fmt.Errorf("%w: sending wallet address is required", e.ErrCollateralDepositUnverified)
+	}
+	txHash, err := blockchain.CanonicalTxHash(req.AssetSymbol, req.TxHash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", e.ErrCollateralDepositUnverified, err)
+	}
+	req.TxHash = txHash
+
+	existing, err := s.repo.GetByTxHash(ctx, req.TxHash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, e.ErrCollateralTxAlreadyUsed
+	}
+
+	var onChain *blockchain.TransactionData
 	if s.verifier != nil {
-		valid, _, err := s.verifier.VerifyTransaction(ctx, req.TxHash, req.AssetSymbol, req.Amount)
+		deposit, err := s.wallets.GetOrCreatePrimary(ctx, userID, req.AssetSymbol)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve deposit address: %w", err)
+		}
+
+		valid, data, err := s.verifier.VerifyTransaction(ctx, blockchain.Expectation{
+			TxHash:      req.TxHash,
+			AssetSymbol: req.AssetSymbol,
+			Network:     req.Network,
+			Amount:      req.Amount,
+			Recipient:   deposit.Address,
+			Sender:      req.WalletAddress,
+			Tolerance:   s.cfg.Blockchain.UnderpaymentTolerance,
+		})
 		if err != nil {
-			return nil, fmt.Errorf("transaction verification failed: %w", err)
+			return nil, fmt.Errorf("%w: %w", e.ErrCollateralDepositUnverified, err)
 		}
 		if !valid {
-			return nil, fmt.Errorf("transaction %s could not be verified", req.TxHash)
+			return nil, fmt.Errorf("%w: transaction %s", e.ErrCollateralDepositUnverified, req.TxHash)
 		}
+		onChain = data
 	}
 
-	price, err := s.pricing.GetPrice(req.AssetSymbol, req.FiatCurrency)
+	price, err := s.pricing.GetPrice(ctx, req.AssetSymbol, req.FiatCurrency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch %s price: %w", req.AssetSymbol, err)
 	}
 
+	// Funds already deposited are accepted during a depeg, valued with the
+	// haircut.
+	peg, err := s.checkPeg(ctx, req.AssetSymbol)
+	if err != nil {
+		return nil, err
+	}
+	if peg != nil {
+		price *= 1 - peg.Haircut
+	}
+
 	assetValue := req.Amount * price
 	ltv := s.cfg.Loan.DefaultLTV
 	loanValue := assetValue * ltv
@@ -144,6 +263,7 @@ func (s *Service) LockCollateral(ctx context.Context, userID uuid.UUID, req Lock
 		ID:            uuid.New(),
 		UserID:        userID,
 		AssetSymbol:   req.AssetSymbol,
+		Network:       req.Network,
 		AssetAmount:   req.Amount,
 		AssetValue:    assetValue,
 		RequiredValue: assetValue,
@@ -156,6 +276,15 @@ func (s *Service) LockCollateral(ctx context.Context, userID uuid.UUID, req Lock
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
+	// The confirmation tracker re-checks this block until the deposit is final.
+	if onChain != nil && onChain.BlockHash != "" {
+		collateral.BlockNumber = &onChain.BlockNumber
+		collateral.BlockHash = &onChain.BlockHash
+	}
+	// A deposit already past the finality depth has nothing left to wait for.
+	if onChain != nil && onChain.Confirmations >= s.cfg.Confirmations.Depth(req.AssetSymbol) {
+		collateral.FinalizedAt = &now
+	}
 
 	if err := s.repo.Create(ctx, collateral); err != nil {
 		return nil, err
@@ -172,7 +301,9 @@ func (s *Service) ListAllCollaterals(ctx context.Context) ([]models.Collateral,
 	return s.repo.ListAll(ctx)
 }
 
-func (s *Service) RequestRelease(ctx context.Context, userID, collateralID uuid.UUID) (*models.Collateral, error) {
+// RequestRelease asks for collateral to be returned to a whitelisted address
+// and quotes the network fee the return will cost.
+func (s *Service) RequestRelease(ctx context.Context, userID, collateralID uuid.UUID, req ReleaseRequest) (*ReleaseResponse, error) {
 	collateral, err := s.repo.GetByID(ctx, collateralID)
 	if err != nil {
 		return nil, err
@@ -186,9 +317,14 @@ func (s *Service) RequestRelease(ctx context.Context, userID, collateralID uuid.
 	if collateral.Status != models.StatusActive {
 		return nil, fmt.Errorf("collateral must be active to request release")
 	}
+	address, err := s.releaseAddress(ctx, collateral, req.WithdrawalAddressID)
+	if err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	collateral.Status = models.StatusReleaseRequested
+	collateral.ReleaseAddressID = &req.WithdrawalAddressID
 	collateral.ReleaseRequestedAt = &now
 	collateral.ReleaseResolvedAt = nil
 	collateral.ReleaseNote = nil
@@ -196,19 +332,55 @@ func (s *Service) RequestRelease(ctx context.Context, userID, collateralID uuid.
 	if err := s.repo.Update(ctx, collateral); err != nil {
 		return nil, err
 	}
-	return collateral, nil
+
+	response := &ReleaseResponse{Collateral: collateral}
+	if fee := s.networkFee(ctx, address.Network, collateral.AssetSymbol, collateral.FiatCurrency, nil); fee != nil {
+		response.NetworkFee = fee
+		response.EstimatedNetAmount = roundTo(math.Max(collateral.AssetAmount-fee.AssetAmount, 0), 8)
+	}
+	return response, nil
 }
 
 func (s *Service) ApproveRelease(ctx context.Context, collateralID uuid.UUID) (*models.Collateral, error) {
-	collateral, err := s.repo.GetByID(ctx, collateralID)
+	collateral, err := s.awaitingRelease(ctx, collateralID)
 	if err != nil {
 		return nil, err
 	}
-	if collateral == nil {
-		return nil, fmt.Errorf("collateral not found")
-	}
-	if collateral.Status != models.StatusReleaseRequested {
-		return nil, fmt.Errorf("collateral not awaiting release")
+
+	// With on-chain withdrawals the collateral stays locked until the
+	// transfer confirms; the withdrawal worker marks it released. Assets the
+	// worker cannot pay fall through to a manual release by an operator.
+	if s.withdrawals != nil {
+		if collateral.ReleaseAddressID == nil {
+			return nil, fmt.Errorf("%w: no withdrawal address on the release request", e.ErrWithdrawalAddressNotFound)
+		}
+		// Re-checked so an address revoked since the request is never paid.
+		destination, err := s.releaseAddress(ctx, collateral, *collateral.ReleaseAddressID)
+		if err != nil {
+			return nil, err
+		}
+		if s.withdrawals.Supports(collateral.AssetSymbol, destination.Network) {
+			// Releasing is stored first: the worker drops a withdrawal whose
+			// collateral is not releasing yet.
+			collateral.Status = models.StatusReleasing
+			collateral.ReleaseNote = nil
+			if err := s.repo.Update(ctx, collateral); err != nil {
+				return nil, err
+			}
+			if _, err := s.withdrawals.Request(ctx, collateral, destination); err != nil {
+				collateral.Status = models.StatusReleaseRequested
+				if restoreErr := s.repo.Update(ctx, collateral); restoreErr != nil {
+					s.logger.Error().Err(restoreErr).Str("collateral_id", collateral.ID.String()).Msg("failed to return collateral to the release queue")
+				}
+				return nil, fmt.Errorf("failed to queue withdrawal: %w", err)
+			}
+			return collateral, nil
+		}
+		s.logger.Info().
+			Str("collateral_id", collateral.ID.String()).
+			Str("asset", collateral.AssetSymbol).
+			Str("network", destination.Network).
+			Msg("no on-chain withdrawal path for asset, releasing for manual payout")
 	}
 
 	now := time.Now()
@@ -223,16 +395,10 @@ func (s *Service) ApproveRelease(ctx context.Context, collateralID uuid.UUID) (*
 }
 
 func (s *Service) RejectRelease(ctx context.Context, collateralID uuid.UUID, reason string) (*models.Collateral, error) {
-	collateral, err := s.repo.GetByID(ctx, collateralID)
+	collateral, err := s.awaitingRelease(ctx, collateralID)
 	if err != nil {
 		return nil, err
 	}
-	if collateral == nil {
-		return nil, fmt.Errorf("collateral not found")
-	}
-	if collateral.Status != models.StatusReleaseRequested {
-		return nil, fmt.Errorf("collateral not awaiting release")
-	}
 
 	now := time.Now()
 	collateral.Status = models.StatusActive
@@ -247,6 +413,189 @@ func (s *Service) RejectRelease(ctx context.Context, collateralID uuid.UUID, rea
 	return collateral, nil
 }
 
+// ReleaseValue is what returning the collateral is worth at the current
+// price, in its fiat currency.
+func (s *Service) ReleaseValue(ctx context.Context, collateralID uuid.UUID) (float64, string, error) {
+	collateral, err := s.repo.GetByID(ctx, collateralID)
+	if err != nil {
+		return 0, "", err
+	}
+	if collateral == nil {
+		return 0, "", e.ErrCollateralNotFound
+	}
+
+	fiat := normalizeFiat(collateral.FiatCurrency)
+	price, err := s.priceOf(ctx, collateral.AssetSymbol, fiat, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to price %s: %w", collateral.AssetSymbol, err)
+	}
+	return roundTo(collateral.AssetAmount*price, 2), fiat, nil
+}
+
+// CheckReleasable reports an error unless the collateral has a pending
+// release request, so a release is refused when proposed rather than when
+// approved.
+func (s *Service) CheckReleasable(ctx context.Context, collateralID uuid.UUID) error {
+	_, err := s.awaitingRelease(ctx, collateralID)
+	return err
+}
+
+func (s *Service) awaitingRelease(ctx context.Context, collateralID uuid.UUID) (*models.Collateral, error) {
+	collateral, err := s.repo.GetByID(ctx, collateralID)
+	if err != nil {
+		return nil, err
+	}
+	if collateral == nil {
+		return nil, e.ErrCollateralNotFound
+	}
+	if collateral.Status != models.StatusReleaseRequested {
+		return nil, e.ErrCollateralNotAwaitingRelease
+	}
+	return collateral, nil
+}
+
+// CurrentLTVs recomputes the loan-to-value ratio of the user's locked
+// collaterals denominated in the given fiat currency.
+func (s *Service) CurrentLTVs(ctx context.Context, userID uuid.UUID, fiat string, prices map[string]float64) ([]LTVUpdate, error) {
+	collaterals, err := s.repo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	fiat = normalizeFiat(fiat)
+	updates := make([]LTVUpdate, 0, len(collaterals))
+	for _, col := range collaterals {
+		if !isLocked(col.Status) || strings.ToUpper(col.FiatCurrency) != fiat {
+			continue
+		}
+		price, ok := prices[col.AssetSymbol]
+		if !ok || price <= 0 || col.AssetAmount <= 0 {
+			continue
+		}
+		if s.pegs != nil {
+			price *= 1 - s.pegs.Haircut(col.AssetSymbol)
+		}
+
+		assetValue := col.AssetAmount * price
+		updates = append(updates, LTVUpdate{
+			CollateralID: col.ID,
+			AssetSymbol:  col.AssetSymbol,
+			FiatCurrency: fiat,
+			AssetPrice:   price,
+			AssetValue:   roundTo(assetValue, 2),
+			LoanAmount:   col.FiatAmount,
+			CurrentLTV:   roundTo(col.FiatAmount/assetValue, 4),
+			TargetLTV:    col.LTV,
+		})
+	}
+	return updates, nil
+}
+
+// releaseAddress resolves the whitelisted address collateral is returned to
+// and checks it carries the collateral's asset on its network.
+func (s *Service) releaseAddress(ctx context.Context, collateral *models.Collateral, id uuid.UUID) (*models.WithdrawalAddress, error) {
+	address, err := s.addresses.Usable(ctx, collateral.UserID, id)
+	if err != nil {
+		return nil, err
+	}
+	network := collateral.Network
+	if network == "" {
+		network = s.defaultNetwork(collateral.AssetSymbol)
+	}
+	if !strings.EqualFold(address.AssetSymbol, collateral.AssetSymbol) ||
+		network == "" || !strings.EqualFold(address.Network, network) {
+		return nil, e.ErrWithdrawalAddressMismatch
+	}
+	return address, nil
+}
+
+// defaultNetwork is the network collateral recorded without one was
+// deposited on: the asset's default in the verifier registry, or empty when
+// the verifier cannot tell.
+func (s *Service) defaultNetwork(asset string) string {
+	networks, ok := s.verifier.(DefaultNetworks)
+	if !ok {
+		return ""
+	}
+	network, _ := networks.DefaultNetwork(asset)
+	return network
+}
+
+// networkFee quotes one transfer of asset and restates the fee in the asset
+// and in fiat, using prices when it has them. Quotes are informational, so a
+// failure is logged and yields nil rather than failing the flow.
+func (s *Service) networkFee(ctx context.Context, network, asset, fiat string, prices map[string]float64) *NetworkFee {
+	if s.fees == nil {
+		return nil
+	}
+	estimate, err := s.fees.EstimateFee(ctx, network, asset)
+	if err != nil {
+		s.logger.Warn().Err(err).Str("asset", asset).Str("network", network).Msg("failed to estimate network fee")
+		return nil
+	}
+
+	feePrice, err := s.priceOf(ctx, estimate.FeeAsset, fiat, prices)
+	if err != nil {
+		s.logger.Warn().Err(err).Str("asset", estimate.FeeAsset).Msg("failed to price network fee")
+		return nil
+	}
+	assetPrice, err := s.priceOf(ctx, asset, fiat, prices)
+	if err != nil || assetPrice <= 0 {
+		s.logger.Warn().Err(err).Str("asset", asset).Msg("failed to price network fee")
+		return nil
+	}
+
+	fiatAmount := estimate.FeeAmount * feePrice
+	return &NetworkFee{
+		FeeEstimate: *estimate,
+		AssetAmount: roundTo(fiatAmount/assetPrice, 8),
+		FiatAmount:  roundTo(fiatAmount, 2),
+	}
+}
+
+// originationFee applies the configured platform fee to a loan amount, or
+// returns nil when none is charged.
+func (s *Service) originationFee(loanAmount, assetPrice float64) *OriginationFee {
+	rate := s.cfg.Loan.OriginationFeeRate
+	if rate <= 0 || assetPrice <= 0 {
+		return nil
+	}
+	fiatAmount := loanAmount * rate
+	return &OriginationFee{
+		Rate:        rate,
+		AssetAmount: roundTo(fiatAmount/assetPrice, 8),
+		FiatAmount:  roundTo(fiatAmount, 2),
+	}
+}
+
+// checkPeg returns the peg status of a stablecoin, or nil for other assets
+// and when no guard is configured.
+func (s *Service) checkPeg(ctx context.Context, asset string) (*stablecoin.Status, error) {
+	if s.pegs == nil {
+		return nil, nil
+	}
+	peg, err := s.pegs.Check(ctx, asset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check %s peg: %w", asset, err)
+	}
+	return peg, nil
+}
+
+func (s *Service) priceOf(ctx context.Context, asset, fiat string, prices map[string]float64) (float64, error) {
+	if price, ok := prices[strings.ToUpper(asset)]; ok {
+		return price, nil
+	}
+	return s.pricing.GetPrice(ctx, strings.ToUpper(asset), fiat)
+}
+
+func isLocked(status models.CollateralStatus) bool {
+	switch status {
+	case models.StatusConfirmed, models.StatusActive, models.StatusReleaseRequested, models.StatusReleasing:
+		return true
+	}
+	return false
+}
+
 func optionalString(value string) *string {
 	if value == "" {
 		return nil
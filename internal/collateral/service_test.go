@@ -2,8 +2,11 @@ package collateral
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
@@ -11,14 +14,39 @@ import (
 	"github.com/thoraf20/loanee/config"
 	"github.com/thoraf20/loanee/internal/blockchain"
 	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/internal/stablecoin"
+	e "github.com/thoraf20/loanee/pkg/error"
 )
 
 func TestPreviewCollateral(t *testing.T) {
 	service, _ := newTestService()
 
+	service.cfg.Loan.OriginationFeeRate = 0.01
+
 	resp, err := service.PreviewCollateral(context.Background(), 1000, "USD")
 	require.NoError(t, err)
 	require.Len(t, resp.Previews, len(SupportedAssets))
+
+	previews := make(map[string]PreviewItem)
+	for _, preview := range resp.Previews {
+		previews[preview.AssetSymbol] = preview
+	}
+
+	// Token fees are paid in the network's coin and restated in the token.
+	usdt := previews["USDT"].NetworkFee
+	require.Equal(t, "ETH", usdt.FeeAsset)
+	require.Equal(t, 2.0, usdt.FiatAmount)
+	require.Equal(t, 2.0, usdt.AssetAmount)
+
+	btc := previews["BTC"]
+	require.Equal(t, 0.0001, btc.NetworkFee.AssetAmount)
+	require.Equal(t, 2.0, btc.NetworkFee.FiatAmount)
+	require.Equal(t, 10.0, btc.OriginationFee.FiatAmount)
+	require.Equal(t, 0.0005, btc.OriginationFee.AssetAmount)
+
+	// A chain that cannot quote fees still gets a preview.
+	require.Nil(t, previews["MATIC"].NetworkFee)
+	require.Equal(t, 4000.0, previews["MATIC"].RequiredAmount)
 }
 
 func TestCreateCollateralRequest(t *testing.T) {
@@ -41,7 +69,7 @@ func TestLockAndReleaseFlow(t *testing.T) {
 
 	collateral, err := service.LockCollateral(context.Background(), userID, LockRequest{
 		AssetSymbol:   "BTC",
-		TxHash:        "0xabc",
+		TxHash:        btcTxID,
 		Amount:        0.5,
 		WalletAddress: "addr",
 		FiatCurrency:  "USD",
@@ -49,22 +77,415 @@ func TestLockAndReleaseFlow(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, models.StatusActive, collateral.Status)
 
-	updated, err := service.RequestRelease(context.Background(), userID, collateral.ID)
+	address := whitelist(service, userID, "BTC", "bitcoin")
+	release, err := service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: address.ID})
+	require.NoError(t, err)
+	require.Equal(t, models.StatusReleaseRequested, release.Status)
+	require.Equal(t, address.ID, *release.ReleaseAddressID)
+
+	updated, err := service.ApproveRelease(context.Background(), collateral.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusReleased, updated.Status)
+}
+
+func TestApproveReleaseWaitsForWithdrawal(t *testing.T) {
+	service, repo := newTestService()
+	withdrawals := &fakeWithdrawals{repo: repo}
+	service.withdrawals = withdrawals
+	userID := uuid.New()
+
+	collateral, err := service.LockCollateral(context.Background(), userID, LockRequest{
+		AssetSymbol:   "ETH",
+		TxHash:        "0xabc",
+		Amount:        1,
+		WalletAddress: "0x00000000000000000000000000000000000000cc",
+		FiatCurrency:  "USD",
+	})
+	require.NoError(t, err)
+	address := whitelist(service, userID, "ETH", "ethereum")
+	require.ErrorIs(t, service.CheckReleasable(context.Background(), collateral.ID), e.ErrCollateralNotAwaitingRelease)
+	release, err := service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: address.ID})
+	require.NoError(t, err)
+	require.NoError(t, service.CheckReleasable(context.Background(), collateral.ID))
+	require.Equal(t, 0.002, release.NetworkFee.AssetAmount)
+	require.Equal(t, 0.998, release.EstimatedNetAmount)
+
+	updated, err := service.ApproveRelease(context.Background(), collateral.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.StatusReleasing, updated.Status)
+	require.Nil(t, updated.ReleaseResolvedAt)
+	require.Equal(t, []uuid.UUID{collateral.ID}, withdrawals.requested)
+	require.Equal(t, models.StatusReleasing, repo.store[collateral.ID].Status)
+	// The worker must never see a withdrawal before its collateral is releasing.
+	require.Equal(t, []models.CollateralStatus{models.StatusReleasing}, withdrawals.statuses)
+}
+
+func TestReleaseValueUsesCurrentPrice(t *testing.T) {
+	service, _ := newTestService()
+	ctx := context.Background()
+
+	collateral, err := service.LockCollateral(ctx, uuid.New(), LockRequest{
+		AssetSymbol:   "ETH",
+		TxHash:        "0xabc",
+		Amount:        2,
+		WalletAddress: "0x00000000000000000000000000000000000000cc",
+		FiatCurrency:  "ngn",
+	})
+	require.NoError(t, err)
+
+	service.pricing.(*fakePricing).prices["ETH"] = 1500
+	value, currency, err := service.ReleaseValue(ctx, collateral.ID)
+	require.NoError(t, err)
+	require.Equal(t, 3000.0, value)
+	require.Equal(t, "NGN", currency)
+
+	_, _, err = service.ReleaseValue(ctx, uuid.New())
+	require.ErrorIs(t, err, e.ErrCollateralNotFound)
+}
+
+func TestApproveReleaseRequeuesWhenWithdrawalFails(t *testing.T) {
+	service, repo := newTestService()
+	service.withdrawals = &fakeWithdrawals{err: errors.New("database unavailable")}
+	userID := uuid.New()
+
+	collateral, err := service.LockCollateral(context.Background(), userID, LockRequest{
+		AssetSymbol:   "ETH",
+		TxHash:        "0xabc",
+		Amount:        1,
+		WalletAddress: "0x00000000000000000000000000000000000000cc",
+		FiatCurrency:  "USD",
+	})
+	require.NoError(t, err)
+	address := whitelist(service, userID, "ETH", "ethereum")
+	_, err = service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: address.ID})
+	require.NoError(t, err)
+
+	_, err = service.ApproveRelease(context.Background(), collateral.ID)
+	require.Error(t, err)
+	require.Equal(t, models.StatusReleaseRequested, repo.store[collateral.ID].Status)
+}
+
+func TestApproveReleaseFallsBackToManualForBTC(t *testing.T) {
+	service, repo := newTestService()
+	withdrawals := &fakeWithdrawals{}
+	service.withdrawals = withdrawals
+	userID := uuid.New()
+
+	collateral, err := service.LockCollateral(context.Background(), userID, LockRequest{
+		AssetSymbol:   "BTC",
+		TxHash:        btcTxID,
+		Amount:        0.5,
+		WalletAddress: "addr",
+		FiatCurrency:  "USD",
+	})
+	require.NoError(t, err)
+	address := whitelist(service, userID, "BTC", "bitcoin")
+	_, err = service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: address.ID})
 	require.NoError(t, err)
-	require.Equal(t, models.StatusReleaseRequested, updated.Status)
 
-	updated, err = service.ApproveRelease(context.Background(), collateral.ID)
+	updated, err := service.ApproveRelease(context.Background(), collateral.ID)
 	require.NoError(t, err)
 	require.Equal(t, models.StatusReleased, updated.Status)
+	require.NotNil(t, updated.ReleaseResolvedAt)
+	require.Empty(t, withdrawals.requested)
+	require.Equal(t, models.StatusReleased, repo.store[collateral.ID].Status)
+}
+
+func TestReleaseRequiresWhitelistedAddress(t *testing.T) {
+	service, repo := newTestService()
+	userID := uuid.New()
+
+	collateral, err := service.LockCollateral(context.Background(), userID, LockRequest{
+		AssetSymbol:   "USDT",
+		Network:       "ethereum",
+		TxHash:        "0xabc",
+		Amount:        100,
+		WalletAddress: "0x00000000000000000000000000000000000000cc",
+		FiatCurrency:  "USD",
+	})
+	require.NoError(t, err)
+
+	_, err = service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: uuid.New()})
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressNotFound)
+
+	// Another user's address is not visible to this borrower.
+	foreign := whitelist(service, uuid.New(), "USDT", "ethereum")
+	_, err = service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: foreign.ID})
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressNotFound)
+
+	wrongNetwork := whitelist(service, userID, "USDT", "bsc")
+	_, err = service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: wrongNetwork.ID})
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressMismatch)
+
+	coolingOff := whitelist(service, userID, "USDT", "ethereum")
+	later := time.Now().Add(time.Hour)
+	coolingOff.UsableFrom = &later
+	_, err = service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: coolingOff.ID})
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressNotUsable)
+	require.Equal(t, models.StatusActive, repo.store[collateral.ID].Status)
+}
+
+func TestReleaseMatchesDefaultNetworkWhenNoneRecorded(t *testing.T) {
+	service, _ := newTestService()
+	userID := uuid.New()
+
+	collateral, err := service.LockCollateral(context.Background(), userID, LockRequest{
+		AssetSymbol:   "USDT",
+		TxHash:        "0xabc",
+		Amount:        100,
+		WalletAddress: "0x00000000000000000000000000000000000000cc",
+		FiatCurrency:  "USD",
+	})
+	require.NoError(t, err)
+	require.Empty(t, collateral.Network)
+
+	// USDT defaults to Ethereum, so a BSC address cannot receive it.
+	wrongNetwork := whitelist(service, userID, "USDT", "bsc")
+	_, err = service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: wrongNetwork.ID})
+	require.ErrorIs(t, err, e.ErrWithdrawalAddressMismatch)
+
+	address := whitelist(service, userID, "USDT", "ethereum")
+	_, err = service.RequestRelease(context.Background(), userID, collateral.ID, ReleaseRequest{WithdrawalAddressID: address.ID})
+	require.NoError(t, err)
+}
+
+func TestDepeggedStablecoinSuspendsLoansAndTakesHaircut(t *testing.T) {
+	service, _ := newTestService()
+	pegs := &fakePegs{haircut: 0.2}
+	service.pegs = pegs
+	ctx := context.Background()
+
+	resp, err := service.PreviewCollateral(ctx, 1000, "USD")
+	require.NoError(t, err)
+	for _, preview := range resp.Previews {
+		require.False(t, preview.Suspended, preview.AssetSymbol)
+	}
+
+	pegs.depegged = true
+	resp, err = service.PreviewCollateral(ctx, 1000, "USD")
+	require.NoError(t, err)
+	for _, preview := range resp.Previews {
+		if preview.AssetSymbol != "USDT" {
+			require.False(t, preview.Suspended, preview.AssetSymbol)
+			continue
+		}
+		require.True(t, preview.Suspended)
+		require.Equal(t, 0.2, preview.Haircut)
+		require.Equal(t, 2500.0, preview.RequiredAmount)
+	}
+
+	_, err = service.CreateCollateralRequest(ctx, CreateRequest{
+		UserID: uuid.New(), LoanAmount: 1000, FiatCurrency: "USD", AssetSymbol: "USDT",
+	})
+	require.ErrorIs(t, err, e.ErrStablecoinDepegged)
+	_, err = service.CreateCollateralRequest(ctx, CreateRequest{
+		UserID: uuid.New(), LoanAmount: 1000, FiatCurrency: "USD", AssetSymbol: "ETH",
+	})
+	require.NoError(t, err)
+
+	// Deposits already made are still accepted, at the haircut value.
+	userID := uuid.New()
+	collateral, err := service.LockCollateral(ctx, userID, LockRequest{
+		AssetSymbol:   "USDT",
+		Network:       "ethereum",
+		TxHash:        "0xabc",
+		Amount:        1000,
+		WalletAddress: "0x00000000000000000000000000000000000000cc",
+		FiatCurrency:  "USD",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 800.0, collateral.AssetValue)
+
+	updates, err := service.CurrentLTVs(ctx, userID, "USD", map[string]float64{"USDT": 1})
+	require.NoError(t, err)
+	require.Len(t, updates, 1)
+	require.Equal(t, 800.0, updates[0].AssetValue)
+}
+
+func TestCurrentLTVs(t *testing.T) {
+	service, _ := newTestService()
+	userID := uuid.New()
+
+	collateral, err := service.LockCollateral(context.Background(), userID, LockRequest{
+		AssetSymbol:   "ETH",
+		TxHash:        "0xdef",
+		Amount:        2,
+		WalletAddress: "addr",
+		FiatCurrency:  "USD",
+	})
+	require.NoError(t, err)
+
+	ltvs, err := service.CurrentLTVs(context.Background(), userID, "usd", map[string]float64{"ETH": 500})
+	require.NoError(t, err)
+	require.Len(t, ltvs, 1)
+	require.Equal(t, collateral.ID, ltvs[0].CollateralID)
+	require.Equal(t, 1.0, ltvs[0].CurrentLTV)
+
+	ltvs, err = service.CurrentLTVs(context.Background(), userID, "NGN", map[string]float64{"ETH": 500})
+	require.NoError(t, err)
+	require.Empty(t, ltvs)
+}
+
+func TestLockBindsDepositAndSender(t *testing.T) {
+	service, _ := newTestService()
+	userID := uuid.New()
+
+	_, err := service.LockCollateral(context.Background(), userID, LockRequest{
+		AssetSymbol:   "ETH",
+		TxHash:        " 0xABC ",
+		Amount:        1,
+		WalletAddress: "0xsender",
+		FiatCurrency:  "USD",
+	})
+	require.NoError(t, err)
+
+	verifier := service.verifier.(*fakeVerifier)
+	require.Equal(t, "0x0000000000000000000000000000000000000000000000000000000000000abc", verifier.last.TxHash)
+	require.Equal(t, "deposit-ETH-"+userID.String(), verifier.last.Recipient)
+	require.Equal(t, "0xsender", verifier.last.Sender)
+	require.Equal(t, 0.01, verifier.last.Tolerance)
+
+	_, err = service.LockCollateral(context.Background(), userID, LockRequest{
+		AssetSymbol:  "ETH",
+		TxHash:       "0xdef",
+		Amount:       1,
+		FiatCurrency: "USD",
+	})
+	require.ErrorIs(t, err, e.ErrCollateralDepositUnverified)
+}
+
+func TestLockRecordsNetwork(t *testing.T) {
+	service, _ := newTestService()
+
+	collateral, err := service.LockCollateral(context.Background(), uuid.New(), LockRequest{
+		AssetSymbol:   "USDT",
+		Network:       "BSC",
+		TxHash:        "0xabc",
+		Amount:        100,
+		WalletAddress: "0xsender",
+		FiatCurrency:  "USD",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "bsc", collateral.Network)
+	require.Equal(t, "bsc", service.verifier.(*fakeVerifier).last.Network)
+}
+
+func TestLockRejectsReusedTxHash(t *testing.T) {
+	evmHash := "0x5c504ed432cb51138bcf09aa5e8a410dd4a1e204ef84bfed1be16dfba1b22060"
+	cases := []struct {
+		asset    string
+		original string
+		resubmit []string
+	}{
+		{
+			asset:    "ETH",
+			original: evmHash,
+			resubmit: []string{
+				strings.ToUpper(evmHash),
+				"0X" + evmHash[2:],
+				evmHash[2:],
+				" " + evmHash + " ",
+			},
+		},
+		{
+			asset:    "BTC",
+			original: btcTxID,
+			resubmit: []string{
+				strings.ToUpper(btcTxID),
+				"0x" + btcTxID,
+				" " + btcTxID + " ",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.asset, func(t *testing.T) {
+			service, _ := newTestService()
+			lock := LockRequest{
+				AssetSymbol:   tc.asset,
+				TxHash:        tc.original,
+				Amount:        0.5,
+				WalletAddress: "addr",
+				FiatCurrency:  "USD",
+			}
+			_, err := service.LockCollateral(context.Background(), uuid.New(), lock)
+			require.NoError(t, err)
+
+			// Another spelling of the same hash must not let the deposit back
+			// a second collateral.
+			for _, hash := range tc.resubmit {
+				lock.TxHash = hash
+				_, err = service.LockCollateral(context.Background(), uuid.New(), lock)
+				require.ErrorIs(t, err, e.ErrCollateralTxAlreadyUsed, hash)
+			}
+		})
+	}
+}
+
+func TestLockRejectsMalformedBitcoinTxID(t *testing.T) {
+	service, repo := newTestService()
+
+	_, err := service.LockCollateral(context.Background(), uuid.New(), LockRequest{
+		AssetSymbol:   "BTC",
+		TxHash:        btcTxID[1:],
+		Amount:        0.5,
+		WalletAddress: "addr",
+		FiatCurrency:  "USD",
+	})
+	require.ErrorIs(t, err, e.ErrCollateralDepositUnverified)
+	require.Empty(t, repo.created)
+}
+
+func TestLockFinalizesDepositPastFinalityDepth(t *testing.T) {
+	service, _ := newTestService()
+	service.cfg.Confirmations.FinalityDepth = map[string]int64{"eth": 64}
+	verifier := service.verifier.(*fakeVerifier)
+	lock := LockRequest{
+		AssetSymbol:   "ETH",
+		TxHash:        "0xabc",
+		Amount:        1,
+		WalletAddress: "addr",
+		FiatCurrency:  "USD",
+	}
+
+	verifier.confirmations = 12
+	shallow, err := service.LockCollateral(context.Background(), uuid.New(), lock)
+	require.NoError(t, err)
+	require.Nil(t, shallow.FinalizedAt)
+
+	verifier.confirmations = 64
+	lock.TxHash = "0xdef"
+	deep, err := service.LockCollateral(context.Background(), uuid.New(), lock)
+	require.NoError(t, err)
+	require.NotNil(t, deep.FinalizedAt)
+}
+
+func TestLockRejectsUnverifiedDeposit(t *testing.T) {
+	service, repo := newTestService()
+	service.verifier.(*fakeVerifier).err = fmt.Errorf("transaction value 0.1 ETH is below expected 1 ETH")
+
+	_, err := service.LockCollateral(context.Background(), uuid.New(), LockRequest{
+		AssetSymbol:   "ETH",
+		TxHash:        "0xabc",
+		Amount:        1,
+		WalletAddress: "addr",
+		FiatCurrency:  "USD",
+	})
+	require.ErrorIs(t, err, e.ErrCollateralDepositUnverified)
+	require.Empty(t, repo.created)
 }
 
+const btcTxID = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+
 func newTestService() (*Service, *mockRepo) {
 	repo := newMockRepo()
 	pricingProvider := &fakePricing{
 		prices: map[string]float64{
-			"BTC":  20000,
-			"ETH":  1000,
-			"USDT": 1,
+			"BTC":   20000,
+			"ETH":   1000,
+			"USDT":  1,
+			"BNB":   300,
+			"MATIC": 0.5,
 		},
 	}
 	verifier := &fakeVerifier{}
@@ -72,9 +493,12 @@ func newTestService() (*Service, *mockRepo) {
 		Loan: config.LoanConfig{
 			DefaultLTV: 0.5,
 		},
+		Blockchain: config.BlockchainConfig{
+			UnderpaymentTolerance: 0.01,
+		},
 	}
 
-	service := NewService(repo, pricingProvider, verifier, nil, cfg, zerolog.Nop())
+	service := NewService(repo, pricingProvider, verifier, fakeFees{}, nil, fakeWallets{}, fakeAddresses{}, nil, nil, cfg, zerolog.Nop())
 	return service, repo
 }
 
@@ -107,6 +531,16 @@ func (m *mockRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Collatera
 	return nil, nil
 }
 
+func (m *mockRepo) GetByTxHash(ctx context.Context, txHash string) (*models.Collateral, error) {
+	for _, col := range m.store {
+		if col.TxHash != nil && *col.TxHash == txHash {
+			copy := *col
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
 func (m *mockRepo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.Collateral, error) {
 	var result []models.Collateral
 	for _, col := range m.store {
@@ -125,6 +559,18 @@ func (m *mockRepo) ListAll(ctx context.Context) ([]models.Collateral, error) {
 	return result, nil
 }
 
+func (m *mockRepo) ListByStatus(ctx context.Context, statuses ...models.CollateralStatus) ([]models.Collateral, error) {
+	var result []models.Collateral
+	for _, col := range m.store {
+		for _, status := range statuses {
+			if col.Status == status {
+				result = append(result, *col)
+			}
+		}
+	}
+	return result, nil
+}
+
 func (m *mockRepo) Update(ctx context.Context, collateral *models.Collateral) error {
 	copy := *collateral
 	m.store[collateral.ID] = &copy
@@ -153,14 +599,14 @@ type fakePricing struct {
 	prices map[string]float64
 }
 
-func (f *fakePricing) GetPrice(symbol, currency string) (float64, error) {
+func (f *fakePricing) GetPrice(ctx context.Context, symbol, currency string) (float64, error) {
 	if price, ok := f.prices[symbol]; ok {
 		return price, nil
 	}
 	return 0, fmt.Errorf("price not found")
 }
 
-func (f *fakePricing) GetPrices(symbols []string, currency string) (map[string]float64, error) {
+func (f *fakePricing) GetPrices(ctx context.Context, symbols []string, currency string) (map[string]float64, error) {
 	result := make(map[string]float64)
 	for _, symbol := range symbols {
 		if price, ok := f.prices[symbol]; ok {
@@ -170,11 +616,131 @@ func (f *fakePricing) GetPrices(symbols []string, currency string) (map[string]f
 	return result, nil
 }
 
-type fakeVerifier struct{}
+// fakeFees quotes a flat fee per network; MATIC cannot be estimated.
+type fakeFees struct{}
+
+func (fakeFees) EstimateFee(ctx context.Context, network, asset string) (*blockchain.FeeEstimate, error) {
+	switch asset {
+	case "BTC":
+		return &blockchain.FeeEstimate{Network: "bitcoin", FeeAsset: "BTC", FeeAmount: 0.0001, SatPerVByte: 70, VBytes: 141}, nil
+	case "ETH", "USDT":
+		return &blockchain.FeeEstimate{Network: "ethereum", FeeAsset: "ETH", FeeAmount: 0.002, GasLimit: 21000}, nil
+	case "BNB":
+		return &blockchain.FeeEstimate{Network: "bsc", FeeAsset: "BNB", FeeAmount: 0.001, GasLimit: 21000}, nil
+	}
+	return nil, fmt.Errorf("no fee estimate for %s", asset)
+}
+
+// fakePegs treats USDT as the only stablecoin.
+type fakePegs struct {
+	depegged bool
+	haircut  float64
+}
 
-func (f *fakeVerifier) VerifyTransaction(ctx context.Context, txHash, assetSymbol string, expectedAmount float64) (bool, *blockchain.TransactionData, error) {
+func (f *fakePegs) Check(ctx context.Context, asset string) (*stablecoin.Status, error) {
+	if asset != "USDT" {
+		return nil, nil
+	}
+	status := &stablecoin.Status{Asset: asset, Price: 1, Peg: 1, Currency: "USD"}
+	if f.depegged {
+		status.Price = 0.9
+		status.Depegged = true
+		status.Haircut = f.haircut
+	}
+	return status, nil
+}
+
+func (f *fakePegs) Haircut(asset string) float64 {
+	if asset == "USDT" && f.depegged {
+		return f.haircut
+	}
+	return 0
+}
+
+type fakeVerifier struct {
+	last          blockchain.Expectation
+	err           error
+	confirmations int64
+}
+
+func (f *fakeVerifier) VerifyTransaction(ctx context.Context, exp blockchain.Expectation) (bool, *blockchain.TransactionData, error) {
+	f.last = exp
+	if f.err != nil {
+		return false, nil, f.err
+	}
 	return true, &blockchain.TransactionData{
-		Hash:   txHash,
-		Amount: expectedAmount,
+		Hash:          exp.TxHash,
+		Amount:        exp.Amount,
+		Confirmations: f.confirmations,
 	}, nil
 }
+
+func (f *fakeVerifier) DefaultNetwork(asset string) (string, bool) {
+	network, ok := map[string]string{
+		"BTC":  "bitcoin",
+		"ETH":  "ethereum",
+		"USDT": "ethereum",
+		"BNB":  "bsc",
+	}[strings.ToUpper(asset)]
+	return network, ok
+}
+
+// fakeWithdrawals records each request and the collateral status stored
+// when it was made.
+type fakeWithdrawals struct {
+	repo      *mockRepo
+	requested []uuid.UUID
+	statuses  []models.CollateralStatus
+	err       error
+}
+
+// Supports mirrors the worker, which only pays out on EVM networks.
+func (f *fakeWithdrawals) Supports(asset, network string) bool {
+	return network != "bitcoin"
+}
+
+func (f *fakeWithdrawals) Request(ctx context.Context, collateral *models.Collateral, destination *models.WithdrawalAddress) (*models.Withdrawal, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.requested = append(f.requested, collateral.ID)
+	if f.repo != nil {
+		f.statuses = append(f.statuses, f.repo.store[collateral.ID].Status)
+	}
+	return &models.Withdrawal{CollateralID: collateral.ID, Status: models.WithdrawalPending}, nil
+}
+
+// whitelist adds a confirmed address past its cooling-off period.
+func whitelist(service *Service, userID uuid.UUID, asset, network string) *models.WithdrawalAddress {
+	usableFrom := time.Now().Add(-time.Minute)
+	address := &models.WithdrawalAddress{
+		ID:          uuid.New(),
+		UserID:      userID,
+		AssetSymbol: asset,
+		Network:     network,
+		Address:     "0x00000000000000000000000000000000000000dd",
+		Status:      models.AddressConfirmed,
+		UsableFrom:  &usableFrom,
+	}
+	service.addresses.(fakeAddresses)[address.ID] = address
+	return address
+}
+
+type fakeAddresses map[uuid.UUID]*models.WithdrawalAddress
+
+func (f fakeAddresses) Usable(ctx context.Context, userID, id uuid.UUID) (*models.WithdrawalAddress, error) {
+	address, ok := f[id]
+	if !ok || address.UserID != userID {
+		return nil, e.ErrWithdrawalAddressNotFound
+	}
+	if !address.Usable(time.Now()) {
+		return nil, e.ErrWithdrawalAddressNotUsable
+	}
+	return address, nil
+}
+
+type fakeWallets struct{}
+
+func (fakeWallets) GetOrCreatePrimary(ctx context.Context, userID uuid.UUID, asset string) (*models.Wallet, error) {
+	return &models.Wallet{UserID: userID, AssetType: asset, Address: "deposit-" + asset + "-" + userID.String()}, nil
+}
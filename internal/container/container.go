@@ -3,21 +3,45 @@ package container
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/thoraf20/loanee/config"
+	"github.com/thoraf20/loanee/internal/addressbook"
+	"github.com/thoraf20/loanee/internal/apikey"
+	"github.com/thoraf20/loanee/internal/approval"
 	"github.com/thoraf20/loanee/internal/auth"
 	"github.com/thoraf20/loanee/internal/blockchain"
 	"github.com/thoraf20/loanee/internal/collateral"
+	"github.com/thoraf20/loanee/internal/custody"
+	"github.com/thoraf20/loanee/internal/email"
 	"github.com/thoraf20/loanee/internal/loan"
+	"github.com/thoraf20/loanee/internal/mfa"
 	"github.com/thoraf20/loanee/internal/models"
 	"github.com/thoraf20/loanee/internal/payment"
+	"github.com/thoraf20/loanee/internal/pricefeed"
 	"github.com/thoraf20/loanee/internal/pricing"
+	"github.com/thoraf20/loanee/internal/rbac"
+	"github.com/thoraf20/loanee/internal/reconciliation"
+	"github.com/thoraf20/loanee/internal/stablecoin"
 	"github.com/thoraf20/loanee/internal/user"
 	jwt "github.com/thoraf20/loanee/internal/utils"
 	"github.com/thoraf20/loanee/internal/wallet"
+	"github.com/thoraf20/loanee/internal/watcher"
+	"github.com/thoraf20/loanee/internal/withdrawal"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/keymanager"
+	"github.com/thoraf20/loanee/pkg/mailer"
+	"github.com/thoraf20/loanee/pkg/ratelimit"
+	"github.com/thoraf20/loanee/pkg/throttle"
 	"github.com/thoraf20/loanee/pkg/tokenblacklist"
+	"github.com/thoraf20/loanee/pkg/tokencutoff"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/thoraf20/loanee/pkg/validator"
@@ -40,6 +64,14 @@ type Container struct {
 	WalletRepo     wallet.Repository
 	LoanRepo       loan.Repository
 	PaymentRepo    payment.Repository
+	HotWalletRepo  custody.Repository
+	WithdrawalRepo withdrawal.Repository
+	AddressRepo    addressbook.Repository
+	MFARepo        mfa.Repository
+	RBACRepo       rbac.Repository
+	ApprovalRepo   approval.Repository
+	APIKeyRepo     apikey.Repository
+	EmailRepo      email.Repository
 
 	// Services
 	AuthService        *auth.Service
@@ -48,8 +80,24 @@ type Container struct {
 	WalletService      *wallet.Service
 	LoanService        *loan.Service
 	PaymentService     *payment.Service
+	CustodyService     *custody.Service
+	WithdrawalService  *withdrawal.Service
+	AddressBookService *addressbook.Service
+	ReconcileService   *reconciliation.Service
+	StablecoinGuard    *stablecoin.Guard
+	MFAService         *mfa.Service
+	RBACService        *rbac.Service
+	ApprovalService    *approval.Service
+	APIKeyService      *apikey.Service
+	EmailService       *email.Service
+	EmailDispatcher    *email.Dispatcher
 	PricingService     pricing.Provider
+	PriceHub           *pricefeed.Hub
 	BlockchainVerifier blockchain.Verifier
+	ChainScanners      []blockchain.Scanner
+	BalanceReaders     []blockchain.BalanceReader
+	DepositWatcher     *watcher.Watcher
+	ConfirmTracker     *watcher.Tracker
 
 	// Handlers
 	AuthHandler       *auth.Handler
@@ -58,10 +106,28 @@ type Container struct {
 	WalletHandler     *wallet.Handler
 	LoanHandler       *loan.Handler
 	PaymentHandler    *payment.Handler
+	PriceHandler      *pricefeed.Handler
+	CustodyHandler    *custody.Handler
+	WithdrawalHandler *withdrawal.Handler
+	AddressHandler    *addressbook.Handler
+	ReconcileHandler  *reconciliation.Handler
+	StablecoinHandler *stablecoin.Handler
+	MFAHandler        *mfa.Handler
+	RBACHandler       *rbac.Handler
+	ApprovalHandler   *approval.Handler
+	APIKeyHandler     *apikey.Handler
 
 	RedisClient    *redis.Client
 	TokenBlacklist tokenblacklist.Blacklist
+	LoginLimiter   *throttle.Limiter
+	RateLimiter    ratelimit.Limiter
+	Mailer         mailer.Mailer
 	JWTManager     *jwt.Manager
+	KeyManager     keymanager.KeyManager
+
+	// Background workers share this context and stop on Shutdown
+	workerCtx   context.Context
+	stopWorkers context.CancelFunc
 }
 
 // New creates and initializes the dependency container
@@ -84,6 +150,18 @@ func New(cfg *config.Config, logger zerolog.Logger) (*Container, error) {
 		return nil, fmt.Errorf("failed to init token blacklist: %w", err)
 	}
 
+	if err := c.initLoginLimiter(); err != nil {
+		return nil, fmt.Errorf("failed to init login limiter: %w", err)
+	}
+
+	if err := c.initRateLimiter(); err != nil {
+		return nil, fmt.Errorf("failed to init rate limiter: %w", err)
+	}
+
+	if err := c.initMailer(); err != nil {
+		return nil, fmt.Errorf("failed to init mailer: %w", err)
+	}
+
 	if err := c.initValidator(); err != nil {
 		return nil, fmt.Errorf("failed to init validator: %w", err)
 	}
@@ -92,6 +170,10 @@ func New(cfg *config.Config, logger zerolog.Logger) (*Container, error) {
 		return nil, fmt.Errorf("failed to init jwt manager: %w", err)
 	}
 
+	if err := c.initKeyManager(); err != nil {
+		return nil, fmt.Errorf("failed to init key manager: %w", err)
+	}
+
 	if err := c.initBlockchainVerifier(); err != nil {
 		return nil, fmt.Errorf("failed to init blockchain verifier: %w", err)
 	}
@@ -108,6 +190,8 @@ func New(cfg *config.Config, logger zerolog.Logger) (*Container, error) {
 		return nil, fmt.Errorf("failed to init handlers: %w", err)
 	}
 
+	c.startWorkers()
+
 	c.Logger.Info().Msg("Container initialized successfully")
 	return c, nil
 }
@@ -149,35 +233,360 @@ func (c *Container) initTokenBlacklist() error {
 	return nil
 }
 
+// cutoffCache shares token cutoffs through Redis when available, so a log out
+// everywhere is seen by every instance as soon as it is invalidated.
+func (c *Container) cutoffCache() tokencutoff.Cache {
+	ttl := c.Config.JWT.CutoffCacheTTL
+	if c.RedisClient != nil {
+		return tokencutoff.NewRedisCache(c.RedisClient, ttl)
+	}
+	return tokencutoff.NewMemoryCache(ttl)
+}
+
+// initLoginLimiter counts failed login, verification and reset attempts in
+// Redis when available, so every instance sees the same counters.
+func (c *Container) initLoginLimiter() error {
+	var store throttle.Store
+	if c.RedisClient != nil {
+		store = throttle.NewRedisStore(c.RedisClient)
+		c.Logger.Info().Msg("Using Redis login throttle")
+	} else {
+		store = throttle.NewMemoryStore()
+		c.Logger.Info().Msg("Using in-memory login throttle")
+	}
+
+	cfg := c.Config.LoginThrottle
+	c.LoginLimiter = throttle.NewLimiter(store, throttle.Options{
+		Account: throttle.Rule{
+			MaxFailures: cfg.AccountMaxFailures,
+			Window:      cfg.AccountWindow,
+			Lockout:     cfg.AccountLockout,
+		},
+		IP: throttle.Rule{
+			MaxFailures: cfg.IPMaxFailures,
+			Window:      cfg.IPWindow,
+			Lockout:     cfg.IPLockout,
+		},
+		FreeFailures: cfg.FreeFailures,
+		BaseDelay:    cfg.BaseDelay,
+		MaxDelay:     cfg.MaxDelay,
+	}, c.Logger)
+	return nil
+}
+
+// initRateLimiter shares request buckets through Redis when available. With
+// rate limiting disabled the limiter stays nil and routes are not limited.
+func (c *Container) initRateLimiter() error {
+	if !c.Config.RateLimit.Enabled {
+		c.Logger.Info().Msg("Rate limiting disabled")
+		return nil
+	}
+
+	if c.RedisClient != nil {
+		c.RateLimiter = ratelimit.NewRedisLimiter(c.RedisClient)
+		c.Logger.Info().Msg("Using Redis rate limiter")
+	} else {
+		c.RateLimiter = ratelimit.NewMemoryLimiter()
+		c.Logger.Info().Msg("Using in-memory rate limiter")
+	}
+	return nil
+}
+
+// initMailer picks how queued emails are delivered
+func (c *Container) initMailer() error {
+	cfg := c.Config.Email
+
+	switch cfg.Driver {
+	case "smtp":
+		m, err := mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:        cfg.SMTP.Host,
+			Port:        cfg.SMTP.Port,
+			Username:    cfg.SMTP.Username,
+			Password:    cfg.SMTP.Password,
+			From:        cfg.From,
+			ImplicitTLS: cfg.SMTP.ImplicitTLS,
+			Timeout:     cfg.SMTP.Timeout,
+		})
+		if err != nil {
+			return err
+		}
+		c.Mailer = m
+	case "file":
+		m, err := mailer.NewFileMailer(cfg.FileDir, cfg.From)
+		if err != nil {
+			return err
+		}
+		c.Mailer = m
+	case "memory":
+		c.Mailer = mailer.NewMemoryMailer()
+	default:
+		return fmt.Errorf("unknown email driver %q", cfg.Driver)
+	}
+
+	c.Logger.Info().Str("driver", cfg.Driver).Msg("Mailer initialized")
+	return nil
+}
+
 // initJWTManager initializes JWT manager
 func (c *Container) initJWTManager() error {
-	c.JWTManager = jwt.NewManager(c.Config)
-	c.Logger.Info().Msg("JWT manager initialized")
+	manager, err := jwt.NewManager(c.Config)
+	if err != nil {
+		return fmt.Errorf("failed to load JWT signing keys: %w", err)
+	}
+	c.JWTManager = manager
+
+	if len(c.Config.JWT.Keys) == 0 {
+		c.Logger.Warn().Msg("No JWT signing keys configured, signing with the legacy HS256 secret")
+	}
+	c.Logger.Info().Int("keys", len(c.Config.JWT.Keys)).Msg("JWT manager initialized")
+	return nil
+}
+
+// initKeyManager sets up envelope encryption for hot wallet keys. Without a
+// master key hot wallets are disabled rather than stored in plaintext.
+func (c *Container) initKeyManager() error {
+	cfg := c.Config.KeyManagement
+	if cfg.Provider != "" && cfg.Provider != "local" {
+		keys, err := keymanager.Connect(context.Background(), cfg.Provider)
+		if err != nil {
+			return err
+		}
+		c.KeyManager = keys
+		c.Logger.Info().Str("provider", cfg.Provider).Str("active_key", keys.ActiveKeyID()).Msg("Key manager initialized")
+		return nil
+	}
+
+	masterKeys := make(map[string]string, len(cfg.MasterKeys)+1)
+	for id, key := range cfg.MasterKeys {
+		masterKeys[id] = key
+	}
+	if cfg.MasterKey != "" {
+		masterKeys[cfg.ActiveKeyID] = cfg.MasterKey
+	}
+	if len(masterKeys) == 0 {
+		c.Logger.Warn().Msg("No master key configured, hot wallets are disabled")
+		return nil
+	}
+
+	keys, err := keymanager.NewLocal(cfg.ActiveKeyID, masterKeys)
+	if err != nil {
+		return err
+	}
+	c.KeyManager = keys
+	c.Logger.Info().Str("provider", "local").Str("active_key", keys.ActiveKeyID()).Msg("Key manager initialized")
 	return nil
 }
 
 func (c *Container) initBlockchainVerifier() error {
-	if c.Config.Blockchain.UseNoopVerifier || c.Config.Blockchain.EthereumRPC == "" {
+	cfg := c.Config.Blockchain
+	networks := enabledNetworks(cfg.Networks)
+	if cfg.UseNoopVerifier || (len(networks) == 0 && cfg.BitcoinAPIURL == "") {
 		c.BlockchainVerifier = blockchain.NewNoopVerifier()
 		c.Logger.Warn().Msg("Using noop blockchain verifier")
 		return nil
 	}
 
-	verifier, err := blockchain.NewEthereumVerifier(
-		c.Config.Blockchain.EthereumRPC,
-		c.Config.Blockchain.MinConfirmations,
-	)
-	if err != nil {
-		c.Logger.Error().Err(err).Msg("Failed to initialize Ethereum verifier, falling back to noop")
+	registry := blockchain.NewRegistry()
+
+	// Bitcoin registers first and EVM networks by ascending chain ID, so an
+	// asset listed on several networks defaults to the oldest one (USDT and
+	// ETH to Ethereum mainnet).
+	if cfg.BitcoinAPIURL != "" {
+		verifier := blockchain.NewBitcoinVerifier(cfg.BitcoinAPIURL, cfg.BitcoinMinConfirmations)
+		registry.Register(verifier.Chain(), verifier, verifier.Assets()...)
+		c.ChainScanners = append(c.ChainScanners, verifier)
+		c.BalanceReaders = append(c.BalanceReaders, verifier)
+	}
+
+	for _, network := range networks {
+		verifier, err := c.newEVMVerifier(network)
+		if err != nil {
+			c.Logger.Error().Err(err).Str("network", network.Name).Msg("Failed to initialize EVM verifier, its assets will be rejected")
+			continue
+		}
+		registry.Register(verifier.Chain(), verifier, verifier.Assets()...)
+		c.ChainScanners = append(c.ChainScanners, verifier)
+		c.BalanceReaders = append(c.BalanceReaders, verifier)
+	}
+
+	if len(registry.Assets()) == 0 {
+		c.Logger.Warn().Msg("No blockchain verifier could be initialized, falling back to noop")
 		c.BlockchainVerifier = blockchain.NewNoopVerifier()
 		return nil
 	}
 
-	c.BlockchainVerifier = verifier
-	c.Logger.Info().Msg("Blockchain verifier initialized")
+	c.BlockchainVerifier = registry
+	c.Logger.Info().Strs("assets", registry.Assets()).Msg("Blockchain verifier registry initialized")
 	return nil
 }
 
+type evmNetwork struct {
+	Name string
+	config.NetworkConfig
+}
+
+// enabledNetworks returns the EVM networks with an RPC URL, ordered by chain ID.
+func enabledNetworks(networks map[string]config.NetworkConfig) []evmNetwork {
+	var enabled []evmNetwork
+	for name, network := range networks {
+		if network.RPCURL == "" {
+			continue
+		}
+		enabled = append(enabled, evmNetwork{Name: strings.ToLower(name), NetworkConfig: network})
+	}
+	sort.Slice(enabled, func(i, j int) bool {
+		return enabled[i].ChainID < enabled[j].ChainID
+	})
+	return enabled
+}
+
+func (c *Container) newEVMVerifier(network evmNetwork) (*blockchain.EVMVerifier, error) {
+	spec, err := network.spec()
+	if err != nil {
+		return nil, err
+	}
+	// Release quotes reserve the same capped fee the withdrawal worker does.
+	spec.MaxFeePerGas = c.maxFeePerGas()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return blockchain.NewEVMVerifier(ctx, network.RPCURL, spec)
+}
+
+// withdrawalNetworks connects a signing client to every enabled EVM network.
+func (c *Container) withdrawalNetworks() []withdrawal.Network {
+	var networks []withdrawal.Network
+	for _, network := range enabledNetworks(c.Config.Blockchain.Networks) {
+		spec, err := network.spec()
+		if err != nil {
+			c.Logger.Error().Err(err).Str("network", network.Name).Msg("Invalid network configuration, withdrawals disabled on it")
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		client, err := ethclient.DialContext(ctx, network.RPCURL)
+		cancel()
+		if err != nil {
+			c.Logger.Error().Err(err).Str("network", network.Name).Msg("Failed to connect withdrawal client, withdrawals disabled on it")
+			continue
+		}
+		networks = append(networks, withdrawal.Network{EVMNetwork: spec, Client: client})
+	}
+	return networks
+}
+
+// registerApprovalActions puts loan approval, disbursement and collateral
+// release under dual control. Loans are valued at the amount being approved
+// or disbursed, in their collateral's fiat currency; collateral at its
+// current price.
+func (c *Container) registerApprovalActions() {
+	loanValue := func(ctx context.Context, id uuid.UUID, amount float64) (approval.Value, error) {
+		loan, err := c.LoanRepo.GetByID(ctx, id)
+		if err != nil {
+			return approval.Value{}, err
+		}
+		if loan == nil {
+			return approval.Value{}, e.ErrLoanNotFound
+		}
+		collateral, err := c.CollateralRepo.GetByID(ctx, loan.CollateralID)
+		if err != nil {
+			return approval.Value{}, err
+		}
+		if collateral == nil {
+			return approval.Value{}, e.ErrCollateralNotFound
+		}
+
+		value := approval.Value{Amount: loan.AmountRequested, Currency: collateral.FiatCurrency}
+		if amount > 0 {
+			value.Amount = amount
+		} else if loan.AmountApproved > 0 {
+			value.Amount = loan.AmountApproved
+		}
+		return value, nil
+	}
+
+	c.ApprovalService.Register(approval.Action{
+		Name:       approval.ActionLoanApprove,
+		Permission: rbac.PermLoansApprove,
+		Value:      loanValue,
+		Execute: func(ctx context.Context, id uuid.UUID, amount float64) (interface{}, error) {
+			return c.LoanService.ApproveLoan(ctx, id, amount)
+		},
+	})
+
+	c.ApprovalService.Register(approval.Action{
+		Name:       approval.ActionLoanDisburse,
+		Permission: rbac.PermLoansDisburse,
+		Validate: func(ctx context.Context, id uuid.UUID, _ float64) error {
+			return c.LoanService.CheckDisbursable(ctx, id)
+		},
+		Value: loanValue,
+		Execute: func(ctx context.Context, id uuid.UUID, _ float64) (interface{}, error) {
+			return c.LoanService.DisburseLoan(ctx, id)
+		},
+	})
+
+	c.ApprovalService.Register(approval.Action{
+		Name:       approval.ActionCollateralRelease,
+		Permission: rbac.PermCollateralsRelease,
+		Validate: func(ctx context.Context, id uuid.UUID, _ float64) error {
+			return c.CollateralService.CheckReleasable(ctx, id)
+		},
+		Value: func(ctx context.Context, id uuid.UUID, _ float64) (approval.Value, error) {
+			amount, currency, err := c.CollateralService.ReleaseValue(ctx, id)
+			return approval.Value{Amount: amount, Currency: currency}, err
+		},
+		Execute: func(ctx context.Context, id uuid.UUID, _ float64) (interface{}, error) {
+			return c.CollateralService.ApproveRelease(ctx, id)
+		},
+	})
+}
+
+// networkAssets lists the assets each configured EVM network carries.
+func networkAssets(networks map[string]config.NetworkConfig) map[string][]string {
+	assets := make(map[string][]string, len(networks))
+	for name, network := range networks {
+		carried := []string{strings.ToUpper(network.NativeSymbol)}
+		for symbol := range network.Tokens {
+			carried = append(carried, strings.ToUpper(symbol))
+		}
+		assets[strings.ToLower(name)] = carried
+	}
+	return assets
+}
+
+// maxFeePerGas is the configured withdrawal fee cap in wei, nil when unset.
+func (c *Container) maxFeePerGas() *big.Int {
+	gwei := c.Config.Withdrawals.MaxFeePerGasGwei
+	if gwei <= 0 {
+		return nil
+	}
+	return new(big.Int).Mul(big.NewInt(gwei), big.NewInt(1_000_000_000))
+}
+
+func (n evmNetwork) spec() (blockchain.EVMNetwork, error) {
+	tokens := make([]blockchain.Token, 0, len(n.Tokens))
+	for symbol, token := range n.Tokens {
+		if !common.IsHexAddress(token.Contract) {
+			return blockchain.EVMNetwork{}, fmt.Errorf("invalid contract address for token %s on %s", symbol, n.Name)
+		}
+		tokens = append(tokens, blockchain.Token{
+			Symbol:   strings.ToUpper(symbol),
+			Contract: common.HexToAddress(token.Contract),
+			Decimals: token.Decimals,
+		})
+	}
+
+	return blockchain.EVMNetwork{
+		Name:             n.Name,
+		ChainID:          n.ChainID,
+		NativeSymbol:     n.NativeSymbol,
+		MinConfirmations: n.MinConfirmations,
+		Tokens:           tokens,
+	}, nil
+}
+
 // initDatabase initializes GORM database connection
 func (c *Container) initDatabase() error {
 	db, err := config.NewDatabase(c.Config)
@@ -192,8 +601,25 @@ func (c *Container) initDatabase() error {
 		&user.PasswordResetToken{},
 		&models.Collateral{},
 		&models.Wallet{},
+		&models.DerivationIndex{},
+		&models.HotWallet{},
 		&models.Loan{},
 		&models.Payment{},
+		&models.ChainCursor{},
+		&models.Withdrawal{},
+		&models.WithdrawalTx{},
+		&models.WithdrawalAddress{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.MFAEnrollment{},
+		&models.MFARecoveryCode{},
+		&models.Role{},
+		&models.Permission{},
+		&models.RolePermission{},
+		&models.RoleAssignment{},
+		&models.Approval{},
+		&models.APIKey{},
+		&models.OutboxEmail{},
 	); err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
@@ -218,6 +644,14 @@ func (c *Container) initRepositories() error {
 	c.WalletRepo = wallet.NewRepository(c.DB, c.Logger)
 	c.LoanRepo = loan.NewRepository(c.DB, c.Logger)
 	c.PaymentRepo = payment.NewRepository(c.DB, c.Logger)
+	c.HotWalletRepo = custody.NewRepository(c.DB, c.Logger)
+	c.WithdrawalRepo = withdrawal.NewRepository(c.DB, c.Logger)
+	c.AddressRepo = addressbook.NewRepository(c.DB, c.Logger)
+	c.MFARepo = mfa.NewRepository(c.DB, c.Logger)
+	c.RBACRepo = rbac.NewRepository(c.DB, c.Logger)
+	c.ApprovalRepo = approval.NewRepository(c.DB, c.Logger)
+	c.APIKeyRepo = apikey.NewRepository(c.DB, c.Logger)
+	c.EmailRepo = email.NewRepository(c.DB, c.Logger)
 
 	c.Logger.Info().Msg("Repositories initialized")
 	return nil
@@ -227,10 +661,42 @@ func (c *Container) initRepositories() error {
 func (c *Container) initServices() error {
 	// Pricing service (external API)
 	c.PricingService = pricing.NewCoinGeckoProvider(
-		c.Config.CoinGecko.APIKey,
+		c.Config.CoinGecko,
+		c.Logger,
+	)
+
+	// Price hub (single upstream poller for streaming clients)
+	c.PriceHub = pricefeed.NewHub(
+		c.PricingService,
+		pricefeed.Options{
+			Symbols:          collateral.SupportedAssets,
+			Currencies:       c.Config.PriceStream.Currencies,
+			PollInterval:     c.Config.PriceStream.PollInterval,
+			MaxConnections:   c.Config.PriceStream.MaxConnections,
+			MaxPerUser:       c.Config.PriceStream.MaxConnectionsPerUser,
+			SubscriberBuffer: c.Config.PriceStream.SubscriberBuffer,
+		},
 		c.Logger,
 	)
 
+	// Stablecoin peg guard
+	var pegs collateral.Pegs
+	if cfg := c.Config.Stablecoins; len(cfg.Assets) > 0 {
+		c.StablecoinGuard = stablecoin.NewGuard(
+			c.PricingService,
+			stablecoin.Options{
+				Assets:   cfg.Assets,
+				Currency: cfg.Currency,
+				Peg:      cfg.Peg,
+				Band:     cfg.Band,
+				Haircut:  cfg.Haircut,
+				Interval: cfg.CheckInterval,
+			},
+			c.Logger,
+		)
+		pegs = c.StablecoinGuard
+	}
+
 	if c.BlockchainVerifier == nil {
 		c.BlockchainVerifier = blockchain.NewNoopVerifier()
 	}
@@ -242,14 +708,49 @@ func (c *Container) initServices() error {
 	)
 
 	// Wallet service
+	deriver, err := wallet.NewDeriver(c.Config.HDWallet)
+	if err != nil {
+		return fmt.Errorf("failed to initialize deposit address derivation: %w", err)
+	}
+	// Every asset a configured chain accepts needs deposit addresses.
+	if registry, ok := c.BlockchainVerifier.(*blockchain.Registry); ok {
+		if err := deriver.Require(registry.Assets()...); err != nil {
+			return fmt.Errorf("deposit addresses are not configured for every enabled asset: %w", err)
+		}
+	}
+	for _, asset := range collateral.SupportedAssets {
+		if !deriver.Supports(asset) {
+			c.Logger.Warn().Str("asset", asset).Msg("No xpub configured, deposits in this asset cannot be accepted")
+		}
+	}
 	c.WalletService = wallet.NewService(
 		c.WalletRepo,
+		deriver,
 		c.Logger,
 	)
 
+	// Custody service (hot wallet keys)
+	hotWalletNetworks := make([]string, 0, len(c.Config.Blockchain.Networks))
+	for name := range c.Config.Blockchain.Networks {
+		hotWalletNetworks = append(hotWalletNetworks, name)
+	}
+	c.CustodyService = custody.NewService(
+		c.HotWalletRepo,
+		c.KeyManager,
+		hotWalletNetworks,
+		c.Logger,
+	)
+
+	// Roles and permissions for admin endpoints
+	c.RBACService = rbac.NewService(c.RBACRepo, c.Logger)
+	if err := c.RBACService.Seed(context.Background()); err != nil {
+		return fmt.Errorf("failed to seed roles: %w", err)
+	}
+
 	// Loan service
 	c.LoanService = loan.NewService(
 		c.LoanRepo,
+		c.CollateralRepo,
 		c.Config,
 		c.Logger,
 	)
@@ -261,25 +762,198 @@ func (c *Container) initServices() error {
 		c.Logger,
 	)
 
+	// Two-factor authentication, sealed with the hot wallet key manager
+	c.MFAService = mfa.NewService(
+		c.MFARepo,
+		c.KeyManager,
+		c.JWTManager,
+		c.LoginLimiter,
+		mfa.Options{
+			Issuer:        c.Config.MFA.Issuer,
+			Skew:          c.Config.MFA.Skew,
+			RecoveryCodes: c.Config.MFA.RecoveryCodes,
+		},
+		c.Logger,
+	)
+
+	// Emails are queued in the outbox and delivered by the dispatcher
+	templates, err := email.LoadTemplates()
+	if err != nil {
+		return fmt.Errorf("failed to load email templates: %w", err)
+	}
+	c.EmailService = email.NewService(
+		c.EmailRepo,
+		templates,
+		c.UserRepo,
+		email.Options{
+			AppName:     c.Config.App.Name,
+			LinkBaseURL: c.Config.Email.LinkBaseURL,
+		},
+		c.Logger,
+	)
+	c.EmailDispatcher = email.NewDispatcher(
+		c.EmailRepo,
+		c.Mailer,
+		email.DispatcherOptions{
+			PollInterval:   c.Config.Email.Outbox.PollInterval,
+			BatchSize:      c.Config.Email.Outbox.BatchSize,
+			MaxAttempts:    c.Config.Email.Outbox.MaxAttempts,
+			RetryBaseDelay: c.Config.Email.Outbox.RetryBaseDelay,
+			RetryMaxDelay:  c.Config.Email.Outbox.RetryMaxDelay,
+			SendTimeout:    c.Config.Email.SMTP.Timeout,
+		},
+		c.Logger,
+	)
+
 	// Auth service
 	c.AuthService = auth.NewService(
 		c.AuthRepo,
 		c.JWTManager,
 		c.TokenBlacklist,
+		c.cutoffCache(),
+		c.MFAService,
+		c.LoginLimiter,
+		c.EmailService,
 		c.Config,
 		c.Logger,
 	)
 
-	// Collateral service
+	// Withdrawal address book
+	// BTC addresses are accepted although the worker can't pay BTC; an
+	// approved BTC release falls back to a manual payout to the address.
+	chains, err := addressbook.NewChains(c.Config.HDWallet.BitcoinNetwork, networkAssets(c.Config.Blockchain.Networks))
+	if err != nil {
+		return fmt.Errorf("failed to initialize withdrawal address validation: %w", err)
+	}
+	c.AddressBookService = addressbook.NewService(
+		c.AddressRepo,
+		chains,
+		c.EmailService,
+		addressbook.Options{
+			CoolingOff:      c.Config.AddressBook.CoolingOff,
+			ConfirmationTTL: c.Config.AddressBook.ConfirmationTTL,
+		},
+		c.Logger,
+	)
+
+	// Withdrawal service (on-chain collateral return)
+	var withdrawals collateral.Withdrawals
+	if c.Config.Withdrawals.Enabled && c.KeyManager != nil {
+		cfg := c.Config.Withdrawals
+		opts := withdrawal.Options{
+			PollInterval: cfg.PollInterval,
+			BumpAfter:    cfg.BumpAfter,
+			BumpPercent:  cfg.BumpPercent,
+			MaxAttempts:  cfg.MaxAttempts,
+		}
+		opts.MaxFeePerGas = c.maxFeePerGas()
+		c.WithdrawalService = withdrawal.NewService(
+			c.WithdrawalRepo,
+			c.withdrawalNetworks(),
+			c.CustodyService,
+			c.CollateralRepo,
+			c.PricingService,
+			opts,
+			c.Logger,
+		)
+		withdrawals = c.WithdrawalService
+	} else {
+		c.Logger.Warn().Msg("On-chain withdrawals disabled, approved releases will not move funds")
+	}
+
+	// Collateral service; fee quotes come from the same nodes deposits are
+	// verified against.
+	var fees collateral.NetworkFees
+	if registry, ok := c.BlockchainVerifier.(*blockchain.Registry); ok {
+		fees = registry
+	}
 	c.CollateralService = collateral.NewService(
 		c.CollateralRepo,
 		c.PricingService,
 		c.BlockchainVerifier,
+		fees,
+		pegs,
+		c.WalletService,
+		c.AddressBookService,
+		withdrawals,
 		c.LoanService,
 		c.Config,
 		c.Logger,
 	)
 
+	// Dual control over high-value admin actions
+	c.ApprovalService = approval.NewService(
+		c.ApprovalRepo,
+		c.RBACService,
+		approval.Options{
+			Enabled:    c.Config.Approvals.Enabled,
+			TTL:        c.Config.Approvals.TTL,
+			Thresholds: c.Config.Approvals.Thresholds,
+		},
+		c.Logger,
+	)
+	c.registerApprovalActions()
+
+	c.APIKeyService = apikey.NewService(
+		c.APIKeyRepo,
+		c.RBACService,
+		apikey.Options{
+			MaxPerUser:       c.Config.APIKeys.MaxPerUser,
+			DefaultTTL:       c.Config.APIKeys.DefaultTTL,
+			MaxTTL:           c.Config.APIKeys.MaxTTL,
+			LastUsedInterval: c.Config.APIKeys.LastUsedInterval,
+		},
+		c.Logger,
+	)
+
+	// Deposit watcher
+	if c.Config.DepositWatcher.Enabled && len(c.ChainScanners) > 0 {
+		c.DepositWatcher = watcher.New(
+			c.ChainScanners,
+			c.BlockchainVerifier,
+			c.CollateralRepo,
+			c.WalletService,
+			watcher.NewCursorRepository(c.DB, c.Logger),
+			watcher.Options{
+				PollInterval:     c.Config.DepositWatcher.PollInterval,
+				MaxBlocksPerScan: c.Config.DepositWatcher.MaxBlocksPerScan,
+				Tolerance:        c.Config.Blockchain.UnderpaymentTolerance,
+			},
+			c.Logger,
+		)
+	}
+
+	// Confirmation tracker
+	if c.Config.Confirmations.Enabled {
+		c.ConfirmTracker = watcher.NewTracker(
+			c.BlockchainVerifier,
+			c.CollateralRepo,
+			c.WalletService,
+			watcher.TrackerOptions{
+				PollInterval:         c.Config.Confirmations.PollInterval,
+				FinalityDepth:        c.Config.Confirmations.FinalityDepth,
+				DefaultFinalityDepth: c.Config.Confirmations.DefaultFinalityDepth,
+				Tolerance:            c.Config.Blockchain.UnderpaymentTolerance,
+			},
+			c.Logger,
+		)
+	}
+
+	// Custody reconciliation
+	if c.Config.Reconciliation.Enabled && len(c.BalanceReaders) > 0 {
+		c.ReconcileService = reconciliation.NewService(
+			c.BalanceReaders,
+			c.WalletRepo,
+			c.HotWalletRepo,
+			c.CollateralRepo,
+			reconciliation.Options{
+				Interval:  c.Config.Reconciliation.Interval,
+				Tolerance: c.Config.Reconciliation.Tolerance,
+			},
+			c.Logger,
+		)
+	}
+
 	c.Logger.Info().Msg("Services initialized")
 	return nil
 }
@@ -300,6 +974,9 @@ func (c *Container) initHandlers() error {
 
 	c.CollateralHandler = collateral.NewHandler(
 		c.CollateralService,
+		c.PriceHub,
+		c.Config.PriceStream.HeartbeatInterval,
+		c.ApprovalService,
 		c.Validator,
 		c.Logger,
 	)
@@ -311,6 +988,7 @@ func (c *Container) initHandlers() error {
 
 	c.LoanHandler = loan.NewHandler(
 		c.LoanService,
+		c.ApprovalService,
 		c.Logger,
 	)
 
@@ -319,14 +997,112 @@ func (c *Container) initHandlers() error {
 		c.Logger,
 	)
 
+	c.PriceHandler = pricefeed.NewHandler(
+		c.PriceHub,
+		c.Config.PriceStream.HeartbeatInterval,
+		c.Logger,
+	)
+
+	c.CustodyHandler = custody.NewHandler(
+		c.CustodyService,
+		c.Validator,
+		c.Logger,
+	)
+
+	c.AddressHandler = addressbook.NewHandler(
+		c.AddressBookService,
+		c.Validator,
+		c.Logger,
+	)
+
+	c.MFAHandler = mfa.NewHandler(
+		c.MFAService,
+		c.Validator,
+		c.Logger,
+	)
+
+	c.RBACHandler = rbac.NewHandler(
+		c.RBACService,
+		c.Validator,
+		c.Logger,
+	)
+
+	c.ApprovalHandler = approval.NewHandler(
+		c.ApprovalService,
+		c.Validator,
+		c.Logger,
+	)
+
+	c.APIKeyHandler = apikey.NewHandler(
+		c.APIKeyService,
+		c.Validator,
+		c.Logger,
+	)
+
+	if c.WithdrawalService != nil {
+		c.WithdrawalHandler = withdrawal.NewHandler(
+			c.WithdrawalService,
+			c.Logger,
+		)
+	}
+
+	if c.StablecoinGuard != nil {
+		c.StablecoinHandler = stablecoin.NewHandler(
+			c.StablecoinGuard,
+			c.Logger,
+		)
+	}
+
+	if c.ReconcileService != nil {
+		c.ReconcileHandler = reconciliation.NewHandler(
+			c.ReconcileService,
+			c.Logger,
+		)
+	}
+
 	c.Logger.Info().Msg("Handlers initialized")
 	return nil
 }
 
+// startWorkers launches long-running background goroutines
+func (c *Container) startWorkers() {
+	c.workerCtx, c.stopWorkers = context.WithCancel(context.Background())
+
+	go c.PriceHub.Run(c.workerCtx)
+	go c.EmailDispatcher.Run(c.workerCtx)
+
+	if c.DepositWatcher != nil {
+		go c.DepositWatcher.Run(c.workerCtx)
+	}
+
+	if c.ConfirmTracker != nil {
+		go c.ConfirmTracker.Run(c.workerCtx)
+	}
+
+	if c.WithdrawalService != nil {
+		go c.WithdrawalService.Run(c.workerCtx)
+	}
+
+	if c.ReconcileService != nil {
+		go c.ReconcileService.Run(c.workerCtx)
+	}
+
+	if c.StablecoinGuard != nil {
+		go c.StablecoinGuard.Run(c.workerCtx)
+	}
+
+	c.Logger.Info().Msg("Background workers started")
+}
+
 // Shutdown gracefully shuts down all resources
 func (c *Container) Shutdown() error {
 	c.Logger.Info().Msg("Shutting down container...")
 
+	// Stop background workers
+	if c.stopWorkers != nil {
+		c.stopWorkers()
+	}
+
 	// Close Redis connection
 	if c.RedisClient != nil {
 		if err := c.RedisClient.Close(); err != nil {
@@ -0,0 +1,83 @@
+package custody
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/utils"
+	"github.com/thoraf20/loanee/pkg/validator"
+)
+
+type Handler struct {
+	service   *Service
+	validator *validator.Validator
+	logger    zerolog.Logger
+}
+
+func NewHandler(service *Service, validator *validator.Validator, logger zerolog.Logger) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator,
+		logger:    logger.With().Str("component", "custody_handler").Logger(),
+	}
+}
+
+type createHotWalletDTO struct {
+	Network string `json:"network" validate:"required"`
+}
+
+func (h *Handler) AdminList(c *gin.Context) {
+	wallets, err := h.service.ListWallets(c.Request.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list hot wallets")
+		utils.InternalServerError(c, "failed to fetch hot wallets", err.Error())
+		return
+	}
+
+	utils.OK(c, "hot wallets retrieved", wallets)
+}
+
+func (h *Handler) AdminCreate(c *gin.Context) {
+	var dto createHotWalletDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		utils.BadRequest(c, "invalid payload", err.Error())
+		return
+	}
+	if err := h.validator.Validate(&dto); err != nil {
+		utils.BadRequest(c, "validation failed", err.Error())
+		return
+	}
+
+	wallet, err := h.service.Generate(c.Request.Context(), dto.Network)
+	if err != nil {
+		h.logger.Error().Err(err).Str("network", dto.Network).Msg("failed to create hot wallet")
+		respondError(c, "failed to create hot wallet", err)
+		return
+	}
+
+	utils.Created(c, "hot wallet created", wallet)
+}
+
+func (h *Handler) AdminRotateKeys(c *gin.Context) {
+	rotated, err := h.service.RotateKeys(c.Request.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Int("rotated", rotated).Msg("failed to rotate hot wallet keys")
+		respondError(c, "failed to rotate hot wallet keys", err)
+		return
+	}
+
+	utils.OK(c, "hot wallet keys rotated", gin.H{"rotated": rotated})
+}
+
+func respondError(c *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, ErrUnsupportedNetwork):
+		utils.BadRequest(c, message, err.Error())
+	case errors.Is(err, ErrKeyManagerUnavailable):
+		utils.Error(c, http.StatusServiceUnavailable, message, err.Error())
+	default:
+		utils.InternalServerError(c, message, err.Error())
+	}
+}
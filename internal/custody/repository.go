@@ -0,0 +1,91 @@
+package custody
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	Create(ctx context.Context, wallet *models.HotWallet) error
+	GetByAddress(ctx context.Context, address string) (*models.HotWallet, error)
+	GetByNetwork(ctx context.Context, network string) (*models.HotWallet, error)
+	List(ctx context.Context) ([]models.HotWallet, error)
+	ListNotWrappedBy(ctx context.Context, keyID string) ([]models.HotWallet, error)
+	Update(ctx context.Context, wallet *models.HotWallet) error
+}
+
+type repository struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewRepository(db *gorm.DB, logger zerolog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, wallet *models.HotWallet) error {
+	now := time.Now()
+	wallet.CreatedAt = now
+	wallet.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(wallet).Error; err != nil {
+		return fmt.Errorf("failed to create hot wallet: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetByAddress(ctx context.Context, address string) (*models.HotWallet, error) {
+	return r.first(ctx, "LOWER(address) = ?", strings.ToLower(address))
+}
+
+// GetByNetwork returns the oldest hot wallet on a network.
+func (r *repository) GetByNetwork(ctx context.Context, network string) (*models.HotWallet, error) {
+	return r.first(ctx, "network = ?", network)
+}
+
+func (r *repository) List(ctx context.Context) ([]models.HotWallet, error) {
+	var wallets []models.HotWallet
+	if err := r.db.WithContext(ctx).Order("network ASC, created_at ASC").Find(&wallets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list hot wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+// ListNotWrappedBy returns hot wallets whose data key is wrapped under any
+// master key other than keyID.
+func (r *repository) ListNotWrappedBy(ctx context.Context, keyID string) ([]models.HotWallet, error) {
+	var wallets []models.HotWallet
+	if err := r.db.WithContext(ctx).Where("key_id <> ?", keyID).Find(&wallets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list hot wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+func (r *repository) Update(ctx context.Context, wallet *models.HotWallet) error {
+	wallet.UpdatedAt = time.Now()
+	if err := r.db.WithContext(ctx).Save(wallet).Error; err != nil {
+		return fmt.Errorf("failed to update hot wallet: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) first(ctx context.Context, query string, args ...any) (*models.HotWallet, error) {
+	var wallet models.HotWallet
+	if err := r.db.WithContext(ctx).Where(query, args...).Order("created_at ASC").First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query hot wallet: %w", err)
+	}
+	return &wallet, nil
+}
@@ -0,0 +1,182 @@
+package custody
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/pkg/keymanager"
+)
+
+var (
+	// ErrKeyManagerUnavailable is returned when no master key is configured,
+	// so hot wallet keys can neither be stored nor read.
+	ErrKeyManagerUnavailable = errors.New("hot wallet key management is not configured")
+	ErrHotWalletNotFound     = errors.New("hot wallet not found")
+	ErrUnsupportedNetwork    = errors.New("hot wallets are not supported on this network")
+)
+
+// Service stores hot wallet private keys envelope-encrypted and hands them
+// out only to signers. Keys are held as keymanager.Secret, which redacts
+// itself in logs and JSON.
+type Service struct {
+	repo     Repository
+	keys     keymanager.KeyManager
+	networks map[string]struct{}
+	logger   zerolog.Logger
+}
+
+// NewService builds the custody service for the given EVM networks. keys may
+// be nil, in which case every operation fails with ErrKeyManagerUnavailable.
+func NewService(repo Repository, keys keymanager.KeyManager, networks []string, logger zerolog.Logger) *Service {
+	supported := make(map[string]struct{}, len(networks))
+	for _, network := range networks {
+		supported[strings.ToLower(network)] = struct{}{}
+	}
+
+	return &Service{
+		repo:     repo,
+		keys:     keys,
+		networks: supported,
+		logger:   logger.With().Str("component", "custody_service").Logger(),
+	}
+}
+
+func (s *Service) ListWallets(ctx context.Context) ([]models.HotWallet, error) {
+	return s.repo.List(ctx)
+}
+
+// Generate creates a hot wallet with a fresh key. The key never leaves the
+// server; fund the returned address to use it.
+func (s *Service) Generate(ctx context.Context, network string) (*models.HotWallet, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	secret := keymanager.Secret(crypto.FromECDSA(key))
+	defer secret.Zero()
+
+	return s.Import(ctx, network, secret)
+}
+
+// Import stores an existing private key as a hot wallet on network.
+func (s *Service) Import(ctx context.Context, network string, privateKey keymanager.Secret) (*models.HotWallet, error) {
+	if s.keys == nil {
+		return nil, ErrKeyManagerUnavailable
+	}
+	network = strings.ToLower(network)
+	if _, ok := s.networks[network]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedNetwork, network)
+	}
+
+	key, err := crypto.ToECDSA(privateKey)
+	if err != nil {
+		return nil, errors.New("invalid private key")
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+
+	env, err := keymanager.Seal(ctx, s.keys, privateKey, associatedData(network, address))
+	if err != nil {
+		return nil, err
+	}
+
+	wallet := &models.HotWallet{
+		ID:             uuid.New(),
+		Network:        network,
+		Address:        address,
+		EncryptedKey:   env.Ciphertext,
+		WrappedDataKey: env.WrappedKey,
+		KeyID:          env.KeyID,
+	}
+	if err := s.repo.Create(ctx, wallet); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().Str("network", network).Str("address", address).Str("key_id", env.KeyID).Msg("Hot wallet stored")
+	return wallet, nil
+}
+
+// ForNetwork returns the hot wallet that signs outgoing transfers on network,
+// or nil when none has been set up.
+func (s *Service) ForNetwork(ctx context.Context, network string) (*models.HotWallet, error) {
+	return s.repo.GetByNetwork(ctx, strings.ToLower(network))
+}
+
+// PrivateKey decrypts the signing key of a hot wallet. Callers must Zero it
+// once the transaction is signed.
+func (s *Service) PrivateKey(ctx context.Context, address string) (keymanager.Secret, error) {
+	if s.keys == nil {
+		return nil, ErrKeyManagerUnavailable
+	}
+
+	wallet, err := s.repo.GetByAddress(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("%w: %s", ErrHotWalletNotFound, address)
+	}
+
+	return keymanager.Open(ctx, s.keys, envelope(wallet), associatedData(wallet.Network, wallet.Address))
+}
+
+// RotateKeys re-wraps every data key still under a retired master key with
+// the active one and returns how many rows changed. Encrypted keys are not
+// touched, so a failure part-way leaves every row readable.
+func (s *Service) RotateKeys(ctx context.Context) (int, error) {
+	if s.keys == nil {
+		return 0, ErrKeyManagerUnavailable
+	}
+
+	stale, err := s.repo.ListNotWrappedBy(ctx, s.keys.ActiveKeyID())
+	if err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for i := range stale {
+		wallet := &stale[i]
+		env := envelope(wallet)
+		previous := env.KeyID
+
+		changed, err := keymanager.Rewrap(ctx, s.keys, env)
+		if err != nil {
+			return rotated, fmt.Errorf("failed to re-wrap hot wallet %s: %w", wallet.Address, err)
+		}
+		if !changed {
+			continue
+		}
+
+		now := time.Now()
+		wallet.WrappedDataKey = env.WrappedKey
+		wallet.KeyID = env.KeyID
+		wallet.RotatedAt = &now
+		if err := s.repo.Update(ctx, wallet); err != nil {
+			return rotated, err
+		}
+		rotated++
+
+		s.logger.Info().Str("address", wallet.Address).Str("from_key", previous).Str("to_key", env.KeyID).Msg("Hot wallet key re-wrapped")
+	}
+	return rotated, nil
+}
+
+func envelope(wallet *models.HotWallet) *keymanager.Envelope {
+	return &keymanager.Envelope{
+		Ciphertext: wallet.EncryptedKey,
+		WrappedKey: wallet.WrappedDataKey,
+		KeyID:      wallet.KeyID,
+	}
+}
+
+// associatedData binds a sealed key to its wallet, so a ciphertext copied
+// onto another row fails to decrypt.
+func associatedData(network, address string) []byte {
+	return []byte("hot_wallet/" + network + "/" + strings.ToLower(address))
+}
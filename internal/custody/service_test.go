@@ -0,0 +1,192 @@
+package custody
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/pkg/keymanager"
+)
+
+func TestImportStoresKeyEncrypted(t *testing.T) {
+	repo := &memoryRepo{}
+	service := NewService(repo, localKeys(t, "k1", "k1"), []string{"ethereum"}, zerolog.Nop())
+	ctx := context.Background()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	plaintext := crypto.FromECDSA(key)
+
+	wallet, err := service.Import(ctx, "Ethereum", keymanager.Secret(append([]byte(nil), plaintext...)))
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey).Hex(), wallet.Address)
+	require.Equal(t, "k1", wallet.KeyID)
+	require.False(t, bytes.Contains(repo.wallets[0].EncryptedKey, plaintext))
+
+	encoded, err := json.Marshal(wallet)
+	require.NoError(t, err)
+	require.NotContains(t, string(encoded), "encrypted")
+	require.NotContains(t, string(encoded), "wrapped")
+
+	secret, err := service.PrivateKey(ctx, strings.ToLower(wallet.Address))
+	require.NoError(t, err)
+	require.Equal(t, plaintext, []byte(secret))
+
+	_, err = service.Import(ctx, "bitcoin", secret)
+	require.ErrorIs(t, err, ErrUnsupportedNetwork)
+}
+
+func TestSecretNeverPrinted(t *testing.T) {
+	secret := keymanager.Secret("super-secret-key")
+
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+	logger.Info().Any("key", secret).Stringer("stringer", secret).Msg("signing")
+	encoded, err := json.Marshal(map[string]any{"key": secret})
+	require.NoError(t, err)
+
+	for _, out := range []string{
+		logs.String(),
+		string(encoded),
+		fmt.Sprintf("%s %v %x %#v %q", secret, secret, secret, secret, secret),
+	} {
+		require.NotContains(t, out, "super-secret-key")
+		require.NotContains(t, out, fmt.Sprintf("%x", []byte(secret)))
+	}
+
+	secret.Zero()
+	require.Equal(t, make([]byte, len(secret)), []byte(secret))
+}
+
+func TestRotateKeysRewrapsRetiredRows(t *testing.T) {
+	repo := &memoryRepo{}
+	ctx := context.Background()
+
+	old := NewService(repo, localKeys(t, "k1", "k1"), []string{"ethereum"}, zerolog.Nop())
+	wallet, err := old.Generate(ctx, "ethereum")
+	require.NoError(t, err)
+	ciphertext := append([]byte(nil), repo.wallets[0].EncryptedKey...)
+	before, err := old.PrivateKey(ctx, wallet.Address)
+	require.NoError(t, err)
+
+	// k2 becomes active while k1 is kept around for reading.
+	keys := localKeys(t, "k2", "k1", "k2")
+	service := NewService(repo, keys, []string{"ethereum"}, zerolog.Nop())
+
+	rotated, err := service.RotateKeys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 1, rotated)
+	require.Equal(t, "k2", repo.wallets[0].KeyID)
+	require.NotNil(t, repo.wallets[0].RotatedAt)
+	require.Equal(t, ciphertext, repo.wallets[0].EncryptedKey)
+
+	rotated, err = service.RotateKeys(ctx)
+	require.NoError(t, err)
+	require.Zero(t, rotated)
+
+	// Once k1 is retired, the row is still readable through k2.
+	service = NewService(repo, localKeys(t, "k2", "k2"), []string{"ethereum"}, zerolog.Nop())
+	after, err := service.PrivateKey(ctx, wallet.Address)
+	require.NoError(t, err)
+	require.Equal(t, []byte(before), []byte(after))
+}
+
+func TestSealedKeyBoundToWallet(t *testing.T) {
+	repo := &memoryRepo{}
+	service := NewService(repo, localKeys(t, "k1", "k1"), []string{"ethereum"}, zerolog.Nop())
+	ctx := context.Background()
+
+	first, err := service.Generate(ctx, "ethereum")
+	require.NoError(t, err)
+	second, err := service.Generate(ctx, "ethereum")
+	require.NoError(t, err)
+
+	// Copying one row's sealed key onto another must not yield a usable key.
+	repo.wallets[1].EncryptedKey = repo.wallets[0].EncryptedKey
+	repo.wallets[1].WrappedDataKey = repo.wallets[0].WrappedDataKey
+	_, err = service.PrivateKey(ctx, second.Address)
+	require.Error(t, err)
+
+	_, err = service.PrivateKey(ctx, first.Address)
+	require.NoError(t, err)
+}
+
+func TestServiceWithoutKeyManager(t *testing.T) {
+	service := NewService(&memoryRepo{}, nil, []string{"ethereum"}, zerolog.Nop())
+
+	_, err := service.Generate(context.Background(), "ethereum")
+	require.ErrorIs(t, err, ErrKeyManagerUnavailable)
+}
+
+// localKeys builds a local key manager holding the named master keys.
+func localKeys(t *testing.T, active string, ids ...string) keymanager.KeyManager {
+	t.Helper()
+	masterKeys := make(map[string]string, len(ids))
+	for _, id := range ids {
+		masterKeys[id] = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte(id[len(id)-1:]), 32))
+	}
+	keys, err := keymanager.NewLocal(active, masterKeys)
+	require.NoError(t, err)
+	return keys
+}
+
+type memoryRepo struct {
+	wallets []models.HotWallet
+}
+
+func (m *memoryRepo) Create(ctx context.Context, wallet *models.HotWallet) error {
+	m.wallets = append(m.wallets, *wallet)
+	return nil
+}
+
+func (m *memoryRepo) GetByAddress(ctx context.Context, address string) (*models.HotWallet, error) {
+	for _, wallet := range m.wallets {
+		if strings.EqualFold(wallet.Address, address) {
+			copy := wallet
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRepo) GetByNetwork(ctx context.Context, network string) (*models.HotWallet, error) {
+	for _, wallet := range m.wallets {
+		if wallet.Network == network {
+			copy := wallet
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRepo) List(ctx context.Context) ([]models.HotWallet, error) {
+	return append([]models.HotWallet(nil), m.wallets...), nil
+}
+
+func (m *memoryRepo) ListNotWrappedBy(ctx context.Context, keyID string) ([]models.HotWallet, error) {
+	var result []models.HotWallet
+	for _, wallet := range m.wallets {
+		if wallet.KeyID != keyID {
+			result = append(result, wallet)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryRepo) Update(ctx context.Context, wallet *models.HotWallet) error {
+	for i := range m.wallets {
+		if m.wallets[i].ID == wallet.ID {
+			m.wallets[i] = *wallet
+			return nil
+		}
+	}
+	return fmt.Errorf("hot wallet %s not found", wallet.ID)
+}
@@ -0,0 +1,142 @@
+package email
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/pkg/mailer"
+)
+
+type DispatcherOptions struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// MaxAttempts is how many sends are tried before an email is abandoned.
+	MaxAttempts int
+	// RetryBaseDelay doubles after each failed attempt, up to RetryMaxDelay.
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// SendTimeout bounds one send; a claimed email is not picked up again
+	// before it has passed.
+	SendTimeout time.Duration
+}
+
+// Dispatcher delivers queued emails, retrying failures with backoff.
+type Dispatcher struct {
+	repo   Repository
+	mailer mailer.Mailer
+	opts   DispatcherOptions
+	now    func() time.Time
+	logger zerolog.Logger
+}
+
+func NewDispatcher(repo Repository, m mailer.Mailer, opts DispatcherOptions, logger zerolog.Logger) *Dispatcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 8
+	}
+	if opts.SendTimeout <= 0 {
+		opts.SendTimeout = 30 * time.Second
+	}
+
+	return &Dispatcher{
+		repo:   repo,
+		mailer: m,
+		opts:   opts,
+		now:    time.Now,
+		logger: logger.With().Str("component", "email_dispatcher").Logger(),
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.opts.PollInterval)
+	defer ticker.Stop()
+
+	d.logger.Info().Dur("interval", d.opts.PollInterval).Msg("Email dispatcher started")
+
+	for {
+		d.Tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			d.logger.Info().Msg("Email dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick sends the emails that are due.
+func (d *Dispatcher) Tick(ctx context.Context) {
+	// Sends are sequential, so the lease covers the whole batch
+	lease := time.Duration(d.opts.BatchSize) * d.opts.SendTimeout
+	due, err := d.repo.ClaimDue(ctx, d.now(), d.opts.BatchSize, lease)
+	if err != nil {
+		d.logger.Warn().Err(err).Msg("Failed to claim queued emails")
+		return
+	}
+
+	for i := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		d.send(ctx, &due[i])
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, mail *models.OutboxEmail) {
+	sendCtx, cancel := context.WithTimeout(ctx, d.opts.SendTimeout)
+	err := d.mailer.Send(sendCtx, mailer.Message{
+		To:      mail.Recipient,
+		Subject: mail.Subject,
+		Text:    mail.TextBody,
+		HTML:    mail.HTMLBody,
+	})
+	cancel()
+
+	log := d.logger.With().Str("email_id", mail.ID.String()).Str("template", mail.Template).Logger()
+	if err == nil {
+		if err := d.repo.MarkSent(ctx, mail.ID, d.now()); err != nil {
+			log.Error().Err(err).Msg("Email sent but not marked sent, it may be sent again")
+			return
+		}
+		log.Info().Msg("Email sent")
+		return
+	}
+
+	attempts := mail.Attempts + 1
+	if attempts >= d.opts.MaxAttempts {
+		log.Error().Err(err).Int("attempts", attempts).Msg("Giving up on email")
+		if err := d.repo.MarkFailed(ctx, mail.ID, attempts, err.Error()); err != nil {
+			log.Error().Err(err).Msg("Failed to mark email failed")
+		}
+		return
+	}
+
+	next := d.now().Add(d.backoff(attempts))
+	log.Warn().Err(err).Int("attempts", attempts).Time("next_attempt_at", next).Msg("Failed to send email, will retry")
+	if err := d.repo.MarkRetry(ctx, mail.ID, attempts, next, err.Error()); err != nil {
+		log.Error().Err(err).Msg("Failed to schedule email retry")
+	}
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.RetryBaseDelay
+	if delay <= 0 {
+		delay = 30 * time.Second
+	}
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if d.opts.RetryMaxDelay > 0 && delay >= d.opts.RetryMaxDelay {
+			return d.opts.RetryMaxDelay
+		}
+	}
+	return delay
+}
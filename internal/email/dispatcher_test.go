@@ -0,0 +1,184 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/pkg/mailer"
+)
+
+func TestTemplatesRenderBothBodies(t *testing.T) {
+	templates, err := LoadTemplates()
+	require.NoError(t, err)
+
+	subject, text, html, err := templates.Render(TemplateAddressConfirmation, map[string]interface{}{
+		"AppName":   "Loanee",
+		"Name":      "<Ada>",
+		"Asset":     "ETH",
+		"Network":   "ethereum",
+		"Address":   "0x9858EfFD232B4033E47d90003D41EC34EcaEda94",
+		"Token":     "abc123",
+		"ExpiresIn": 90 * time.Minute,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Confirm your new withdrawal address", subject)
+	require.Contains(t, text, "Hi <Ada>,")
+	require.Contains(t, text, "90 minutes")
+	require.Contains(t, html, "Hi &lt;Ada&gt;,")
+	require.Contains(t, html, "<title>Confirm your new withdrawal address</title>")
+
+	// A missing value is an error rather than a blank in the email
+	_, _, _, err = templates.Render(TemplateVerification, map[string]interface{}{"AppName": "Loanee", "Name": "Ada"})
+	require.Error(t, err)
+	_, _, _, err = templates.Render("welcome", nil)
+	require.Error(t, err)
+}
+
+func TestDispatcherRetriesWithBackoffThenGivesUp(t *testing.T) {
+	repo := &memoryRepo{}
+	sender := &flakyMailer{failures: 2}
+	dispatcher := NewDispatcher(repo, sender, DispatcherOptions{
+		BatchSize:      10,
+		MaxAttempts:    3,
+		RetryBaseDelay: time.Minute,
+		RetryMaxDelay:  90 * time.Second,
+	}, zerolog.Nop())
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	mail := repo.add(now)
+	dispatcher.Tick(ctx)
+	require.Equal(t, 1, repo.get(mail).Attempts)
+	require.Equal(t, now.Add(time.Minute), repo.get(mail).NextAttemptAt)
+
+	// Not due yet, so nothing is sent
+	dispatcher.Tick(ctx)
+	require.Equal(t, 1, sender.calls)
+
+	now = now.Add(time.Minute)
+	dispatcher.Tick(ctx)
+	require.Equal(t, 2, repo.get(mail).Attempts)
+	require.Equal(t, now.Add(90*time.Second), repo.get(mail).NextAttemptAt)
+
+	now = now.Add(90 * time.Second)
+	dispatcher.Tick(ctx)
+	sent := repo.get(mail)
+	require.Equal(t, models.OutboxSent, sent.Status)
+	require.Empty(t, sent.TextBody)
+	require.Empty(t, sent.HTMLBody)
+	require.Len(t, sender.Messages(), 1)
+	require.Equal(t, "ada@example.com", sender.Messages()[0].To)
+
+	// An email that keeps failing is abandoned after MaxAttempts
+	sender.failures = 100
+	doomed := repo.add(now)
+	for i := 0; i < 3; i++ {
+		dispatcher.Tick(ctx)
+		now = now.Add(time.Hour)
+	}
+	failed := repo.get(doomed)
+	require.Equal(t, models.OutboxFailed, failed.Status)
+	require.Equal(t, 3, failed.Attempts)
+	require.Equal(t, "connection refused", failed.LastError)
+	require.Empty(t, failed.TextBody)
+}
+
+type flakyMailer struct {
+	mailer.MemoryMailer
+	failures int
+	calls    int
+}
+
+func (f *flakyMailer) Send(ctx context.Context, msg mailer.Message) error {
+	f.calls++
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("connection refused")
+	}
+	return f.MemoryMailer.Send(ctx, msg)
+}
+
+type memoryRepo struct {
+	mails []models.OutboxEmail
+}
+
+func (m *memoryRepo) add(now time.Time) uuid.UUID {
+	mail := models.OutboxEmail{
+		ID:            uuid.New(),
+		Template:      TemplateVerification,
+		Recipient:     "ada@example.com",
+		Subject:       "Verify your email address",
+		TextBody:      "Your code is 123456",
+		HTMLBody:      "<p>Your code is 123456</p>",
+		Status:        models.OutboxPending,
+		NextAttemptAt: now,
+	}
+	m.mails = append(m.mails, mail)
+	return mail.ID
+}
+
+func (m *memoryRepo) get(id uuid.UUID) models.OutboxEmail {
+	for _, mail := range m.mails {
+		if mail.ID == id {
+			return mail
+		}
+	}
+	return models.OutboxEmail{}
+}
+
+func (m *memoryRepo) Create(ctx context.Context, mail *models.OutboxEmail) error {
+	m.mails = append(m.mails, *mail)
+	return nil
+}
+
+// ClaimDue skips the lease, so tests control timing through MarkRetry alone.
+func (m *memoryRepo) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.OutboxEmail, error) {
+	var due []models.OutboxEmail
+	for _, mail := range m.mails {
+		if mail.Status == models.OutboxPending && !mail.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, mail)
+		}
+	}
+	return due, nil
+}
+
+func (m *memoryRepo) MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return m.update(id, func(mail *models.OutboxEmail) {
+		mail.Status = models.OutboxSent
+		mail.SentAt = &at
+		mail.TextBody, mail.HTMLBody = "", ""
+	})
+}
+
+func (m *memoryRepo) MarkRetry(ctx context.Context, id uuid.UUID, attempts int, next time.Time, lastError string) error {
+	return m.update(id, func(mail *models.OutboxEmail) {
+		mail.Attempts = attempts
+		mail.NextAttemptAt = next
+		mail.LastError = lastError
+	})
+}
+
+func (m *memoryRepo) MarkFailed(ctx context.Context, id uuid.UUID, attempts int, lastError string) error {
+	return m.update(id, func(mail *models.OutboxEmail) {
+		mail.Status = models.OutboxFailed
+		mail.Attempts = attempts
+		mail.LastError = lastError
+		mail.TextBody, mail.HTMLBody = "", ""
+	})
+}
+
+func (m *memoryRepo) update(id uuid.UUID, change func(*models.OutboxEmail)) error {
+	for i := range m.mails {
+		if m.mails[i].ID == id {
+			change(&m.mails[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	Create(ctx context.Context, mail *models.OutboxEmail) error
+	// ClaimDue returns up to limit pending emails due at now and pushes
+	// their next attempt back by lease, so no other dispatcher sends them
+	// meanwhile.
+	ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.OutboxEmail, error)
+	MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error
+	// MarkRetry records a failed attempt and when to try again.
+	MarkRetry(ctx context.Context, id uuid.UUID, attempts int, next time.Time, lastError string) error
+	// MarkFailed abandons an email after its last attempt.
+	MarkFailed(ctx context.Context, id uuid.UUID, attempts int, lastError string) error
+}
+
+type repository struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewRepository(db *gorm.DB, logger zerolog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, mail *models.OutboxEmail) error {
+	if err := r.db.WithContext(ctx).Create(mail).Error; err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) ClaimDue(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]models.OutboxEmail, error) {
+	var due []models.OutboxEmail
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, now).
+			Order("next_attempt_at ASC").
+			Limit(limit).
+			Find(&due).Error; err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(due))
+		for i, mail := range due {
+			ids[i] = mail.ID
+		}
+		return tx.Model(&models.OutboxEmail{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{"next_attempt_at": now.Add(lease), "updated_at": now}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim queued emails: %w", err)
+	}
+	return due, nil
+}
+
+func (r *repository) MarkSent(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.update(ctx, id, map[string]interface{}{
+		"status":     models.OutboxSent,
+		"sent_at":    at,
+		"text_body":  "",
+		"html_body":  "",
+		"last_error": "",
+		"updated_at": at,
+	})
+}
+
+func (r *repository) MarkRetry(ctx context.Context, id uuid.UUID, attempts int, next time.Time, lastError string) error {
+	return r.update(ctx, id, map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": next,
+		"last_error":      truncate(lastError, 500),
+		"updated_at":      time.Now(),
+	})
+}
+
+func (r *repository) MarkFailed(ctx context.Context, id uuid.UUID, attempts int, lastError string) error {
+	return r.update(ctx, id, map[string]interface{}{
+		"status":     models.OutboxFailed,
+		"attempts":   attempts,
+		"text_body":  "",
+		"html_body":  "",
+		"last_error": truncate(lastError, 500),
+		"updated_at": time.Now(),
+	})
+}
+
+func (r *repository) update(ctx context.Context, id uuid.UUID, values map[string]interface{}) error {
+	if err := r.db.WithContext(ctx).Model(&models.OutboxEmail{}).Where("id = ?", id).Updates(values).Error; err != nil {
+		return fmt.Errorf("failed to update queued email: %w", err)
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
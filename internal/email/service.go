@@ -0,0 +1,118 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/internal/user"
+)
+
+// Users looks up who an email goes to.
+type Users interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+type Options struct {
+	AppName string
+	// LinkBaseURL is the frontend that links in emails point to.
+	LinkBaseURL string
+}
+
+// Service renders emails into the outbox. Callers that store a code or
+// token pass the composed email to their repository to insert in the same
+// transaction; the Dispatcher delivers it after commit.
+type Service struct {
+	repo      Repository
+	templates *Templates
+	users     Users
+	opts      Options
+	now       func() time.Time
+	logger    zerolog.Logger
+}
+
+func NewService(repo Repository, templates *Templates, users Users, opts Options, logger zerolog.Logger) *Service {
+	return &Service{
+		repo:      repo,
+		templates: templates,
+		users:     users,
+		opts:      opts,
+		now:       time.Now,
+		logger:    logger.With().Str("component", "email_service").Logger(),
+	}
+}
+
+// Compose renders the named template for to, ready to be queued.
+func (s *Service) Compose(to, template string, data map[string]interface{}) (*models.OutboxEmail, error) {
+	values := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		values[k] = v
+	}
+	values["AppName"] = s.opts.AppName
+
+	subject, text, html, err := s.templates.Render(template, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OutboxEmail{
+		ID:            uuid.New(),
+		Template:      template,
+		Recipient:     to,
+		Subject:       subject,
+		TextBody:      text,
+		HTMLBody:      html,
+		Status:        models.OutboxPending,
+		NextAttemptAt: s.now(),
+	}, nil
+}
+
+// Queue adds an email that is not tied to a database change, such as one
+// carrying a signed token.
+func (s *Service) Queue(ctx context.Context, mail *models.OutboxEmail) error {
+	if err := s.repo.Create(ctx, mail); err != nil {
+		return err
+	}
+	s.logger.Info().
+		Str("email_id", mail.ID.String()).
+		Str("template", mail.Template).
+		Msg("Email queued")
+	return nil
+}
+
+// Link builds a frontend link carrying token.
+func (s *Service) Link(path, token string) string {
+	return strings.TrimRight(s.opts.LinkBaseURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// SendAddressConfirmation queues the email confirming a new withdrawal
+// address, for the address book.
+func (s *Service) SendAddressConfirmation(ctx context.Context, userID uuid.UUID, address *models.WithdrawalAddress, token string) error {
+	recipient, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	var expiresIn time.Duration
+	if address.ConfirmationExpiresAt != nil {
+		expiresIn = address.ConfirmationExpiresAt.Sub(s.now())
+	}
+
+	mail, err := s.Compose(recipient.Email, TemplateAddressConfirmation, map[string]interface{}{
+		"Name":      recipient.FirstName,
+		"Asset":     address.AssetSymbol,
+		"Network":   address.Network,
+		"Address":   address.Address,
+		"Token":     token,
+		"ExpiresIn": expiresIn,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render address confirmation: %w", err)
+	}
+	return s.Queue(ctx, mail)
+}
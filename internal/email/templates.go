@@ -0,0 +1,107 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+)
+
+// Templates the service sends.
+const (
+	TemplateVerification        = "verification"
+	TemplatePasswordReset       = "password_reset"
+	TemplateAccountUnlock       = "account_unlock"
+	TemplateAddressConfirmation = "address_confirmation"
+)
+
+var subjects = map[string]string{
+	TemplateVerification:        "Verify your email address",
+	TemplatePasswordReset:       "Reset your password",
+	TemplateAccountUnlock:       "Your account has been locked",
+	TemplateAddressConfirmation: "Confirm your new withdrawal address",
+}
+
+// funcs are available to every template.
+var funcs = map[string]interface{}{
+	"duration": humanDuration,
+}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Templates renders each email as a plain-text body and an HTML body wrapped
+// in the shared layout.
+type Templates struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+func LoadTemplates() (*Templates, error) {
+	t := &Templates{
+		text: make(map[string]*texttemplate.Template, len(subjects)),
+		html: make(map[string]*htmltemplate.Template, len(subjects)),
+	}
+
+	for name := range subjects {
+		text, err := texttemplate.New(name).Funcs(funcs).ParseFS(templateFS, "templates/"+name+".txt.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s text template: %w", name, err)
+		}
+		html, err := htmltemplate.New(name).Funcs(funcs).ParseFS(templateFS, "templates/layout.html.tmpl", "templates/"+name+".html.tmpl")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s HTML template: %w", name, err)
+		}
+		t.text[name] = text.Option("missingkey=error")
+		t.html[name] = html.Option("missingkey=error")
+	}
+	return t, nil
+}
+
+// Render returns the subject and both bodies of the named email. data must
+// be a map; Subject is added to it for the layout.
+func (t *Templates) Render(name string, data map[string]interface{}) (subject, text, html string, err error) {
+	subject, ok := subjects[name]
+	if !ok {
+		return "", "", "", fmt.Errorf("unknown email template %q", name)
+	}
+
+	values := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		values[k] = v
+	}
+	values["Subject"] = subject
+
+	var textBuf, htmlBuf bytes.Buffer
+	if err := t.text[name].ExecuteTemplate(&textBuf, name+".txt.tmpl", values); err != nil {
+		return "", "", "", fmt.Errorf("failed to render %s text body: %w", name, err)
+	}
+	if err := t.html[name].ExecuteTemplate(&htmlBuf, "layout", values); err != nil {
+		return "", "", "", fmt.Errorf("failed to render %s HTML body: %w", name, err)
+	}
+	return subject, textBuf.String(), htmlBuf.String(), nil
+}
+
+// humanDuration reads as "24 hours" or "15 minutes", rounded to the minute.
+func humanDuration(d time.Duration) string {
+	d = d.Round(time.Minute)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+
+	switch {
+	case d >= 48*time.Hour && d%(24*time.Hour) == 0:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int(d/time.Hour), "hour")
+	case d >= time.Minute:
+		return plural(int(d/time.Minute), "minute")
+	default:
+		return "a minute"
+	}
+}
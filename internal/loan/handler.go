@@ -6,18 +6,24 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/approval"
 	"github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
 )
 
 type Handler struct {
-	service *Service
-	logger  zerolog.Logger
+	service   *Service
+	approvals *approval.Service
+	logger    zerolog.Logger
 }
 
-func NewHandler(service *Service, logger zerolog.Logger) *Handler {
+// NewHandler builds the loan handler. Approvals and disbursements go through
+// approvals, which holds high-value ones for a second administrator.
+func NewHandler(service *Service, approvals *approval.Service, logger zerolog.Logger) *Handler {
 	return &Handler{
-		service: service,
-		logger:  logger.With().Str("component", "loan_handler").Logger(),
+		service:   service,
+		approvals: approvals,
+		logger:    logger.With().Str("component", "loan_handler").Logger(),
 	}
 }
 
@@ -54,6 +60,12 @@ type approveLoanDTO struct {
 }
 
 func (h *Handler) AdminApprove(c *gin.Context) {
+	adminID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
 	loanID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		utils.BadRequest(c, "invalid loan id", err.Error())
@@ -68,10 +80,19 @@ func (h *Handler) AdminApprove(c *gin.Context) {
 		}
 	}
 
-	loan, err := h.service.ApproveLoan(c.Request.Context(), loanID, dto.Amount)
+	loan, pending, err := h.approvals.Submit(c.Request.Context(), approval.Proposal{
+		Action:     approval.ActionLoanApprove,
+		ResourceID: loanID,
+		Amount:     dto.Amount,
+		ProposedBy: adminID,
+	})
 	if err != nil {
 		h.logger.Error().Err(err).Any("loan_id", loanID).Msg("failed to approve loan")
-		utils.InternalServerError(c, "failed to approve loan", err.Error())
+		respondError(c, "failed to approve loan", err)
+		return
+	}
+	if pending != nil {
+		utils.Success(c, http.StatusAccepted, "loan approval awaiting a second administrator", pending)
 		return
 	}
 
@@ -79,18 +100,40 @@ func (h *Handler) AdminApprove(c *gin.Context) {
 }
 
 func (h *Handler) AdminDisburse(c *gin.Context) {
+	adminID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
 	loanID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		utils.BadRequest(c, "invalid loan id", err.Error())
 		return
 	}
 
-	loan, err := h.service.DisburseLoan(c.Request.Context(), loanID)
+	loan, pending, err := h.approvals.Submit(c.Request.Context(), approval.Proposal{
+		Action:     approval.ActionLoanDisburse,
+		ResourceID: loanID,
+		ProposedBy: adminID,
+	})
 	if err != nil {
 		h.logger.Error().Err(err).Any("loan_id", loanID).Msg("failed to disburse loan")
-		utils.InternalServerError(c, "failed to disburse loan", err.Error())
+		respondError(c, "failed to disburse loan", err)
+		return
+	}
+	if pending != nil {
+		utils.Success(c, http.StatusAccepted, "loan disbursement awaiting a second administrator", pending)
 		return
 	}
 
 	utils.Success(c, http.StatusOK, "loan disbursed", loan)
 }
+
+func respondError(c *gin.Context, message string, err error) {
+	if appErr := e.GetAppError(err); appErr != nil {
+		utils.Error(c, appErr.StatusCode, message, err.Error())
+		return
+	}
+	utils.InternalServerError(c, message, err.Error())
+}
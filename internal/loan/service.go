@@ -10,19 +10,27 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/thoraf20/loanee/config"
 	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
 )
 
+// CollateralLookup fetches the collateral backing a loan.
+type CollateralLookup interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Collateral, error)
+}
+
 type Service struct {
-	repo   Repository
-	cfg    *config.Config
-	logger zerolog.Logger
+	repo        Repository
+	collaterals CollateralLookup
+	cfg         *config.Config
+	logger      zerolog.Logger
 }
 
-func NewService(repo Repository, cfg *config.Config, logger zerolog.Logger) *Service {
+func NewService(repo Repository, collaterals CollateralLookup, cfg *config.Config, logger zerolog.Logger) *Service {
 	return &Service{
-		repo:   repo,
-		cfg:    cfg,
-		logger: logger.With().Str("component", "loan_service").Logger(),
+		repo:        repo,
+		collaterals: collaterals,
+		cfg:         cfg,
+		logger:      logger.With().Str("component", "loan_service").Logger(),
 	}
 }
 
@@ -80,13 +88,11 @@ func (s *Service) ApproveLoan(ctx context.Context, id uuid.UUID, amount float64)
 }
 
 func (s *Service) DisburseLoan(ctx context.Context, id uuid.UUID) (*models.Loan, error) {
-	loan, err := s.repo.GetByID(ctx, id)
+	loan, err := s.disbursable(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if loan == nil {
-		return nil, fmt.Errorf("loan not found")
-	}
+
 	now := time.Now()
 	loan.DisbursedAt = &now
 	loan.PrincipalOutstanding = loan.AmountApproved
@@ -99,6 +105,46 @@ func (s *Service) DisburseLoan(ctx context.Context, id uuid.UUID) (*models.Loan,
 	return loan, nil
 }
 
+// CheckDisbursable reports why a loan cannot be disbursed yet, if it cannot,
+// so a disbursement is refused when proposed rather than when approved.
+func (s *Service) CheckDisbursable(ctx context.Context, id uuid.UUID) error {
+	_, err := s.disbursable(ctx, id)
+	return err
+}
+
+// disbursable loads a loan and checks it is approved and backed by a
+// deposit that can no longer be reversed.
+func (s *Service) disbursable(ctx context.Context, id uuid.UUID) (*models.Loan, error) {
+	loan, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if loan == nil {
+		return nil, e.ErrLoanNotFound
+	}
+	if loan.Status != "approved" {
+		return nil, e.ErrLoanNotApproved
+	}
+
+	// Funds only leave once the deposit backing the loan cannot be reorganised
+	// away. Finality is only ever recorded at lock time or by the confirmation
+	// tracker, so without the tracker a deposit locked short of it would
+	// never disburse.
+	collateral, err := s.collaterals.GetByID(ctx, loan.CollateralID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case collateral == nil:
+		return nil, e.ErrCollateralNotFound
+	case collateral.Status == models.StatusInvalidated:
+		return nil, e.ErrCollateralInvalidated
+	case collateral.FinalizedAt == nil && s.cfg.Confirmations.Enabled:
+		return nil, e.ErrCollateralNotFinal
+	}
+	return loan, nil
+}
+
 type RepaymentBreakdown struct {
 	Principal float64 `json:"principal"`
 	Interest  float64 `json:"interest"`
@@ -0,0 +1,139 @@
+package loan
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/config"
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+func TestDisburseWaitsForFinality(t *testing.T) {
+	repo := newMockRepo()
+	collaterals := &fakeCollaterals{store: make(map[uuid.UUID]*models.Collateral)}
+	service := NewService(repo, collaterals, &config.Config{
+		Loan:          config.LoanConfig{RepaymentFrequencyDays: 30},
+		Confirmations: config.ConfirmationConfig{Enabled: true},
+	}, zerolog.Nop())
+
+	collateral := &models.Collateral{ID: uuid.New(), Status: models.StatusActive}
+	collaterals.store[collateral.ID] = collateral
+
+	loan, err := service.CreateFromCollateral(context.Background(), collateral)
+	require.NoError(t, err)
+
+	_, err = service.DisburseLoan(context.Background(), loan.ID)
+	require.ErrorIs(t, err, e.ErrLoanNotApproved)
+	require.ErrorIs(t, service.CheckDisbursable(context.Background(), loan.ID), e.ErrLoanNotApproved)
+	_, err = service.ApproveLoan(context.Background(), loan.ID, 0)
+	require.NoError(t, err)
+
+	_, err = service.DisburseLoan(context.Background(), loan.ID)
+	require.ErrorIs(t, err, e.ErrCollateralNotFinal)
+	require.ErrorIs(t, service.CheckDisbursable(context.Background(), loan.ID), e.ErrCollateralNotFinal)
+
+	collateral.Status = models.StatusInvalidated
+	_, err = service.DisburseLoan(context.Background(), loan.ID)
+	require.ErrorIs(t, err, e.ErrCollateralInvalidated)
+
+	finalizedAt := time.Now()
+	collateral.Status = models.StatusActive
+	collateral.FinalizedAt = &finalizedAt
+	disbursed, err := service.DisburseLoan(context.Background(), loan.ID)
+	require.NoError(t, err)
+	require.Equal(t, "active", disbursed.Status)
+	require.NotNil(t, disbursed.DisbursedAt)
+}
+
+func TestDisburseSkipsFinalityWithoutTracker(t *testing.T) {
+	repo := newMockRepo()
+	collaterals := &fakeCollaterals{store: make(map[uuid.UUID]*models.Collateral)}
+	service := NewService(repo, collaterals, &config.Config{
+		Loan: config.LoanConfig{RepaymentFrequencyDays: 30},
+	}, zerolog.Nop())
+
+	collateral := &models.Collateral{ID: uuid.New(), Status: models.StatusActive}
+	collaterals.store[collateral.ID] = collateral
+
+	loan, err := service.CreateFromCollateral(context.Background(), collateral)
+	require.NoError(t, err)
+	_, err = service.ApproveLoan(context.Background(), loan.ID, 0)
+	require.NoError(t, err)
+
+	disbursed, err := service.DisburseLoan(context.Background(), loan.ID)
+	require.NoError(t, err)
+	require.Equal(t, "active", disbursed.Status)
+}
+
+type mockRepo struct {
+	store map[uuid.UUID]*models.Loan
+}
+
+func newMockRepo() *mockRepo {
+	return &mockRepo{store: make(map[uuid.UUID]*models.Loan)}
+}
+
+func (m *mockRepo) Create(ctx context.Context, loan *models.Loan) error {
+	if loan.ID == uuid.Nil {
+		loan.ID = uuid.New()
+	}
+	copy := *loan
+	m.store[loan.ID] = &copy
+	return nil
+}
+
+func (m *mockRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Loan, error) {
+	var result []models.Loan
+	for _, loan := range m.store {
+		if loan.UserID == userID {
+			result = append(result, *loan)
+		}
+	}
+	return result, nil
+}
+
+func (m *mockRepo) ListAll(ctx context.Context) ([]models.Loan, error) {
+	var result []models.Loan
+	for _, loan := range m.store {
+		result = append(result, *loan)
+	}
+	return result, nil
+}
+
+func (m *mockRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status string) error {
+	if loan, ok := m.store[id]; ok {
+		loan.Status = status
+	}
+	return nil
+}
+
+func (m *mockRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Loan, error) {
+	if loan, ok := m.store[id]; ok {
+		copy := *loan
+		return &copy, nil
+	}
+	return nil, nil
+}
+
+func (m *mockRepo) Update(ctx context.Context, loan *models.Loan) error {
+	copy := *loan
+	m.store[loan.ID] = &copy
+	return nil
+}
+
+type fakeCollaterals struct {
+	store map[uuid.UUID]*models.Collateral
+}
+
+func (f *fakeCollaterals) GetByID(ctx context.Context, id uuid.UUID) (*models.Collateral, error) {
+	if col, ok := f.store[id]; ok {
+		copy := *col
+		return &copy, nil
+	}
+	return nil, nil
+}
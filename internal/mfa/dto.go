@@ -0,0 +1,22 @@
+package mfa
+
+type CodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type EnrollResponse struct {
+	// Secret is the base32 key for manual entry when the QR code cannot be
+	// scanned.
+	Secret string `json:"secret"`
+	// ProvisioningURI is the otpauth:// URI to render as a QR code.
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type ConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type StepUpResponse struct {
+	// StepUpToken goes in the X-Step-Up-Token header of sensitive requests.
+	StepUpToken string `json:"step_up_token"`
+}
@@ -0,0 +1,125 @@
+package mfa
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/validator"
+)
+
+type Handler struct {
+	service   *Service
+	validator *validator.Validator
+	logger    zerolog.Logger
+}
+
+func NewHandler(service *Service, validator *validator.Validator, logger zerolog.Logger) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator,
+		logger:    logger.With().Str("component", "mfa_handler").Logger(),
+	}
+}
+
+// Enroll starts TOTP enrollment and returns the secret and provisioning URI
+// to show as a QR code.
+func (h *Handler) Enroll(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	account := c.GetString("user_email")
+	if account == "" {
+		account = userID.String()
+	}
+
+	resp, err := h.service.Enroll(c.Request.Context(), userID, account)
+	if err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Msg("failed to start MFA enrollment")
+		respondError(c, "failed to start two-factor enrollment", err)
+		return
+	}
+
+	utils.OK(c, "scan the code with your authenticator app, then confirm it", resp)
+}
+
+// Confirm enables two-factor authentication and returns the recovery codes.
+func (h *Handler) Confirm(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var payload CodeRequest
+	if !h.bind(c, &payload) {
+		return
+	}
+
+	resp, err := h.service.Confirm(c.Request.Context(), userID, payload.Code)
+	if err != nil {
+		h.logger.Warn().Err(err).Any("user_id", userID).Msg("failed to confirm MFA enrollment")
+		respondError(c, "failed to confirm two-factor enrollment", err)
+		return
+	}
+
+	utils.OK(c, "two-factor authentication enabled, store your recovery codes safely", resp)
+}
+
+// StepUp verifies a code and returns a short-lived token for sensitive
+// actions.
+func (h *Handler) StepUp(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	var payload CodeRequest
+	if !h.bind(c, &payload) {
+		return
+	}
+
+	resp, err := h.service.StepUp(c.Request.Context(), userID, payload.Code, c.ClientIP())
+	if err != nil {
+		h.logger.Warn().Err(err).Any("user_id", userID).Msg("step-up verification failed")
+		respondError(c, "step-up verification failed", err)
+		return
+	}
+
+	utils.OK(c, "step-up verified", resp)
+}
+
+func (h *Handler) bind(c *gin.Context, payload *CodeRequest) bool {
+	if err := c.ShouldBindJSON(payload); err != nil {
+		utils.BadRequest(c, "invalid payload", err.Error())
+		return false
+	}
+	if err := h.validator.Validate(payload); err != nil {
+		utils.BadRequest(c, "validation failed", err.Error())
+		return false
+	}
+	return true
+}
+
+// respondError maps service errors to their status, telling throttled
+// clients when to retry.
+func respondError(c *gin.Context, message string, err error) {
+	appErr := e.GetAppError(err)
+	if appErr == nil {
+		utils.InternalServerError(c, message, err.Error())
+		return
+	}
+
+	if appErr.RetryAfter > 0 {
+		seconds := int64(math.Ceil(appErr.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+	utils.Error(c, appErr.StatusCode, message, err.Error())
+}
@@ -0,0 +1,118 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// GetEnrollment returns nil when the user has never started enrollment.
+	GetEnrollment(ctx context.Context, userID uuid.UUID) (*models.MFAEnrollment, error)
+	SaveEnrollment(ctx context.Context, enrollment *models.MFAEnrollment) error
+	// AdvanceStep records step as the last accepted TOTP step, reporting
+	// false when it was not newer than the one already recorded.
+	AdvanceStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	// ReplaceRecoveryCodes discards the user's recovery codes in favour of
+	// codes.
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []models.MFARecoveryCode) error
+	ListUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]models.MFARecoveryCode, error)
+	// UseRecoveryCode marks a code used, reporting false if it already was.
+	UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+type repository struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewRepository(db *gorm.DB, logger zerolog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) GetEnrollment(ctx context.Context, userID uuid.UUID) (*models.MFAEnrollment, error) {
+	var enrollment models.MFAEnrollment
+	if err := r.db.WithContext(ctx).First(&enrollment, "user_id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get MFA enrollment: %w", err)
+	}
+	return &enrollment, nil
+}
+
+func (r *repository) SaveEnrollment(ctx context.Context, enrollment *models.MFAEnrollment) error {
+	now := time.Now()
+	if enrollment.CreatedAt.IsZero() {
+		enrollment.CreatedAt = now
+	}
+	enrollment.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(enrollment).Error; err != nil {
+		return fmt.Errorf("failed to save MFA enrollment: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) AdvanceStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.MFAEnrollment{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Updates(map[string]interface{}{
+			"last_used_step": step,
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to record TOTP step: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *repository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []models.MFARecoveryCode) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) ListUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]models.MFARecoveryCode, error) {
+	var codes []models.MFARecoveryCode
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Find(&codes).Error; err != nil {
+		return nil, fmt.Errorf("failed to list recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+func (r *repository) UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&models.MFARecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
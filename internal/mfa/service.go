@@ -0,0 +1,315 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/keymanager"
+	"github.com/thoraf20/loanee/pkg/throttle"
+)
+
+// scopeStepUp counts failed step-up codes apart from login attempts, so a
+// signed-in session guessing codes is locked out on its own.
+const scopeStepUp = "mfa_step_up"
+
+// StepUpTokens issues the token that proves a recent second-factor check.
+type StepUpTokens interface {
+	GenerateStepUpToken(userID uuid.UUID) (string, error)
+}
+
+type Options struct {
+	// Issuer names the account in authenticator apps.
+	Issuer string
+	// Skew is how many time steps either side of now a code is accepted for.
+	Skew          int
+	RecoveryCodes int
+}
+
+// Service enrolls users in TOTP two-factor authentication and verifies their
+// codes. Secrets are envelope-encrypted with the same key manager as hot
+// wallet keys; without one, enrollment is unavailable.
+type Service struct {
+	repo    Repository
+	keys    keymanager.KeyManager
+	tokens  StepUpTokens
+	limiter *throttle.Limiter
+	opts    Options
+	now     func() time.Time
+	logger  zerolog.Logger
+}
+
+func NewService(repo Repository, keys keymanager.KeyManager, tokens StepUpTokens, limiter *throttle.Limiter, opts Options, logger zerolog.Logger) *Service {
+	if opts.Issuer == "" {
+		opts.Issuer = "Loanee"
+	}
+	if opts.Skew < 0 {
+		opts.Skew = 0
+	}
+	if opts.RecoveryCodes <= 0 {
+		opts.RecoveryCodes = 10
+	}
+
+	return &Service{
+		repo:    repo,
+		keys:    keys,
+		tokens:  tokens,
+		limiter: limiter,
+		opts:    opts,
+		now:     time.Now,
+		logger:  logger.With().Str("component", "mfa_service").Logger(),
+	}
+}
+
+// Enroll starts (or restarts) enrollment with a fresh secret. Two-factor
+// authentication stays off until Confirm sees a code from the secret.
+func (s *Service) Enroll(ctx context.Context, userID uuid.UUID, account string) (*EnrollResponse, error) {
+	if s.keys == nil {
+		return nil, e.ErrMFAUnavailable
+	}
+
+	enrollment, err := s.repo.GetEnrollment(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment != nil && enrollment.ConfirmedAt != nil {
+		return nil, e.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	env, err := keymanager.Seal(ctx, s.keys, keymanager.Secret(secret), associatedData(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	if enrollment == nil {
+		enrollment = &models.MFAEnrollment{UserID: userID}
+	}
+	enrollment.EncryptedSecret = env.Ciphertext
+	enrollment.WrappedDataKey = env.WrappedKey
+	enrollment.KeyID = env.KeyID
+	enrollment.LastUsedStep = 0
+
+	if err := s.repo.SaveEnrollment(ctx, enrollment); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().Any("user_id", userID).Msg("MFA enrollment started")
+	return &EnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: provisioningURI(s.opts.Issuer, account, secret),
+	}, nil
+}
+
+// Confirm turns two-factor authentication on once the user proves their app
+// holds the secret, and returns their recovery codes. The codes are only
+// ever shown here; just their hashes are kept.
+func (s *Service) Confirm(ctx context.Context, userID uuid.UUID, code string) (*ConfirmResponse, error) {
+	enrollment, err := s.repo.GetEnrollment(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, e.ErrMFANotEnrolled
+	}
+	if enrollment.ConfirmedAt != nil {
+		return nil, e.ErrMFAAlreadyEnabled
+	}
+
+	if err := s.checkTOTP(ctx, enrollment, normalizeCode(code)); err != nil {
+		return nil, err
+	}
+
+	codes, records, err := s.newRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, records); err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	enrollment.ConfirmedAt = &now
+	if err := s.repo.SaveEnrollment(ctx, enrollment); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().Any("user_id", userID).Msg("MFA enabled")
+	return &ConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+// Enabled reports whether the user has confirmed two-factor authentication.
+func (s *Service) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	enrollment, err := s.repo.GetEnrollment(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return enrollment != nil && enrollment.ConfirmedAt != nil, nil
+}
+
+// Verify checks a second factor: a current TOTP code, or else one of the
+// user's unused recovery codes, which is consumed.
+func (s *Service) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	enrollment, err := s.repo.GetEnrollment(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if enrollment == nil || enrollment.ConfirmedAt == nil {
+		return e.ErrMFANotEnrolled
+	}
+
+	code = normalizeCode(code)
+	if len(code) == totpDigits {
+		return s.checkTOTP(ctx, enrollment, code)
+	}
+	return s.useRecoveryCode(ctx, userID, code)
+}
+
+// StepUp verifies a second factor and issues a step-up token for sensitive
+// actions. Wrong codes are throttled per user and per IP like the login
+// challenge.
+func (s *Service) StepUp(ctx context.Context, userID uuid.UUID, code, ip string) (*StepUpResponse, error) {
+	attempt := throttle.Attempt{Scope: scopeStepUp, Account: userID.String(), IP: ip}
+	if err := s.limiter.Check(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	if err := s.Verify(ctx, userID, code); err != nil {
+		if appErr := e.GetAppError(err); appErr != nil && appErr.Code == e.CodeInvalidCredentials {
+			if s.limiter.Fail(ctx, attempt) {
+				if locked := s.limiter.Check(ctx, attempt); locked != nil {
+					return nil, locked
+				}
+			}
+		}
+		return nil, err
+	}
+	s.limiter.Succeed(ctx, attempt)
+
+	token, err := s.tokens.GenerateStepUpToken(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &StepUpResponse{StepUpToken: token}, nil
+}
+
+// checkTOTP accepts code if it matches the enrollment's secret at a time step
+// later than the last one accepted.
+func (s *Service) checkTOTP(ctx context.Context, enrollment *models.MFAEnrollment, code string) error {
+	if s.keys == nil {
+		return e.ErrMFAUnavailable
+	}
+
+	secret, err := keymanager.Open(ctx, s.keys, envelope(enrollment), associatedData(enrollment.UserID))
+	if err != nil {
+		return fmt.Errorf("failed to open MFA secret: %w", err)
+	}
+	defer secret.Zero()
+
+	step, ok := matchTOTP(string(secret), code, s.now(), s.opts.Skew)
+	if !ok || step <= enrollment.LastUsedStep {
+		return e.ErrMFACodeInvalid
+	}
+
+	// Claim the step atomically so the same code cannot pass twice, even
+	// from two concurrent requests.
+	advanced, err := s.repo.AdvanceStep(ctx, enrollment.UserID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return e.ErrMFACodeInvalid
+	}
+	enrollment.LastUsedStep = step
+	return nil
+}
+
+func (s *Service) useRecoveryCode(ctx context.Context, userID uuid.UUID, code string) error {
+	codes, err := s.repo.ListUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range codes {
+		if bcrypt.CompareHashAndPassword([]byte(candidate.CodeHash), []byte(code)) != nil {
+			continue
+		}
+
+		used, err := s.repo.UseRecoveryCode(ctx, candidate.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			break
+		}
+
+		s.logger.Warn().
+			Any("user_id", userID).
+			Int("remaining", len(codes)-1).
+			Msg("MFA recovery code used")
+		return nil
+	}
+	return e.ErrMFACodeInvalid
+}
+
+// newRecoveryCodes returns fresh codes in the form shown to the user along
+// with their hashed records.
+func (s *Service) newRecoveryCodes(userID uuid.UUID) ([]string, []models.MFARecoveryCode, error) {
+	codes := make([]string, 0, s.opts.RecoveryCodes)
+	records := make([]models.MFARecoveryCode, 0, s.opts.RecoveryCodes)
+	now := s.now()
+
+	for i := 0; i < s.opts.RecoveryCodes; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := hex.EncodeToString(b)
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to hash recovery code: %w", err)
+		}
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		records = append(records, models.MFARecoveryCode{
+			ID:        uuid.New(),
+			UserID:    userID,
+			CodeHash:  string(hash),
+			CreatedAt: now,
+		})
+	}
+	return codes, records, nil
+}
+
+// normalizeCode strips the separators users type or paste along with a code.
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer(" ", "", "-", "").Replace(code)
+}
+
+func envelope(enrollment *models.MFAEnrollment) *keymanager.Envelope {
+	return &keymanager.Envelope{
+		Ciphertext: enrollment.EncryptedSecret,
+		WrappedKey: enrollment.WrappedDataKey,
+		KeyID:      enrollment.KeyID,
+	}
+}
+
+// associatedData binds a sealed secret to its user, so a ciphertext copied
+// onto another account does not decrypt.
+func associatedData(userID uuid.UUID) []byte {
+	return []byte("mfa/" + userID.String())
+}
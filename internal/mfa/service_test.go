@@ -0,0 +1,215 @@
+package mfa
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/keymanager"
+	"github.com/thoraf20/loanee/pkg/throttle"
+)
+
+func TestEnrollConfirmAndVerify(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// Codes are refused before enrollment.
+	require.True(t, errors.Is(svc.Verify(ctx, userID, "123456"), e.ErrMFANotEnrolled))
+
+	enrolled, err := svc.Enroll(ctx, userID, "ada@example.com")
+	require.NoError(t, err)
+	require.Contains(t, enrolled.ProvisioningURI, "secret="+enrolled.Secret)
+	require.NotContains(t, string(repo.enrollments[userID].EncryptedSecret), enrolled.Secret)
+
+	enabled, err := svc.Enabled(ctx, userID)
+	require.NoError(t, err)
+	require.False(t, enabled)
+
+	_, err = svc.Confirm(ctx, userID, "000000")
+	require.True(t, errors.Is(err, e.ErrMFACodeInvalid))
+
+	confirmed, err := svc.Confirm(ctx, userID, codeAt(t, enrolled.Secret, svc.now()))
+	require.NoError(t, err)
+	require.Len(t, confirmed.RecoveryCodes, 3)
+
+	enabled, err = svc.Enabled(ctx, userID)
+	require.NoError(t, err)
+	require.True(t, enabled)
+
+	_, err = svc.Enroll(ctx, userID, "ada@example.com")
+	require.True(t, errors.Is(err, e.ErrMFAAlreadyEnabled))
+
+	// The code used to confirm cannot be replayed, but the next step's can.
+	require.True(t, errors.Is(svc.Verify(ctx, userID, codeAt(t, enrolled.Secret, svc.now())), e.ErrMFACodeInvalid))
+	svc.now = func() time.Time { return time.Unix(1700000000, 0).Add(totpPeriod) }
+	next := codeAt(t, enrolled.Secret, svc.now())
+	require.NoError(t, svc.Verify(ctx, userID, next[:3]+" "+next[3:]))
+
+	// Recovery codes work once each, typed with or without the dash.
+	recovery := confirmed.RecoveryCodes[0]
+	require.NoError(t, svc.Verify(ctx, userID, strings.ToUpper(recovery)))
+	require.True(t, errors.Is(svc.Verify(ctx, userID, recovery), e.ErrMFACodeInvalid))
+	require.NoError(t, svc.Verify(ctx, userID, strings.ReplaceAll(confirmed.RecoveryCodes[1], "-", "")))
+
+	stepUp, err := svc.StepUp(ctx, userID, confirmed.RecoveryCodes[2], "203.0.113.7")
+	require.NoError(t, err)
+	require.Equal(t, "step-up:"+userID.String(), stepUp.StepUpToken)
+}
+
+func TestStepUpIsThrottled(t *testing.T) {
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	enrolled, err := svc.Enroll(ctx, userID, "ada@example.com")
+	require.NoError(t, err)
+	_, err = svc.Confirm(ctx, userID, codeAt(t, enrolled.Secret, svc.now()))
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = svc.StepUp(ctx, userID, "000000", "203.0.113.7")
+		require.True(t, errors.Is(err, e.ErrMFACodeInvalid))
+	}
+	_, err = svc.StepUp(ctx, userID, "000000", "203.0.113.7")
+	require.Equal(t, e.CodeAccountLocked, e.GetAppError(err).Code)
+
+	// The right code is refused too until the lockout expires.
+	svc.now = func() time.Time { return time.Unix(1700000000, 0).Add(totpPeriod) }
+	_, err = svc.StepUp(ctx, userID, codeAt(t, enrolled.Secret, svc.now()), "203.0.113.7")
+	require.Equal(t, e.CodeAccountLocked, e.GetAppError(err).Code)
+}
+
+func TestEnrollNeedsKeyManager(t *testing.T) {
+	repo := newMemoryRepo()
+	svc := NewService(repo, nil, fakeTokens{}, newTestLimiter(), Options{}, zerolog.Nop())
+
+	_, err := svc.Enroll(context.Background(), uuid.New(), "ada@example.com")
+	require.True(t, errors.Is(err, e.ErrMFAUnavailable))
+}
+
+func newTestService(t *testing.T) (*Service, *memoryRepo) {
+	t.Helper()
+
+	keys, err := keymanager.NewLocal("k1", map[string]string{
+		"k1": base64.StdEncoding.EncodeToString(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	repo := newMemoryRepo()
+	svc := NewService(repo, keys, fakeTokens{}, newTestLimiter(), Options{Skew: 1, RecoveryCodes: 3}, zerolog.Nop())
+	svc.now = func() time.Time { return time.Unix(1700000000, 0) }
+	return svc, repo
+}
+
+func newTestLimiter() *throttle.Limiter {
+	return throttle.NewLimiter(throttle.NewMemoryStore(), throttle.Options{
+		Account: throttle.Rule{MaxFailures: 3, Window: time.Hour, Lockout: time.Hour},
+		IP:      throttle.Rule{MaxFailures: 100, Window: time.Hour, Lockout: time.Hour},
+	}, zerolog.Nop())
+}
+
+func codeAt(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	key, err := secretEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return totpCode(key, timeStep(at), totpDigits)
+}
+
+type fakeTokens struct{}
+
+func (fakeTokens) GenerateStepUpToken(userID uuid.UUID) (string, error) {
+	return "step-up:" + userID.String(), nil
+}
+
+type memoryRepo struct {
+	mu          sync.Mutex
+	enrollments map[uuid.UUID]*models.MFAEnrollment
+	codes       map[uuid.UUID]*models.MFARecoveryCode
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{
+		enrollments: make(map[uuid.UUID]*models.MFAEnrollment),
+		codes:       make(map[uuid.UUID]*models.MFARecoveryCode),
+	}
+}
+
+func (m *memoryRepo) GetEnrollment(ctx context.Context, userID uuid.UUID) (*models.MFAEnrollment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	enrollment, ok := m.enrollments[userID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *enrollment
+	return &copied, nil
+}
+
+func (m *memoryRepo) SaveEnrollment(ctx context.Context, enrollment *models.MFAEnrollment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *enrollment
+	m.enrollments[enrollment.UserID] = &stored
+	return nil
+}
+
+func (m *memoryRepo) AdvanceStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	enrollment, ok := m.enrollments[userID]
+	if !ok || enrollment.LastUsedStep >= step {
+		return false, nil
+	}
+	enrollment.LastUsedStep = step
+	return true, nil
+}
+
+func (m *memoryRepo) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, codes []models.MFARecoveryCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, code := range m.codes {
+		if code.UserID == userID {
+			delete(m.codes, id)
+		}
+	}
+	for i := range codes {
+		stored := codes[i]
+		m.codes[stored.ID] = &stored
+	}
+	return nil
+}
+
+func (m *memoryRepo) ListUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]models.MFARecoveryCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var codes []models.MFARecoveryCode
+	for _, code := range m.codes {
+		if code.UserID == userID && code.UsedAt == nil {
+			codes = append(codes, *code)
+		}
+	}
+	return codes, nil
+}
+
+func (m *memoryRepo) UseRecoveryCode(ctx context.Context, id uuid.UUID) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	code, ok := m.codes[id]
+	if !ok || code.UsedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	code.UsedAt = &now
+	return true, nil
+}
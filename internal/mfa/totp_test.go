@@ -0,0 +1,53 @@
+package mfa
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPMatchesRFC6238Vectors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+
+	// SHA-1 test vectors from RFC 6238 appendix B.
+	vectors := map[int64]string{
+		59:         "94287082",
+		1111111109: "07081804",
+		1111111111: "14050471",
+		1234567890: "89005924",
+		2000000000: "69279037",
+	}
+	for unix, want := range vectors {
+		require.Equal(t, want, totpCode(secret, timeStep(time.Unix(unix, 0)), 8), "t=%d", unix)
+	}
+
+	encoded := secretEncoding.EncodeToString(secret)
+	now := time.Unix(1111111111, 0)
+	code := totpCode(secret, timeStep(now), totpDigits)
+	require.Equal(t, "050471", code)
+
+	step, ok := matchTOTP(encoded, code, now, 1)
+	require.True(t, ok)
+	require.Equal(t, timeStep(now), step)
+
+	// A code from the previous step passes within the skew, not beyond it.
+	_, ok = matchTOTP(encoded, code, now.Add(totpPeriod), 1)
+	require.True(t, ok)
+	_, ok = matchTOTP(encoded, code, now.Add(2*totpPeriod), 1)
+	require.False(t, ok)
+	_, ok = matchTOTP(encoded, "123", now, 1)
+	require.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri, err := url.Parse(provisioningURI("Loanee", "ada@example.com", "JBSWY3DPEHPK3PXP"))
+	require.NoError(t, err)
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/Loanee:ada@example.com", uri.Path)
+	require.Equal(t, "JBSWY3DPEHPK3PXP", uri.Query().Get("secret"))
+	require.Equal(t, "Loanee", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+}
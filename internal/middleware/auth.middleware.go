@@ -1,11 +1,16 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/thoraf20/loanee/internal/models"
 	"github.com/thoraf20/loanee/internal/utils"
 	jwt "github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
 	"github.com/thoraf20/loanee/pkg/tokenblacklist"
 )
 
@@ -13,10 +18,37 @@ type Service struct {
 	jwtManager *jwt.Manager
 }
 
-// AuthRequired validates JWT token, checks blacklist, and sets user info in context
-func AuthRequired(jwtManager *jwt.Manager, tokenBlacklist tokenblacklist.Blacklist) gin.HandlerFunc {
+// TokenCutoffs reports when a user last logged out of all devices; their
+// tokens issued up to then are no longer accepted.
+type TokenCutoffs interface {
+	TokensValidAfter(ctx context.Context, userID uuid.UUID) (*time.Time, error)
+}
+
+// APIKeyHeader carries an API key in place of a bearer token.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyAuthenticator resolves an API key presented from ip.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key, ip string) (*models.APIKey, error)
+}
+
+// AuthRequired validates JWT token, checks blacklist and the user's token
+// cutoff, and sets user info in context. When apiKeys is set, a request may
+// authenticate with an API key instead; the routes it reaches must then
+// declare the scope they need with RequireScope.
+func AuthRequired(jwtManager *jwt.Manager, tokenBlacklist tokenblacklist.Blacklist, cutoffs TokenCutoffs, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+		if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" && authHeader == "" {
+			if apiKeys == nil {
+				utils.Forbidden(c, "API keys are not accepted on this endpoint")
+				c.Abort()
+				return
+			}
+			authenticateAPIKey(c, apiKeys, apiKey)
+			return
+		}
+
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
 			c.Abort()
@@ -45,9 +77,25 @@ func AuthRequired(jwtManager *jwt.Manager, tokenBlacklist tokenblacklist.Blackli
 			return
 		}
 
-		claims, err := jwtManager.ValidateToken(token)
+		claims, err := jwtManager.ValidateAccessToken(token)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		// Unlike the blacklist, fail closed: a user who logged out everywhere
+		// must not be let back in because the lookup failed
+		cutoff, err := cutoffs.TokensValidAfter(c.Request.Context(), claims.UserID)
 		if err != nil {
 			c.Error(err)
+			utils.Unauthorized(c, "Unable to verify token")
+			c.Abort()
+			return
+		}
+
+		if jwt.IssuedBefore(claims, cutoff) {
+			utils.Unauthorized(c, "Token has been revoked")
 			c.Abort()
 			return
 		}
@@ -63,6 +111,28 @@ func AuthRequired(jwtManager *jwt.Manager, tokenBlacklist tokenblacklist.Blackli
 	}
 }
 
+// authenticateAPIKey sets the key's owner as the user. No role is set, so
+// role checks fail closed; the key's scopes stand in for them.
+func authenticateAPIKey(c *gin.Context, apiKeys APIKeyAuthenticator, raw string) {
+	key, err := apiKeys.Authenticate(c.Request.Context(), raw, c.ClientIP())
+	if err != nil {
+		if appErr := e.GetAppError(err); appErr != nil {
+			utils.Error(c, appErr.StatusCode, "API key rejected", err.Error())
+		} else {
+			c.Error(err)
+			utils.InternalServerError(c, "unable to verify API key", err.Error())
+		}
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", key.UserID)
+	c.Set("api_key_id", key.ID.String())
+	c.Set("api_key_scopes", key.Scopes)
+
+	c.Next()
+}
+
 func RequireRole(role string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("user_role")
@@ -104,6 +174,143 @@ func RequireAnyRole(roles ...string) gin.HandlerFunc {
 	}
 }
 
+// PermissionChecker reports whether a user's role grants a permission.
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, userID uuid.UUID, permission string) (bool, error)
+}
+
+// RequirePermission lets the request through only if the user's current role
+// grants permission. The role is looked up rather than taken from the token,
+// so a demotion applies immediately. It must run after AuthRequired.
+func RequirePermission(checker PermissionChecker, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := utils.UserIDFromGin(c)
+		if !ok {
+			utils.Unauthorized(c, "authentication required")
+			c.Abort()
+			return
+		}
+
+		allowed, err := checker.HasPermission(c.Request.Context(), userID, permission)
+		if err != nil {
+			c.Error(err)
+			utils.InternalServerError(c, "unable to check permissions", err.Error())
+			c.Abort()
+			return
+		}
+		if !allowed {
+			utils.Error(c, http.StatusForbidden, "Insufficient permissions", e.ErrPermissionDenied)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireScope gates a route that accepts API keys. A key must carry
+// permission among its scopes and its owner's role must still grant it;
+// requests with a bearer token pass, as the route is the user's own. It must
+// run after AuthRequired.
+func RequireScope(checker PermissionChecker, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, isAPIKey := c.Get("api_key_scopes")
+		if !isAPIKey {
+			c.Next()
+			return
+		}
+
+		scopes, _ := value.([]string)
+		if !hasScope(scopes, permission) {
+			utils.Error(c, http.StatusForbidden, "API key lacks the scope "+permission, e.ErrPermissionDenied)
+			c.Abort()
+			return
+		}
+
+		// Checked on each request, so a demotion also narrows the user's keys
+		userID, _ := utils.UserIDFromGin(c)
+		allowed, err := checker.HasPermission(c.Request.Context(), userID, permission)
+		if err != nil {
+			c.Error(err)
+			utils.InternalServerError(c, "unable to check permissions", err.Error())
+			c.Abort()
+			return
+		}
+		if !allowed {
+			utils.Error(c, http.StatusForbidden, "Insufficient permissions", e.ErrPermissionDenied)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func hasScope(scopes []string, permission string) bool {
+	for _, scope := range scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// StepUpHeader carries the step-up token on sensitive requests.
+const StepUpHeader = "X-Step-Up-Token"
+
+// MFAStatus reports whether a user has two-factor authentication enabled.
+type MFAStatus interface {
+	Enabled(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// RequireStepUp guards sensitive actions behind a recent second-factor check.
+// Users with two-factor authentication must send a step-up token issued to
+// them; users without it pass, unless requireEnrollment is set. It must run
+// after AuthRequired.
+func RequireStepUp(jwtManager *jwt.Manager, mfa MFAStatus, requireEnrollment bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := utils.UserIDFromGin(c)
+		if !ok {
+			utils.Unauthorized(c, "authentication required")
+			c.Abort()
+			return
+		}
+
+		enabled, err := mfa.Enabled(c.Request.Context(), userID)
+		if err != nil {
+			c.Error(err)
+			utils.InternalServerError(c, "unable to check two-factor status", err.Error())
+			c.Abort()
+			return
+		}
+		if !enabled {
+			if requireEnrollment {
+				utils.Forbidden(c, "Enable two-factor authentication to perform this action")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		token := c.GetHeader(StepUpHeader)
+		if token == "" {
+			utils.Error(c, http.StatusForbidden, "Step-up verification required", e.ErrStepUpRequired)
+			c.Abort()
+			return
+		}
+
+		claims, err := jwtManager.ValidateStepUpToken(token)
+		if err != nil || claims.UserID != userID {
+			utils.Error(c, http.StatusForbidden, "Step-up token is invalid or expired", e.ErrStepUpRequired)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // OptionalAuth attempts to authenticate but doesn't fail if token is missing
 // Useful for endpoints that work with or without authentication
 func OptionalAuth(jwtManager *jwt.Manager, tokenBlacklist tokenblacklist.Blacklist) gin.HandlerFunc {
@@ -132,7 +339,7 @@ func OptionalAuth(jwtManager *jwt.Manager, tokenBlacklist tokenblacklist.Blackli
 		}
 
 		// Validate token
-		claims, err := jwtManager.ValidateToken(token)
+		claims, err := jwtManager.ValidateAccessToken(token)
 		if err != nil {
 			// Invalid token, continue without authentication
 			c.Next()
@@ -160,20 +367,3 @@ func RequireEmailVerified() gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-// RateLimitByUser applies rate limiting per user
-func RateLimitByUser() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userID, exists := c.Get("user_id")
-		if !exists {
-			c.Next()
-			return
-		}
-
-		// TODO: Implement rate limiting logic using Redis
-		// For now, just pass through
-		_ = userID
-
-		c.Next()
-	}
-}
@@ -0,0 +1,17 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// TrustProxies makes c.ClientIP() honour X-Forwarded-For and X-Real-IP only
+// from the given proxy addresses or CIDR ranges. With none, the client IP is
+// always the connection's remote address, so allow-lists and per-IP limits
+// cannot be bypassed with a forged header. Gin's default trusts every peer.
+func TrustProxies(r *gin.Engine, proxies []string) error {
+	if len(proxies) == 0 {
+		r.ForwardedByClientIP = false
+		return r.SetTrustedProxies(nil)
+	}
+
+	r.ForwardedByClientIP = true
+	return r.SetTrustedProxies(proxies)
+}
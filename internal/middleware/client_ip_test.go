@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+func TestSpoofedForwardedForDoesNotPassAPIKeyAllowList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(proxies []string) int {
+		r := gin.New()
+		require.NoError(t, TrustProxies(r, proxies))
+		r.Use(AuthRequired(nil, nil, nil, allowListKeys{allowed: "10.0.0.1"}))
+		r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		// httptest requests come from 192.0.2.1
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(APIKeyHeader, "lk_test_secret")
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusForbidden, serve(nil))
+	require.Equal(t, http.StatusForbidden, serve([]string{"203.0.113.0/24"}))
+
+	// Behind a trusted load balancer the forwarded client IP is used
+	require.Equal(t, http.StatusOK, serve([]string{"192.0.2.0/24"}))
+}
+
+type allowListKeys struct {
+	allowed string
+}
+
+func (a allowListKeys) Authenticate(ctx context.Context, key, ip string) (*models.APIKey, error) {
+	if ip != a.allowed {
+		return nil, e.ErrAPIKeyIPNotAllowed
+	}
+	return &models.APIKey{ID: uuid.New(), UserID: uuid.New()}, nil
+}
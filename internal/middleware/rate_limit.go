@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/ratelimit"
+)
+
+// RateLimit caps each client's requests to limit within a route group. The
+// client is the authenticated user, else the API key, else the IP address,
+// so it must run after any authentication middleware. Limiter errors let the
+// request through rather than take the API down with the store.
+func RateLimit(limiter ratelimit.Limiter, group string, limit ratelimit.Limit) gin.HandlerFunc {
+	return rateLimit(limiter, group, limit, rateLimitSubject)
+}
+
+// RateLimitByIP caps each client IP's requests to limit within a group. It
+// runs before authentication, so requests with bad or revoked credentials
+// are counted too and can't make every request cost a credential lookup.
+func RateLimitByIP(limiter ratelimit.Limiter, group string, limit ratelimit.Limit) gin.HandlerFunc {
+	return rateLimit(limiter, group, limit, func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	})
+}
+
+func rateLimit(limiter ratelimit.Limiter, group string, limit ratelimit.Limit, subject func(*gin.Context) string) gin.HandlerFunc {
+	if limiter == nil || limit.Requests <= 0 || limit.Window <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	policy := strconv.Itoa(limit.Requests) + ";w=" + strconv.FormatInt(ceilSeconds(limit.Window), 10)
+
+	return func(c *gin.Context) {
+		result, err := limiter.Allow(c.Request.Context(), group+":"+subject(c), limit)
+		if err != nil {
+			c.Error(err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+			utils.Error(c, http.StatusTooManyRequests, "Too many requests", e.NewRateLimitedError(result.RetryAfter))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitSubject names who a request is counted against.
+func rateLimitSubject(c *gin.Context) string {
+	if userID, ok := utils.UserIDFromGin(c); ok {
+		return "user:" + userID.String()
+	}
+	if keyID, ok := c.Get("api_key_id"); ok {
+		if id, ok := keyID.(string); ok && id != "" {
+			return "key:" + id
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thoraf20/loanee/pkg/ratelimit"
+)
+
+func TestRateLimitRefusesOverLimitPerClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	alice, bob := uuid.New(), uuid.New()
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		switch c.GetHeader("X-User") {
+		case "alice":
+			c.Set("user_id", alice)
+		case "bob":
+			c.Set("user_id", bob)
+		}
+	})
+	r.Use(RateLimit(ratelimit.NewMemoryLimiter(), "api", ratelimit.Limit{Requests: 2, Window: time.Minute}))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("alice")
+	require.Equal(t, http.StatusOK, first.Code)
+	require.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "2;w=60", first.Header().Get("RateLimit-Policy"))
+
+	require.Equal(t, http.StatusOK, get("alice").Code)
+
+	refused := get("alice")
+	require.Equal(t, http.StatusTooManyRequests, refused.Code)
+	require.Equal(t, "0", refused.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", refused.Header().Get("Retry-After"))
+	require.Contains(t, refused.Body.String(), `"status":"error"`)
+
+	// Other users and anonymous clients have their own buckets.
+	require.Equal(t, http.StatusOK, get("bob").Code)
+	require.Equal(t, http.StatusOK, get("").Code)
+}
+
+func TestRateLimitByIPCountsRequestsBeforeAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(RateLimitByIP(ratelimit.NewMemoryLimiter(), "ip", ratelimit.Limit{Requests: 2, Window: time.Minute}))
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer good" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set("user_id", uuid.New())
+	})
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(token, remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Rejected credentials still spend the address's budget.
+	require.Equal(t, http.StatusUnauthorized, get("bad", "10.0.0.1:1234"))
+	require.Equal(t, http.StatusOK, get("good", "10.0.0.1:1234"))
+	require.Equal(t, http.StatusTooManyRequests, get("good", "10.0.0.1:1234"))
+
+	require.Equal(t, http.StatusOK, get("good", "10.0.0.2:1234"))
+}
@@ -9,9 +9,20 @@ import (
 	"github.com/thoraf20/loanee/internal/utils"
 )
 
-// Timeout adds a timeout to each request
-func Timeout(timeout time.Duration) gin.HandlerFunc {
+// Timeout adds a timeout to each request.
+// Routes listed in skipPaths (e.g. long-lived streams) are left untouched.
+func Timeout(timeout time.Duration, skipPaths ...string) gin.HandlerFunc {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, path := range skipPaths {
+		skip[path] = true
+	}
+
 	return func(c *gin.Context) {
+		if skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
 		defer cancel()
 
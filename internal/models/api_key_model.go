@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKey lets a partner's backend act for a user without a login. The key is
+// shown once at creation; only its prefix, which looks it up, and a hash of
+// its secret are kept.
+type APIKey struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name       string    `gorm:"size:100;not null" json:"name"`
+	Prefix     string    `gorm:"size:32;not null;uniqueIndex" json:"prefix"`
+	SecretHash string    `gorm:"size:64;not null" json:"-"`
+	// Scopes are the permissions the key carries, each also granted to
+	// its owner's role.
+	Scopes []string `gorm:"serializer:json;not null" json:"scopes"`
+	// AllowedIPs are addresses or CIDR ranges; empty allows any.
+	AllowedIPs []string   `gorm:"serializer:json" json:"allowed_ips,omitempty"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `gorm:"size:45" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy  *uuid.UUID `gorm:"type:uuid" json:"revoked_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
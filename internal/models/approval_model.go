@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ApprovalStatus string
+
+const (
+	// ApprovalPending waits for a second administrator.
+	ApprovalPending ApprovalStatus = "pending"
+	// ApprovalApproved has been approved and its action is running.
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalExecuted ApprovalStatus = "executed"
+	// ApprovalFailed was approved but its action returned an error.
+	ApprovalFailed   ApprovalStatus = "failed"
+	ApprovalRejected ApprovalStatus = "rejected"
+	ApprovalExpired  ApprovalStatus = "expired"
+)
+
+// Approval is a high-value admin action held for a second administrator to
+// confirm (maker-checker). It records who proposed it and who decided it.
+type Approval struct {
+	ID     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Action string    `gorm:"size:50;not null;uniqueIndex:idx_approvals_pending,where:status = 'pending'" json:"action"`
+	// ResourceID is the loan or collateral the action applies to.
+	ResourceID uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_approvals_pending,where:status = 'pending'" json:"resource_id"`
+	// Amount is the action's own amount argument, if it takes one.
+	Amount float64 `gorm:"not null;default:0" json:"amount,omitempty"`
+	// Value is what was weighed against the action's threshold, in Currency.
+	Value        float64        `gorm:"not null" json:"value"`
+	Currency     string         `gorm:"size:5" json:"currency,omitempty"`
+	Status       ApprovalStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	ProposedBy   uuid.UUID      `gorm:"type:uuid;not null;index" json:"proposed_by"`
+	ProposalNote string         `gorm:"size:500" json:"proposal_note,omitempty"`
+	DecidedBy    *uuid.UUID     `gorm:"type:uuid" json:"decided_by,omitempty"`
+	DecidedAt    *time.Time     `json:"decided_at,omitempty"`
+	DecisionNote string         `gorm:"size:500" json:"decision_note,omitempty"`
+	ExecutedAt   *time.Time     `json:"executed_at,omitempty"`
+	// Error is why the action failed after approval.
+	Error     string    `gorm:"size:500" json:"error,omitempty"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
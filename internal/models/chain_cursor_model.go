@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ChainCursor records the last block a background scanner has processed on a
+// chain so it can resume after a restart.
+type ChainCursor struct {
+	Chain     string    `gorm:"size:32;primaryKey" json:"chain"`
+	LastBlock uint64    `gorm:"not null" json:"last_block"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
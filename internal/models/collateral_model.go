@@ -15,8 +15,14 @@ const (
 	StatusConfirmed        CollateralStatus = "confirmed"
 	StatusActive           CollateralStatus = "active"
 	StatusReleaseRequested CollateralStatus = "release_requested"
-	StatusReleased         CollateralStatus = "released"
-	StatusLiquidated       CollateralStatus = "liquidated"
+	// StatusReleasing marks an approved release whose on-chain return has
+	// not confirmed yet.
+	StatusReleasing  CollateralStatus = "releasing"
+	StatusReleased   CollateralStatus = "released"
+	StatusLiquidated CollateralStatus = "liquidated"
+	// StatusInvalidated marks collateral whose deposit was reorganised out of
+	// the chain or reverted before reaching finality.
+	StatusInvalidated CollateralStatus = "invalidated"
 )
 
 type Collateral struct {
@@ -24,6 +30,7 @@ type Collateral struct {
 	UserID             uuid.UUID        `gorm:"type:uuid;not null" json:"user_id"`
 	LoanRequestID      *uuid.UUID       `gorm:"type:uuid" json:"loan_request_id,omitempty"`
 	AssetSymbol        string           `gorm:"size:10;not null" json:"asset_symbol"`
+	Network            string           `gorm:"size:32" json:"network,omitempty"`
 	AssetAmount        float64          `gorm:"not null" json:"asset_amount"`
 	AssetValue         float64          `gorm:"not null" json:"asset_value"`
 	RequiredValue      float64          `gorm:"not null" json:"required_value"`
@@ -31,14 +38,20 @@ type Collateral struct {
 	FiatAmount         float64          `gorm:"not null" json:"fiat_amount"`
 	LTV                float64          `gorm:"not null;default:0.65" json:"ltv"`
 	Status             CollateralStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
-	TxHash             *string          `gorm:"size:255" json:"tx_hash,omitempty"`
+	TxHash             *string          `gorm:"size:255;uniqueIndex" json:"tx_hash,omitempty"`
 	WalletAddress      *string          `gorm:"size:255" json:"wallet_address,omitempty"`
+	BlockNumber        *uint64          `json:"block_number,omitempty"`
+	BlockHash          *string          `gorm:"size:100" json:"block_hash,omitempty"`
+	FinalizedAt        *time.Time       `json:"finalized_at,omitempty"`
+	InvalidatedAt      *time.Time       `json:"invalidated_at,omitempty"`
 	CreatedAt          time.Time        `json:"created_at"`
 	UpdatedAt          time.Time        `json:"updated_at"`
 	VerifiedAt         *time.Time       `json:"verified_at,omitempty"`
 	ReleaseRequestedAt *time.Time       `json:"release_requested_at,omitempty"`
 	ReleaseResolvedAt  *time.Time       `json:"release_resolved_at,omitempty"`
 	ReleaseNote        *string          `json:"release_note,omitempty"`
+	// ReleaseAddressID is the whitelisted address the collateral is returned to.
+	ReleaseAddressID *uuid.UUID `gorm:"type:uuid" json:"release_address_id,omitempty"`
 }
 
 // BeforeCreate GORM hook — auto-generate UUIDs
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DerivationIndex allocates each user the BIP32 child index shared by all of
+// their deposit addresses. Index is a serial key, so allocation only grows
+// and no two users ever derive from the same index.
+type DerivationIndex struct {
+	Index     uint32    `gorm:"primaryKey;autoIncrement"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	CreatedAt time.Time
+}
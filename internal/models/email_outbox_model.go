@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending"
+	OutboxSent    OutboxStatus = "sent"
+	// OutboxFailed gave up after the maximum number of attempts.
+	OutboxFailed OutboxStatus = "failed"
+)
+
+// OutboxEmail is a rendered email queued in the same transaction as the
+// change it reports, and delivered by the dispatcher once that commits.
+// Bodies are cleared once the email is sent or abandoned, since they may
+// hold codes and links.
+type OutboxEmail struct {
+	ID        uuid.UUID    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Template  string       `gorm:"size:50;not null" json:"template"`
+	Recipient string       `gorm:"size:255;not null" json:"recipient"`
+	Subject   string       `gorm:"size:255;not null" json:"subject"`
+	TextBody  string       `gorm:"type:text" json:"-"`
+	HTMLBody  string       `gorm:"type:text" json:"-"`
+	Status    OutboxStatus `gorm:"type:varchar(20);not null;index:idx_outbox_due,priority:1" json:"status"`
+	Attempts  int          `gorm:"not null;default:0" json:"attempts"`
+	// NextAttemptAt is when the dispatcher next picks the email up.
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_outbox_due,priority:2" json:"next_attempt_at"`
+	LastError     string     `gorm:"size:500" json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HotWallet is a platform-controlled wallet that signs outgoing transfers
+// such as collateral releases. Its private key is envelope-encrypted: the
+// key is sealed under a per-row data key, which is itself wrapped by a
+// master key identified by KeyID.
+type HotWallet struct {
+	ID             uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Network        string     `gorm:"size:32;not null;index" json:"network"`
+	Address        string     `gorm:"size:255;not null;uniqueIndex" json:"address"`
+	EncryptedKey   []byte     `gorm:"not null" json:"-"`
+	WrappedDataKey []byte     `gorm:"not null" json:"-"`
+	KeyID          string     `gorm:"size:64;not null;index" json:"key_id"`
+	RotatedAt      *time.Time `json:"rotated_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MFAEnrollment holds a user's TOTP secret, envelope-encrypted like hot
+// wallet keys. Two-factor authentication is on once ConfirmedAt is set.
+type MFAEnrollment struct {
+	UserID          uuid.UUID  `gorm:"type:uuid;primaryKey" json:"user_id"`
+	EncryptedSecret []byte     `gorm:"not null" json:"-"`
+	WrappedDataKey  []byte     `gorm:"not null" json:"-"`
+	KeyID           string     `gorm:"size:64;not null;index" json:"-"`
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty"`
+	// LastUsedStep is the TOTP time step of the last accepted code, so a
+	// code cannot be replayed within its validity window.
+	LastUsedStep int64     `gorm:"not null;default:0" json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MFARecoveryCode is a single-use fallback code, stored as a bcrypt hash.
+type MFARecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Role is a named set of permissions. A user holds one role, by name.
+type Role struct {
+	Name        string    `gorm:"size:50;primaryKey" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Permission names one action, as "resource:action".
+type Permission struct {
+	Name        string    `gorm:"size:100;primaryKey" json:"name"`
+	Description string    `gorm:"size:255" json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RolePermission grants a permission to a role.
+type RolePermission struct {
+	RoleName       string    `gorm:"size:50;primaryKey" json:"role"`
+	PermissionName string    `gorm:"size:100;primaryKey" json:"permission"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// RoleAssignment is the audit record of a change to a user's role.
+type RoleAssignment struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID       uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	PreviousRole string    `gorm:"size:50;not null" json:"previous_role"`
+	Role         string    `gorm:"size:50;not null" json:"role"`
+	AssignedBy   uuid.UUID `gorm:"type:uuid;not null;index" json:"assigned_by"`
+	Reason       string    `gorm:"size:500;not null" json:"reason"`
+	IPAddress    string    `gorm:"size:45" json:"ip_address,omitempty"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is one signed-in device. Its ID is the refresh token family
+// started at login, so revoking the session revokes every refresh token the
+// device could still use.
+type Session struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	RefreshTokenID uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	Device         string     `gorm:"type:varchar(100)" json:"device,omitempty"`
+	IPAddress      string     `gorm:"type:varchar(64)" json:"ip_address,omitempty"`
+	UserAgent      string     `gorm:"type:varchar(512)" json:"user_agent,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastSeenAt     time.Time  `gorm:"not null" json:"last_seen_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+}
@@ -6,14 +6,18 @@ import (
 	"github.com/google/uuid"
 )
 
+// Wallet is a platform deposit address assigned to a user for one asset. The
+// address is derived from an account xpub at DerivationPath; the matching
+// private key is held offline.
 type Wallet struct {
-	ID              uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	UserID  				uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
-	AssetType  			string    `gorm:"asset_type" db:"asset_type"` // e.g. ETH, BNB
-	Address    			string    `gorm:"address" db:"address"`
-	PrivateKey  		string    `gorm:"type:text" json:"-"`
-	Balance    			float64   `gorm:"balance" db:"balance"`
-	IsPrimary  			bool      `gorm:"is_primary" db:"is_primary"`
-	CreatedAt   		time.Time
-	UpdatedAt   		time.Time
-}
\ No newline at end of file
+	ID              uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID          uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
+	AssetType       string    `gorm:"asset_type" db:"asset_type"` // e.g. ETH, BNB
+	Address         string    `gorm:"size:255;uniqueIndex" db:"address"`
+	DerivationIndex uint32    `json:"derivation_index"`
+	DerivationPath  string    `gorm:"size:64" json:"derivation_path"`
+	Balance         float64   `gorm:"balance" db:"balance"`
+	IsPrimary       bool      `gorm:"is_primary" db:"is_primary"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
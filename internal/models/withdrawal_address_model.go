@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WithdrawalAddressStatus string
+
+const (
+	// AddressPendingConfirmation awaits the emailed confirmation link.
+	AddressPendingConfirmation WithdrawalAddressStatus = "pending_confirmation"
+	// AddressConfirmed may receive funds once UsableFrom has passed.
+	AddressConfirmed WithdrawalAddressStatus = "confirmed"
+	AddressRevoked   WithdrawalAddressStatus = "revoked"
+)
+
+// WithdrawalAddress is an entry in a borrower's address book. Released
+// collateral is only ever sent to a confirmed entry whose cooling-off period
+// has elapsed, so a hijacked session cannot redirect funds straight away.
+type WithdrawalAddress struct {
+	ID                    uuid.UUID               `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID                uuid.UUID               `gorm:"type:uuid;not null;uniqueIndex:idx_withdrawal_address_entry,priority:1" json:"user_id"`
+	AssetSymbol           string                  `gorm:"size:10;not null;uniqueIndex:idx_withdrawal_address_entry,priority:2" json:"asset_symbol"`
+	Network               string                  `gorm:"size:32;not null;uniqueIndex:idx_withdrawal_address_entry,priority:3" json:"network"`
+	Address               string                  `gorm:"size:255;not null;uniqueIndex:idx_withdrawal_address_entry,priority:4" json:"address"`
+	Label                 string                  `gorm:"size:100" json:"label,omitempty"`
+	Status                WithdrawalAddressStatus `gorm:"type:varchar(32);not null;default:'pending_confirmation'" json:"status"`
+	ConfirmationHash      *string                 `gorm:"size:64;index" json:"-"`
+	ConfirmationExpiresAt *time.Time              `json:"-"`
+	ConfirmedAt           *time.Time              `json:"confirmed_at,omitempty"`
+	UsableFrom            *time.Time              `json:"usable_from,omitempty"`
+	RevokedAt             *time.Time              `json:"revoked_at,omitempty"`
+	CreatedAt             time.Time               `json:"created_at"`
+	UpdatedAt             time.Time               `json:"updated_at"`
+}
+
+// Usable reports whether the address may receive funds at now.
+func (a *WithdrawalAddress) Usable(now time.Time) bool {
+	return a.Status == AddressConfirmed && a.UsableFrom != nil && !now.Before(*a.UsableFrom)
+}
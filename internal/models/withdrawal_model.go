@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WithdrawalStatus string
+
+const (
+	// WithdrawalPending is queued and has not been signed yet.
+	WithdrawalPending WithdrawalStatus = "pending"
+	// WithdrawalBroadcast has at least one signed transaction in flight.
+	WithdrawalBroadcast WithdrawalStatus = "broadcast"
+	// WithdrawalConfirmed reached the network's confirmation depth.
+	WithdrawalConfirmed WithdrawalStatus = "confirmed"
+	// WithdrawalFailed reverted, could not be funded or was abandoned.
+	WithdrawalFailed WithdrawalStatus = "failed"
+	// WithdrawalReview had its nonce spent without a receipt for any of its
+	// transactions being found. It may have paid out, so the collateral
+	// stays releasing until an operator has checked the chain.
+	WithdrawalReview WithdrawalStatus = "review"
+)
+
+// Withdrawal returns released collateral to its owner on-chain. Every signed
+// transaction, including fee bumps that replace an earlier one at the same
+// nonce, is kept as a WithdrawalTx so whichever one is mined is recognised.
+type Withdrawal struct {
+	ID            uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	CollateralID  uuid.UUID        `gorm:"type:uuid;not null;index" json:"collateral_id"`
+	UserID        uuid.UUID        `gorm:"type:uuid;not null;index" json:"user_id"`
+	Network       string           `gorm:"size:32;not null" json:"network"`
+	AssetSymbol   string           `gorm:"size:10;not null" json:"asset_symbol"`
+	Amount        float64          `gorm:"not null" json:"amount"`
+	NetAmount     float64          `json:"net_amount"`
+	FeeAmount     float64          `json:"fee_amount"`
+	FromAddress   string           `gorm:"size:255" json:"from_address,omitempty"`
+	ToAddress     string           `gorm:"size:255;not null" json:"to_address"`
+	Status        WithdrawalStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Nonce         *uint64          `json:"nonce,omitempty"`
+	TxHash        *string          `gorm:"size:100" json:"tx_hash,omitempty"`
+	Attempts      int              `gorm:"not null;default:0" json:"attempts"`
+	FailureReason *string          `json:"failure_reason,omitempty"`
+	BroadcastAt   *time.Time       `json:"broadcast_at,omitempty"`
+	ConfirmedAt   *time.Time       `json:"confirmed_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+// WithdrawalTx is one signed transaction for a withdrawal. RawTx is kept so
+// the transaction can be re-broadcast if a node drops it.
+type WithdrawalTx struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	WithdrawalID uuid.UUID `gorm:"type:uuid;not null;index" json:"withdrawal_id"`
+	TxHash       string    `gorm:"size:100;not null;uniqueIndex" json:"tx_hash"`
+	Nonce        uint64    `json:"nonce"`
+	GasTipCap    string    `gorm:"size:80" json:"gas_tip_cap"`
+	GasFeeCap    string    `gorm:"size:80" json:"gas_fee_cap"`
+	RawTx        []byte    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,106 @@
+package pricefeed
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/utils"
+)
+
+type Handler struct {
+	hub       *Hub
+	heartbeat time.Duration
+	logger    zerolog.Logger
+}
+
+func NewHandler(hub *Hub, heartbeat time.Duration, logger zerolog.Logger) *Handler {
+	if heartbeat <= 0 {
+		heartbeat = 20 * time.Second
+	}
+	return &Handler{
+		hub:       hub,
+		heartbeat: heartbeat,
+		logger:    logger.With().Str("component", "price_stream_handler").Logger(),
+	}
+}
+
+// StreamPrices streams price snapshots as server-sent events.
+// An optional `fiat` query parameter restricts the stream to one currency.
+func (h *Handler) StreamPrices(c *gin.Context) {
+	userID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	sub, err := h.hub.Subscribe(userID)
+	if err != nil {
+		RejectSubscription(c, err)
+		return
+	}
+	defer sub.Close()
+
+	fiat := strings.ToUpper(strings.TrimSpace(c.Query("fiat")))
+
+	OpenStream(c)
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if !Heartbeat(c) {
+				return
+			}
+		case update, open := <-sub.Updates():
+			if !open {
+				return
+			}
+			if fiat != "" && update.Currency != fiat {
+				continue
+			}
+			c.SSEvent("price", update)
+			c.Writer.Flush()
+		}
+	}
+}
+
+// RejectSubscription maps hub subscription errors to HTTP responses.
+func RejectSubscription(c *gin.Context, err error) {
+	if errors.Is(err, ErrTooManyConnections) || errors.Is(err, ErrTooManyUserConnections) {
+		c.Header("Retry-After", "30")
+		utils.Error(c, http.StatusTooManyRequests, err.Error(), nil)
+		return
+	}
+	utils.InternalServerError(c, "failed to open stream", err.Error())
+}
+
+// OpenStream writes the event-stream headers and lifts the server write
+// deadline so the connection can outlive http.Server.WriteTimeout.
+func OpenStream(c *gin.Context) {
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+}
+
+// Heartbeat writes an SSE comment to keep intermediaries from closing an
+// idle connection. It reports false once the client has gone away.
+func Heartbeat(c *gin.Context) bool {
+	if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
@@ -0,0 +1,237 @@
+package pricefeed
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/pricing"
+)
+
+var (
+	// ErrTooManyConnections is returned when the global stream limit is reached.
+	ErrTooManyConnections = errors.New("too many open price streams")
+	// ErrTooManyUserConnections is returned when a user already holds the maximum number of streams.
+	ErrTooManyUserConnections = errors.New("too many open price streams for this user")
+)
+
+// Update is a single price snapshot for one fiat currency.
+type Update struct {
+	Currency  string             `json:"currency"`
+	Prices    map[string]float64 `json:"prices"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// Options configures a Hub.
+type Options struct {
+	Symbols          []string
+	Currencies       []string
+	PollInterval     time.Duration
+	MaxConnections   int
+	MaxPerUser       int
+	SubscriberBuffer int
+}
+
+// Subscription receives updates from the hub until Close is called.
+type Subscription struct {
+	id      uuid.UUID
+	userID  uuid.UUID
+	updates chan Update
+	hub     *Hub
+	once    sync.Once
+}
+
+// Updates returns the channel on which price snapshots are delivered.
+func (s *Subscription) Updates() <-chan Update {
+	return s.updates
+}
+
+// Close detaches the subscription from the hub. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.unsubscribe(s)
+	})
+}
+
+// Hub polls the pricing provider from a single goroutine and fans the
+// results out to every subscriber, so upstream calls do not grow with the
+// number of connected clients.
+type Hub struct {
+	provider pricing.Provider
+	opts     Options
+	logger   zerolog.Logger
+
+	mu      sync.RWMutex
+	subs    map[uuid.UUID]*Subscription
+	perUser map[uuid.UUID]int
+	latest  map[string]Update
+}
+
+// NewHub creates a hub. Call Run to start polling.
+func NewHub(provider pricing.Provider, opts Options, logger zerolog.Logger) *Hub {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 15 * time.Second
+	}
+	if opts.SubscriberBuffer <= 0 {
+		opts.SubscriberBuffer = 4
+	}
+	if len(opts.Currencies) == 0 {
+		opts.Currencies = []string{"USD"}
+	}
+	for i, currency := range opts.Currencies {
+		opts.Currencies[i] = strings.ToUpper(strings.TrimSpace(currency))
+	}
+
+	return &Hub{
+		provider: provider,
+		opts:     opts,
+		logger:   logger.With().Str("component", "price_hub").Logger(),
+		subs:     make(map[uuid.UUID]*Subscription),
+		perUser:  make(map[uuid.UUID]int),
+		latest:   make(map[string]Update),
+	}
+}
+
+// Subscribe registers a new stream for the given user. The most recent
+// snapshot for every currency is delivered immediately when available.
+func (h *Hub) Subscribe(userID uuid.UUID) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.opts.MaxConnections > 0 && len(h.subs) >= h.opts.MaxConnections {
+		return nil, ErrTooManyConnections
+	}
+	if h.opts.MaxPerUser > 0 && h.perUser[userID] >= h.opts.MaxPerUser {
+		return nil, ErrTooManyUserConnections
+	}
+
+	sub := &Subscription{
+		id:      uuid.New(),
+		userID:  userID,
+		updates: make(chan Update, h.opts.SubscriberBuffer),
+		hub:     h,
+	}
+	h.subs[sub.id] = sub
+	h.perUser[userID]++
+
+	for _, update := range h.latest {
+		deliver(sub, update)
+	}
+
+	return sub, nil
+}
+
+// Latest returns the last snapshot fetched for a currency.
+func (h *Hub) Latest(currency string) (Update, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	update, ok := h.latest[strings.ToUpper(currency)]
+	return update, ok
+}
+
+// Subscribers reports the number of open subscriptions.
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// Run polls the provider until ctx is cancelled. Polling is skipped while
+// nobody is subscribed.
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.opts.PollInterval)
+	defer ticker.Stop()
+
+	h.logger.Info().Dur("interval", h.opts.PollInterval).Msg("Price hub started")
+
+	for {
+		if h.Subscribers() > 0 {
+			h.poll(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			h.closeAll()
+			h.logger.Info().Msg("Price hub stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Hub) poll(ctx context.Context) {
+	for _, currency := range h.opts.Currencies {
+		if ctx.Err() != nil {
+			return
+		}
+
+		prices, err := h.provider.GetPrices(ctx, h.opts.Symbols, currency)
+		if err != nil {
+			h.logger.Warn().Err(err).Str("currency", currency).Msg("Failed to poll prices")
+			continue
+		}
+
+		h.publish(Update{
+			Currency:  currency,
+			Prices:    prices,
+			Timestamp: time.Now().UTC(),
+		})
+	}
+}
+
+func (h *Hub) publish(update Update) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.latest[update.Currency] = update
+	for _, sub := range h.subs {
+		deliver(sub, update)
+	}
+}
+
+// deliver never blocks the poller. When a subscriber's buffer is full the
+// oldest pending snapshot is dropped, since only the newest price matters.
+func deliver(sub *Subscription, update Update) {
+	for {
+		select {
+		case sub.updates <- update:
+			return
+		default:
+		}
+
+		select {
+		case <-sub.updates:
+		default:
+		}
+	}
+}
+
+func (h *Hub) unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[sub.id]; !ok {
+		return
+	}
+	delete(h.subs, sub.id)
+	h.perUser[sub.userID]--
+	if h.perUser[sub.userID] <= 0 {
+		delete(h.perUser, sub.userID)
+	}
+	close(sub.updates)
+}
+
+func (h *Hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, sub := range h.subs {
+		delete(h.subs, id)
+		close(sub.updates)
+	}
+	h.perUser = make(map[uuid.UUID]int)
+}
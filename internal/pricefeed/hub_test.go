@@ -0,0 +1,103 @@
+package pricefeed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeEnforcesLimits(t *testing.T) {
+	hub := NewHub(&fakePricing{}, Options{MaxConnections: 2, MaxPerUser: 1}, zerolog.Nop())
+
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+
+	sub, err := hub.Subscribe(alice)
+	require.NoError(t, err)
+
+	_, err = hub.Subscribe(alice)
+	require.ErrorIs(t, err, ErrTooManyUserConnections)
+
+	_, err = hub.Subscribe(bob)
+	require.NoError(t, err)
+
+	_, err = hub.Subscribe(carol)
+	require.ErrorIs(t, err, ErrTooManyConnections)
+
+	sub.Close()
+	sub.Close()
+	_, err = hub.Subscribe(carol)
+	require.NoError(t, err)
+}
+
+func TestPublishDropsOldestForSlowSubscribers(t *testing.T) {
+	hub := NewHub(&fakePricing{}, Options{SubscriberBuffer: 2}, zerolog.Nop())
+
+	sub, err := hub.Subscribe(uuid.New())
+	require.NoError(t, err)
+	defer sub.Close()
+
+	for i := 1; i <= 5; i++ {
+		hub.publish(Update{Currency: "USD", Prices: map[string]float64{"BTC": float64(i)}})
+	}
+
+	first := <-sub.Updates()
+	second := <-sub.Updates()
+	require.Equal(t, 4.0, first.Prices["BTC"])
+	require.Equal(t, 5.0, second.Prices["BTC"])
+}
+
+func TestRunPollsOnceForAllSubscribers(t *testing.T) {
+	provider := &fakePricing{}
+	hub := NewHub(provider, Options{
+		Symbols:      []string{"BTC"},
+		Currencies:   []string{"usd"},
+		PollInterval: time.Hour,
+	}, zerolog.Nop())
+
+	subs := make([]*Subscription, 3)
+	for i := range subs {
+		sub, err := hub.Subscribe(uuid.New())
+		require.NoError(t, err)
+		subs[i] = sub
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+
+	for _, sub := range subs {
+		update := <-sub.Updates()
+		require.Equal(t, "USD", update.Currency)
+		require.Equal(t, 20000.0, update.Prices["BTC"])
+	}
+	require.Equal(t, 1, provider.calls)
+
+	cancel()
+	<-done
+	_, open := <-subs[0].Updates()
+	require.False(t, open)
+}
+
+type fakePricing struct {
+	calls int
+}
+
+func (f *fakePricing) GetPrice(ctx context.Context, symbol, currency string) (float64, error) {
+	return 20000, nil
+}
+
+func (f *fakePricing) GetPrices(ctx context.Context, symbols []string, currency string) (map[string]float64, error) {
+	f.calls++
+	result := make(map[string]float64)
+	for _, symbol := range symbols {
+		result[symbol] = 20000
+	}
+	return result, nil
+}
@@ -1,57 +1,110 @@
 package pricing
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/config"
+	e "github.com/thoraf20/loanee/pkg/error"
 )
 
+const defaultCoinGeckoBaseURL = "https://api.coingecko.com/api/v3"
+
+// maxLoggedBody caps how much of an upstream error body ends up in logs.
+const maxLoggedBody = 256
+
 type CoinGeckoProvider struct {
-	apiKey  string
-	baseURL string
-	client  *http.Client
-	logger  zerolog.Logger
+	apiKey         string
+	baseURL        string
+	client         *http.Client
+	maxRetries     int
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	breaker        *circuitBreaker
+	logger         zerolog.Logger
+
+	// throttledUntil honours the Retry-After of a rate limit that outlasted
+	// the retries; calls before it are refused without a request.
+	mu             sync.Mutex
+	throttledUntil time.Time
 }
 
 // Coin symbol to CoinGecko ID mapping
 var coinIDMap = map[string]string{
-	"BTC":  "bitcoin",
-	"ETH":  "ethereum",
-	"BNB":  "binancecoin",
-	"USDT": "tether",
-	"USDC": "usd-coin",
-	"XRP":  "ripple",
-	"ADA":  "cardano",
-	"DOGE": "dogecoin",
-	"SOL":  "solana",
-	"TRX":  "tron",
+	"BTC":   "bitcoin",
+	"ETH":   "ethereum",
+	"BNB":   "binancecoin",
+	"USDT":  "tether",
+	"USDC":  "usd-coin",
+	"XRP":   "ripple",
+	"ADA":   "cardano",
+	"DOGE":  "dogecoin",
+	"SOL":   "solana",
+	"TRX":   "tron",
 	"MATIC": "matic-network",
-	"DOT":  "polkadot",
-	"AVAX": "avalanche-2",
-	"LINK": "chainlink",
+	"DOT":   "polkadot",
+	"AVAX":  "avalanche-2",
+	"LINK":  "chainlink",
 }
 
 type CoinGeckoPriceResponse map[string]map[string]float64
 
-func NewCoinGeckoProvider(apiKey string, logger zerolog.Logger) Provider {
+// statusError is returned for non-200 upstream responses.
+type statusError struct {
+	status     int
+	retryAfter time.Duration
+}
+
+func (s *statusError) Error() string {
+	return fmt.Sprintf("coingecko returned status %d", s.status)
+}
+
+func (s *statusError) rateLimited() bool {
+	return s.status == http.StatusTooManyRequests
+}
+
+func (s *statusError) retryable() bool {
+	return s.status == http.StatusTooManyRequests || s.status >= http.StatusInternalServerError
+}
+
+func NewCoinGeckoProvider(cfg config.CoinGeckoConfig, logger zerolog.Logger) Provider {
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultCoinGeckoBaseURL
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
 	return &CoinGeckoProvider{
-		apiKey:  apiKey,
-		baseURL: "https://api.coingecko.com/api/v3",
+		apiKey:  cfg.APIKey,
+		baseURL: baseURL,
 		client: &http.Client{
-				Timeout: 10 * time.Second,
+			Timeout: timeout,
 		},
-		logger: logger,
+		maxRetries:     cfg.MaxRetries,
+		retryBaseDelay: cfg.RetryBaseDelay,
+		retryMaxDelay:  cfg.RetryMaxDelay,
+		breaker:        newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		logger:         logger.With().Str("component", "coingecko").Logger(),
 	}
 }
 
-func (p *CoinGeckoProvider) GetPrice(symbol, currency string) (float64, error) {
-	prices, err := p.GetPrices([]string{symbol}, currency)
+func (p *CoinGeckoProvider) GetPrice(ctx context.Context, symbol, currency string) (float64, error) {
+	prices, err := p.GetPrices(ctx, []string{symbol}, currency)
 	if err != nil {
 		return 0, err
 	}
@@ -64,7 +117,7 @@ func (p *CoinGeckoProvider) GetPrice(symbol, currency string) (float64, error) {
 	return price, nil
 }
 
-func (p *CoinGeckoProvider) GetPrices(symbols []string, currency string) (map[string]float64, error) {
+func (p *CoinGeckoProvider) GetPrices(ctx context.Context, symbols []string, currency string) (map[string]float64, error) {
 	if len(symbols) == 0 {
 		return nil, fmt.Errorf("no symbols provided")
 	}
@@ -76,10 +129,10 @@ func (p *CoinGeckoProvider) GetPrices(symbols []string, currency string) (map[st
 	for _, symbol := range symbols {
 		symbol = strings.ToUpper(strings.TrimSpace(symbol))
 		if coinID, ok := coinIDMap[symbol]; ok {
-				coinIDs = append(coinIDs, coinID)
-				symbolToID[coinID] = symbol
+			coinIDs = append(coinIDs, coinID)
+			symbolToID[coinID] = symbol
 		} else {
-				p.logger.Warn().Str("symbol", symbol).Msg("Unknown cryptocurrency symbol")
+			p.logger.Warn().Str("symbol", symbol).Msg("Unknown cryptocurrency symbol")
 		}
 	}
 
@@ -94,9 +147,8 @@ func (p *CoinGeckoProvider) GetPrices(symbols []string, currency string) (map[st
 	}
 
 	// Build URL
-	ids := strings.Join(coinIDs, ",")
 	params := url.Values{}
-	params.Add("ids", ids)
+	params.Add("ids", strings.Join(coinIDs, ","))
 	params.Add("vs_currencies", currency)
 
 	fullURL := fmt.Sprintf("%s/simple/price?%s", p.baseURL, params.Encode())
@@ -107,10 +159,134 @@ func (p *CoinGeckoProvider) GetPrices(symbols []string, currency string) (map[st
 		Str("currency", currency).
 		Msg("Fetching prices from CoinGecko")
 
+	body, err := p.fetchWithRetry(ctx, fullURL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse response
+	var cgResponse CoinGeckoPriceResponse
+	if err := json.Unmarshal(body, &cgResponse); err != nil {
+		p.logger.Error().
+			Err(err).
+			Str("body", truncate(body)).
+			Msg("Failed to parse CoinGecko response")
+		return nil, fmt.Errorf("%w: invalid response payload", e.ErrPriceFetchFailed)
+	}
+
+	// Convert to symbol-based map
+	prices := make(map[string]float64)
+	for coinID, priceData := range cgResponse {
+		if symbol, ok := symbolToID[coinID]; ok {
+			if price, ok := priceData[currency]; ok {
+				prices[symbol] = price
+			}
+		}
+	}
+
+	p.logger.Info().
+		Int("count", len(prices)).
+		Interface("prices", prices).
+		Msg("Successfully fetched prices")
+
+	return prices, nil
+}
+
+// fetchWithRetry performs the request behind the circuit breaker, retrying
+// rate limits, server errors and transport failures with jittered backoff.
+// Rate limits mean the upstream is up, so they never count towards opening
+// the breaker; one that outlasts the retries is waited out instead.
+func (p *CoinGeckoProvider) fetchWithRetry(ctx context.Context, fullURL string) ([]byte, error) {
+	if wait := p.throttled(); wait > 0 {
+		return nil, fmt.Errorf("%w: rate limited for another %s", e.ErrPricingServiceUnavailable, wait.Round(time.Second))
+	}
+	if !p.breaker.Allow() {
+		return nil, e.ErrPricingServiceUnavailable
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+		body, err := p.fetch(ctx, fullURL)
+		if err == nil {
+			p.breaker.Success()
+			return body, nil
+		}
+		lastErr = err
+
+		if ctx.Err() != nil {
+			p.breaker.Abandon()
+			return nil, ctx.Err()
+		}
+
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && !statusErr.retryable() {
+			// The upstream answered; the request itself is at fault.
+			p.breaker.Success()
+			return nil, fmt.Errorf("%w: %w", e.ErrPriceFetchFailed, err)
+		}
+
+		if attempt == p.maxRetries {
+			break
+		}
+
+		wait := p.backoff(attempt)
+		if statusErr != nil && statusErr.retryAfter > 0 {
+			if statusErr.retryAfter > p.retryMaxDelay {
+				// Waiting that long would stall the caller; give up now.
+				break
+			}
+			wait = statusErr.retryAfter
+		}
+
+		p.logger.Warn().
+			Err(err).
+			Int("attempt", attempt+1).
+			Dur("wait", wait).
+			Msg("CoinGecko request failed, retrying")
+
+		if err := sleep(ctx, wait); err != nil {
+			p.breaker.Abandon()
+			return nil, err
+		}
+	}
+
+	var statusErr *statusError
+	if errors.As(lastErr, &statusErr) && statusErr.rateLimited() {
+		p.breaker.Abandon()
+		p.throttle(statusErr.retryAfter)
+		return nil, fmt.Errorf("%w: %w", e.ErrPriceFetchFailed, lastErr)
+	}
+
+	if p.breaker.Failure() {
+		p.logger.Error().Err(lastErr).Msg("CoinGecko circuit breaker opened")
+	}
+	return nil, fmt.Errorf("%w: %w", e.ErrPriceFetchFailed, lastErr)
+}
+
+// throttled returns how long calls are still held back by a rate limit.
+func (p *CoinGeckoProvider) throttled() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Until(p.throttledUntil)
+}
+
+func (p *CoinGeckoProvider) throttle(retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if until := time.Now().Add(retryAfter); until.After(p.throttledUntil) {
+		p.throttledUntil = until
+	}
+	p.logger.Warn().Dur("retry_after", retryAfter).Msg("CoinGecko rate limit outlasted retries, pausing requests")
+}
+
+func (p *CoinGeckoProvider) fetch(ctx context.Context, fullURL string) ([]byte, error) {
 	// Create request
-	req, err := http.NewRequest("GET", fullURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
 	if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add API key if available
@@ -133,37 +309,73 @@ func (p *CoinGeckoProvider) GetPrices(symbols []string, currency string) (map[st
 
 	// Handle non-200 responses
 	if resp.StatusCode != http.StatusOK {
-		p.logger.Error().
+		p.logger.Warn().
 			Int("status", resp.StatusCode).
-			Str("body", string(body)).
+			Str("body", truncate(body)).
 			Msg("CoinGecko API error")
-		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(body))
+		return nil, &statusError{
+			status:     resp.StatusCode,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
 	}
 
-	// Parse response
-	var cgResponse CoinGeckoPriceResponse
-	if err := json.Unmarshal(body, &cgResponse); err != nil {
-		p.logger.Error().
-			Err(err).
-			Str("body", string(body)).
-			Msg("Failed to parse CoinGecko response")
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	return body, nil
+}
+
+// backoff returns an exponentially growing delay with equal jitter.
+func (p *CoinGeckoProvider) backoff(attempt int) time.Duration {
+	base := p.retryBaseDelay
+	if base <= 0 {
+		base = 250 * time.Millisecond
+	}
+	max := p.retryMaxDelay
+	if max <= 0 {
+		max = 5 * time.Second
 	}
 
-	// Convert to symbol-based map
-	prices := make(map[string]float64)
-	for coinID, priceData := range cgResponse {
-		if symbol, ok := symbolToID[coinID]; ok {
-			if price, ok := priceData[currency]; ok {
-				prices[symbol] = price
-			}
+	delay := base << attempt
+	if delay <= 0 || delay > max {
+		delay = max
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// parseRetryAfter accepts both the delay-seconds and HTTP-date forms.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
 		}
 	}
+	return 0
+}
 
-	p.logger.Info().
-		Int("count", len(prices)).
-		Interface("prices", prices).
-		Msg("Successfully fetched prices")
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
 
-  return prices, nil
-}
\ No newline at end of file
+func truncate(body []byte) string {
+	if len(body) > maxLoggedBody {
+		return string(body[:maxLoggedBody]) + "..."
+	}
+	return string(body)
+}
@@ -0,0 +1,152 @@
+package pricing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/config"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+func TestGetPricesRetriesAfterRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/simple/price", r.URL.Path)
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"status":{"error_code":429}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"bitcoin":{"usd":65000}}`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL, 2, 5)
+
+	price, err := provider.GetPrice(context.Background(), "btc", "USD")
+	require.NoError(t, err)
+	require.Equal(t, 65000.0, price)
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestGetPricesDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`secret upstream detail`))
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL, 3, 5)
+
+	_, err := provider.GetPrices(context.Background(), []string{"BTC"}, "usd")
+	require.ErrorIs(t, err, e.ErrPriceFetchFailed)
+	require.NotContains(t, err.Error(), "secret upstream detail")
+	require.EqualValues(t, 1, calls.Load())
+}
+
+func TestCircuitBreakerShortCircuits(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL, 0, 2)
+
+	for i := 0; i < 2; i++ {
+		_, err := provider.GetPrices(context.Background(), []string{"ETH"}, "usd")
+		require.ErrorIs(t, err, e.ErrPriceFetchFailed)
+	}
+
+	_, err := provider.GetPrices(context.Background(), []string{"ETH"}, "usd")
+	require.ErrorIs(t, err, e.ErrPricingServiceUnavailable)
+	require.EqualValues(t, 2, calls.Load())
+}
+
+func TestRateLimitsDoNotOpenTheBreaker(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL, 0, 2)
+
+	for i := 0; i < 3; i++ {
+		_, err := provider.GetPrices(context.Background(), []string{"ETH"}, "usd")
+		require.ErrorIs(t, err, e.ErrPriceFetchFailed)
+	}
+	require.EqualValues(t, 3, calls.Load())
+	require.Equal(t, breakerClosed, provider.(*CoinGeckoProvider).breaker.State())
+}
+
+func TestLongRetryAfterPausesRequests(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	provider := newTestProvider(server.URL, 2, 1)
+
+	_, err := provider.GetPrices(context.Background(), []string{"ETH"}, "usd")
+	require.ErrorIs(t, err, e.ErrPriceFetchFailed)
+
+	// The Retry-After is honoured without a request or a breaker failure.
+	_, err = provider.GetPrices(context.Background(), []string{"ETH"}, "usd")
+	require.ErrorIs(t, err, e.ErrPricingServiceUnavailable)
+	require.EqualValues(t, 1, calls.Load())
+	require.Equal(t, breakerClosed, provider.(*CoinGeckoProvider).breaker.State())
+}
+
+func TestCircuitBreakerHalfOpenTrial(t *testing.T) {
+	now := time.Now()
+	breaker := newCircuitBreaker(1, time.Minute)
+	breaker.now = func() time.Time { return now }
+
+	require.True(t, breaker.Allow())
+	require.True(t, breaker.Failure())
+	require.False(t, breaker.Allow())
+
+	now = now.Add(time.Minute)
+	require.True(t, breaker.Allow())
+	require.False(t, breaker.Allow())
+
+	breaker.Success()
+	require.Equal(t, breakerClosed, breaker.State())
+	require.True(t, breaker.Allow())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	require.Equal(t, 3*time.Second, parseRetryAfter("3"))
+	require.Zero(t, parseRetryAfter(""))
+	require.Zero(t, parseRetryAfter("garbage"))
+
+	future := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	wait := parseRetryAfter(future)
+	require.Greater(t, wait, 5*time.Second)
+}
+
+func newTestProvider(baseURL string, retries, threshold int) Provider {
+	return NewCoinGeckoProvider(config.CoinGeckoConfig{
+		BaseURL:          baseURL,
+		MaxRetries:       retries,
+		RetryBaseDelay:   time.Millisecond,
+		RetryMaxDelay:    10 * time.Millisecond,
+		BreakerThreshold: threshold,
+		BreakerCooldown:  time.Minute,
+	}, zerolog.Nop())
+}
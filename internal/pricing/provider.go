@@ -1,6 +1,8 @@
 package pricing
 
+import "context"
+
 type Provider interface {
-	GetPrice(symbol, currency string) (float64, error)
-	GetPrices(symbols []string, currency string) (map[string]float64, error)
-}
\ No newline at end of file
+	GetPrice(ctx context.Context, symbol, currency string) (float64, error)
+	GetPrices(ctx context.Context, symbols []string, currency string) (map[string]float64, error)
+}
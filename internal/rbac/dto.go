@@ -0,0 +1,13 @@
+package rbac
+
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required"`
+	// Reason is kept in the audit trail.
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type RoleResponse struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
@@ -0,0 +1,97 @@
+package rbac
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/utils"
+	e "github.com/thoraf20/loanee/pkg/error"
+	"github.com/thoraf20/loanee/pkg/validator"
+)
+
+type Handler struct {
+	service   *Service
+	validator *validator.Validator
+	logger    zerolog.Logger
+}
+
+func NewHandler(service *Service, validator *validator.Validator, logger zerolog.Logger) *Handler {
+	return &Handler{
+		service:   service,
+		validator: validator,
+		logger:    logger.With().Str("component", "rbac_handler").Logger(),
+	}
+}
+
+// AdminListRoles returns every role with the permissions it grants.
+func (h *Handler) AdminListRoles(c *gin.Context) {
+	roles, err := h.service.ListRoles(c.Request.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list roles")
+		utils.InternalServerError(c, "failed to fetch roles", err.Error())
+		return
+	}
+
+	utils.OK(c, "roles retrieved", roles)
+}
+
+// AdminAssignRole changes a user's role.
+func (h *Handler) AdminAssignRole(c *gin.Context) {
+	actorID, ok := utils.UserIDFromGin(c)
+	if !ok {
+		utils.Unauthorized(c, "authentication required")
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid user id", err.Error())
+		return
+	}
+
+	var payload AssignRoleRequest
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		utils.BadRequest(c, "invalid payload", err.Error())
+		return
+	}
+	if err := h.validator.Validate(&payload); err != nil {
+		utils.BadRequest(c, "validation failed", err.Error())
+		return
+	}
+
+	assignment, err := h.service.AssignRole(c.Request.Context(), actorID, userID, payload, c.ClientIP())
+	if err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Any("actor_id", actorID).Msg("failed to assign role")
+		respondError(c, "failed to assign role", err)
+		return
+	}
+
+	utils.OK(c, "role assigned", assignment)
+}
+
+// AdminListAssignments returns the audit trail of a user's role changes,
+// newest first.
+func (h *Handler) AdminListAssignments(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid user id", err.Error())
+		return
+	}
+
+	assignments, err := h.service.ListAssignments(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error().Err(err).Any("user_id", userID).Msg("failed to list role assignments")
+		utils.InternalServerError(c, "failed to fetch role assignments", err.Error())
+		return
+	}
+
+	utils.OK(c, "role assignments retrieved", assignments)
+}
+
+func respondError(c *gin.Context, message string, err error) {
+	if appErr := e.GetAppError(err); appErr != nil {
+		utils.Error(c, appErr.StatusCode, message, err.Error())
+		return
+	}
+	utils.InternalServerError(c, message, err.Error())
+}
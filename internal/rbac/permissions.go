@@ -0,0 +1,130 @@
+package rbac
+
+import "github.com/thoraf20/loanee/internal/models"
+
+// Permissions checked by admin endpoints.
+const (
+	PermLoansRead          = "loans:read"
+	PermLoansApprove       = "loans:approve"
+	PermLoansDisburse      = "loans:disburse"
+	PermCollateralsRead    = "collaterals:read"
+	PermCollateralsRelease = "collaterals:release"
+	PermLiquidations       = "liquidations:execute"
+	PermCustodyRead        = "custody:read"
+	PermCustodyManage      = "custody:manage"
+	PermWithdrawalsRead    = "withdrawals:read"
+	PermWithdrawalsManage  = "withdrawals:manage"
+	PermStablecoinsRead    = "stablecoins:read"
+	PermReconciliationRead = "reconciliation:read"
+	PermReconciliationRun  = "reconciliation:run"
+	PermApprovalsRead      = "approvals:read"
+	PermRolesRead          = "roles:read"
+	PermRolesAssign        = "roles:assign"
+	PermAPIKeysManage      = "api_keys:manage"
+)
+
+// Permissions over a user's own account. Every role grants them; they exist
+// so API keys can be scoped to part of what their owner may do.
+const (
+	PermOwnLoansRead        = "own:loans:read"
+	PermOwnRepaymentsCreate = "own:repayments:create"
+)
+
+// Built-in roles. RoleAdmin predates granular permissions and keeps every
+// permission, so existing admin accounts are unaffected.
+const (
+	RoleUser          = "user"
+	RoleSupport       = "support"
+	RoleCreditOfficer = "credit_officer"
+	RoleTreasury      = "treasury"
+	RoleRisk          = "risk"
+	RoleSuperAdmin    = "super_admin"
+	RoleAdmin         = "admin"
+)
+
+var defaultPermissions = []models.Permission{
+	{Name: PermLoansRead, Description: "View loans"},
+	{Name: PermLoansApprove, Description: "Approve or reject loan applications"},
+	{Name: PermLoansDisburse, Description: "Disburse approved loans"},
+	{Name: PermCollateralsRead, Description: "View collateral"},
+	{Name: PermCollateralsRelease, Description: "Approve or reject collateral releases"},
+	{Name: PermLiquidations, Description: "Liquidate under-collateralized loans"},
+	{Name: PermCustodyRead, Description: "View hot wallets"},
+	{Name: PermCustodyManage, Description: "Create hot wallets and rotate their keys"},
+	{Name: PermWithdrawalsRead, Description: "View on-chain withdrawals"},
+	{Name: PermWithdrawalsManage, Description: "Fee-bump stuck withdrawals"},
+	{Name: PermStablecoinsRead, Description: "View stablecoin peg status"},
+	{Name: PermReconciliationRead, Description: "View custody reconciliation reports"},
+	{Name: PermReconciliationRun, Description: "Run custody reconciliation"},
+	{Name: PermApprovalsRead, Description: "View actions awaiting a second administrator"},
+	{Name: PermRolesRead, Description: "View roles and role assignment history"},
+	{Name: PermRolesAssign, Description: "Change users' roles"},
+	{Name: PermAPIKeysManage, Description: "View and revoke any user's API keys"},
+	{Name: PermOwnLoansRead, Description: "View your own loans and repayments"},
+	{Name: PermOwnRepaymentsCreate, Description: "Repay your own loans"},
+}
+
+// borrowerPermissions are granted to every role.
+var borrowerPermissions = []string{PermOwnLoansRead, PermOwnRepaymentsCreate}
+
+type roleGrant struct {
+	role        models.Role
+	permissions []string
+}
+
+// defaultRoles are seeded at startup. Grants are only ever added, so
+// permissions granted directly in the database survive restarts.
+var defaultRoles = []roleGrant{
+	{
+		role:        models.Role{Name: RoleUser, Description: "Borrower, no admin access"},
+		permissions: borrowerPermissions,
+	},
+	{
+		role: models.Role{Name: RoleSupport, Description: "Read-only access for customer support"},
+		permissions: []string{
+			PermLoansRead, PermCollateralsRead, PermCustodyRead, PermWithdrawalsRead,
+			PermStablecoinsRead, PermReconciliationRead, PermApprovalsRead,
+			PermOwnLoansRead, PermOwnRepaymentsCreate,
+		},
+	},
+	{
+		role: models.Role{Name: RoleCreditOfficer, Description: "Approves and rejects loans"},
+		permissions: []string{
+			PermLoansRead, PermLoansApprove, PermCollateralsRead, PermApprovalsRead,
+			PermOwnLoansRead, PermOwnRepaymentsCreate,
+		},
+	},
+	{
+		role: models.Role{Name: RoleTreasury, Description: "Moves funds: disbursements, releases and hot wallets"},
+		permissions: []string{
+			PermLoansRead, PermLoansDisburse, PermCollateralsRead, PermCollateralsRelease,
+			PermCustodyRead, PermCustodyManage, PermWithdrawalsRead, PermWithdrawalsManage,
+			PermStablecoinsRead, PermReconciliationRead, PermReconciliationRun, PermApprovalsRead,
+			PermOwnLoansRead, PermOwnRepaymentsCreate,
+		},
+	},
+	{
+		role: models.Role{Name: RoleRisk, Description: "Monitors exposure and runs liquidations"},
+		permissions: []string{
+			PermLoansRead, PermCollateralsRead, PermLiquidations, PermWithdrawalsRead,
+			PermStablecoinsRead, PermReconciliationRead,
+			PermOwnLoansRead, PermOwnRepaymentsCreate,
+		},
+	},
+	{
+		role:        models.Role{Name: RoleSuperAdmin, Description: "Every permission, including role assignment"},
+		permissions: allPermissions(),
+	},
+	{
+		role:        models.Role{Name: RoleAdmin, Description: "Legacy administrator, equivalent to super_admin"},
+		permissions: allPermissions(),
+	},
+}
+
+func allPermissions() []string {
+	names := make([]string, 0, len(defaultPermissions))
+	for _, p := range defaultPermissions {
+		names = append(names, p.Name)
+	}
+	return names
+}
@@ -0,0 +1,140 @@
+package rbac
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/internal/user"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+type Repository interface {
+	// Seed adds the permissions, roles and grants that are missing,
+	// leaving existing rows alone.
+	Seed(ctx context.Context, permissions []models.Permission, roles []models.Role, grants []models.RolePermission) error
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	// GetRole returns nil when the role does not exist.
+	GetRole(ctx context.Context, name string) (*models.Role, error)
+	ListGrants(ctx context.Context) ([]models.RolePermission, error)
+	GetUserRole(ctx context.Context, userID uuid.UUID) (string, error)
+	// AssignRole changes the user's role from assignment.PreviousRole and
+	// records the assignment, failing with ErrRoleChanged if the role changed
+	// in the meantime.
+	AssignRole(ctx context.Context, assignment *models.RoleAssignment) error
+	ListAssignments(ctx context.Context, userID uuid.UUID) ([]models.RoleAssignment, error)
+}
+
+type repository struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewRepository(db *gorm.DB, logger zerolog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Seed(ctx context.Context, permissions []models.Permission, roles []models.Role, grants []models.RolePermission) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		doNothing := tx.Clauses(clause.OnConflict{DoNothing: true})
+		if err := doNothing.Create(&permissions).Error; err != nil {
+			return err
+		}
+		if err := doNothing.Create(&roles).Error; err != nil {
+			return err
+		}
+		return doNothing.Create(&grants).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to seed roles: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) ListRoles(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	if err := r.db.WithContext(ctx).Order("name ASC").Find(&roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	return roles, nil
+}
+
+func (r *repository) GetRole(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.WithContext(ctx).First(&role, "name = ?", name).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	return &role, nil
+}
+
+func (r *repository) ListGrants(ctx context.Context) ([]models.RolePermission, error) {
+	var grants []models.RolePermission
+	if err := r.db.WithContext(ctx).Find(&grants).Error; err != nil {
+		return nil, fmt.Errorf("failed to list role permissions: %w", err)
+	}
+	return grants, nil
+}
+
+func (r *repository) GetUserRole(ctx context.Context, userID uuid.UUID) (string, error) {
+	var u user.User
+	if err := r.db.WithContext(ctx).Select("id", "role").First(&u, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", e.ErrUserNotFound
+		}
+		return "", fmt.Errorf("failed to get user role: %w", err)
+	}
+	return u.Role, nil
+}
+
+func (r *repository) AssignRole(ctx context.Context, assignment *models.RoleAssignment) error {
+	if assignment.ID == uuid.Nil {
+		assignment.ID = uuid.New()
+	}
+	if assignment.CreatedAt.IsZero() {
+		assignment.CreatedAt = time.Now()
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&user.User{}).
+			Where("id = ? AND role = ?", assignment.UserID, assignment.PreviousRole).
+			Updates(map[string]interface{}{
+				"role":       assignment.Role,
+				"updated_at": assignment.CreatedAt,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update user role: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return e.ErrRoleChanged
+		}
+
+		if err := tx.Create(assignment).Error; err != nil {
+			return fmt.Errorf("failed to record role assignment: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *repository) ListAssignments(ctx context.Context, userID uuid.UUID) ([]models.RoleAssignment, error) {
+	var assignments []models.RoleAssignment
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&assignments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list role assignments: %w", err)
+	}
+	return assignments, nil
+}
@@ -0,0 +1,193 @@
+package rbac
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+// grantsTTL bounds how long a grant changed directly in the database takes
+// to reach every instance. Role assignments apply at once, since a user's
+// role is read on each check.
+const grantsTTL = 30 * time.Second
+
+// Service decides which permissions a user's role grants and changes roles
+// with an audit trail.
+type Service struct {
+	repo   Repository
+	now    func() time.Time
+	logger zerolog.Logger
+
+	mu       sync.Mutex
+	grants   map[string]map[string]bool
+	loadedAt time.Time
+}
+
+func NewService(repo Repository, logger zerolog.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		now:    time.Now,
+		logger: logger.With().Str("component", "rbac_service").Logger(),
+	}
+}
+
+// Seed adds the built-in roles and permissions missing from the database.
+func (s *Service) Seed(ctx context.Context) error {
+	now := s.now()
+	permissions := make([]models.Permission, len(defaultPermissions))
+	for i, p := range defaultPermissions {
+		p.CreatedAt = now
+		permissions[i] = p
+	}
+
+	var roles []models.Role
+	var grants []models.RolePermission
+	for _, d := range defaultRoles {
+		role := d.role
+		role.CreatedAt, role.UpdatedAt = now, now
+		roles = append(roles, role)
+		for _, permission := range d.permissions {
+			grants = append(grants, models.RolePermission{RoleName: role.Name, PermissionName: permission, CreatedAt: now})
+		}
+	}
+
+	if err := s.repo.Seed(ctx, permissions, roles, grants); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// HasPermission reports whether the user's current role grants permission.
+func (s *Service) HasPermission(ctx context.Context, userID uuid.UUID, permission string) (bool, error) {
+	role, err := s.repo.GetUserRole(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	grants, err := s.loadGrants(ctx)
+	if err != nil {
+		return false, err
+	}
+	return grants[role][permission], nil
+}
+
+func (s *Service) ListRoles(ctx context.Context) ([]RoleResponse, error) {
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	grants, err := s.loadGrants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		permissions := make([]string, 0, len(grants[role.Name]))
+		for permission := range grants[role.Name] {
+			permissions = append(permissions, permission)
+		}
+		sort.Strings(permissions)
+
+		resp = append(resp, RoleResponse{
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: permissions,
+		})
+	}
+	return resp, nil
+}
+
+// AssignRole changes a user's role on behalf of actorID and records who did
+// it and why. Nobody may change their own role, so escalation always takes
+// a second administrator.
+func (s *Service) AssignRole(ctx context.Context, actorID, userID uuid.UUID, req AssignRoleRequest, ipAddress string) (*models.RoleAssignment, error) {
+	if actorID == userID {
+		return nil, e.ErrSelfRoleAssignment
+	}
+
+	roleName := strings.ToLower(strings.TrimSpace(req.Role))
+	role, err := s.repo.GetRole(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, e.ErrRoleNotFound
+	}
+
+	current, err := s.repo.GetUserRole(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current == role.Name {
+		return nil, e.ErrRoleAlreadyAssigned
+	}
+
+	assignment := &models.RoleAssignment{
+		ID:           uuid.New(),
+		UserID:       userID,
+		PreviousRole: current,
+		Role:         role.Name,
+		AssignedBy:   actorID,
+		Reason:       strings.TrimSpace(req.Reason),
+		IPAddress:    ipAddress,
+		CreatedAt:    s.now(),
+	}
+	if err := s.repo.AssignRole(ctx, assignment); err != nil {
+		return nil, err
+	}
+
+	s.logger.Warn().
+		Any("user_id", userID).
+		Any("assigned_by", actorID).
+		Str("previous_role", current).
+		Str("role", role.Name).
+		Msg("User role changed")
+	return assignment, nil
+}
+
+func (s *Service) ListAssignments(ctx context.Context, userID uuid.UUID) ([]models.RoleAssignment, error) {
+	return s.repo.ListAssignments(ctx, userID)
+}
+
+// loadGrants returns the role to permissions table, reloading it once it is
+// older than grantsTTL.
+func (s *Service) loadGrants(ctx context.Context) (map[string]map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.grants != nil && s.now().Sub(s.loadedAt) < grantsTTL {
+		return s.grants, nil
+	}
+
+	rows, err := s.repo.ListGrants(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make(map[string]map[string]bool)
+	for _, row := range rows {
+		if grants[row.RoleName] == nil {
+			grants[row.RoleName] = make(map[string]bool)
+		}
+		grants[row.RoleName][row.PermissionName] = true
+	}
+	s.grants = grants
+	s.loadedAt = s.now()
+	return grants, nil
+}
+
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.grants = nil
+}
@@ -0,0 +1,166 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thoraf20/loanee/internal/models"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+func TestSeededRolesGrantTheirPermissions(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+
+	officer, treasurer, support := uuid.New(), uuid.New(), uuid.New()
+	repo.users[officer] = RoleCreditOfficer
+	repo.users[treasurer] = RoleTreasury
+	repo.users[support] = RoleSupport
+
+	for _, tc := range []struct {
+		user       uuid.UUID
+		permission string
+		allowed    bool
+	}{
+		{officer, PermLoansApprove, true},
+		{officer, PermLoansDisburse, false},
+		{treasurer, PermLoansDisburse, true},
+		{treasurer, PermCollateralsRelease, true},
+		{treasurer, PermLoansApprove, false},
+		{support, PermLoansRead, true},
+		{support, PermReconciliationRun, false},
+	} {
+		allowed, err := svc.HasPermission(ctx, tc.user, tc.permission)
+		require.NoError(t, err)
+		require.Equal(t, tc.allowed, allowed, "%s %s", repo.users[tc.user], tc.permission)
+	}
+
+	// Seeding again adds nothing.
+	grants := len(repo.grants)
+	require.NoError(t, svc.Seed(ctx))
+	require.Len(t, repo.grants, grants)
+}
+
+func TestAssignRoleIsAuditedAndAppliesImmediately(t *testing.T) {
+	svc, repo := newTestService(t)
+	ctx := context.Background()
+
+	admin, staff := uuid.New(), uuid.New()
+	repo.users[admin] = RoleSuperAdmin
+	repo.users[staff] = RoleUser
+
+	_, err := svc.AssignRole(ctx, admin, admin, AssignRoleRequest{Role: RoleUser, Reason: "test"}, "")
+	require.ErrorIs(t, err, e.ErrSelfRoleAssignment)
+
+	_, err = svc.AssignRole(ctx, admin, staff, AssignRoleRequest{Role: "janitor", Reason: "test"}, "")
+	require.ErrorIs(t, err, e.ErrRoleNotFound)
+
+	allowed, err := svc.HasPermission(ctx, staff, PermLoansApprove)
+	require.NoError(t, err)
+	require.False(t, allowed)
+
+	assignment, err := svc.AssignRole(ctx, admin, staff, AssignRoleRequest{Role: " Credit_Officer ", Reason: "joined credit team"}, "203.0.113.7")
+	require.NoError(t, err)
+	require.Equal(t, RoleUser, assignment.PreviousRole)
+	require.Equal(t, RoleCreditOfficer, assignment.Role)
+	require.Equal(t, admin, assignment.AssignedBy)
+
+	allowed, err = svc.HasPermission(ctx, staff, PermLoansApprove)
+	require.NoError(t, err)
+	require.True(t, allowed)
+
+	_, err = svc.AssignRole(ctx, admin, staff, AssignRoleRequest{Role: RoleCreditOfficer, Reason: "again"}, "")
+	require.ErrorIs(t, err, e.ErrRoleAlreadyAssigned)
+
+	history, err := svc.ListAssignments(ctx, staff)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	require.Equal(t, "joined credit team", history[0].Reason)
+}
+
+func newTestService(t *testing.T) (*Service, *memoryRepo) {
+	t.Helper()
+
+	repo := &memoryRepo{
+		roles:  make(map[string]models.Role),
+		grants: make(map[models.RolePermission]bool),
+		users:  make(map[uuid.UUID]string),
+	}
+	svc := NewService(repo, zerolog.Nop())
+	require.NoError(t, svc.Seed(context.Background()))
+	return svc, repo
+}
+
+type memoryRepo struct {
+	roles       map[string]models.Role
+	grants      map[models.RolePermission]bool
+	users       map[uuid.UUID]string
+	assignments []models.RoleAssignment
+}
+
+func (m *memoryRepo) Seed(ctx context.Context, permissions []models.Permission, roles []models.Role, grants []models.RolePermission) error {
+	for _, role := range roles {
+		if _, ok := m.roles[role.Name]; !ok {
+			m.roles[role.Name] = role
+		}
+	}
+	for _, grant := range grants {
+		m.grants[models.RolePermission{RoleName: grant.RoleName, PermissionName: grant.PermissionName}] = true
+	}
+	return nil
+}
+
+func (m *memoryRepo) ListRoles(ctx context.Context) ([]models.Role, error) {
+	roles := make([]models.Role, 0, len(m.roles))
+	for _, role := range m.roles {
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+func (m *memoryRepo) GetRole(ctx context.Context, name string) (*models.Role, error) {
+	role, ok := m.roles[name]
+	if !ok {
+		return nil, nil
+	}
+	return &role, nil
+}
+
+func (m *memoryRepo) ListGrants(ctx context.Context) ([]models.RolePermission, error) {
+	grants := make([]models.RolePermission, 0, len(m.grants))
+	for grant := range m.grants {
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+func (m *memoryRepo) GetUserRole(ctx context.Context, userID uuid.UUID) (string, error) {
+	role, ok := m.users[userID]
+	if !ok {
+		return "", e.ErrUserNotFound
+	}
+	return role, nil
+}
+
+func (m *memoryRepo) AssignRole(ctx context.Context, assignment *models.RoleAssignment) error {
+	if m.users[assignment.UserID] != assignment.PreviousRole {
+		return e.ErrRoleChanged
+	}
+	m.users[assignment.UserID] = assignment.Role
+	m.assignments = append(m.assignments, *assignment)
+	return nil
+}
+
+func (m *memoryRepo) ListAssignments(ctx context.Context, userID uuid.UUID) ([]models.RoleAssignment, error) {
+	var assignments []models.RoleAssignment
+	for _, a := range m.assignments {
+		if a.UserID == userID {
+			assignments = append(assignments, a)
+		}
+	}
+	return assignments, nil
+}
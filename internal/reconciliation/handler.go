@@ -0,0 +1,42 @@
+package reconciliation
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/utils"
+)
+
+type Handler struct {
+	service *Service
+	logger  zerolog.Logger
+}
+
+func NewHandler(service *Service, logger zerolog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger.With().Str("component", "reconciliation_handler").Logger(),
+	}
+}
+
+// AdminLatest returns the most recent reconciliation report.
+func (h *Handler) AdminLatest(c *gin.Context) {
+	report := h.service.Latest()
+	if report == nil {
+		utils.NotFound(c, "no reconciliation has completed yet")
+		return
+	}
+
+	utils.OK(c, "reconciliation report retrieved", report)
+}
+
+// AdminRun reconciles now instead of waiting for the worker.
+func (h *Handler) AdminRun(c *gin.Context) {
+	report, err := h.service.Reconcile(c.Request.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to reconcile custody")
+		utils.InternalServerError(c, "failed to reconcile custody", err.Error())
+		return
+	}
+
+	utils.OK(c, "reconciliation completed", report)
+}
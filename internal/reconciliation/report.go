@@ -0,0 +1,31 @@
+package reconciliation
+
+import "time"
+
+// AssetBalance compares what the platform holds on-chain for one asset with
+// what it owes borrowers.
+type AssetBalance struct {
+	Asset string `json:"asset"`
+	// DepositBalance is held on the users' deposit addresses.
+	DepositBalance float64 `json:"deposit_balance"`
+	// HotWalletBalance is held on the hot wallets.
+	HotWalletBalance float64 `json:"hot_wallet_balance"`
+	Custody          float64 `json:"custody"`
+	// Books is the collateral still owed back to borrowers.
+	Books float64 `json:"books"`
+	// Difference is Custody minus Books; negative means a shortfall.
+	Difference  float64 `json:"difference"`
+	Discrepancy bool    `json:"discrepancy"`
+	// Incomplete is set when a balance could not be read, in which case the
+	// custody figure is a lower bound and only a shortfall is raised.
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// Report is the outcome of one reconciliation run.
+type Report struct {
+	GeneratedAt   time.Time      `json:"generated_at"`
+	Assets        []AssetBalance `json:"assets"`
+	Discrepancies int            `json:"discrepancies"`
+	// Errors lists the balance reads that failed.
+	Errors []string `json:"errors,omitempty"`
+}
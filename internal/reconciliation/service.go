@@ -0,0 +1,287 @@
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/blockchain"
+	"github.com/thoraf20/loanee/internal/models"
+)
+
+// dust is the smallest difference worth reporting, below any asset's
+// precision that matters.
+const dust = 1e-8
+
+// heldStatuses are the collateral states whose funds must still be in
+// custody. Confirmed deposits are held before they activate, and releasing
+// collateral stays on the books until its return transfer confirms.
+var heldStatuses = []models.CollateralStatus{
+	models.StatusConfirmed,
+	models.StatusActive,
+	models.StatusReleaseRequested,
+	models.StatusReleasing,
+}
+
+// Wallets lists deposit addresses and records their synced balances.
+type Wallets interface {
+	ListAll(ctx context.Context) ([]models.Wallet, error)
+	UpdateBalance(ctx context.Context, id uuid.UUID, balance float64) error
+}
+
+// HotWallets lists the platform's signing wallets.
+type HotWallets interface {
+	List(ctx context.Context) ([]models.HotWallet, error)
+}
+
+// Collaterals is the subset of collateral persistence the books are read from.
+type Collaterals interface {
+	ListByStatus(ctx context.Context, statuses ...models.CollateralStatus) ([]models.Collateral, error)
+}
+
+type Options struct {
+	Interval time.Duration
+	// Tolerance is the relative difference between custody and the books
+	// accepted before a discrepancy is raised.
+	Tolerance float64
+}
+
+// Service periodically reads the on-chain balance of every deposit address
+// and hot wallet, stores it on the wallet, and checks that custody covers
+// the collateral owed to borrowers for each asset. Disagreements are logged
+// as alerts and kept in the latest report for ops.
+type Service struct {
+	readers     []blockchain.BalanceReader
+	wallets     Wallets
+	hotWallets  HotWallets
+	collaterals Collaterals
+	opts        Options
+	now         func() time.Time
+	logger      zerolog.Logger
+
+	// running serialises reconciliations so a manual run cannot overlap
+	// the worker.
+	running sync.Mutex
+	mu      sync.RWMutex
+	latest  *Report
+}
+
+func NewService(readers []blockchain.BalanceReader, wallets Wallets, hotWallets HotWallets, collaterals Collaterals, opts Options, logger zerolog.Logger) *Service {
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Minute
+	}
+	if opts.Tolerance < 0 {
+		opts.Tolerance = 0
+	}
+
+	return &Service{
+		readers:     readers,
+		wallets:     wallets,
+		hotWallets:  hotWallets,
+		collaterals: collaterals,
+		opts:        opts,
+		now:         time.Now,
+		logger:      logger.With().Str("component", "reconciliation_service").Logger(),
+	}
+}
+
+// Run reconciles until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+
+	s.logger.Info().Dur("interval", s.opts.Interval).Int("chains", len(s.readers)).Msg("Reconciliation worker started")
+
+	for {
+		s.Tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info().Msg("Reconciliation worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs one reconciliation.
+func (s *Service) Tick(ctx context.Context) {
+	if _, err := s.Reconcile(ctx); err != nil && ctx.Err() == nil {
+		s.logger.Warn().Err(err).Msg("Reconciliation failed")
+	}
+}
+
+// Latest returns the most recent report, or nil before the first run.
+func (s *Service) Latest() *Report {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.latest
+}
+
+// Reconcile syncs wallet balances from chain and compares custody with the
+// books for every asset.
+func (s *Service) Reconcile(ctx context.Context) (*Report, error) {
+	s.running.Lock()
+	defer s.running.Unlock()
+
+	wallets, err := s.wallets.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hotWallets, err := s.hotWallets.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collaterals, err := s.collaterals.ListByStatus(ctx, heldStatuses...)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{GeneratedAt: s.now()}
+	assets := make(map[string]*AssetBalance)
+	entry := func(asset string) *AssetBalance {
+		asset = strings.ToUpper(asset)
+		if assets[asset] == nil {
+			assets[asset] = &AssetBalance{Asset: asset}
+		}
+		return assets[asset]
+	}
+
+	for _, wallet := range wallets {
+		readers := s.readersFor(wallet.AssetType)
+		if len(readers) == 0 {
+			continue
+		}
+		balance, complete := 0.0, true
+		for _, reader := range readers {
+			amount, err := reader.Balance(ctx, wallet.AssetType, wallet.Address)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s %s on %s: %v", wallet.AssetType, wallet.Address, reader.Chain(), err))
+				complete = false
+				continue
+			}
+			balance += amount
+		}
+
+		asset := entry(wallet.AssetType)
+		asset.DepositBalance += balance
+		if !complete {
+			asset.Incomplete = true
+			continue
+		}
+		if balance != wallet.Balance {
+			if err := s.wallets.UpdateBalance(ctx, wallet.ID, balance); err != nil {
+				s.logger.Warn().Err(err).Str("wallet_id", wallet.ID.String()).Msg("Failed to store wallet balance")
+			}
+		}
+	}
+
+	for _, hotWallet := range hotWallets {
+		reader, ok := s.readerForChain(hotWallet.Network)
+		if !ok {
+			continue
+		}
+		for _, symbol := range reader.Assets() {
+			asset := entry(symbol)
+			amount, err := reader.Balance(ctx, symbol, hotWallet.Address)
+			if err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s hot wallet %s on %s: %v", symbol, hotWallet.Address, hotWallet.Network, err))
+				asset.Incomplete = true
+				continue
+			}
+			asset.HotWalletBalance += amount
+		}
+	}
+
+	for _, collateral := range collaterals {
+		entry(collateral.AssetSymbol).Books += collateral.AssetAmount
+	}
+
+	for _, asset := range assets {
+		asset.Custody = asset.DepositBalance + asset.HotWalletBalance
+		asset.Difference = asset.Custody - asset.Books
+		asset.Discrepancy = s.disagrees(asset)
+		if asset.Incomplete {
+			// Unread balances would only add to custody, so the sum read is
+			// a lower bound: a surplus cannot be judged, but custody short
+			// of the books even before them is still flagged.
+			asset.Discrepancy = asset.Discrepancy && asset.Difference < 0
+		}
+		if asset.Discrepancy {
+			report.Discrepancies++
+		}
+		report.Assets = append(report.Assets, *asset)
+	}
+	sort.Slice(report.Assets, func(i, j int) bool {
+		return report.Assets[i].Asset < report.Assets[j].Asset
+	})
+
+	s.alert(report)
+
+	s.mu.Lock()
+	s.latest = report
+	s.mu.Unlock()
+	return report, nil
+}
+
+// disagrees reports whether custody and the books differ by more than the
+// tolerance, relative to the larger of the two.
+func (s *Service) disagrees(asset *AssetBalance) bool {
+	diff := math.Abs(asset.Difference)
+	return diff > dust && diff > s.opts.Tolerance*math.Max(asset.Books, asset.Custody)
+}
+
+// alert logs every discrepancy. A shortfall means custody cannot cover what
+// borrowers are owed; a surplus usually means an unattributed deposit.
+func (s *Service) alert(report *Report) {
+	for _, asset := range report.Assets {
+		if !asset.Discrepancy {
+			continue
+		}
+		event := s.logger.Warn()
+		message := "Custody holds more than the books"
+		if asset.Difference < 0 {
+			event = s.logger.Error()
+			message = "Custody shortfall against the books"
+		}
+		event.
+			Str("asset", asset.Asset).
+			Float64("custody", asset.Custody).
+			Float64("books", asset.Books).
+			Float64("difference", asset.Difference).
+			Msg(message)
+	}
+
+	if len(report.Errors) > 0 {
+		s.logger.Warn().Strs("errors", report.Errors).Msg("Reconciliation could not read some balances")
+	}
+	s.logger.Info().Int("assets", len(report.Assets)).Int("discrepancies", report.Discrepancies).Msg("Reconciliation completed")
+}
+
+func (s *Service) readersFor(asset string) []blockchain.BalanceReader {
+	var readers []blockchain.BalanceReader
+	for _, reader := range s.readers {
+		for _, symbol := range reader.Assets() {
+			if strings.EqualFold(symbol, asset) {
+				readers = append(readers, reader)
+				break
+			}
+		}
+	}
+	return readers
+}
+
+func (s *Service) readerForChain(chain string) (blockchain.BalanceReader, bool) {
+	for _, reader := range s.readers {
+		if strings.EqualFold(reader.Chain(), chain) {
+			return reader, true
+		}
+	}
+	return nil, false
+}
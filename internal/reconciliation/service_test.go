@@ -0,0 +1,171 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/internal/blockchain"
+	"github.com/thoraf20/loanee/internal/models"
+)
+
+func TestReconcileSyncsBalancesAndFlagsShortfall(t *testing.T) {
+	ethereum := &fakeReader{chain: "ethereum", assets: []string{"ETH", "USDT"}, balances: map[string]float64{
+		"ETH/0xa":  1.5,
+		"USDT/0xb": 400,
+		"ETH/0xh":  0.5,
+		"USDT/0xh": 100,
+	}}
+	bitcoin := &fakeReader{chain: "bitcoin", assets: []string{"BTC"}, balances: map[string]float64{
+		"BTC/bc1a": 0.2,
+	}}
+	wallets := &memoryWallets{wallets: []models.Wallet{
+		{ID: uuid.New(), AssetType: "ETH", Address: "0xa"},
+		{ID: uuid.New(), AssetType: "USDT", Address: "0xb"},
+		{ID: uuid.New(), AssetType: "BTC", Address: "bc1a", Balance: 0.2},
+	}}
+	hotWallets := fakeHotWallets{{Network: "ethereum", Address: "0xh"}}
+	collaterals := fakeCollaterals{
+		{AssetSymbol: "ETH", AssetAmount: 1.5, Status: models.StatusActive},
+		{AssetSymbol: "ETH", AssetAmount: 0.5, Status: models.StatusConfirmed},
+		{AssetSymbol: "USDT", AssetAmount: 300, Status: models.StatusReleaseRequested},
+		{AssetSymbol: "USDT", AssetAmount: 200, Status: models.StatusReleasing},
+		{AssetSymbol: "BTC", AssetAmount: 0.25, Status: models.StatusActive},
+		{AssetSymbol: "BTC", AssetAmount: 5, Status: models.StatusReleased},
+	}
+
+	service := NewService([]blockchain.BalanceReader{bitcoin, ethereum}, wallets, hotWallets, collaterals, Options{Tolerance: 0.001}, zerolog.Nop())
+	require.Nil(t, service.Latest())
+
+	report, err := service.Reconcile(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, report.Errors)
+	require.Same(t, report, service.Latest())
+
+	require.Equal(t, 1.5, wallets.wallets[0].Balance)
+	require.Equal(t, 400.0, wallets.wallets[1].Balance)
+	// Unchanged balances are not rewritten.
+	require.Equal(t, 2, wallets.updates)
+
+	require.Len(t, report.Assets, 3)
+	btc, eth, usdt := report.Assets[0], report.Assets[1], report.Assets[2]
+
+	require.Equal(t, "BTC", btc.Asset)
+	require.InDelta(t, -0.05, btc.Difference, 1e-9)
+	require.True(t, btc.Discrepancy)
+
+	require.Equal(t, "ETH", eth.Asset)
+	require.Equal(t, 2.0, eth.Custody)
+	require.Equal(t, 2.0, eth.Books)
+	require.False(t, eth.Discrepancy)
+
+	require.Equal(t, "USDT", usdt.Asset)
+	require.Equal(t, 400.0, usdt.DepositBalance)
+	require.Equal(t, 100.0, usdt.HotWalletBalance)
+	require.Equal(t, 500.0, usdt.Books)
+	require.False(t, usdt.Discrepancy)
+
+	require.Equal(t, 1, report.Discrepancies)
+}
+
+func TestFailedReadMarksAssetIncomplete(t *testing.T) {
+	ethereum := &fakeReader{chain: "ethereum", assets: []string{"ETH"}, balances: map[string]float64{
+		"ETH/0xa": 1,
+	}}
+	wallets := &memoryWallets{wallets: []models.Wallet{
+		{ID: uuid.New(), AssetType: "ETH", Address: "0xa", Balance: 1},
+		{ID: uuid.New(), AssetType: "ETH", Address: "0xdead", Balance: 3},
+	}}
+	collaterals := fakeCollaterals{{AssetSymbol: "ETH", AssetAmount: 4, Status: models.StatusActive}}
+
+	service := NewService([]blockchain.BalanceReader{ethereum}, wallets, fakeHotWallets{}, collaterals, Options{}, zerolog.Nop())
+	report, err := service.Reconcile(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, report.Errors, 1)
+	require.True(t, report.Assets[0].Incomplete)
+	// What could be read already falls short of the books.
+	require.True(t, report.Assets[0].Discrepancy)
+	require.Equal(t, 1, report.Discrepancies)
+	// The unreadable wallet keeps its last known balance.
+	require.Equal(t, 3.0, wallets.wallets[1].Balance)
+	require.Zero(t, wallets.updates)
+}
+
+func TestIncompleteReadDoesNotFlagSurplus(t *testing.T) {
+	ethereum := &fakeReader{chain: "ethereum", assets: []string{"ETH"}, balances: map[string]float64{
+		"ETH/0xa": 5,
+	}}
+	wallets := &memoryWallets{wallets: []models.Wallet{
+		{ID: uuid.New(), AssetType: "ETH", Address: "0xa"},
+		{ID: uuid.New(), AssetType: "ETH", Address: "0xdead"},
+	}}
+	collaterals := fakeCollaterals{{AssetSymbol: "ETH", AssetAmount: 4, Status: models.StatusActive}}
+
+	service := NewService([]blockchain.BalanceReader{ethereum}, wallets, fakeHotWallets{}, collaterals, Options{}, zerolog.Nop())
+	report, err := service.Reconcile(context.Background())
+	require.NoError(t, err)
+
+	require.True(t, report.Assets[0].Incomplete)
+	require.False(t, report.Assets[0].Discrepancy)
+	require.Zero(t, report.Discrepancies)
+}
+
+type fakeReader struct {
+	chain    string
+	assets   []string
+	balances map[string]float64
+}
+
+func (f *fakeReader) Chain() string    { return f.chain }
+func (f *fakeReader) Assets() []string { return f.assets }
+
+func (f *fakeReader) Balance(ctx context.Context, asset, address string) (float64, error) {
+	balance, ok := f.balances[asset+"/"+address]
+	if !ok {
+		return 0, errors.New("rpc unavailable")
+	}
+	return balance, nil
+}
+
+type memoryWallets struct {
+	wallets []models.Wallet
+	updates int
+}
+
+func (m *memoryWallets) ListAll(ctx context.Context) ([]models.Wallet, error) {
+	return append([]models.Wallet(nil), m.wallets...), nil
+}
+
+func (m *memoryWallets) UpdateBalance(ctx context.Context, id uuid.UUID, balance float64) error {
+	for i := range m.wallets {
+		if m.wallets[i].ID == id {
+			m.wallets[i].Balance = balance
+			m.updates++
+		}
+	}
+	return nil
+}
+
+type fakeHotWallets []models.HotWallet
+
+func (f fakeHotWallets) List(ctx context.Context) ([]models.HotWallet, error) {
+	return f, nil
+}
+
+type fakeCollaterals []models.Collateral
+
+func (f fakeCollaterals) ListByStatus(ctx context.Context, statuses ...models.CollateralStatus) ([]models.Collateral, error) {
+	var result []models.Collateral
+	for _, collateral := range f {
+		for _, status := range statuses {
+			if collateral.Status == status {
+				result = append(result, collateral)
+			}
+		}
+	}
+	return result, nil
+}
@@ -9,6 +9,8 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"github.com/thoraf20/loanee/internal/container"
 	"github.com/thoraf20/loanee/internal/middleware"
+	"github.com/thoraf20/loanee/internal/rbac"
+	"github.com/thoraf20/loanee/pkg/ratelimit"
 )
 
 // Setup configures all routes with handlers from container
@@ -19,13 +21,21 @@ func Setup(c *container.Container) *gin.Engine {
 	}
 
 	r := gin.New()
+	if err := middleware.TrustProxies(r, c.Config.Server.TrustedProxies); err != nil {
+		// Unreachable once config validation has passed; trust no proxy
+		c.Logger.Error().Err(err).Msg("Invalid trusted proxies, ignoring forwarded headers")
+		middleware.TrustProxies(r, nil)
+	}
 
 	// Global middleware (order matters!)
 	r.Use(middleware.Recovery(c.Logger))
 	r.Use(middleware.RequestID())
 	r.Use(middleware.Logger(c.Logger))
 	r.Use(middleware.CORS(c.Config))
-	r.Use(middleware.Timeout(30 * time.Second))
+	r.Use(middleware.Timeout(30*time.Second,
+		"/api/v1/prices/stream",
+		"/api/v1/collaterals/stream",
+	))
 
 	// Health check
 	r.GET("/health", func(ctx *gin.Context) {
@@ -35,31 +45,73 @@ func Setup(c *container.Container) *gin.Engine {
 		})
 	})
 
+	// Public keys for verifying our tokens, re-fetched by other services
+	// as keys rotate
+	r.GET("/.well-known/jwks.json", func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, c.JWTManager.JWKS())
+	})
+
 	// Swagger documentation
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// Per-group request limits, keyed by user, API key or client IP
+	rateLimit := func(group string) gin.HandlerFunc {
+		rule := c.Config.RateLimit.Groups[group]
+		return middleware.RateLimit(c.RateLimiter, group, ratelimit.Limit{
+			Requests: rule.Requests,
+			Window:   rule.Window,
+		})
+	}
+
+	// One limit per client IP across every authenticated route, checked
+	// before the credentials are
+	ipRule := c.Config.RateLimit.Groups["ip"]
+	ipRateLimit := middleware.RateLimitByIP(c.RateLimiter, "ip", ratelimit.Limit{
+		Requests: ipRule.Requests,
+		Window:   ipRule.Window,
+	})
+
 	// API v1 routes
 	v1 := r.Group("/api/v1")
 	{
 		// Public routes - Auth
 		auth := v1.Group("/auth")
+		auth.Use(rateLimit("auth"))
 
 		{
 			auth.POST("/register", c.AuthHandler.Register)
 			auth.POST("/login", c.AuthHandler.Login)
+			auth.POST("/login/mfa", c.AuthHandler.LoginMFA)
+			auth.POST("/unlock", c.AuthHandler.UnlockAccount)
 			auth.POST("/verify-email", c.AuthHandler.VerifyEmail)
 			auth.POST("/resend-code", c.AuthHandler.ResendVerificationCode)
 			auth.POST("/forgot-password", c.AuthHandler.ForgotPassword)
 			auth.POST("/reset-password", c.AuthHandler.ResetPassword)
-			// auth.POST("/refresh", c.AuthHandler.RefreshToken)
+			auth.POST("/refresh", c.AuthHandler.RefreshToken)
 		}
 
 		// Protected routes - require authentication
 		protected := v1.Group("")
-		protected.Use(middleware.AuthRequired(c.JWTManager, c.TokenBlacklist))
+		protected.Use(ipRateLimit)
+		protected.Use(middleware.AuthRequired(c.JWTManager, c.TokenBlacklist, c.AuthService, nil))
+		protected.Use(rateLimit("api"))
+
+		// Sensitive actions need a recent second-factor check
+		stepUp := middleware.RequireStepUp(c.JWTManager, c.MFAService, c.Config.MFA.RequireForSensitiveActions)
 
 		{
 			protected.POST("/auth/logout", c.AuthHandler.Logout)
+			protected.POST("/auth/logout-all", c.AuthHandler.LogoutAllDevices)
+			protected.GET("/auth/sessions", c.AuthHandler.ListSessions)
+			protected.DELETE("/auth/sessions/:id", c.AuthHandler.RevokeSession)
+
+			mfa := protected.Group("/auth/mfa")
+			{
+				mfa.POST("/enroll", c.MFAHandler.Enroll)
+				mfa.POST("/confirm", c.MFAHandler.Confirm)
+				mfa.POST("/step-up", c.MFAHandler.StepUp)
+			}
 
 			// User routes
 			users := protected.Group("/users")
@@ -72,9 +124,15 @@ func Setup(c *container.Container) *gin.Engine {
 			{
 				collaterals.GET("", c.CollateralHandler.ListMine)
 				collaterals.GET("/preview", c.CollateralHandler.Preview)
+				collaterals.GET("/stream", c.CollateralHandler.StreamLTV)
 				collaterals.POST("/request", c.CollateralHandler.CreateRequest)
 				collaterals.POST("/lock", c.CollateralHandler.Lock)
-				collaterals.POST("/:id/release-request", c.CollateralHandler.RequestRelease)
+				collaterals.POST("/:id/release-request", stepUp, c.CollateralHandler.RequestRelease)
+			}
+
+			prices := protected.Group("/prices")
+			{
+				prices.GET("/stream", c.PriceHandler.StreamPrices)
 			}
 
 			wallets := protected.Group("/wallets")
@@ -82,25 +140,87 @@ func Setup(c *container.Container) *gin.Engine {
 				wallets.GET("", c.WalletHandler.ListMine)
 			}
 
-			loans := protected.Group("/loans")
+			withdrawalAddresses := protected.Group("/withdrawal-addresses")
+			{
+				withdrawalAddresses.GET("", c.AddressHandler.ListMine)
+				withdrawalAddresses.POST("", stepUp, c.AddressHandler.Add)
+				withdrawalAddresses.POST("/confirm", stepUp, c.AddressHandler.Confirm)
+				withdrawalAddresses.DELETE("/:id", stepUp, c.AddressHandler.Revoke)
+			}
+
+			apiKeys := protected.Group("/api-keys")
 			{
-				loans.GET("", c.LoanHandler.ListMine)
-				loans.POST("/:id/repay", c.PaymentHandler.RepayLoan)
-				loans.GET("/:id/repayments", c.PaymentHandler.ListRepayments)
+				apiKeys.GET("", c.APIKeyHandler.ListMine)
+				apiKeys.POST("", stepUp, c.APIKeyHandler.Create)
+				apiKeys.DELETE("/:id", c.APIKeyHandler.Revoke)
 			}
 		}
 
-		// Admin routes
+		// Routes partners' backends reach with an API key as well as a
+		// bearer token; each declares the scope a key needs
+		integrations := v1.Group("")
+		integrations.Use(ipRateLimit)
+		integrations.Use(middleware.AuthRequired(c.JWTManager, c.TokenBlacklist, c.AuthService, c.APIKeyService))
+		integrations.Use(rateLimit("api"))
+
+		scope := func(permission string) gin.HandlerFunc {
+			return middleware.RequireScope(c.RBACService, permission)
+		}
+
+		{
+			loans := integrations.Group("/loans")
+			{
+				loans.GET("", scope(rbac.PermOwnLoansRead), c.LoanHandler.ListMine)
+				loans.POST("/:id/repay", scope(rbac.PermOwnRepaymentsCreate), c.PaymentHandler.RepayLoan)
+				loans.GET("/:id/repayments", scope(rbac.PermOwnLoansRead), c.PaymentHandler.ListRepayments)
+			}
+		}
+
+		// Admin routes, each gated by the permission it needs
 		admin := v1.Group("/admin")
-		admin.Use(middleware.AuthRequired(c.JWTManager, c.TokenBlacklist))
-		admin.Use(middleware.RequireRole("admin"))
+		admin.Use(ipRateLimit)
+		admin.Use(middleware.AuthRequired(c.JWTManager, c.TokenBlacklist, c.AuthService, nil))
+		admin.Use(rateLimit("admin"))
+
+		can := func(permission string) gin.HandlerFunc {
+			return middleware.RequirePermission(c.RBACService, permission)
+		}
+
 		{
-			admin.GET("/collaterals", c.CollateralHandler.AdminList)
-			admin.PUT("/collaterals/:id/approve-release", c.CollateralHandler.AdminApproveRelease)
-			admin.PUT("/collaterals/:id/reject-release", c.CollateralHandler.AdminRejectRelease)
-			admin.GET("/loans", c.LoanHandler.AdminList)
-			admin.PUT("/loans/:id/approve", c.LoanHandler.AdminApprove)
-			admin.POST("/loans/:id/disburse", c.LoanHandler.AdminDisburse)
+			admin.GET("/collaterals", can(rbac.PermCollateralsRead), c.CollateralHandler.AdminList)
+			admin.PUT("/collaterals/:id/approve-release", can(rbac.PermCollateralsRelease), c.CollateralHandler.AdminApproveRelease)
+			admin.PUT("/collaterals/:id/reject-release", can(rbac.PermCollateralsRelease), c.CollateralHandler.AdminRejectRelease)
+			admin.GET("/loans", can(rbac.PermLoansRead), c.LoanHandler.AdminList)
+			admin.PUT("/loans/:id/approve", can(rbac.PermLoansApprove), c.LoanHandler.AdminApprove)
+			admin.POST("/loans/:id/disburse", can(rbac.PermLoansDisburse), c.LoanHandler.AdminDisburse)
+			admin.GET("/hot-wallets", can(rbac.PermCustodyRead), c.CustodyHandler.AdminList)
+			admin.POST("/hot-wallets", can(rbac.PermCustodyManage), c.CustodyHandler.AdminCreate)
+			admin.POST("/hot-wallets/rotate-keys", can(rbac.PermCustodyManage), c.CustodyHandler.AdminRotateKeys)
+			if c.WithdrawalHandler != nil {
+				admin.GET("/withdrawals", can(rbac.PermWithdrawalsRead), c.WithdrawalHandler.AdminList)
+				admin.POST("/withdrawals/:id/bump", can(rbac.PermWithdrawalsManage), c.WithdrawalHandler.AdminBump)
+			}
+			if c.StablecoinHandler != nil {
+				admin.GET("/stablecoins", can(rbac.PermStablecoinsRead), c.StablecoinHandler.AdminList)
+			}
+			if c.ReconcileHandler != nil {
+				admin.GET("/reconciliation", can(rbac.PermReconciliationRead), c.ReconcileHandler.AdminLatest)
+				admin.POST("/reconciliation/run", can(rbac.PermReconciliationRun), c.ReconcileHandler.AdminRun)
+			}
+
+			// A second administrator confirms actions held for dual control;
+			// the service checks the permission each action needs
+			admin.GET("/approvals", can(rbac.PermApprovalsRead), c.ApprovalHandler.AdminList)
+			// The action's own permission is checked again when deciding
+			admin.POST("/approvals/:id/approve", can(rbac.PermApprovalsRead), stepUp, c.ApprovalHandler.AdminApprove)
+			admin.POST("/approvals/:id/reject", can(rbac.PermApprovalsRead), c.ApprovalHandler.AdminReject)
+
+			admin.GET("/api-keys", can(rbac.PermAPIKeysManage), c.APIKeyHandler.AdminList)
+			admin.DELETE("/api-keys/:id", can(rbac.PermAPIKeysManage), c.APIKeyHandler.AdminRevoke)
+
+			admin.GET("/roles", can(rbac.PermRolesRead), c.RBACHandler.AdminListRoles)
+			admin.PUT("/users/:id/role", can(rbac.PermRolesAssign), stepUp, c.RBACHandler.AdminAssignRole)
+			admin.GET("/users/:id/role-assignments", can(rbac.PermRolesRead), c.RBACHandler.AdminListAssignments)
 		}
 	}
 
@@ -0,0 +1,193 @@
+package stablecoin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/pricing"
+)
+
+// Status is the last observed price of a stablecoin against its peg.
+type Status struct {
+	Asset    string  `json:"asset"`
+	Price    float64 `json:"price"`
+	Peg      float64 `json:"peg"`
+	Currency string  `json:"currency"`
+	// Deviation is the relative distance from the peg, negative below it.
+	Deviation float64 `json:"deviation"`
+	Depegged  bool    `json:"depegged"`
+	// Haircut is the fraction taken off the asset's value while depegged.
+	Haircut       float64    `json:"haircut,omitempty"`
+	DepeggedSince *time.Time `json:"depegged_since,omitempty"`
+	CheckedAt     time.Time  `json:"checked_at"`
+}
+
+type Options struct {
+	Assets   []string
+	Currency string
+	Peg      float64
+	// Band is the relative deviation from Peg tolerated before an asset
+	// counts as depegged.
+	Band    float64
+	Haircut float64
+	// Interval is how often the worker re-checks every peg.
+	Interval time.Duration
+}
+
+// Guard watches stablecoin prices against their peg. While an asset trades
+// outside the band, new loans against it are suspended and its collateral is
+// valued with a haircut; entering and leaving that state raises an alert.
+type Guard struct {
+	prices pricing.Provider
+	assets map[string]bool
+	opts   Options
+	now    func() time.Time
+	logger zerolog.Logger
+
+	mu       sync.RWMutex
+	statuses map[string]Status
+}
+
+func NewGuard(prices pricing.Provider, opts Options, logger zerolog.Logger) *Guard {
+	if opts.Currency == "" {
+		opts.Currency = "USD"
+	}
+	if opts.Peg <= 0 {
+		opts.Peg = 1
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	opts.Currency = strings.ToUpper(opts.Currency)
+
+	assets := make(map[string]bool, len(opts.Assets))
+	for _, asset := range opts.Assets {
+		assets[strings.ToUpper(asset)] = true
+	}
+
+	return &Guard{
+		prices:   prices,
+		assets:   assets,
+		opts:     opts,
+		now:      time.Now,
+		logger:   logger.With().Str("component", "stablecoin_guard").Logger(),
+		statuses: make(map[string]Status),
+	}
+}
+
+// Run re-checks every peg until ctx is cancelled.
+func (g *Guard) Run(ctx context.Context) {
+	ticker := time.NewTicker(g.opts.Interval)
+	defer ticker.Stop()
+
+	g.logger.Info().Dur("interval", g.opts.Interval).Int("assets", len(g.assets)).Msg("Stablecoin guard started")
+
+	for {
+		g.Tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			g.logger.Info().Msg("Stablecoin guard stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick checks every guarded asset once.
+func (g *Guard) Tick(ctx context.Context) {
+	for asset := range g.assets {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := g.Check(ctx, asset); err != nil {
+			g.logger.Warn().Err(err).Str("asset", asset).Msg("Failed to check stablecoin peg")
+		}
+	}
+}
+
+// Check prices asset against its peg now. It returns nil for assets that
+// are not guarded stablecoins.
+func (g *Guard) Check(ctx context.Context, asset string) (*Status, error) {
+	asset = strings.ToUpper(asset)
+	if !g.assets[asset] {
+		return nil, nil
+	}
+
+	price, err := g.prices.GetPrice(ctx, asset, g.opts.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to price %s: %w", asset, err)
+	}
+	return g.record(asset, price), nil
+}
+
+// Haircut returns the haircut applied to asset as of its most recent check,
+// zero while it holds its peg or was never checked.
+func (g *Guard) Haircut(asset string) float64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.statuses[strings.ToUpper(asset)].Haircut
+}
+
+// Statuses lists the most recent status of every checked asset.
+func (g *Guard) Statuses() []Status {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(g.statuses))
+	for _, status := range g.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Asset < statuses[j].Asset
+	})
+	return statuses
+}
+
+func (g *Guard) record(asset string, price float64) *Status {
+	now := g.now()
+	deviation := (price - g.opts.Peg) / g.opts.Peg
+	status := Status{
+		Asset:     asset,
+		Price:     price,
+		Peg:       g.opts.Peg,
+		Currency:  g.opts.Currency,
+		Deviation: deviation,
+		Depegged:  math.Abs(deviation) > g.opts.Band,
+		CheckedAt: now,
+	}
+
+	g.mu.Lock()
+	previous := g.statuses[asset]
+	if status.Depegged {
+		status.Haircut = g.opts.Haircut
+		status.DepeggedSince = &now
+		if previous.Depegged {
+			status.DepeggedSince = previous.DepeggedSince
+		}
+	}
+	g.statuses[asset] = status
+	g.mu.Unlock()
+
+	switch {
+	case status.Depegged && !previous.Depegged:
+		g.logger.Error().
+			Str("asset", asset).
+			Float64("price", price).
+			Float64("deviation", deviation).
+			Float64("haircut", status.Haircut).
+			Msg("Stablecoin depegged, new loans suspended and collateral haircut applied")
+	case !status.Depegged && previous.Depegged:
+		g.logger.Warn().
+			Str("asset", asset).
+			Float64("price", price).
+			Msg("Stablecoin back inside its peg band, new loans resumed")
+	}
+	return &status
+}
@@ -0,0 +1,75 @@
+package stablecoin
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuardTracksDepegAndRecovery(t *testing.T) {
+	prices := &fakePrices{price: 0.995}
+	guard := NewGuard(prices, Options{Assets: []string{"usdt"}, Band: 0.02, Haircut: 0.1}, zerolog.Nop())
+	now := time.Now()
+	guard.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	status, err := guard.Check(ctx, "USDT")
+	require.NoError(t, err)
+	require.False(t, status.Depegged)
+	require.Zero(t, guard.Haircut("USDT"))
+
+	// Other assets are not guarded.
+	status, err = guard.Check(ctx, "ETH")
+	require.NoError(t, err)
+	require.Nil(t, status)
+
+	prices.price = 0.95
+	status, err = guard.Check(ctx, "usdt")
+	require.NoError(t, err)
+	require.True(t, status.Depegged)
+	require.InDelta(t, -0.05, status.Deviation, 1e-9)
+	require.Equal(t, 0.1, guard.Haircut("usdt"))
+	depeggedAt := now
+
+	// The depeg keeps its start time across checks.
+	now = now.Add(time.Minute)
+	prices.price = 1.03
+	status, err = guard.Check(ctx, "USDT")
+	require.NoError(t, err)
+	require.True(t, status.Depegged)
+	require.Equal(t, depeggedAt, *status.DepeggedSince)
+
+	now = now.Add(time.Minute)
+	prices.price = 1.001
+	guard.Tick(ctx)
+	require.Zero(t, guard.Haircut("USDT"))
+	statuses := guard.Statuses()
+	require.Len(t, statuses, 1)
+	require.False(t, statuses[0].Depegged)
+	require.Nil(t, statuses[0].DepeggedSince)
+
+	prices.err = errors.New("provider down")
+	_, err = guard.Check(ctx, "USDT")
+	require.Error(t, err)
+}
+
+type fakePrices struct {
+	price float64
+	err   error
+}
+
+func (f *fakePrices) GetPrice(ctx context.Context, symbol, currency string) (float64, error) {
+	return f.price, f.err
+}
+
+func (f *fakePrices) GetPrices(ctx context.Context, symbols []string, currency string) (map[string]float64, error) {
+	prices := make(map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		prices[symbol] = f.price
+	}
+	return prices, f.err
+}
@@ -0,0 +1,24 @@
+package stablecoin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/utils"
+)
+
+type Handler struct {
+	guard  *Guard
+	logger zerolog.Logger
+}
+
+func NewHandler(guard *Guard, logger zerolog.Logger) *Handler {
+	return &Handler{
+		guard:  guard,
+		logger: logger.With().Str("component", "stablecoin_handler").Logger(),
+	}
+}
+
+// AdminList returns the latest peg status of every stablecoin.
+func (h *Handler) AdminList(c *gin.Context) {
+	utils.OK(c, "stablecoin peg status retrieved", h.guard.Statuses())
+}
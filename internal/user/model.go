@@ -19,6 +19,9 @@ type User struct {
 	PreferredFiat   string     `gorm:"type:varchar(10);default:'NGN'" json:"preferred_fiat"` // e.g., NGN, USD
 	DefaultCurrency string     `gorm:"type:varchar(10);default:'NGN'" json:"default_currency"`
 	LastLogin       *time.Time `json:"last_login,omitempty"`
+	// TokensValidAfter rejects every token issued at or before it; set when
+	// the user logs out of all devices.
+	TokensValidAfter *time.Time `json:"-"`
 	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
@@ -11,67 +11,159 @@ import (
 	e "github.com/thoraf20/loanee/pkg/error"
 )
 
+// Token types. Each type is also the token's audience, so a token of one
+// type is rejected wherever the other is expected.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// TokenTypeMFAChallenge is returned by a login that still needs its
+	// second factor.
+	TokenTypeMFAChallenge = "mfa_challenge"
+	// TokenTypeStepUp proves a recent second-factor check for sensitive
+	// actions.
+	TokenTypeStepUp = "step_up"
+	// TokenTypeUnlock lifts a login lockout, sent by email when it begins.
+	TokenTypeUnlock = "unlock"
+)
+
 type Claims struct {
-	UserID uuid.UUID   `json:"user_id"`
-	Email  string `json:"email"`
-	Name   string `json:"name"`
-	Role   string `json:"role"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	TokenType string    `json:"typ"`
 	jwt.RegisteredClaims
 }
 
 // Manager handles JWT operations with injected config
 type Manager struct {
 	config *config.Config
+	keys   []*signingKey
+	// legacyUntil is when production stops accepting HS256 tokens; zero
+	// rejects them.
+	legacyUntil time.Time
+	now         func() time.Time
 }
 
-// NewManager creates a new JWT manager
-func NewManager(cfg *config.Config) *Manager {
+// NewManager creates a new JWT manager, loading the configured signing keys.
+func NewManager(cfg *config.Config) (*Manager, error) {
+	keys, err := loadSigningKeys(cfg.JWT.Keys)
+	if err != nil {
+		return nil, err
+	}
+	legacyUntil, err := parseKeyTime(cfg.JWT.LegacySecretUntil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid legacy_secret_until: %w", err)
+	}
+
 	return &Manager{
-		config: cfg,
+		config:      cfg,
+		keys:        keys,
+		legacyUntil: legacyUntil,
+		now:         time.Now,
+	}, nil
+}
+
+// currentKey returns the most recently activated key that has not retired,
+// or nil when none is usable.
+func (m *Manager) currentKey(now time.Time) *signingKey {
+	var current *signingKey
+	for _, key := range m.keys {
+		if key.activeFrom.After(now) || key.retired(now) {
+			continue
+		}
+		current = key
 	}
+	return current
 }
 
-// GenerateTokenPair generates both access and refresh tokens
-func (m *Manager) GenerateTokenPair(userID uuid.UUID, email, name, role string) (accessToken, refreshToken string, err error) {
-	accessToken, err = m.GenerateAccessToken(userID, email, name, role)
-	if err != nil {
-		return "", "", err
+// sign signs claims with the current key, naming it in the kid header. The
+// legacy HS256 secret signs only when no key is configured; with keys
+// configured but none active, signing fails rather than fall back to it.
+func (m *Manager) sign(claims *Claims) (string, error) {
+	if len(m.keys) > 0 {
+		key := m.currentKey(m.now())
+		if key == nil {
+			return "", e.NewInternalError("no JWT signing key is active", nil)
+		}
+		token := jwt.NewWithClaims(key.method, claims)
+		token.Header["kid"] = key.id
+		return token.SignedString(key.private)
 	}
 
-	refreshToken, err = m.GenerateRefreshToken(userID, email, name, role)
-	if err != nil {
-		return "", "", err
+	if m.config.JWT.Secret == "" {
+		return "", e.NewInternalError("no JWT signing key is configured", nil)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(m.config.JWT.Secret))
+}
+
+// verificationKey resolves the key a token claims to be signed with. Keys
+// verify from the moment they are configured until they retire, so tokens
+// survive a rotation for as long as the old key is kept.
+func (m *Manager) verificationKey(token *jwt.Token) (interface{}, error) {
+	now := m.now()
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if m.config.JWT.Secret == "" || !m.acceptsLegacy(now) {
+			return nil, errors.New("HMAC tokens are not accepted")
+		}
+		return []byte(m.config.JWT.Secret), nil
 	}
 
-	return accessToken, refreshToken, nil
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range m.keys {
+		if key.id == kid && key.method.Alg() == token.Method.Alg() && !key.retired(now) {
+			return key.public, nil
+		}
+	}
+	return nil, errors.New("unknown or retired signing key")
 }
 
-// GenerateAccessToken creates a new access token
-func (m *Manager) GenerateAccessToken(userID uuid.UUID, email, name, role string) (string, error) {
-	if m.config.JWT.Secret == "" {
-		return "", e.NewInternalError("JWT secret is not configured", nil)
+// acceptsLegacy reports whether HS256 tokens still verify at now.
+// Production accepts them only until the configured cut-off.
+func (m *Manager) acceptsLegacy(now time.Time) bool {
+	if m.config.App.Environment != "production" {
+		return true
 	}
+	return !m.legacyUntil.IsZero() && now.Before(m.legacyUntil)
+}
+
+// JWKS returns the public half of every key that has not retired, for other
+// services to verify our tokens with.
+func (m *Manager) JWKS() JWKSet {
+	now := m.now()
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range m.keys {
+		if !key.retired(now) {
+			set.Keys = append(set.Keys, key.jwk())
+		}
+	}
+	return set
+}
 
-	now := time.Now()
+// GenerateAccessToken creates a new access token
+func (m *Manager) GenerateAccessToken(userID uuid.UUID, email, name, role string) (string, error) {
+	now := m.now()
 	expiresAt := now.Add(m.config.JWT.AccessTokenExpiry)
 
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Name:      name,
+		Role:      role,
+		TokenType: TokenTypeAccess,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    m.config.App.Name,
-			Subject:   fmt.Sprintf("%d", userID),
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{TokenTypeAccess},
 			ID:        uuid.New().String(), // Unique token ID
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(m.config.JWT.Secret))
+	tokenString, err := m.sign(claims)
 	if err != nil {
 		return "", e.NewInternalError("failed to sign token", err)
 	}
@@ -79,54 +171,123 @@ func (m *Manager) GenerateAccessToken(userID uuid.UUID, email, name, role string
 	return tokenString, nil
 }
 
-// GenerateRefreshToken creates a new refresh token with longer expiry
-func (m *Manager) GenerateRefreshToken(userID uuid.UUID, email, name, role string) (string, error) {
-	if m.config.JWT.Secret == "" {
-		return "", e.NewInternalError("JWT secret is not configured", nil)
-	}
-
-	now := time.Now()
+// GenerateRefreshToken creates a refresh token with a longer expiry. It
+// carries only the user ID; the returned claims hold the jti the token is
+// tracked by server-side.
+func (m *Manager) GenerateRefreshToken(userID uuid.UUID) (string, *Claims, error) {
+	now := m.now()
 	expiresAt := now.Add(m.config.JWT.RefreshTokenExpiry)
 
 	claims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
-		Role:   role,
+		UserID:    userID,
+		TokenType: TokenTypeRefresh,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    m.config.App.Name,
-			Subject:   fmt.Sprintf("%d", userID),
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{TokenTypeRefresh},
 			ID:        uuid.New().String(),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(m.config.JWT.Secret))
+	tokenString, err := m.sign(claims)
 	if err != nil {
-		return "", e.NewInternalError("failed to sign refresh token", err)
+		return "", nil, e.NewInternalError("failed to sign refresh token", err)
 	}
 
-	return tokenString, nil
+	return tokenString, claims, nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
-func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
-	if m.config.JWT.Secret == "" {
-		return nil, e.NewInternalError("JWT secret is not configured", nil)
+// GenerateMFAChallengeToken issues the token a login returns in place of
+// access and refresh tokens until the second factor is verified.
+func (m *Manager) GenerateMFAChallengeToken(userID uuid.UUID) (string, error) {
+	return m.signShortLived(userID, TokenTypeMFAChallenge, m.config.MFA.ChallengeTTL)
+}
+
+// GenerateStepUpToken issues the token that authorizes sensitive actions for
+// a short while after a second-factor check.
+func (m *Manager) GenerateStepUpToken(userID uuid.UUID) (string, error) {
+	return m.signShortLived(userID, TokenTypeStepUp, m.config.MFA.StepUpTTL)
+}
+
+// GenerateUnlockToken issues the emailed token that lifts a login lockout. It
+// lives as long as the lockout itself.
+func (m *Manager) GenerateUnlockToken(userID uuid.UUID) (string, error) {
+	return m.signShortLived(userID, TokenTypeUnlock, m.config.LoginThrottle.AccountLockout)
+}
+
+// signShortLived signs a token that carries only the user ID and its type.
+func (m *Manager) signShortLived(userID uuid.UUID, tokenType string, ttl time.Duration) (string, error) {
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	now := m.now()
+	claims := &Claims{
+		UserID:    userID,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    m.config.App.Name,
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{tokenType},
+			ID:        uuid.New().String(),
+		},
+	}
+
+	tokenString, err := m.sign(claims)
+	if err != nil {
+		return "", e.NewInternalError("failed to sign token", err)
 	}
 
+	return tokenString, nil
+}
+
+// ValidateAccessToken validates an access token and returns the claims
+func (m *Manager) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return m.validate(tokenString, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates a refresh token and returns the claims. It
+// checks only the signature, expiry and type; whether the token has been
+// used is tracked server-side.
+func (m *Manager) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return m.validate(tokenString, TokenTypeRefresh)
+}
+
+// ValidateMFAChallengeToken validates a login's MFA challenge token.
+func (m *Manager) ValidateMFAChallengeToken(tokenString string) (*Claims, error) {
+	return m.validate(tokenString, TokenTypeMFAChallenge)
+}
+
+// ValidateStepUpToken validates a step-up token.
+func (m *Manager) ValidateStepUpToken(tokenString string) (*Claims, error) {
+	return m.validate(tokenString, TokenTypeStepUp)
+}
+
+// ValidateUnlockToken validates an account unlock token.
+func (m *Manager) ValidateUnlockToken(tokenString string) (*Claims, error) {
+	return m.validate(tokenString, TokenTypeUnlock)
+}
+
+// validate parses a token and requires it to be of tokenType, both in its
+// type claim and its audience.
+func (m *Manager) validate(tokenString, tokenType string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&Claims{},
-		func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, e.ErrTokenInvalid.WithDetail("signing_method", token.Header["alg"])
-			}
-			return []byte(m.config.JWT.Secret), nil
-		},
+		m.verificationKey,
+		jwt.WithAudience(tokenType),
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodEdDSA.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodHS256.Alg(),
+		}),
+		jwt.WithTimeFunc(m.now),
 	)
 
 	if err != nil {
@@ -150,19 +311,24 @@ func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
 	if !ok {
 		return nil, e.ErrTokenInvalid.WithDetail("reason", "invalid claims structure")
 	}
+	if claims.TokenType != tokenType {
+		return nil, e.ErrTokenInvalid.WithDetail("reason", "wrong token type")
+	}
 
 	return claims, nil
 }
 
-// RefreshAccessToken generates a new access token from a valid refresh token
-func (m *Manager) RefreshAccessToken(refreshToken string) (string, error) {
-	claims, err := m.ValidateToken(refreshToken)
-	if err != nil {
-		return "", err
+// IssuedBefore reports whether claims were issued at or before cutoff, the
+// moment a user's tokens were revoked. IssuedAt has one-second precision, so
+// a token issued within the same second as cutoff counts as revoked.
+func IssuedBefore(claims *Claims, cutoff *time.Time) bool {
+	if cutoff == nil {
+		return false
 	}
-
-	// Generate new access token with same user info
-	return m.GenerateAccessToken(claims.UserID, claims.Email, claims.Name, claims.Role)
+	if claims.IssuedAt == nil {
+		return true
+	}
+	return !claims.IssuedAt.Time.After(*cutoff)
 }
 
 // ExtractTokenFromHeader extracts token from Authorization header
@@ -187,4 +353,4 @@ func ExtractTokenFromHeader(authHeader string) (string, error) {
 	}
 
 	return token, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/thoraf20/loanee/config"
+)
+
+// signingKey is one asymmetric key of the JWT key set, identified in token
+// headers by its kid.
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+	// activeFrom is when the key starts signing; zero means it always has.
+	activeFrom time.Time
+	// retireAt is when tokens it signed stop verifying; zero means never.
+	retireAt time.Time
+}
+
+func (k *signingKey) retired(now time.Time) bool {
+	return !k.retireAt.IsZero() && !now.Before(k.retireAt)
+}
+
+// loadSigningKeys parses the configured keys, ordered by activation.
+func loadSigningKeys(cfgs []config.JWTKeyConfig) ([]*signingKey, error) {
+	keys := make([]*signingKey, 0, len(cfgs))
+	seen := make(map[string]bool, len(cfgs))
+
+	for _, cfg := range cfgs {
+		if cfg.ID == "" {
+			return nil, fmt.Errorf("JWT key is missing its id")
+		}
+		if seen[cfg.ID] {
+			return nil, fmt.Errorf("JWT key %q is configured twice", cfg.ID)
+		}
+		seen[cfg.ID] = true
+
+		key, err := parseSigningKey(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", cfg.ID, err)
+		}
+		keys = append(keys, key)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].activeFrom.Before(keys[j].activeFrom)
+	})
+	return keys, nil
+}
+
+func parseSigningKey(cfg config.JWTKeyConfig) (*signingKey, error) {
+	data := []byte(cfg.PrivateKey)
+	if cfg.PrivateKeyFile != "" {
+		var err error
+		if data, err = os.ReadFile(cfg.PrivateKeyFile); err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("private key is not PKCS#8: %w", err)
+	}
+
+	key := &signingKey{id: cfg.ID}
+	switch private := parsed.(type) {
+	case ed25519.PrivateKey:
+		key.method = jwt.SigningMethodEdDSA
+		key.private = private
+		key.public = private.Public()
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA keys must be at least 2048 bits")
+		}
+		key.method = jwt.SigningMethodRS256
+		key.private = private
+		key.public = private.Public()
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use Ed25519 or RSA", parsed)
+	}
+
+	if key.activeFrom, err = parseKeyTime(cfg.ActiveFrom); err != nil {
+		return nil, fmt.Errorf("invalid active_from: %w", err)
+	}
+	if key.retireAt, err = parseKeyTime(cfg.RetireAt); err != nil {
+		return nil, fmt.Errorf("invalid retire_at: %w", err)
+	}
+	return key, nil
+}
+
+func parseKeyTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// JWK is a public key in JSON Web Key form (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// Curve and X describe an Ed25519 key (RFC 8037).
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+	// N and E describe an RSA key.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (k *signingKey) jwk() JWK {
+	jwk := JWK{
+		KeyID:     k.id,
+		Use:       "sig",
+		Algorithm: k.method.Alg(),
+	}
+
+	switch public := k.public.(type) {
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+	return jwk
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/config"
+)
+
+func TestRotationSignsWithNewestKeyAndVerifiesBoth(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	cfg := testJWTConfig()
+	cfg.JWT.AccessTokenExpiry = 90 * 24 * time.Hour
+	cfg.JWT.Keys = []config.JWTKeyConfig{
+		{ID: "old", PrivateKey: ed25519PEM(t), RetireAt: "2026-11-01T00:00:00Z"},
+		{ID: "new", PrivateKey: rsaPEM(t), ActiveFrom: "2026-10-15T00:00:00Z"},
+	}
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return now }
+
+	// The next key is published before it starts signing.
+	require.Len(t, manager.JWKS().Keys, 2)
+
+	userID := uuid.New()
+	before, err := manager.GenerateAccessToken(userID, "a@example.com", "A", "user")
+	require.NoError(t, err)
+	require.Equal(t, "old", tokenHeader(t, before, "kid"))
+	require.Equal(t, "EdDSA", tokenHeader(t, before, "alg"))
+
+	now = now.Add(15 * 24 * time.Hour)
+	after, err := manager.GenerateAccessToken(userID, "a@example.com", "A", "user")
+	require.NoError(t, err)
+	require.Equal(t, "new", tokenHeader(t, after, "kid"))
+	require.Equal(t, "RS256", tokenHeader(t, after, "alg"))
+
+	// Tokens from the old key keep working until it retires.
+	claims, err := manager.ValidateAccessToken(before)
+	require.NoError(t, err)
+	require.Equal(t, userID, claims.UserID)
+	_, err = manager.ValidateAccessToken(after)
+	require.NoError(t, err)
+
+	now = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	_, err = manager.ValidateAccessToken(before)
+	require.Error(t, err)
+
+	jwks := manager.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	require.Equal(t, "new", jwks.Keys[0].KeyID)
+	require.Equal(t, "RSA", jwks.Keys[0].KeyType)
+	require.Equal(t, "AQAB", jwks.Keys[0].E)
+}
+
+func TestTokensAreBoundToTheirKey(t *testing.T) {
+	cfg := testJWTConfig()
+	cfg.JWT.Keys = []config.JWTKeyConfig{{ID: "k1", PrivateKey: ed25519PEM(t)}}
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+
+	other := testJWTConfig()
+	other.JWT.Keys = []config.JWTKeyConfig{{ID: "k1", PrivateKey: ed25519PEM(t)}}
+	forger, err := NewManager(other)
+	require.NoError(t, err)
+
+	forged, err := forger.GenerateAccessToken(uuid.New(), "", "", "admin")
+	require.NoError(t, err)
+	_, err = manager.ValidateAccessToken(forged)
+	require.Error(t, err)
+
+	// Once keys are configured the HS256 secret no longer signs, and a
+	// manager without it rejects HMAC tokens outright.
+	legacy, err := NewManager(testJWTConfig())
+	require.NoError(t, err)
+	hmacToken, err := legacy.GenerateAccessToken(uuid.New(), "", "", "user")
+	require.NoError(t, err)
+	require.Equal(t, "HS256", tokenHeader(t, hmacToken, "alg"))
+
+	_, err = manager.ValidateAccessToken(hmacToken)
+	require.NoError(t, err)
+	manager.config.JWT.Secret = ""
+	_, err = manager.ValidateAccessToken(hmacToken)
+	require.Error(t, err)
+
+	jwks := manager.JWKS()
+	require.Len(t, jwks.Keys, 1)
+	require.Equal(t, "OKP", jwks.Keys[0].KeyType)
+	require.Equal(t, "Ed25519", jwks.Keys[0].Curve)
+}
+
+func TestSignFailsWithoutAnActiveKey(t *testing.T) {
+	cfg := testJWTConfig()
+	cfg.JWT.Keys = []config.JWTKeyConfig{
+		{ID: "next", PrivateKey: ed25519PEM(t), ActiveFrom: "2026-12-01T00:00:00Z"},
+	}
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC) }
+
+	// The secret is still set, but must not sign in place of the keys.
+	_, err = manager.GenerateAccessToken(uuid.New(), "", "", "user")
+	require.Error(t, err)
+}
+
+func TestProductionAcceptsHMACOnlyUntilCutoff(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	legacy, err := NewManager(testJWTConfig())
+	require.NoError(t, err)
+	legacy.now = func() time.Time { return now }
+	hmacToken, err := legacy.GenerateAccessToken(uuid.New(), "", "", "user")
+	require.NoError(t, err)
+
+	cfg := testJWTConfig()
+	cfg.App.Environment = "production"
+	cfg.JWT.Keys = []config.JWTKeyConfig{{ID: "k1", PrivateKey: ed25519PEM(t)}}
+	manager, err := NewManager(cfg)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return now }
+
+	_, err = manager.ValidateAccessToken(hmacToken)
+	require.Error(t, err)
+
+	cfg.JWT.LegacySecretUntil = "2026-10-01T00:30:00Z"
+	manager, err = NewManager(cfg)
+	require.NoError(t, err)
+	manager.now = func() time.Time { return now }
+	_, err = manager.ValidateAccessToken(hmacToken)
+	require.NoError(t, err)
+
+	now = now.Add(45 * time.Minute)
+	_, err = manager.ValidateAccessToken(hmacToken)
+	require.Error(t, err)
+}
+
+func TestNewManagerRejectsBadKeys(t *testing.T) {
+	for _, keys := range [][]config.JWTKeyConfig{
+		{{PrivateKey: ed25519PEM(t)}},
+		{{ID: "a", PrivateKey: "not a key"}},
+		{{ID: "a", PrivateKey: ed25519PEM(t)}, {ID: "a", PrivateKey: ed25519PEM(t)}},
+		{{ID: "a", PrivateKey: ed25519PEM(t), ActiveFrom: "tomorrow"}},
+	} {
+		cfg := testJWTConfig()
+		cfg.JWT.Keys = keys
+		_, err := NewManager(cfg)
+		require.Error(t, err)
+	}
+}
+
+func testJWTConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.JWT.Secret = "test-secret"
+	cfg.JWT.AccessTokenExpiry = time.Hour
+	return cfg
+}
+
+func tokenHeader(t *testing.T, token, name string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	require.NoError(t, err)
+	value, _ := parsed.Header[name].(string)
+	return value
+}
+
+func ed25519PEM(t *testing.T) string {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return pkcs8PEM(t, private)
+}
+
+func rsaPEM(t *testing.T) string {
+	t.Helper()
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return pkcs8PEM(t, private)
+}
+
+func pkcs8PEM(t *testing.T, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
@@ -0,0 +1,160 @@
+package wallet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/btcsuite/btcd/btcutil"
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/thoraf20/loanee/config"
+)
+
+// ErrDerivationUnavailable is returned when no account xpub is configured for
+// an asset, so no deposit address can be issued for it.
+var ErrDerivationUnavailable = errors.New("deposit addresses are not configured for asset")
+
+// accountDepth is the BIP32 depth of an account-level key (purpose/coin/account).
+const accountDepth = 3
+
+// account derives receive addresses from the external chain of one account xpub.
+type account struct {
+	external *hdkeychain.ExtendedKey
+	path     string
+	encode   func(*btcec.PublicKey) (string, error)
+}
+
+// Deriver issues deposit addresses from account-level extended public keys:
+// BIP84 native segwit for BTC and BIP44 for EVM assets. Addresses live on the
+// external chain, so the address at index i has path <account>/0/i.
+type Deriver struct {
+	accounts map[string]account
+}
+
+// NewDeriver parses the configured account xpubs. Private extended keys are
+// rejected, as is an xpub shared by two assets, since the same index would
+// then yield the same address for both.
+func NewDeriver(cfg config.HDWalletConfig) (*Deriver, error) {
+	params, err := BitcoinParams(cfg.BitcoinNetwork)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Deriver{accounts: make(map[string]account)}
+	seen := make(map[string]string)
+	for asset, acc := range cfg.Accounts {
+		asset = strings.ToUpper(asset)
+		if acc.XPub == "" {
+			continue
+		}
+		if other, ok := seen[acc.XPub]; ok {
+			return nil, fmt.Errorf("%s and %s share an xpub, configure one account per asset", other, asset)
+		}
+		seen[acc.XPub] = asset
+
+		key, err := hdkeychain.NewKeyFromString(acc.XPub)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xpub for %s: %w", asset, err)
+		}
+		if key.IsPrivate() {
+			return nil, fmt.Errorf("%s key is private, configure the account xpub instead", asset)
+		}
+		if key.Depth() != accountDepth {
+			return nil, fmt.Errorf("%s xpub has depth %d, expected an account-level key", asset, key.Depth())
+		}
+
+		external, err := key.Derive(0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive %s external chain: %w", asset, err)
+		}
+
+		encode := evmAddress
+		if asset == "BTC" {
+			encode = segwitAddress(params)
+		}
+		d.accounts[asset] = account{
+			external: external,
+			path:     strings.TrimSuffix(acc.Path, "/"),
+			encode:   encode,
+		}
+	}
+	return d, nil
+}
+
+// Supports reports whether addresses can be derived for asset.
+func (d *Deriver) Supports(asset string) bool {
+	_, ok := d.accounts[strings.ToUpper(asset)]
+	return ok
+}
+
+// Require fails unless every one of assets has an account xpub, naming the
+// ones that are missing so a misconfigured deployment stops at startup
+// rather than on its first deposit.
+func (d *Deriver) Require(assets ...string) error {
+	var missing []string
+	for _, asset := range assets {
+		if !d.Supports(asset) {
+			missing = append(missing, strings.ToUpper(asset))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: set hd_wallet.accounts.<asset>.xpub for %s", ErrDerivationUnavailable, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Derive returns the receive address at index for asset and its full path.
+func (d *Deriver) Derive(asset string, index uint32) (string, string, error) {
+	acc, ok := d.accounts[strings.ToUpper(asset)]
+	if !ok {
+		return "", "", fmt.Errorf("%w: %s", ErrDerivationUnavailable, asset)
+	}
+	if index >= hdkeychain.HardenedKeyStart {
+		return "", "", fmt.Errorf("derivation index %d is out of range", index)
+	}
+
+	child, err := acc.external.Derive(index)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to derive %s address %d: %w", asset, index, err)
+	}
+	pub, err := child.ECPubKey()
+	if err != nil {
+		return "", "", err
+	}
+	address, err := acc.encode(pub)
+	if err != nil {
+		return "", "", err
+	}
+	return address, fmt.Sprintf("%s/0/%d", acc.path, index), nil
+}
+
+func evmAddress(pub *btcec.PublicKey) (string, error) {
+	return crypto.PubkeyToAddress(*pub.ToECDSA()).Hex(), nil
+}
+
+func segwitAddress(params *chaincfg.Params) func(*btcec.PublicKey) (string, error) {
+	return func(pub *btcec.PublicKey) (string, error) {
+		address, err := btcutil.NewAddressWitnessPubKeyHash(btcutil.Hash160(pub.SerializeCompressed()), params)
+		if err != nil {
+			return "", err
+		}
+		return address.EncodeAddress(), nil
+	}
+}
+
+// BitcoinParams maps a configured network name to its chain parameters.
+func BitcoinParams(network string) (*chaincfg.Params, error) {
+	switch strings.ToLower(network) {
+	case "", "mainnet":
+		return &chaincfg.MainNetParams, nil
+	case "testnet":
+		return &chaincfg.TestNet3Params, nil
+	case "regtest":
+		return &chaincfg.RegressionNetParams, nil
+	default:
+		return nil, fmt.Errorf("unknown bitcoin network %q", network)
+	}
+}
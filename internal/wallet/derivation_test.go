@@ -0,0 +1,111 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcutil/hdkeychain"
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/config"
+)
+
+// Seed of the BIP39 test mnemonic "abandon abandon ... about".
+const testSeed = "5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4"
+
+// BIP84 test vector account key for the same mnemonic, m/84'/0'/0'.
+const testZpub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+
+func TestDeriveKnownVectors(t *testing.T) {
+	deriver, err := NewDeriver(config.HDWalletConfig{
+		Accounts: map[string]config.HDAccountConfig{
+			"btc": {XPub: testZpub, Path: "m/84'/0'/0'"},
+			"eth": {XPub: accountXPub(t, 44, 60, 0), Path: "m/44'/60'/0'"},
+		},
+	})
+	require.NoError(t, err)
+
+	address, path, err := deriver.Derive("BTC", 0)
+	require.NoError(t, err)
+	require.Equal(t, "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu", address)
+	require.Equal(t, "m/84'/0'/0'/0/0", path)
+
+	address, path, err = deriver.Derive("eth", 0)
+	require.NoError(t, err)
+	require.Equal(t, "0x9858EfFD232B4033E47d90003D41EC34EcaEda94", address)
+	require.Equal(t, "m/44'/60'/0'/0/0", path)
+
+	next, _, err := deriver.Derive("ETH", 1)
+	require.NoError(t, err)
+	require.NotEqual(t, address, next)
+
+	_, _, err = deriver.Derive("MATIC", 0)
+	require.ErrorIs(t, err, ErrDerivationUnavailable)
+}
+
+func TestRequireNamesAssetsWithoutXPub(t *testing.T) {
+	deriver, err := NewDeriver(config.HDWalletConfig{
+		Accounts: map[string]config.HDAccountConfig{
+			"btc": {XPub: ""},
+			"eth": {XPub: accountXPub(t, 44, 60, 0)},
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, deriver.Require("eth"))
+	err = deriver.Require("ETH", "btc", "USDT")
+	require.ErrorIs(t, err, ErrDerivationUnavailable)
+	require.ErrorContains(t, err, "BTC, USDT")
+}
+
+func TestNewDeriverRejectsUnsafeKeys(t *testing.T) {
+	master := masterKey(t)
+	account := deriveAccount(t, master, 44, 60, 0)
+
+	_, err := NewDeriver(config.HDWalletConfig{
+		Accounts: map[string]config.HDAccountConfig{"eth": {XPub: account.String()}},
+	})
+	require.ErrorContains(t, err, "private")
+
+	shared := accountXPub(t, 44, 60, 0)
+	_, err = NewDeriver(config.HDWalletConfig{
+		Accounts: map[string]config.HDAccountConfig{
+			"eth":  {XPub: shared},
+			"usdt": {XPub: shared},
+		},
+	})
+	require.ErrorContains(t, err, "share an xpub")
+
+	neutered, err := master.Neuter()
+	require.NoError(t, err)
+	_, err = NewDeriver(config.HDWalletConfig{
+		Accounts: map[string]config.HDAccountConfig{"eth": {XPub: neutered.String()}},
+	})
+	require.ErrorContains(t, err, "account-level")
+}
+
+func masterKey(t *testing.T) *hdkeychain.ExtendedKey {
+	t.Helper()
+	seed, err := hex.DecodeString(testSeed)
+	require.NoError(t, err)
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	require.NoError(t, err)
+	return master
+}
+
+func deriveAccount(t *testing.T, key *hdkeychain.ExtendedKey, path ...uint32) *hdkeychain.ExtendedKey {
+	t.Helper()
+	for _, index := range path {
+		var err error
+		key, err = key.Derive(hdkeychain.HardenedKeyStart + index)
+		require.NoError(t, err)
+	}
+	return key
+}
+
+func accountXPub(t *testing.T, purpose, coin, account uint32) string {
+	t.Helper()
+	public, err := deriveAccount(t, masterKey(t), purpose, coin, account).Neuter()
+	require.NoError(t, err)
+	return public.String()
+}
@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,10 +12,16 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrDuplicateAddress is returned when a wallet's address is already assigned.
+var ErrDuplicateAddress = errors.New("wallet address already assigned")
+
 type Repository interface {
 	Create(ctx context.Context, wallet *models.Wallet) error
 	ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Wallet, error)
 	GetPrimaryByAsset(ctx context.Context, userID uuid.UUID, asset string) (*models.Wallet, error)
+	AllocateIndex(ctx context.Context, userID uuid.UUID) (uint32, error)
+	ListAll(ctx context.Context) ([]models.Wallet, error)
+	UpdateBalance(ctx context.Context, id uuid.UUID, balance float64) error
 }
 
 type repository struct {
@@ -38,6 +45,9 @@ func (r *repository) Create(ctx context.Context, wallet *models.Wallet) error {
 	wallet.UpdatedAt = now
 
 	if err := r.db.WithContext(ctx).Create(wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrDuplicateAddress
+		}
 		return fmt.Errorf("failed to create wallet: %w", err)
 	}
 	return nil
@@ -67,3 +77,60 @@ func (r *repository) GetPrimaryByAsset(ctx context.Context, userID uuid.UUID, as
 	}
 	return &wallet, nil
 }
+
+func (r *repository) ListAll(ctx context.Context) ([]models.Wallet, error) {
+	var wallets []models.Wallet
+	if err := r.db.WithContext(ctx).Order("created_at ASC").Find(&wallets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list wallets: %w", err)
+	}
+	return wallets, nil
+}
+
+func (r *repository) UpdateBalance(ctx context.Context, id uuid.UUID, balance float64) error {
+	if err := r.db.WithContext(ctx).
+		Model(&models.Wallet{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"balance": balance, "updated_at": time.Now()}).Error; err != nil {
+		return fmt.Errorf("failed to update wallet balance: %w", err)
+	}
+	return nil
+}
+
+// AllocateIndex returns the user's derivation index, allocating the next one
+// on first use. A concurrent allocation for the same user loses on the unique
+// user constraint and reads back the winner's index.
+func (r *repository) AllocateIndex(ctx context.Context, userID uuid.UUID) (uint32, error) {
+	if existing, err := r.findIndex(ctx, userID); err != nil {
+		return 0, err
+	} else if existing != nil {
+		return existing.Index, nil
+	}
+
+	index := &models.DerivationIndex{UserID: userID}
+	err := r.db.WithContext(ctx).Create(index).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		existing, err := r.findIndex(ctx, userID)
+		if err != nil {
+			return 0, err
+		}
+		if existing == nil {
+			return 0, fmt.Errorf("derivation index for user %s vanished", userID)
+		}
+		return existing.Index, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate derivation index: %w", err)
+	}
+	return index.Index, nil
+}
+
+func (r *repository) findIndex(ctx context.Context, userID uuid.UUID) (*models.DerivationIndex, error) {
+	var index models.DerivationIndex
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&index).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query derivation index: %w", err)
+	}
+	return &index, nil
+}
@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -9,15 +10,22 @@ import (
 	"github.com/thoraf20/loanee/internal/models"
 )
 
+// AddressDeriver derives the deposit address at a BIP32 index for an asset.
+type AddressDeriver interface {
+	Derive(asset string, index uint32) (address, path string, err error)
+}
+
 type Service struct {
-	repo   Repository
-	logger zerolog.Logger
+	repo    Repository
+	deriver AddressDeriver
+	logger  zerolog.Logger
 }
 
-func NewService(repo Repository, logger zerolog.Logger) *Service {
+func NewService(repo Repository, deriver AddressDeriver, logger zerolog.Logger) *Service {
 	return &Service{
-		repo:   repo,
-		logger: logger.With().Str("component", "wallet_service").Logger(),
+		repo:    repo,
+		deriver: deriver,
+		logger:  logger.With().Str("component", "wallet_service").Logger(),
 	}
 }
 
@@ -25,6 +33,8 @@ func (s *Service) ListUserWallets(ctx context.Context, userID uuid.UUID) ([]mode
 	return s.repo.ListByUser(ctx, userID)
 }
 
+// GetOrCreatePrimary returns the user's deposit address for asset, deriving
+// it at the user's derivation index on first use.
 func (s *Service) GetOrCreatePrimary(ctx context.Context, userID uuid.UUID, asset string) (*models.Wallet, error) {
 	if wallet, err := s.repo.GetPrimaryByAsset(ctx, userID, asset); err != nil {
 		return nil, err
@@ -32,18 +42,49 @@ func (s *Service) GetOrCreatePrimary(ctx context.Context, userID uuid.UUID, asse
 		return wallet, nil
 	}
 
+	if s.deriver == nil {
+		return nil, fmt.Errorf("%w: %s", ErrDerivationUnavailable, asset)
+	}
+
+	index, err := s.repo.AllocateIndex(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	address, path, err := s.deriver.Derive(asset, index)
+	if err != nil {
+		return nil, err
+	}
+
 	newWallet := &models.Wallet{
-		ID:         uuid.New(),
-		UserID:     userID,
-		AssetType:  asset,
-		Address:    fmt.Sprintf("auto-generated-%s-%s", asset, uuid.New().String()),
-		Balance:    0,
-		IsPrimary:  true,
-		PrivateKey: "", // never expose; placeholder until real generation
+		ID:              uuid.New(),
+		UserID:          userID,
+		AssetType:       asset,
+		Address:         address,
+		DerivationIndex: index,
+		DerivationPath:  path,
+		Balance:         0,
+		IsPrimary:       true,
 	}
 
 	if err := s.repo.Create(ctx, newWallet); err != nil {
-		return nil, err
+		if !errors.Is(err, ErrDuplicateAddress) {
+			return nil, err
+		}
+		// A concurrent request derived the same address first.
+		existing, lookupErr := s.repo.GetPrimaryByAsset(ctx, userID, asset)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("%w: %s", err, address)
+		}
+		return existing, nil
 	}
+
+	s.logger.Info().
+		Str("user_id", userID.String()).
+		Str("asset", asset).
+		Str("path", path).
+		Msg("Deposit address derived")
 	return newWallet, nil
 }
@@ -0,0 +1,113 @@
+package wallet
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/internal/models"
+)
+
+func TestGetOrCreatePrimaryDerivesPerUserIndex(t *testing.T) {
+	repo := newMemoryRepo()
+	service := NewService(repo, fakeDeriver{}, zerolog.Nop())
+	ctx := context.Background()
+
+	alice, bob := uuid.New(), uuid.New()
+
+	eth, err := service.GetOrCreatePrimary(ctx, alice, "ETH")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), eth.DerivationIndex)
+	require.Equal(t, "ETH/1", eth.Address)
+	require.Equal(t, "m/ETH/0/1", eth.DerivationPath)
+
+	// Every asset of a user shares the index; other users get the next one.
+	btc, err := service.GetOrCreatePrimary(ctx, alice, "BTC")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), btc.DerivationIndex)
+
+	other, err := service.GetOrCreatePrimary(ctx, bob, "ETH")
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), other.DerivationIndex)
+
+	again, err := service.GetOrCreatePrimary(ctx, alice, "ETH")
+	require.NoError(t, err)
+	require.Equal(t, eth.ID, again.ID)
+}
+
+func TestGetOrCreatePrimaryRequiresDeriver(t *testing.T) {
+	service := NewService(newMemoryRepo(), nil, zerolog.Nop())
+
+	_, err := service.GetOrCreatePrimary(context.Background(), uuid.New(), "ETH")
+	require.ErrorIs(t, err, ErrDerivationUnavailable)
+}
+
+type fakeDeriver struct{}
+
+func (fakeDeriver) Derive(asset string, index uint32) (string, string, error) {
+	return fmt.Sprintf("%s/%d", asset, index), fmt.Sprintf("m/%s/0/%d", asset, index), nil
+}
+
+type memoryRepo struct {
+	wallets []models.Wallet
+	indexes map[uuid.UUID]uint32
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{indexes: make(map[uuid.UUID]uint32)}
+}
+
+func (m *memoryRepo) Create(ctx context.Context, wallet *models.Wallet) error {
+	for _, existing := range m.wallets {
+		if existing.Address == wallet.Address {
+			return ErrDuplicateAddress
+		}
+	}
+	m.wallets = append(m.wallets, *wallet)
+	return nil
+}
+
+func (m *memoryRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]models.Wallet, error) {
+	var result []models.Wallet
+	for _, wallet := range m.wallets {
+		if wallet.UserID == userID {
+			result = append(result, wallet)
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryRepo) GetPrimaryByAsset(ctx context.Context, userID uuid.UUID, asset string) (*models.Wallet, error) {
+	for _, wallet := range m.wallets {
+		if wallet.UserID == userID && wallet.AssetType == asset {
+			copy := wallet
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRepo) ListAll(ctx context.Context) ([]models.Wallet, error) {
+	return m.wallets, nil
+}
+
+func (m *memoryRepo) UpdateBalance(ctx context.Context, id uuid.UUID, balance float64) error {
+	for i := range m.wallets {
+		if m.wallets[i].ID == id {
+			m.wallets[i].Balance = balance
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) AllocateIndex(ctx context.Context, userID uuid.UUID) (uint32, error) {
+	if index, ok := m.indexes[userID]; ok {
+		return index, nil
+	}
+	index := uint32(len(m.indexes) + 1)
+	m.indexes[userID] = index
+	return index, nil
+}
@@ -0,0 +1,56 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CursorRepository persists how far each chain has been scanned.
+type CursorRepository interface {
+	Get(ctx context.Context, chain string) (*models.ChainCursor, error)
+	Save(ctx context.Context, chain string, lastBlock uint64) error
+}
+
+type cursorRepository struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewCursorRepository(db *gorm.DB, logger zerolog.Logger) CursorRepository {
+	return &cursorRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *cursorRepository) Get(ctx context.Context, chain string) (*models.ChainCursor, error) {
+	var cursor models.ChainCursor
+	if err := r.db.WithContext(ctx).First(&cursor, "chain = ?", chain).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch chain cursor: %w", err)
+	}
+	return &cursor, nil
+}
+
+func (r *cursorRepository) Save(ctx context.Context, chain string, lastBlock uint64) error {
+	cursor := models.ChainCursor{
+		Chain:     chain,
+		LastBlock: lastBlock,
+		UpdatedAt: time.Now(),
+	}
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&cursor).Error; err != nil {
+		r.logger.Error().Err(err).Str("chain", chain).Msg("failed to save chain cursor")
+		return fmt.Errorf("failed to save chain cursor: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/blockchain"
+	"github.com/thoraf20/loanee/internal/models"
+)
+
+// TrackerOptions tunes the confirmation tracker.
+type TrackerOptions struct {
+	PollInterval time.Duration
+	// FinalityDepth is the confirmation count, per asset, after which a
+	// deposit is treated as irreversible. Assets without an entry use
+	// DefaultFinalityDepth.
+	FinalityDepth        map[string]int64
+	DefaultFinalityDepth int64
+	Tolerance            float64
+}
+
+// Tracker follows locked collateral until its deposit is final. Each pass it
+// re-reads the deposit's receipt and block: a deposit that moved to another
+// block is re-anchored, one that vanished or reverted invalidates the
+// collateral, and one deep enough is marked final so the loan can disburse.
+type Tracker struct {
+	verifier    blockchain.Verifier
+	collaterals Collaterals
+	wallets     DepositAddresses
+	opts        TrackerOptions
+	logger      zerolog.Logger
+}
+
+func NewTracker(verifier blockchain.Verifier, collaterals Collaterals, wallets DepositAddresses, opts TrackerOptions, logger zerolog.Logger) *Tracker {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 30 * time.Second
+	}
+	if opts.DefaultFinalityDepth <= 0 {
+		opts.DefaultFinalityDepth = 12
+	}
+
+	finality := make(map[string]int64, len(opts.FinalityDepth))
+	for asset, depth := range opts.FinalityDepth {
+		finality[strings.ToUpper(asset)] = depth
+	}
+	opts.FinalityDepth = finality
+
+	return &Tracker{
+		verifier:    verifier,
+		collaterals: collaterals,
+		wallets:     wallets,
+		opts:        opts,
+		logger:      logger.With().Str("component", "confirmation_tracker").Logger(),
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.opts.PollInterval)
+	defer ticker.Stop()
+
+	t.logger.Info().Dur("interval", t.opts.PollInterval).Msg("Confirmation tracker started")
+
+	for {
+		t.Tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			t.logger.Info().Msg("Confirmation tracker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick re-checks every locked collateral that has not reached finality.
+func (t *Tracker) Tick(ctx context.Context) {
+	locked, err := t.collaterals.ListByStatus(ctx, models.StatusActive, models.StatusReleaseRequested, models.StatusReleasing)
+	if err != nil {
+		t.logger.Warn().Err(err).Msg("Failed to list locked collaterals")
+		return
+	}
+
+	for _, col := range locked {
+		if ctx.Err() != nil {
+			return
+		}
+		if col.FinalizedAt != nil || col.TxHash == nil {
+			continue
+		}
+		if err := t.track(ctx, &col); err != nil {
+			t.logger.Warn().Err(err).Str("collateral_id", col.ID.String()).Msg("Failed to track collateral")
+		}
+	}
+}
+
+func (t *Tracker) track(ctx context.Context, col *models.Collateral) error {
+	valid, data, err := recheck(ctx, t.verifier, t.wallets, col, t.opts.Tolerance)
+	switch {
+	case droppedFromChain(err):
+		return invalidate(ctx, t.collaterals, col, err, t.logger)
+	case errors.Is(err, blockchain.ErrInsufficientConfirmations):
+		// Re-included in a newer block or back in the mempool; keep waiting.
+		t.logger.Warn().Err(err).Str("collateral_id", col.ID.String()).Msg("Collateral deposit lost confirmations")
+		return nil
+	case err != nil:
+		return err
+	case !valid:
+		return nil
+	}
+
+	changed := false
+	if data.BlockHash != "" && (col.BlockHash == nil || *col.BlockHash != data.BlockHash) {
+		if col.BlockHash != nil {
+			t.logger.Warn().
+				Str("collateral_id", col.ID.String()).
+				Str("previous_block", *col.BlockHash).
+				Str("block", data.BlockHash).
+				Msg("Collateral deposit moved to a different block")
+		}
+		col.BlockNumber = &data.BlockNumber
+		col.BlockHash = &data.BlockHash
+		changed = true
+	}
+
+	if data.Confirmations >= t.finalityDepth(col.AssetSymbol) {
+		now := time.Now()
+		col.FinalizedAt = &now
+		changed = true
+		t.logger.Info().Str("collateral_id", col.ID.String()).Int64("confirmations", data.Confirmations).Msg("Collateral deposit finalized")
+	}
+
+	if !changed {
+		return nil
+	}
+	return t.collaterals.Update(ctx, col)
+}
+
+func (t *Tracker) finalityDepth(asset string) int64 {
+	if depth, ok := t.opts.FinalityDepth[strings.ToUpper(asset)]; ok && depth > 0 {
+		return depth
+	}
+	return t.opts.DefaultFinalityDepth
+}
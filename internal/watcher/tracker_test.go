@@ -0,0 +1,87 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/internal/blockchain"
+	"github.com/thoraf20/loanee/internal/models"
+)
+
+func TestTrackerFinalizesAfterDepth(t *testing.T) {
+	collaterals := newFakeCollaterals()
+	col := lockedCollateral(collaterals, "BTC", "0xoriginal")
+
+	verifier := &fakeVerifier{data: blockchain.TransactionData{Confirmations: 3, BlockNumber: 100, BlockHash: "0xoriginal"}}
+	tracker := NewTracker(verifier, collaterals, fakeWallets{}, TrackerOptions{
+		FinalityDepth: map[string]int64{"btc": 6},
+	}, zerolog.Nop())
+
+	tracker.Tick(context.Background())
+	require.Nil(t, collaterals.get(col.ID).FinalizedAt)
+
+	// The deposit was reorganised into a different block but survived.
+	verifier.data = blockchain.TransactionData{Confirmations: 6, BlockNumber: 101, BlockHash: "0xreplacement"}
+	tracker.Tick(context.Background())
+
+	tracked := collaterals.get(col.ID)
+	require.Equal(t, models.StatusActive, tracked.Status)
+	require.Equal(t, "0xreplacement", *tracked.BlockHash)
+	require.Equal(t, uint64(101), *tracked.BlockNumber)
+	require.NotNil(t, tracked.FinalizedAt)
+
+	// Final collateral is no longer re-checked.
+	verifier.last = blockchain.Expectation{}
+	tracker.Tick(context.Background())
+	require.Empty(t, verifier.last.TxHash)
+}
+
+func TestTrackerInvalidatesDroppedDeposit(t *testing.T) {
+	for name, cause := range map[string]error{
+		"vanished": fmt.Errorf("%w: 0xabc", blockchain.ErrTransactionNotFound),
+		"reverted": blockchain.ErrTransactionFailed,
+	} {
+		t.Run(name, func(t *testing.T) {
+			collaterals := newFakeCollaterals()
+			col := lockedCollateral(collaterals, "ETH", "0xoriginal")
+
+			tracker := NewTracker(&fakeVerifier{err: cause}, collaterals, fakeWallets{}, TrackerOptions{}, zerolog.Nop())
+			tracker.Tick(context.Background())
+
+			tracked := collaterals.get(col.ID)
+			require.Equal(t, models.StatusInvalidated, tracked.Status)
+			require.NotNil(t, tracked.InvalidatedAt)
+			require.Nil(t, tracked.FinalizedAt)
+		})
+	}
+}
+
+func TestTrackerWaitsWhileConfirmationsRebuild(t *testing.T) {
+	collaterals := newFakeCollaterals()
+	col := lockedCollateral(collaterals, "ETH", "0xoriginal")
+
+	verifier := &fakeVerifier{err: fmt.Errorf("%w: transaction is still pending", blockchain.ErrInsufficientConfirmations)}
+	tracker := NewTracker(verifier, collaterals, fakeWallets{}, TrackerOptions{}, zerolog.Nop())
+	tracker.Tick(context.Background())
+
+	tracked := collaterals.get(col.ID)
+	require.Equal(t, models.StatusActive, tracked.Status)
+	require.Equal(t, "0xoriginal", *tracked.BlockHash)
+	require.Nil(t, tracked.FinalizedAt)
+}
+
+func lockedCollateral(collaterals *fakeCollaterals, asset, blockHash string) *models.Collateral {
+	col := collaterals.add(uuid.New(), asset, 1, time.Now())
+	txHash := "0xabc"
+	block := uint64(100)
+	col.TxHash = &txHash
+	col.BlockNumber = &block
+	col.BlockHash = &blockHash
+	col.Status = models.StatusActive
+	return col
+}
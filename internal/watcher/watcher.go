@@ -0,0 +1,330 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/blockchain"
+	"github.com/thoraf20/loanee/internal/models"
+)
+
+// Collaterals is the subset of collateral persistence the watcher drives.
+type Collaterals interface {
+	ListByStatus(ctx context.Context, statuses ...models.CollateralStatus) ([]models.Collateral, error)
+	GetByTxHash(ctx context.Context, txHash string) (*models.Collateral, error)
+	Update(ctx context.Context, collateral *models.Collateral) error
+}
+
+// DepositAddresses resolves the platform deposit address assigned to a user.
+type DepositAddresses interface {
+	GetOrCreatePrimary(ctx context.Context, userID uuid.UUID, asset string) (*models.Wallet, error)
+}
+
+// Options tunes the watcher loop.
+type Options struct {
+	PollInterval time.Duration
+	// MaxBlocksPerScan bounds how far a single pass reads, so catching up
+	// after downtime happens in small steps.
+	MaxBlocksPerScan uint64
+	// Tolerance is the fraction of the requested amount a deposit may fall
+	// short by and still be matched.
+	Tolerance float64
+}
+
+// Watcher scans chains for deposits to users' deposit addresses, attaches
+// them to pending collateral requests and activates the collateral once the
+// deposit has enough confirmations. Deposits that vanish before activation
+// are invalidated.
+type Watcher struct {
+	scanners    []blockchain.Scanner
+	verifier    blockchain.Verifier
+	collaterals Collaterals
+	wallets     DepositAddresses
+	cursors     CursorRepository
+	opts        Options
+	logger      zerolog.Logger
+}
+
+func New(scanners []blockchain.Scanner, verifier blockchain.Verifier, collaterals Collaterals, wallets DepositAddresses, cursors CursorRepository, opts Options, logger zerolog.Logger) *Watcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 15 * time.Second
+	}
+	if opts.MaxBlocksPerScan == 0 {
+		opts.MaxBlocksPerScan = 100
+	}
+
+	return &Watcher{
+		scanners:    scanners,
+		verifier:    verifier,
+		collaterals: collaterals,
+		wallets:     wallets,
+		cursors:     cursors,
+		opts:        opts,
+		logger:      logger.With().Str("component", "deposit_watcher").Logger(),
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	w.logger.Info().Dur("interval", w.opts.PollInterval).Int("chains", len(w.scanners)).Msg("Deposit watcher started")
+
+	for {
+		w.Tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info().Msg("Deposit watcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick runs one scan of every chain followed by one promotion pass.
+func (w *Watcher) Tick(ctx context.Context) {
+	for _, scanner := range w.scanners {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := w.scan(ctx, scanner); err != nil {
+			w.logger.Warn().Err(err).Str("chain", scanner.Chain()).Msg("Failed to scan chain")
+		}
+	}
+
+	if err := w.promote(ctx); err != nil {
+		w.logger.Warn().Err(err).Msg("Failed to promote confirmed collaterals")
+	}
+}
+
+// scan reads the next block range of a chain and attaches matching deposits
+// to pending collaterals. The cursor only advances once the range is handled.
+func (w *Watcher) scan(ctx context.Context, scanner blockchain.Scanner) error {
+	chain := scanner.Chain()
+
+	head, err := scanner.LatestBlock(ctx)
+	if err != nil {
+		return err
+	}
+
+	cursor, err := w.cursors.Get(ctx, chain)
+	if err != nil {
+		return err
+	}
+
+	// A chain seen for the first time starts at the tip.
+	from := head
+	if cursor != nil {
+		from = cursor.LastBlock + 1
+	}
+	if from > head {
+		return nil
+	}
+	to := min(head, from+w.opts.MaxBlocksPerScan-1)
+
+	candidates, addresses, err := w.pendingByAddress(ctx, chain, scanner.Assets())
+	if err != nil {
+		return err
+	}
+
+	if len(addresses) > 0 {
+		deposits, err := scanner.ScanDeposits(ctx, from, to, addresses)
+		if err != nil {
+			return err
+		}
+		for _, deposit := range deposits {
+			key := depositKey(deposit.To, deposit.AssetSymbol)
+			if err := w.match(ctx, chain, deposit, candidates[key]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return w.cursors.Save(ctx, chain, to)
+}
+
+// pendingByAddress groups pending collaterals for the given assets on chain
+// by the owner's deposit address, oldest request first. Requests that did not
+// name a network are matched on whichever chain the deposit arrives.
+func (w *Watcher) pendingByAddress(ctx context.Context, chain string, assets []string) (map[string][]*models.Collateral, []string, error) {
+	supported := make(map[string]struct{}, len(assets))
+	for _, asset := range assets {
+		supported[strings.ToUpper(asset)] = struct{}{}
+	}
+
+	pending, err := w.collaterals.ListByStatus(ctx, models.StatusPending)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candidates := make(map[string][]*models.Collateral)
+	seen := make(map[string]struct{})
+	var addresses []string
+	for i := range pending {
+		col := &pending[i]
+		if _, ok := supported[strings.ToUpper(col.AssetSymbol)]; !ok {
+			continue
+		}
+		if col.Network != "" && !strings.EqualFold(col.Network, chain) {
+			continue
+		}
+
+		wallet, err := w.wallets.GetOrCreatePrimary(ctx, col.UserID, col.AssetSymbol)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve deposit address: %w", err)
+		}
+
+		key := depositKey(wallet.Address, col.AssetSymbol)
+		candidates[key] = append(candidates[key], col)
+		if _, ok := seen[wallet.Address]; !ok {
+			seen[wallet.Address] = struct{}{}
+			addresses = append(addresses, wallet.Address)
+		}
+	}
+	return candidates, addresses, nil
+}
+
+// match attaches a deposit to the oldest pending collateral it covers and
+// pins the collateral to the chain the deposit arrived on.
+func (w *Watcher) match(ctx context.Context, chain string, deposit blockchain.Deposit, candidates []*models.Collateral) error {
+	txHash := strings.ToLower(deposit.TxHash)
+
+	existing, err := w.collaterals.GetByTxHash(ctx, txHash)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return nil
+	}
+
+	for _, col := range candidates {
+		if col.Status != models.StatusPending {
+			continue
+		}
+		if deposit.Amount < col.AssetAmount*(1-w.opts.Tolerance) {
+			continue
+		}
+
+		col.TxHash = &txHash
+		col.WalletAddress = &deposit.From
+		col.Network = chain
+		col.Status = models.StatusConfirmed
+		if err := w.collaterals.Update(ctx, col); err != nil {
+			return err
+		}
+
+		w.logger.Info().
+			Str("collateral_id", col.ID.String()).
+			Str("tx_hash", txHash).
+			Str("chain", chain).
+			Str("asset", deposit.AssetSymbol).
+			Float64("amount", deposit.Amount).
+			Msg("Deposit matched to pending collateral")
+		return nil
+	}
+
+	w.logger.Warn().
+		Str("tx_hash", txHash).
+		Str("to", deposit.To).
+		Str("asset", deposit.AssetSymbol).
+		Float64("amount", deposit.Amount).
+		Msg("Deposit does not cover any pending collateral")
+	return nil
+}
+
+// promote re-verifies confirmed collaterals and activates those whose deposit
+// has reached the required confirmation depth.
+func (w *Watcher) promote(ctx context.Context) error {
+	confirmed, err := w.collaterals.ListByStatus(ctx, models.StatusConfirmed)
+	if err != nil {
+		return err
+	}
+
+	for _, col := range confirmed {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if col.TxHash == nil {
+			continue
+		}
+
+		valid, data, err := recheck(ctx, w.verifier, w.wallets, &col, w.opts.Tolerance)
+		switch {
+		case errors.Is(err, blockchain.ErrInsufficientConfirmations):
+			continue
+		case droppedFromChain(err):
+			if err := invalidate(ctx, w.collaterals, &col, err, w.logger); err != nil {
+				return err
+			}
+			continue
+		case err != nil || !valid:
+			w.logger.Warn().Err(err).Str("collateral_id", col.ID.String()).Msg("Confirmed deposit failed verification")
+			continue
+		}
+
+		col.Status = models.StatusActive
+		if data != nil && data.BlockHash != "" {
+			col.BlockNumber = &data.BlockNumber
+			col.BlockHash = &data.BlockHash
+		}
+		if err := w.collaterals.Update(ctx, &col); err != nil {
+			return err
+		}
+		w.logger.Info().Str("collateral_id", col.ID.String()).Msg("Collateral activated")
+	}
+	return nil
+}
+
+// recheck verifies the deposit attached to a collateral against the chain.
+func recheck(ctx context.Context, verifier blockchain.Verifier, wallets DepositAddresses, col *models.Collateral, tolerance float64) (bool, *blockchain.TransactionData, error) {
+	wallet, err := wallets.GetOrCreatePrimary(ctx, col.UserID, col.AssetSymbol)
+	if err != nil {
+		return false, nil, fmt.Errorf("failed to resolve deposit address: %w", err)
+	}
+
+	exp := blockchain.Expectation{
+		TxHash:      *col.TxHash,
+		AssetSymbol: col.AssetSymbol,
+		Network:     col.Network,
+		Amount:      col.AssetAmount,
+		Recipient:   wallet.Address,
+		Tolerance:   tolerance,
+	}
+	if col.WalletAddress != nil {
+		exp.Sender = *col.WalletAddress
+	}
+	return verifier.VerifyTransaction(ctx, exp)
+}
+
+// droppedFromChain reports whether a verification error means the deposit is
+// no longer a successful transaction on the canonical chain.
+func droppedFromChain(err error) bool {
+	return errors.Is(err, blockchain.ErrTransactionNotFound) || errors.Is(err, blockchain.ErrTransactionFailed)
+}
+
+func invalidate(ctx context.Context, collaterals Collaterals, col *models.Collateral, cause error, logger zerolog.Logger) error {
+	now := time.Now()
+	col.Status = models.StatusInvalidated
+	col.InvalidatedAt = &now
+	if err := collaterals.Update(ctx, col); err != nil {
+		return err
+	}
+
+	logger.Error().
+		Err(cause).
+		Str("collateral_id", col.ID.String()).
+		Str("tx_hash", *col.TxHash).
+		Msg("Collateral deposit dropped from chain, collateral invalidated")
+	return nil
+}
+
+func depositKey(address, asset string) string {
+	return strings.ToLower(address) + "/" + strings.ToUpper(asset)
+}
@@ -0,0 +1,282 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/internal/blockchain"
+	"github.com/thoraf20/loanee/internal/models"
+)
+
+func TestWatcherDrivesPendingToActive(t *testing.T) {
+	scanner := &fakeScanner{head: 100}
+	verifier := &fakeVerifier{err: fmt.Errorf("%w: transaction has only 1 confirmations", blockchain.ErrInsufficientConfirmations)}
+	collaterals := newFakeCollaterals()
+	cursors := &memoryCursors{}
+	w := New([]blockchain.Scanner{scanner}, verifier, collaterals, fakeWallets{}, cursors, Options{Tolerance: 0.01}, zerolog.Nop())
+
+	// The first pass only records the tip.
+	w.Tick(context.Background())
+	require.Equal(t, uint64(100), cursors.blocks["ethereum"])
+	require.Empty(t, scanner.scanned)
+
+	userID := uuid.New()
+	older := collaterals.add(userID, "ETH", 2, time.Now().Add(-time.Hour))
+	newer := collaterals.add(userID, "ETH", 1, time.Now())
+	other := collaterals.add(uuid.New(), "ETH", 1, time.Now())
+
+	scanner.head = 102
+	scanner.deposits = []blockchain.Deposit{
+		// Underpaid relative to every pending request of the other user.
+		{TxHash: "0xAAA", AssetSymbol: "ETH", From: "0xsender", To: depositAddress(other.UserID, "ETH"), Amount: 0.5, BlockNumber: 101},
+		// Covers only the newer request once the tolerance is applied.
+		{TxHash: "0xBBB", AssetSymbol: "ETH", From: "0xsender", To: depositAddress(userID, "ETH"), Amount: 0.995, BlockNumber: 102},
+	}
+	w.Tick(context.Background())
+
+	require.Equal(t, [][2]uint64{{101, 102}}, scanner.scanned)
+	require.Equal(t, uint64(102), cursors.blocks["ethereum"])
+	require.Equal(t, models.StatusPending, collaterals.get(older.ID).Status)
+	require.Equal(t, models.StatusPending, collaterals.get(other.ID).Status)
+
+	matched := collaterals.get(newer.ID)
+	require.Equal(t, models.StatusConfirmed, matched.Status)
+	require.Equal(t, "0xbbb", *matched.TxHash)
+	require.Equal(t, "0xsender", *matched.WalletAddress)
+	require.Equal(t, "ethereum", matched.Network)
+
+	// Not deep enough yet: stays confirmed.
+	require.Equal(t, depositAddress(userID, "ETH"), verifier.last.Recipient)
+	require.Equal(t, "0xsender", verifier.last.Sender)
+
+	verifier.err = nil
+	verifier.data = blockchain.TransactionData{Confirmations: 3, BlockNumber: 102, BlockHash: "0xblock"}
+	scanner.deposits = nil
+	w.Tick(context.Background())
+
+	activated := collaterals.get(newer.ID)
+	require.Equal(t, models.StatusActive, activated.Status)
+	require.Equal(t, "0xblock", *activated.BlockHash)
+	require.Nil(t, activated.FinalizedAt)
+}
+
+func TestWatcherInvalidatesVanishedDeposit(t *testing.T) {
+	collaterals := newFakeCollaterals()
+	col := collaterals.add(uuid.New(), "ETH", 1, time.Now())
+	txHash := "0xddd"
+	col.TxHash = &txHash
+	col.Status = models.StatusConfirmed
+
+	verifier := &fakeVerifier{err: fmt.Errorf("%w: 0xddd", blockchain.ErrTransactionNotFound)}
+	w := New(nil, verifier, collaterals, fakeWallets{}, &memoryCursors{}, Options{}, zerolog.Nop())
+	w.Tick(context.Background())
+
+	require.Equal(t, models.StatusInvalidated, collaterals.get(col.ID).Status)
+	require.NotNil(t, collaterals.get(col.ID).InvalidatedAt)
+}
+
+func TestWatcherResumesFromCursor(t *testing.T) {
+	scanner := &fakeScanner{head: 500}
+	collaterals := newFakeCollaterals()
+	collaterals.add(uuid.New(), "ETH", 1, time.Now())
+	cursors := &memoryCursors{blocks: map[string]uint64{"ethereum": 200}}
+
+	w := New([]blockchain.Scanner{scanner}, &fakeVerifier{}, collaterals, fakeWallets{}, cursors, Options{MaxBlocksPerScan: 50}, zerolog.Nop())
+	w.Tick(context.Background())
+	w.Tick(context.Background())
+
+	require.Equal(t, [][2]uint64{{201, 250}, {251, 300}}, scanner.scanned)
+	require.Equal(t, uint64(300), cursors.blocks["ethereum"])
+
+	// A failed scan leaves the cursor where it was.
+	scanner.err = fmt.Errorf("rpc unavailable")
+	w.Tick(context.Background())
+	require.Equal(t, uint64(300), cursors.blocks["ethereum"])
+}
+
+func TestWatcherIgnoresAttachedTransactions(t *testing.T) {
+	scanner := &fakeScanner{head: 10}
+	collaterals := newFakeCollaterals()
+	cursors := &memoryCursors{blocks: map[string]uint64{"ethereum": 9}}
+
+	userID := uuid.New()
+	locked := collaterals.add(userID, "ETH", 1, time.Now())
+	txHash := "0xccc"
+	locked.TxHash = &txHash
+	locked.Status = models.StatusActive
+	pending := collaterals.add(userID, "ETH", 1, time.Now())
+
+	scanner.deposits = []blockchain.Deposit{
+		{TxHash: "0xCCC", AssetSymbol: "ETH", To: depositAddress(userID, "ETH"), Amount: 1, BlockNumber: 10},
+	}
+
+	w := New([]blockchain.Scanner{scanner}, &fakeVerifier{}, collaterals, fakeWallets{}, cursors, Options{}, zerolog.Nop())
+	w.Tick(context.Background())
+
+	require.Equal(t, models.StatusPending, collaterals.get(pending.ID).Status)
+}
+
+func TestWatcherHonoursRequestedNetwork(t *testing.T) {
+	ethereum := &fakeScanner{head: 10}
+	bsc := &fakeScanner{chain: "bsc", head: 10}
+	collaterals := newFakeCollaterals()
+	cursors := &memoryCursors{blocks: map[string]uint64{"ethereum": 9, "bsc": 9}}
+
+	userID := uuid.New()
+	pinned := collaterals.add(userID, "USDT", 100, time.Now())
+	pinned.Network = "bsc"
+
+	to := depositAddress(userID, "USDT")
+	ethereum.deposits = []blockchain.Deposit{{TxHash: "0xeth", AssetSymbol: "USDT", From: "0xsender", To: to, Amount: 100, BlockNumber: 10}}
+	bsc.deposits = []blockchain.Deposit{{TxHash: "0xbsc", AssetSymbol: "USDT", From: "0xsender", To: to, Amount: 100, BlockNumber: 10}}
+
+	w := New([]blockchain.Scanner{ethereum, bsc}, &fakeVerifier{err: blockchain.ErrInsufficientConfirmations}, collaterals, fakeWallets{}, cursors, Options{}, zerolog.Nop())
+	w.Tick(context.Background())
+
+	// The same transfer on Ethereum is ignored; only the BSC deposit matches.
+	matched := collaterals.get(pinned.ID)
+	require.Equal(t, models.StatusConfirmed, matched.Status)
+	require.Equal(t, "0xbsc", *matched.TxHash)
+	require.Equal(t, "bsc", matched.Network)
+}
+
+func depositAddress(userID uuid.UUID, asset string) string {
+	return "0xdeposit-" + asset + "-" + userID.String()
+}
+
+type fakeScanner struct {
+	chain    string
+	head     uint64
+	deposits []blockchain.Deposit
+	scanned  [][2]uint64
+	err      error
+}
+
+func (f *fakeScanner) Chain() string {
+	if f.chain == "" {
+		return "ethereum"
+	}
+	return f.chain
+}
+
+func (f *fakeScanner) Assets() []string { return []string{"ETH", "USDT"} }
+
+func (f *fakeScanner) LatestBlock(ctx context.Context) (uint64, error) {
+	return f.head, nil
+}
+
+func (f *fakeScanner) ScanDeposits(ctx context.Context, from, to uint64, addresses []string) ([]blockchain.Deposit, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.scanned = append(f.scanned, [2]uint64{from, to})
+	return f.deposits, nil
+}
+
+type fakeVerifier struct {
+	last blockchain.Expectation
+	data blockchain.TransactionData
+	err  error
+}
+
+func (f *fakeVerifier) VerifyTransaction(ctx context.Context, exp blockchain.Expectation) (bool, *blockchain.TransactionData, error) {
+	f.last = exp
+	if f.err != nil {
+		return false, nil, f.err
+	}
+	data := f.data
+	data.Hash = exp.TxHash
+	return true, &data, nil
+}
+
+type fakeWallets struct{}
+
+func (fakeWallets) GetOrCreatePrimary(ctx context.Context, userID uuid.UUID, asset string) (*models.Wallet, error) {
+	return &models.Wallet{UserID: userID, AssetType: asset, Address: depositAddress(userID, asset)}, nil
+}
+
+type memoryCursors struct {
+	blocks map[string]uint64
+}
+
+func (m *memoryCursors) Get(ctx context.Context, chain string) (*models.ChainCursor, error) {
+	block, ok := m.blocks[chain]
+	if !ok {
+		return nil, nil
+	}
+	return &models.ChainCursor{Chain: chain, LastBlock: block}, nil
+}
+
+func (m *memoryCursors) Save(ctx context.Context, chain string, lastBlock uint64) error {
+	if m.blocks == nil {
+		m.blocks = make(map[string]uint64)
+	}
+	m.blocks[chain] = lastBlock
+	return nil
+}
+
+type fakeCollaterals struct {
+	store []*models.Collateral
+}
+
+func newFakeCollaterals() *fakeCollaterals {
+	return &fakeCollaterals{}
+}
+
+func (f *fakeCollaterals) add(userID uuid.UUID, asset string, amount float64, createdAt time.Time) *models.Collateral {
+	col := &models.Collateral{
+		ID:          uuid.New(),
+		UserID:      userID,
+		AssetSymbol: asset,
+		AssetAmount: amount,
+		Status:      models.StatusPending,
+		CreatedAt:   createdAt,
+	}
+	f.store = append(f.store, col)
+	return col
+}
+
+func (f *fakeCollaterals) get(id uuid.UUID) *models.Collateral {
+	for _, col := range f.store {
+		if col.ID == id {
+			return col
+		}
+	}
+	return nil
+}
+
+func (f *fakeCollaterals) ListByStatus(ctx context.Context, statuses ...models.CollateralStatus) ([]models.Collateral, error) {
+	var result []models.Collateral
+	for _, col := range f.store {
+		for _, status := range statuses {
+			if col.Status == status {
+				result = append(result, *col)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (f *fakeCollaterals) GetByTxHash(ctx context.Context, txHash string) (*models.Collateral, error) {
+	for _, col := range f.store {
+		if col.TxHash != nil && *col.TxHash == txHash {
+			copy := *col
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeCollaterals) Update(ctx context.Context, collateral *models.Collateral) error {
+	col := f.get(collateral.ID)
+	if col == nil {
+		return fmt.Errorf("collateral %s not found", collateral.ID)
+	}
+	*col = *collateral
+	return nil
+}
@@ -0,0 +1,58 @@
+package withdrawal
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/utils"
+)
+
+type Handler struct {
+	service *Service
+	logger  zerolog.Logger
+}
+
+func NewHandler(service *Service, logger zerolog.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger.With().Str("component", "withdrawal_handler").Logger(),
+	}
+}
+
+func (h *Handler) AdminList(c *gin.Context) {
+	withdrawals, err := h.service.ListAll(c.Request.Context())
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list withdrawals")
+		utils.InternalServerError(c, "failed to fetch withdrawals", err.Error())
+		return
+	}
+
+	utils.OK(c, "withdrawals retrieved", withdrawals)
+}
+
+// AdminBump replaces a stuck withdrawal transaction with a higher fee.
+func (h *Handler) AdminBump(c *gin.Context) {
+	withdrawalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.BadRequest(c, "invalid withdrawal id", err.Error())
+		return
+	}
+
+	withdrawal, err := h.service.Bump(c.Request.Context(), withdrawalID)
+	if err != nil {
+		h.logger.Error().Err(err).Any("withdrawal_id", withdrawalID).Msg("failed to bump withdrawal")
+		switch {
+		case errors.Is(err, ErrWithdrawalNotFound):
+			utils.NotFound(c, "withdrawal not found")
+		case errors.Is(err, ErrNotInFlight), errors.Is(err, ErrFeeCapReached):
+			utils.Conflict(c, "failed to bump withdrawal", err.Error())
+		default:
+			utils.InternalServerError(c, "failed to bump withdrawal", err.Error())
+		}
+		return
+	}
+
+	utils.OK(c, "withdrawal fee bumped", withdrawal)
+}
@@ -0,0 +1,195 @@
+package withdrawal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	Create(ctx context.Context, withdrawal *models.Withdrawal) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Withdrawal, error)
+	GetOpenByCollateral(ctx context.Context, collateralID uuid.UUID) (*models.Withdrawal, error)
+	ListAll(ctx context.Context) ([]models.Withdrawal, error)
+	ListByStatus(ctx context.Context, statuses ...models.WithdrawalStatus) ([]models.Withdrawal, error)
+	Update(ctx context.Context, withdrawal *models.Withdrawal) error
+	// RecordTx stores a signed transaction and the withdrawal state that
+	// references it in one database transaction, so a crash between the two
+	// cannot leave a broadcast nobody tracks.
+	RecordTx(ctx context.Context, withdrawal *models.Withdrawal, tx *models.WithdrawalTx) error
+	// RecordFirstTx records a withdrawal's first transaction at the next
+	// nonce of its hot wallet: one past the highest nonce recorded for the
+	// wallet, or pending, the chain's pending nonce, if that is higher. sign
+	// builds the transaction for that nonce. The hot wallet row stays locked
+	// until the transaction is stored, so no two withdrawals share a nonce.
+	RecordFirstTx(ctx context.Context, withdrawal *models.Withdrawal, hotWallet string, pending uint64, sign func(nonce uint64) (*models.WithdrawalTx, error)) error
+	ListTxs(ctx context.Context, withdrawalID uuid.UUID) ([]models.WithdrawalTx, error)
+}
+
+type repository struct {
+	db     *gorm.DB
+	logger zerolog.Logger
+}
+
+func NewRepository(db *gorm.DB, logger zerolog.Logger) Repository {
+	return &repository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *repository) Create(ctx context.Context, withdrawal *models.Withdrawal) error {
+	now := time.Now()
+	if withdrawal.ID == uuid.Nil {
+		withdrawal.ID = uuid.New()
+	}
+	withdrawal.CreatedAt = now
+	withdrawal.UpdatedAt = now
+
+	if err := r.db.WithContext(ctx).Create(withdrawal).Error; err != nil {
+		return fmt.Errorf("failed to create withdrawal: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*models.Withdrawal, error) {
+	var withdrawal models.Withdrawal
+	if err := r.db.WithContext(ctx).First(&withdrawal, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch withdrawal: %w", err)
+	}
+	return &withdrawal, nil
+}
+
+// GetOpenByCollateral returns the collateral's withdrawal that is still
+// pending, in flight or held for review, if any.
+func (r *repository) GetOpenByCollateral(ctx context.Context, collateralID uuid.UUID) (*models.Withdrawal, error) {
+	var withdrawal models.Withdrawal
+	if err := r.db.WithContext(ctx).
+		Where("collateral_id = ? AND status IN ?", collateralID, []models.WithdrawalStatus{models.WithdrawalPending, models.WithdrawalBroadcast, models.WithdrawalReview}).
+		First(&withdrawal).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch withdrawal: %w", err)
+	}
+	return &withdrawal, nil
+}
+
+func (r *repository) ListAll(ctx context.Context) ([]models.Withdrawal, error) {
+	var withdrawals []models.Withdrawal
+	if err := r.db.WithContext(ctx).Order("created_at DESC").Find(&withdrawals).Error; err != nil {
+		return nil, fmt.Errorf("failed to list withdrawals: %w", err)
+	}
+	return withdrawals, nil
+}
+
+func (r *repository) ListByStatus(ctx context.Context, statuses ...models.WithdrawalStatus) ([]models.Withdrawal, error) {
+	var withdrawals []models.Withdrawal
+	if err := r.db.WithContext(ctx).
+		Where("status IN ?", statuses).
+		Order("created_at ASC").
+		Find(&withdrawals).Error; err != nil {
+		return nil, fmt.Errorf("failed to list withdrawals: %w", err)
+	}
+	return withdrawals, nil
+}
+
+func (r *repository) Update(ctx context.Context, withdrawal *models.Withdrawal) error {
+	withdrawal.UpdatedAt = time.Now()
+	if err := r.db.WithContext(ctx).Save(withdrawal).Error; err != nil {
+		return fmt.Errorf("failed to update withdrawal: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) RecordTx(ctx context.Context, withdrawal *models.Withdrawal, tx *models.WithdrawalTx) error {
+	now := time.Now()
+	if tx.ID == uuid.Nil {
+		tx.ID = uuid.New()
+	}
+	tx.CreatedAt = now
+	withdrawal.UpdatedAt = now
+
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		if err := db.Create(tx).Error; err != nil {
+			return err
+		}
+		return db.Save(withdrawal).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record withdrawal transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) RecordFirstTx(ctx context.Context, withdrawal *models.Withdrawal, hotWallet string, pending uint64, sign func(nonce uint64) (*models.WithdrawalTx, error)) error {
+	var signErr error
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		var wallet models.HotWallet
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("address = ?", hotWallet).
+			First(&wallet).Error; err != nil {
+			return err
+		}
+
+		var last sql.NullInt64
+		if err := db.Model(&models.WithdrawalTx{}).
+			Joins("JOIN withdrawals ON withdrawals.id = withdrawal_txs.withdrawal_id").
+			Where("withdrawals.network = ? AND withdrawals.from_address = ?", withdrawal.Network, common.HexToAddress(hotWallet).Hex()).
+			Select("MAX(withdrawal_txs.nonce)").
+			Scan(&last).Error; err != nil {
+			return err
+		}
+		nonce := pending
+		if last.Valid && uint64(last.Int64) >= nonce {
+			nonce = uint64(last.Int64) + 1
+		}
+
+		tx, err := sign(nonce)
+		if err != nil {
+			signErr = err
+			return err
+		}
+
+		now := time.Now()
+		if tx.ID == uuid.Nil {
+			tx.ID = uuid.New()
+		}
+		tx.CreatedAt = now
+		withdrawal.UpdatedAt = now
+		if err := db.Create(tx).Error; err != nil {
+			return err
+		}
+		return db.Save(withdrawal).Error
+	})
+	if signErr != nil {
+		return signErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record withdrawal transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *repository) ListTxs(ctx context.Context, withdrawalID uuid.UUID) ([]models.WithdrawalTx, error) {
+	var txs []models.WithdrawalTx
+	if err := r.db.WithContext(ctx).
+		Where("withdrawal_id = ?", withdrawalID).
+		Order("created_at ASC").
+		Find(&txs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list withdrawal transactions: %w", err)
+	}
+	return txs, nil
+}
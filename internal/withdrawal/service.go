@@ -0,0 +1,718 @@
+package withdrawal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/thoraf20/loanee/internal/blockchain"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/pkg/keymanager"
+)
+
+var (
+	ErrWithdrawalNotFound   = errors.New("withdrawal not found")
+	ErrWithdrawalInProgress = errors.New("collateral already has a withdrawal in progress")
+	ErrUnsupportedAsset     = errors.New("on-chain release is not supported for this asset")
+	ErrNoDestination        = errors.New("collateral has no valid withdrawal address")
+	ErrNotInFlight          = errors.New("withdrawal has no transaction in flight")
+	ErrFeeCapReached        = errors.New("fee bump would exceed the maximum fee per gas")
+
+	errAmountBelowFee = errors.New("amount does not cover the network fee")
+)
+
+const feeCurrency = "USD"
+
+// Client is the subset of the Ethereum JSON-RPC API used to send and follow
+// withdrawals. Both *ethclient.Client and the simulated backend satisfy it.
+type Client interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	SuggestGasTipCap(ctx context.Context) (*big.Int, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+}
+
+// Network is an EVM network withdrawals can be sent on.
+type Network struct {
+	blockchain.EVMNetwork
+	Client Client
+}
+
+// HotWallets provides the signing wallet for a network.
+type HotWallets interface {
+	ForNetwork(ctx context.Context, network string) (*models.HotWallet, error)
+	PrivateKey(ctx context.Context, address string) (keymanager.Secret, error)
+}
+
+// Collaterals is the subset of collateral persistence withdrawals settle.
+type Collaterals interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Collateral, error)
+	Update(ctx context.Context, collateral *models.Collateral) error
+}
+
+// Prices converts the native-coin network fee into token units.
+type Prices interface {
+	GetPrice(ctx context.Context, symbol, currency string) (float64, error)
+}
+
+// Options tunes the withdrawal worker.
+type Options struct {
+	PollInterval time.Duration
+	// BumpAfter is how long a transaction may sit unmined before it is
+	// replaced with a higher fee.
+	BumpAfter time.Duration
+	// BumpPercent raises both fee caps on replacement; nodes require at
+	// least 10.
+	BumpPercent int64
+	// MaxFeePerGas caps the fee cap in wei; nil leaves it unbounded.
+	MaxFeePerGas *big.Int
+	MaxAttempts  int
+}
+
+// Service returns released collateral on-chain. Approval queues a pending
+// withdrawal; the worker signs it with the network's hot wallet, broadcasts
+// it, bumps the fee while it is stuck, and marks the collateral released
+// once the transfer has the network's confirmation depth. The network fee
+// is deducted from the returned amount.
+type Service struct {
+	repo        Repository
+	networks    []Network
+	hotWallets  HotWallets
+	collaterals Collaterals
+	prices      Prices
+	opts        Options
+	logger      zerolog.Logger
+
+	// mu serialises signing so a manual bump cannot race the worker for
+	// the same nonce.
+	mu sync.Mutex
+}
+
+func NewService(repo Repository, networks []Network, hotWallets HotWallets, collaterals Collaterals, prices Prices, opts Options, logger zerolog.Logger) *Service {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 15 * time.Second
+	}
+	if opts.BumpAfter <= 0 {
+		opts.BumpAfter = 5 * time.Minute
+	}
+	if opts.BumpPercent < 10 {
+		opts.BumpPercent = 20
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+
+	return &Service{
+		repo:        repo,
+		networks:    networks,
+		hotWallets:  hotWallets,
+		collaterals: collaterals,
+		prices:      prices,
+		opts:        opts,
+		logger:      logger.With().Str("component", "withdrawal_service").Logger(),
+	}
+}
+
+// Supports reports whether the worker can pay asset out on network. Assets
+// it cannot carry, BTC among them, are returned by an operator instead.
+func (s *Service) Supports(asset, network string) bool {
+	n, ok := s.networkByName(network)
+	return ok && n.carries(asset)
+}
+
+// Request queues the on-chain return of a collateral approved for release
+// to the borrower's whitelisted destination.
+func (s *Service) Request(ctx context.Context, collateral *models.Collateral, destination *models.WithdrawalAddress) (*models.Withdrawal, error) {
+	existing, err := s.repo.GetOpenByCollateral(ctx, collateral.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrWithdrawalInProgress
+	}
+
+	if destination == nil || !common.IsHexAddress(destination.Address) {
+		return nil, ErrNoDestination
+	}
+	network, ok := s.networkByName(destination.Network)
+	if !ok || !network.carries(collateral.AssetSymbol) {
+		return nil, fmt.Errorf("%w: %s on %s", ErrUnsupportedAsset, collateral.AssetSymbol, destination.Network)
+	}
+
+	withdrawal := &models.Withdrawal{
+		ID:           uuid.New(),
+		CollateralID: collateral.ID,
+		UserID:       collateral.UserID,
+		Network:      network.Name,
+		AssetSymbol:  strings.ToUpper(collateral.AssetSymbol),
+		Amount:       collateral.AssetAmount,
+		ToAddress:    common.HexToAddress(destination.Address).Hex(),
+		Status:       models.WithdrawalPending,
+	}
+	if err := s.repo.Create(ctx, withdrawal); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info().
+		Str("withdrawal_id", withdrawal.ID.String()).
+		Str("collateral_id", collateral.ID.String()).
+		Str("network", network.Name).
+		Msg("Withdrawal queued")
+	return withdrawal, nil
+}
+
+func (s *Service) ListAll(ctx context.Context) ([]models.Withdrawal, error) {
+	return s.repo.ListAll(ctx)
+}
+
+// Run polls until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+
+	s.logger.Info().Dur("interval", s.opts.PollInterval).Int("networks", len(s.networks)).Msg("Withdrawal worker started")
+
+	for {
+		s.Tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info().Msg("Withdrawal worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick broadcasts pending withdrawals and follows those in flight.
+func (s *Service) Tick(ctx context.Context) {
+	open, err := s.repo.ListByStatus(ctx, models.WithdrawalPending, models.WithdrawalBroadcast)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("Failed to list open withdrawals")
+		return
+	}
+
+	for i := range open {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.process(ctx, &open[i]); err != nil {
+			s.logger.Warn().Err(err).Str("withdrawal_id", open[i].ID.String()).Msg("Failed to process withdrawal")
+		}
+	}
+}
+
+// Bump replaces a withdrawal's in-flight transaction with a higher fee now,
+// without waiting for BumpAfter.
+func (s *Service) Bump(ctx context.Context, id uuid.UUID) (*models.Withdrawal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	withdrawal, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if withdrawal == nil {
+		return nil, ErrWithdrawalNotFound
+	}
+	if withdrawal.Status != models.WithdrawalBroadcast {
+		return nil, ErrNotInFlight
+	}
+
+	network, ok := s.networkByName(withdrawal.Network)
+	if !ok {
+		return nil, fmt.Errorf("network %s is not configured", withdrawal.Network)
+	}
+	txs, err := s.repo.ListTxs(ctx, withdrawal.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(txs) == 0 {
+		return nil, ErrNotInFlight
+	}
+
+	if err := s.broadcast(ctx, network, withdrawal, &txs[len(txs)-1]); err != nil {
+		return nil, err
+	}
+	return withdrawal, nil
+}
+
+func (s *Service) process(ctx context.Context, withdrawal *models.Withdrawal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	network, ok := s.networkByName(withdrawal.Network)
+	if !ok {
+		return fmt.Errorf("network %s is not configured", withdrawal.Network)
+	}
+
+	if withdrawal.Status == models.WithdrawalPending {
+		return s.broadcast(ctx, network, withdrawal, nil)
+	}
+	return s.track(ctx, network, withdrawal)
+}
+
+// broadcast signs and sends a withdrawal. With previous set it replaces that
+// transaction at the same nonce with bumped fees; otherwise it takes the hot
+// wallet's next nonce. The signed transaction is persisted before it is
+// sent, so a crash never loses track of a payout.
+func (s *Service) broadcast(ctx context.Context, network Network, withdrawal *models.Withdrawal, previous *models.WithdrawalTx) error {
+	if previous == nil {
+		collateral, err := s.collaterals.GetByID(ctx, withdrawal.CollateralID)
+		if err != nil {
+			return err
+		}
+		if collateral == nil || collateral.Status != models.StatusReleasing {
+			return s.fail(ctx, withdrawal, "collateral is not awaiting release")
+		}
+	}
+
+	hot, err := s.hotWallets.ForNetwork(ctx, network.Name)
+	if err != nil {
+		return err
+	}
+	if hot == nil {
+		return fmt.Errorf("no hot wallet configured for %s", network.Name)
+	}
+	from := common.HexToAddress(hot.Address)
+
+	tip, feeCap, err := s.fees(ctx, network, previous)
+	if err != nil {
+		return err
+	}
+
+	var signed *types.Transaction
+	signAt := func(nonce uint64) (*models.WithdrawalTx, error) {
+		unsigned, net, fee, err := s.build(ctx, network, withdrawal, nonce, tip, feeCap)
+		if err != nil {
+			return nil, err
+		}
+		if signed, err = s.sign(ctx, network, hot.Address, unsigned); err != nil {
+			return nil, err
+		}
+		raw, err := signed.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode transaction: %w", err)
+		}
+
+		now := time.Now()
+		hash := signed.Hash().Hex()
+		withdrawal.FromAddress = from.Hex()
+		withdrawal.Nonce = &nonce
+		withdrawal.TxHash = &hash
+		withdrawal.NetAmount = net
+		withdrawal.FeeAmount = fee
+		withdrawal.Status = models.WithdrawalBroadcast
+		withdrawal.Attempts++
+		withdrawal.BroadcastAt = &now
+		return &models.WithdrawalTx{
+			WithdrawalID: withdrawal.ID,
+			TxHash:       hash,
+			Nonce:        nonce,
+			GasTipCap:    tip.String(),
+			GasFeeCap:    feeCap.String(),
+			RawTx:        raw,
+		}, nil
+	}
+
+	if previous != nil {
+		tx, err := signAt(previous.Nonce)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.RecordTx(ctx, withdrawal, tx); err != nil {
+			return err
+		}
+	} else {
+		// The pending nonce misses transactions recorded but not yet sent,
+		// so the repository also counts every nonce it has handed out.
+		pending, err := network.Client.PendingNonceAt(ctx, from)
+		if err != nil {
+			return fmt.Errorf("failed to fetch nonce: %w", err)
+		}
+		err = s.repo.RecordFirstTx(ctx, withdrawal, hot.Address, pending, signAt)
+		if errors.Is(err, errAmountBelowFee) {
+			return s.fail(ctx, withdrawal, errAmountBelowFee.Error())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	hash := signed.Hash().Hex()
+	if err := network.Client.SendTransaction(ctx, signed); err != nil && !s.sent(ctx, network, signed, err) {
+		// Recorded already; the next pass re-broadcasts or bumps it.
+		s.logger.Warn().Err(err).Str("withdrawal_id", withdrawal.ID.String()).Str("tx_hash", hash).Msg("Failed to broadcast withdrawal")
+		return nil
+	}
+
+	event := s.logger.Info()
+	if previous != nil {
+		event = event.Str("replaces", previous.TxHash)
+	}
+	event.
+		Str("withdrawal_id", withdrawal.ID.String()).
+		Str("tx_hash", hash).
+		Uint64("nonce", signed.Nonce()).
+		Str("gas_fee_cap", feeCap.String()).
+		Msg("Withdrawal broadcast")
+	return nil
+}
+
+// track settles a withdrawal once any of its transactions is mined deep
+// enough, and bumps or re-broadcasts it while none is.
+func (s *Service) track(ctx context.Context, network Network, withdrawal *models.Withdrawal) error {
+	txs, err := s.repo.ListTxs(ctx, withdrawal.ID)
+	if err != nil {
+		return err
+	}
+	if len(txs) == 0 {
+		// Marked broadcast without a recorded transaction; start over.
+		withdrawal.Status = models.WithdrawalPending
+		return s.repo.Update(ctx, withdrawal)
+	}
+
+	if found, err := s.settle(ctx, network, withdrawal, txs); found || err != nil {
+		return err
+	}
+
+	// None of our transactions is known to be mined, but the nonce may be
+	// spent anyway: one of them was mined after the receipts were read, or a
+	// node still indexing hid its receipt. Look again; if still none turns
+	// up, the payout may have happened, so an operator has to check before
+	// the collateral can be paid again.
+	mined, err := network.Client.NonceAt(ctx, common.HexToAddress(withdrawal.FromAddress), nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch nonce: %w", err)
+	}
+	if withdrawal.Nonce != nil && mined > *withdrawal.Nonce {
+		if found, err := s.settle(ctx, network, withdrawal, txs); found || err != nil {
+			return err
+		}
+		return s.review(ctx, withdrawal, "nonce was spent but no receipt was found for any of its transactions")
+	}
+
+	latest := txs[len(txs)-1]
+	if time.Since(latest.CreatedAt) >= s.opts.BumpAfter && withdrawal.Attempts < s.opts.MaxAttempts {
+		err := s.broadcast(ctx, network, withdrawal, &latest)
+		if !errors.Is(err, ErrFeeCapReached) {
+			return err
+		}
+		s.logger.Warn().Str("withdrawal_id", withdrawal.ID.String()).Msg("Withdrawal stuck at the maximum fee")
+	}
+
+	// Nodes drop transactions under load; re-send the newest one.
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(latest.RawTx); err != nil {
+		return fmt.Errorf("failed to decode transaction %s: %w", latest.TxHash, err)
+	}
+	if err := network.Client.SendTransaction(ctx, &tx); err != nil && !s.sent(ctx, network, &tx, err) {
+		return fmt.Errorf("failed to re-broadcast %s: %w", latest.TxHash, err)
+	}
+	return nil
+}
+
+// settle looks for a mined transaction among txs, newest first, and
+// confirms or fails the withdrawal on it. It reports whether one was found.
+func (s *Service) settle(ctx context.Context, network Network, withdrawal *models.Withdrawal, txs []models.WithdrawalTx) (bool, error) {
+	head, err := network.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("could not fetch latest block header: %w", err)
+	}
+
+	for i := len(txs) - 1; i >= 0; i-- {
+		receipt, err := network.Client.TransactionReceipt(ctx, common.HexToHash(txs[i].TxHash))
+		if notMined(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("could not fetch transaction receipt: %w", err)
+		}
+
+		// A reverted transfer moved nothing, so the release can be retried.
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			return true, s.fail(ctx, withdrawal, "transaction "+txs[i].TxHash+" reverted")
+		}
+		confirmations := head.Number.Int64() - receipt.BlockNumber.Int64() + 1
+		if confirmations < int64(network.MinConfirmations) {
+			return true, nil
+		}
+		return true, s.confirm(ctx, withdrawal, txs[i].TxHash)
+	}
+	return false, nil
+}
+
+func (s *Service) confirm(ctx context.Context, withdrawal *models.Withdrawal, txHash string) error {
+	now := time.Now()
+	withdrawal.Status = models.WithdrawalConfirmed
+	withdrawal.TxHash = &txHash
+	withdrawal.ConfirmedAt = &now
+	withdrawal.FailureReason = nil
+	if err := s.repo.Update(ctx, withdrawal); err != nil {
+		return err
+	}
+
+	collateral, err := s.collaterals.GetByID(ctx, withdrawal.CollateralID)
+	if err != nil {
+		return err
+	}
+	if collateral != nil {
+		collateral.Status = models.StatusReleased
+		collateral.ReleaseResolvedAt = &now
+		if err := s.collaterals.Update(ctx, collateral); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info().
+		Str("withdrawal_id", withdrawal.ID.String()).
+		Str("collateral_id", withdrawal.CollateralID.String()).
+		Str("tx_hash", txHash).
+		Msg("Withdrawal confirmed, collateral released")
+	return nil
+}
+
+// fail abandons a withdrawal and hands the collateral back to the release
+// queue so an admin can retry.
+func (s *Service) fail(ctx context.Context, withdrawal *models.Withdrawal, reason string) error {
+	withdrawal.Status = models.WithdrawalFailed
+	withdrawal.FailureReason = &reason
+	if err := s.repo.Update(ctx, withdrawal); err != nil {
+		return err
+	}
+
+	collateral, err := s.collaterals.GetByID(ctx, withdrawal.CollateralID)
+	if err != nil {
+		return err
+	}
+	if collateral != nil && collateral.Status == models.StatusReleasing {
+		note := "withdrawal failed: " + reason
+		collateral.Status = models.StatusReleaseRequested
+		collateral.ReleaseNote = &note
+		if err := s.collaterals.Update(ctx, collateral); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Error().
+		Str("withdrawal_id", withdrawal.ID.String()).
+		Str("collateral_id", withdrawal.CollateralID.String()).
+		Str("reason", reason).
+		Msg("Withdrawal failed")
+	return nil
+}
+
+// review holds a withdrawal whose outcome is unknown for an operator. The
+// collateral stays releasing, so it can't be approved and paid again.
+func (s *Service) review(ctx context.Context, withdrawal *models.Withdrawal, reason string) error {
+	withdrawal.Status = models.WithdrawalReview
+	withdrawal.FailureReason = &reason
+	if err := s.repo.Update(ctx, withdrawal); err != nil {
+		return err
+	}
+
+	s.logger.Error().
+		Str("withdrawal_id", withdrawal.ID.String()).
+		Str("collateral_id", withdrawal.CollateralID.String()).
+		Str("reason", reason).
+		Msg("Withdrawal needs manual review")
+	return nil
+}
+
+// fees picks EIP-1559 fee caps. A replacement raises both caps by at least
+// BumpPercent over the transaction it replaces.
+func (s *Service) fees(ctx context.Context, network Network, previous *models.WithdrawalTx) (*big.Int, *big.Int, error) {
+	tip, err := network.Client.SuggestGasTipCap(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to suggest gas tip: %w", err)
+	}
+	head, err := network.Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not fetch latest block header: %w", err)
+	}
+
+	baseFee := new(big.Int)
+	if head.BaseFee != nil {
+		baseFee.Set(head.BaseFee)
+	}
+	if previous == nil {
+		tip, feeCap := blockchain.FeeCaps(baseFee, tip, s.opts.MaxFeePerGas)
+		return tip, feeCap, nil
+	}
+
+	// A replacement that would need more than the limit is not sent.
+	tip, feeCap := blockchain.FeeCaps(baseFee, tip, nil)
+	prevTip, _ := new(big.Int).SetString(previous.GasTipCap, 10)
+	prevCap, _ := new(big.Int).SetString(previous.GasFeeCap, 10)
+	if prevTip == nil || prevCap == nil {
+		return nil, nil, fmt.Errorf("transaction %s has unreadable fees", previous.TxHash)
+	}
+	tip = bigMax(tip, s.bump(prevTip))
+	feeCap = bigMax(bigMax(feeCap, s.bump(prevCap)), tip)
+
+	if limit := s.opts.MaxFeePerGas; limit != nil && feeCap.Cmp(limit) > 0 {
+		return nil, nil, ErrFeeCapReached
+	}
+	return tip, feeCap, nil
+}
+
+func (s *Service) bump(value *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(value, big.NewInt(100+s.opts.BumpPercent))
+	bumped.Div(bumped, big.NewInt(100))
+	// Tiny values would not move at all after integer division.
+	if bumped.Cmp(value) <= 0 {
+		bumped.Add(value, big.NewInt(1))
+	}
+	return bumped
+}
+
+// build assembles the unsigned transfer. The worst-case fee, gas limit times
+// fee cap, is deducted from the amount; for tokens it is converted into
+// token units at current prices. It returns the net amount sent and the fee
+// reserved, both in asset units.
+func (s *Service) build(ctx context.Context, network Network, withdrawal *models.Withdrawal, nonce uint64, tip, feeCap *big.Int) (*types.DynamicFeeTx, float64, float64, error) {
+	to := common.HexToAddress(withdrawal.ToAddress)
+	native := blockchain.Token{Symbol: strings.ToUpper(network.NativeSymbol), Decimals: 18}
+	tx := &types.DynamicFeeTx{
+		ChainID:   big.NewInt(network.ChainID),
+		Nonce:     nonce,
+		GasTipCap: tip,
+		GasFeeCap: feeCap,
+	}
+
+	if strings.EqualFold(withdrawal.AssetSymbol, native.Symbol) {
+		tx.Gas = blockchain.NativeTransferGas
+		feeWei := new(big.Int).Mul(big.NewInt(blockchain.NativeTransferGas), feeCap)
+		value := new(big.Int).Sub(native.ToBaseUnits(withdrawal.Amount), feeWei)
+		if value.Sign() <= 0 {
+			return nil, 0, 0, errAmountBelowFee
+		}
+		tx.To = &to
+		tx.Value = value
+		net, _ := native.FromBaseUnits(value).Float64()
+		fee, _ := native.FromBaseUnits(feeWei).Float64()
+		return tx, net, fee, nil
+	}
+
+	token, ok := network.token(withdrawal.AssetSymbol)
+	if !ok {
+		return nil, 0, 0, fmt.Errorf("%w: %s on %s", ErrUnsupportedAsset, withdrawal.AssetSymbol, network.Name)
+	}
+	tx.Gas = blockchain.TokenTransferGas
+	feeWei := new(big.Int).Mul(big.NewInt(blockchain.TokenTransferGas), feeCap)
+	feeNative, _ := native.FromBaseUnits(feeWei).Float64()
+	fee, err := s.convert(ctx, feeNative, native.Symbol, token.Symbol)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	net := withdrawal.Amount - fee
+	if net <= 0 {
+		return nil, 0, 0, errAmountBelowFee
+	}
+	contract := token.Contract
+	tx.To = &contract
+	tx.Value = new(big.Int)
+	tx.Data = blockchain.TransferCalldata(to, token.ToBaseUnits(net))
+	return tx, net, fee, nil
+}
+
+func (s *Service) convert(ctx context.Context, amount float64, from, to string) (float64, error) {
+	fromPrice, err := s.prices.GetPrice(ctx, from, feeCurrency)
+	if err != nil {
+		return 0, fmt.Errorf("failed to price %s: %w", from, err)
+	}
+	toPrice, err := s.prices.GetPrice(ctx, to, feeCurrency)
+	if err != nil {
+		return 0, fmt.Errorf("failed to price %s: %w", to, err)
+	}
+	if toPrice <= 0 {
+		return 0, fmt.Errorf("invalid %s price", to)
+	}
+	return amount * fromPrice / toPrice, nil
+}
+
+func (s *Service) sign(ctx context.Context, network Network, address string, unsigned *types.DynamicFeeTx) (*types.Transaction, error) {
+	secret, err := s.hotWallets.PrivateKey(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	defer secret.Zero()
+
+	key, err := crypto.ToECDSA(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hot wallet key: %w", err)
+	}
+	signed, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(network.ChainID)), unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign withdrawal: %w", err)
+	}
+	return signed, nil
+}
+
+func (s *Service) networkByName(name string) (Network, bool) {
+	for _, network := range s.networks {
+		if strings.EqualFold(network.Name, name) {
+			return network, true
+		}
+	}
+	return Network{}, false
+}
+
+func (n Network) carries(asset string) bool {
+	if strings.EqualFold(n.NativeSymbol, asset) {
+		return true
+	}
+	_, ok := n.token(asset)
+	return ok
+}
+
+func (n Network) token(symbol string) (blockchain.Token, bool) {
+	for _, token := range n.Tokens {
+		if strings.EqualFold(token.Symbol, symbol) {
+			return token, true
+		}
+	}
+	return blockchain.Token{}, false
+}
+
+// sent reports whether a send that failed with err got tx on-chain anyway:
+// the node already knows it, or its nonce is spent by tx itself. A spent
+// nonce alone may belong to another transaction, so it only counts once
+// tx's own receipt is found.
+func (s *Service) sent(ctx context.Context, network Network, tx *types.Transaction, err error) bool {
+	message := strings.ToLower(err.Error())
+	if strings.Contains(message, "already known") {
+		return true
+	}
+	if !strings.Contains(message, "nonce too low") {
+		return false
+	}
+	_, err = network.Client.TransactionReceipt(ctx, tx.Hash())
+	return err == nil
+}
+
+// notMined reports receipt lookup errors meaning the transaction is not in
+// a block yet. Nodes still building their transaction index answer with an
+// error instead of NotFound.
+func notMined(err error) bool {
+	return errors.Is(err, ethereum.NotFound) || (err != nil && strings.Contains(err.Error(), "indexing is in progress"))
+}
+
+func bigMax(a, b *big.Int) *big.Int {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,449 @@
+package withdrawal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"github.com/thoraf20/loanee/internal/blockchain"
+	"github.com/thoraf20/loanee/internal/models"
+	"github.com/thoraf20/loanee/pkg/keymanager"
+)
+
+var (
+	revertContract = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	borrower       = common.HexToAddress("0x00000000000000000000000000000000000000cc")
+	destination    = &models.WithdrawalAddress{Network: "ethereum", Address: borrower.Hex()}
+)
+
+// simulatedChainID is the chain ID of the go-ethereum simulated backend.
+const simulatedChainID = 1337
+
+func TestNativeReleaseConfirmsAfterDepth(t *testing.T) {
+	env := newTestEnv(t, Options{BumpAfter: time.Hour})
+	ctx := context.Background()
+	col := env.releasing("ETH", 1)
+
+	withdrawal, err := env.service.Request(ctx, col, destination)
+	require.NoError(t, err)
+	require.Equal(t, "ethereum", withdrawal.Network)
+	_, err = env.service.Request(ctx, col, destination)
+	require.ErrorIs(t, err, ErrWithdrawalInProgress)
+
+	env.service.Tick(ctx)
+	sent := env.withdrawal(t)
+	require.Equal(t, models.WithdrawalBroadcast, sent.Status)
+	require.Len(t, env.repo.txs, 1)
+
+	// One block is below the network's confirmation depth.
+	env.backend.Commit()
+	env.service.Tick(ctx)
+	require.Equal(t, models.WithdrawalBroadcast, env.withdrawal(t).Status)
+	require.Equal(t, models.StatusReleasing, env.collaterals.items[col.ID].Status)
+
+	env.backend.Commit()
+	env.service.Tick(ctx)
+	require.Equal(t, models.WithdrawalConfirmed, env.withdrawal(t).Status)
+	require.Equal(t, models.StatusReleased, env.collaterals.items[col.ID].Status)
+	require.NotNil(t, env.collaterals.items[col.ID].ReleaseResolvedAt)
+
+	// The borrower receives the amount minus the reserved network fee.
+	feeCap, _ := new(big.Int).SetString(env.repo.txs[0].GasFeeCap, 10)
+	expected := new(big.Int).Sub(big.NewInt(params.Ether), new(big.Int).Mul(big.NewInt(blockchain.NativeTransferGas), feeCap))
+	balance, err := env.backend.Client().BalanceAt(ctx, borrower, nil)
+	require.NoError(t, err)
+	require.Equal(t, expected, balance)
+	require.InDelta(t, 1, sent.NetAmount+sent.FeeAmount, 1e-12)
+}
+
+func TestStuckWithdrawalIsFeeBumped(t *testing.T) {
+	env := newTestEnv(t, Options{BumpAfter: time.Nanosecond})
+	ctx := context.Background()
+	col := env.releasing("ETH", 1)
+
+	_, err := env.service.Request(ctx, col, destination)
+	require.NoError(t, err)
+	env.service.Tick(ctx)
+	first := env.repo.txs[0]
+
+	// Nothing is mined, so the next pass replaces the transaction.
+	env.service.Tick(ctx)
+	require.Len(t, env.repo.txs, 2)
+	second := env.repo.txs[1]
+	require.Equal(t, first.Nonce, second.Nonce)
+	require.NotEqual(t, first.TxHash, second.TxHash)
+	require.Equal(t, 1, bigCmp(second.GasTipCap, first.GasTipCap))
+	require.Equal(t, 1, bigCmp(second.GasFeeCap, first.GasFeeCap))
+	require.Equal(t, 2, env.withdrawal(t).Attempts)
+
+	env.backend.Commit()
+	env.backend.Commit()
+	env.service.Tick(ctx)
+
+	confirmed := env.withdrawal(t)
+	require.Equal(t, models.WithdrawalConfirmed, confirmed.Status)
+	require.Equal(t, second.TxHash, *confirmed.TxHash)
+	require.Equal(t, models.StatusReleased, env.collaterals.items[col.ID].Status)
+
+	_, err = env.service.Bump(ctx, confirmed.ID)
+	require.ErrorIs(t, err, ErrNotInFlight)
+}
+
+func TestRevertedWithdrawalReturnsToReleaseQueue(t *testing.T) {
+	env := newTestEnv(t, Options{BumpAfter: time.Hour})
+	ctx := context.Background()
+	col := env.releasing("USDT", 100)
+
+	_, err := env.service.Request(ctx, col, destination)
+	require.NoError(t, err)
+	env.service.Tick(ctx)
+	require.Less(t, env.withdrawal(t).NetAmount, 100.0)
+
+	env.backend.Commit()
+	env.service.Tick(ctx)
+
+	failed := env.withdrawal(t)
+	require.Equal(t, models.WithdrawalFailed, failed.Status)
+	require.Contains(t, *failed.FailureReason, "reverted")
+	require.Equal(t, models.StatusReleaseRequested, env.collaterals.items[col.ID].Status)
+	require.NotNil(t, env.collaterals.items[col.ID].ReleaseNote)
+
+	// A failed withdrawal no longer blocks a retry.
+	env.collaterals.items[col.ID].Status = models.StatusReleasing
+	_, err = env.service.Request(ctx, col, destination)
+	require.NoError(t, err)
+}
+
+func TestSpentNonceRechecksReceiptsBeforeGivingUp(t *testing.T) {
+	env := newTestEnv(t, Options{BumpAfter: time.Hour})
+	ctx := context.Background()
+	col := env.releasing("ETH", 1)
+
+	_, err := env.service.Request(ctx, col, destination)
+	require.NoError(t, err)
+	env.service.Tick(ctx)
+	env.backend.Commit()
+	env.backend.Commit()
+
+	// The first lookup misses a transaction that is mined by the time the
+	// nonce is read.
+	env.client.hiddenReceipts = 1
+	env.service.Tick(ctx)
+	require.Equal(t, models.WithdrawalConfirmed, env.withdrawal(t).Status)
+	require.Equal(t, models.StatusReleased, env.collaterals.items[col.ID].Status)
+}
+
+func TestSpentNonceWithoutReceiptIsHeldForReview(t *testing.T) {
+	env := newTestEnv(t, Options{BumpAfter: time.Hour})
+	ctx := context.Background()
+	col := env.releasing("ETH", 1)
+
+	_, err := env.service.Request(ctx, col, destination)
+	require.NoError(t, err)
+	env.service.Tick(ctx)
+	env.backend.Commit()
+	env.backend.Commit()
+
+	// A node still indexing hides the receipt of the mined transaction.
+	env.client.hiddenReceipts = 100
+	env.service.Tick(ctx)
+
+	held := env.withdrawal(t)
+	require.Equal(t, models.WithdrawalReview, held.Status)
+	require.NotNil(t, held.FailureReason)
+	require.Equal(t, models.StatusReleasing, env.collaterals.items[col.ID].Status)
+
+	// The collateral can't be queued for a second payout.
+	_, err = env.service.Request(ctx, col, destination)
+	require.ErrorIs(t, err, ErrWithdrawalInProgress)
+	env.service.Tick(ctx)
+	require.Len(t, env.repo.txs, 1)
+}
+
+func TestUnsentWithdrawalKeepsItsNonce(t *testing.T) {
+	env := newTestEnv(t, Options{BumpAfter: time.Hour})
+	ctx := context.Background()
+
+	// The first transaction is recorded but never reaches the node, so the
+	// chain's pending nonce does not count it.
+	env.client.failedSends = 1
+	_, err := env.service.Request(ctx, env.releasing("ETH", 1), destination)
+	require.NoError(t, err)
+	_, err = env.service.Request(ctx, env.releasing("ETH", 1), destination)
+	require.NoError(t, err)
+	env.service.Tick(ctx)
+
+	require.Len(t, env.repo.txs, 2)
+	require.Equal(t, uint64(0), env.repo.txs[0].Nonce)
+	require.Equal(t, uint64(1), env.repo.txs[1].Nonce)
+}
+
+func TestNonceTooLowIsSentOnlyWithAReceipt(t *testing.T) {
+	env := newTestEnv(t, Options{BumpAfter: time.Hour})
+	ctx := context.Background()
+
+	_, err := env.service.Request(ctx, env.releasing("ETH", 1), destination)
+	require.NoError(t, err)
+	env.service.Tick(ctx)
+
+	var tx types.Transaction
+	require.NoError(t, tx.UnmarshalBinary(env.repo.txs[0].RawTx))
+	network := env.service.networks[0]
+	tooLow := errors.New("nonce too low")
+
+	require.False(t, env.service.sent(ctx, network, &tx, tooLow))
+	env.backend.Commit()
+	require.True(t, env.service.sent(ctx, network, &tx, tooLow))
+	require.True(t, env.service.sent(ctx, network, &tx, errors.New("already known")))
+	require.False(t, env.service.sent(ctx, network, &tx, errors.New("insufficient funds")))
+}
+
+func TestRequestRejectsUnsupportedAsset(t *testing.T) {
+	env := newTestEnv(t, Options{})
+	ctx := context.Background()
+
+	_, err := env.service.Request(ctx, env.releasing("BTC", 1), &models.WithdrawalAddress{Network: "bitcoin", Address: "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu"})
+	require.ErrorIs(t, err, ErrNoDestination)
+
+	_, err = env.service.Request(ctx, env.releasing("BNB", 1), destination)
+	require.ErrorIs(t, err, ErrUnsupportedAsset)
+
+	_, err = env.service.Request(ctx, env.releasing("ETH", 1), nil)
+	require.ErrorIs(t, err, ErrNoDestination)
+}
+
+func TestSupportsOnlyCarriedAssets(t *testing.T) {
+	env := newTestEnv(t, Options{})
+
+	require.True(t, env.service.Supports("eth", destination.Network))
+	require.False(t, env.service.Supports("BNB", destination.Network))
+	require.False(t, env.service.Supports("BTC", "bitcoin"))
+}
+
+type testEnv struct {
+	backend     *simulated.Backend
+	client      *indexingClient
+	service     *Service
+	repo        *memoryRepo
+	collaterals *memoryCollaterals
+}
+
+func newTestEnv(t *testing.T, opts Options) *testEnv {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	hot := crypto.PubkeyToAddress(key.PublicKey)
+
+	balance := new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether))
+	backend := simulated.NewBackend(types.GenesisAlloc{
+		hot:            {Balance: balance},
+		revertContract: {Code: common.FromHex("0x60006000fd")},
+	})
+	t.Cleanup(func() { backend.Close() })
+
+	network := Network{
+		EVMNetwork: blockchain.EVMNetwork{
+			Name:             "ethereum",
+			ChainID:          simulatedChainID,
+			NativeSymbol:     "ETH",
+			MinConfirmations: 2,
+			Tokens:           []blockchain.Token{{Symbol: "USDT", Contract: revertContract, Decimals: 6}},
+		},
+		Client: &indexingClient{Client: backend.Client()},
+	}
+
+	env := &testEnv{
+		backend:     backend,
+		client:      network.Client.(*indexingClient),
+		repo:        &memoryRepo{},
+		collaterals: &memoryCollaterals{items: map[uuid.UUID]*models.Collateral{}},
+	}
+	env.service = NewService(env.repo, []Network{network}, &fakeHotWallets{address: hot, key: key}, env.collaterals, fakePrices{"ETH": 2000, "USDT": 1}, opts, zerolog.Nop())
+	return env
+}
+
+func (e *testEnv) releasing(asset string, amount float64) *models.Collateral {
+	col := &models.Collateral{
+		ID:          uuid.New(),
+		UserID:      uuid.New(),
+		AssetSymbol: asset,
+		AssetAmount: amount,
+		Status:      models.StatusReleasing,
+	}
+	e.collaterals.items[col.ID] = col
+	return col
+}
+
+// withdrawal returns the most recently created withdrawal.
+func (e *testEnv) withdrawal(t *testing.T) models.Withdrawal {
+	t.Helper()
+	require.NotEmpty(t, e.repo.withdrawals)
+	return e.repo.withdrawals[len(e.repo.withdrawals)-1]
+}
+
+func bigCmp(a, b string) int {
+	x, _ := new(big.Int).SetString(a, 10)
+	y, _ := new(big.Int).SetString(b, 10)
+	return x.Cmp(y)
+}
+
+// indexingClient answers its next hiddenReceipts receipt lookups the way a
+// node still building its transaction index does, and drops its next
+// failedSends transactions.
+type indexingClient struct {
+	Client
+	hiddenReceipts int
+	failedSends    int
+}
+
+func (c *indexingClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	if c.failedSends > 0 {
+		c.failedSends--
+		return errors.New("connection reset")
+	}
+	return c.Client.SendTransaction(ctx, tx)
+}
+
+func (c *indexingClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if c.hiddenReceipts > 0 {
+		c.hiddenReceipts--
+		return nil, errors.New("transaction indexing is in progress")
+	}
+	return c.Client.TransactionReceipt(ctx, txHash)
+}
+
+type fakeHotWallets struct {
+	address common.Address
+	key     *ecdsa.PrivateKey
+}
+
+func (f *fakeHotWallets) ForNetwork(ctx context.Context, network string) (*models.HotWallet, error) {
+	return &models.HotWallet{Network: network, Address: f.address.Hex()}, nil
+}
+
+func (f *fakeHotWallets) PrivateKey(ctx context.Context, address string) (keymanager.Secret, error) {
+	return keymanager.Secret(crypto.FromECDSA(f.key)), nil
+}
+
+type fakePrices map[string]float64
+
+func (f fakePrices) GetPrice(ctx context.Context, symbol, currency string) (float64, error) {
+	return f[symbol], nil
+}
+
+type memoryCollaterals struct {
+	items map[uuid.UUID]*models.Collateral
+}
+
+func (m *memoryCollaterals) GetByID(ctx context.Context, id uuid.UUID) (*models.Collateral, error) {
+	col, ok := m.items[id]
+	if !ok {
+		return nil, nil
+	}
+	copy := *col
+	return &copy, nil
+}
+
+func (m *memoryCollaterals) Update(ctx context.Context, collateral *models.Collateral) error {
+	copy := *collateral
+	m.items[collateral.ID] = &copy
+	return nil
+}
+
+type memoryRepo struct {
+	withdrawals []models.Withdrawal
+	txs         []models.WithdrawalTx
+}
+
+func (m *memoryRepo) Create(ctx context.Context, withdrawal *models.Withdrawal) error {
+	withdrawal.CreatedAt = time.Now()
+	m.withdrawals = append(m.withdrawals, *withdrawal)
+	return nil
+}
+
+func (m *memoryRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.Withdrawal, error) {
+	for _, withdrawal := range m.withdrawals {
+		if withdrawal.ID == id {
+			copy := withdrawal
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRepo) GetOpenByCollateral(ctx context.Context, collateralID uuid.UUID) (*models.Withdrawal, error) {
+	for _, withdrawal := range m.withdrawals {
+		if withdrawal.CollateralID == collateralID && withdrawal.Status != models.WithdrawalConfirmed && withdrawal.Status != models.WithdrawalFailed {
+			copy := withdrawal
+			return &copy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryRepo) ListAll(ctx context.Context) ([]models.Withdrawal, error) {
+	return append([]models.Withdrawal(nil), m.withdrawals...), nil
+}
+
+func (m *memoryRepo) ListByStatus(ctx context.Context, statuses ...models.WithdrawalStatus) ([]models.Withdrawal, error) {
+	var result []models.Withdrawal
+	for _, withdrawal := range m.withdrawals {
+		for _, status := range statuses {
+			if withdrawal.Status == status {
+				result = append(result, withdrawal)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryRepo) Update(ctx context.Context, withdrawal *models.Withdrawal) error {
+	for i := range m.withdrawals {
+		if m.withdrawals[i].ID == withdrawal.ID {
+			m.withdrawals[i] = *withdrawal
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) RecordTx(ctx context.Context, withdrawal *models.Withdrawal, tx *models.WithdrawalTx) error {
+	tx.CreatedAt = time.Now()
+	m.txs = append(m.txs, *tx)
+	return m.Update(ctx, withdrawal)
+}
+
+func (m *memoryRepo) RecordFirstTx(ctx context.Context, withdrawal *models.Withdrawal, hotWallet string, pending uint64, sign func(nonce uint64) (*models.WithdrawalTx, error)) error {
+	nonce := pending
+	for _, tx := range m.txs {
+		if tx.Nonce >= nonce {
+			nonce = tx.Nonce + 1
+		}
+	}
+	tx, err := sign(nonce)
+	if err != nil {
+		return err
+	}
+	return m.RecordTx(ctx, withdrawal, tx)
+}
+
+func (m *memoryRepo) ListTxs(ctx context.Context, withdrawalID uuid.UUID) ([]models.WithdrawalTx, error) {
+	var result []models.WithdrawalTx
+	for _, tx := range m.txs {
+		if tx.WithdrawalID == withdrawalID {
+			result = append(result, tx)
+		}
+	}
+	return result, nil
+}
@@ -3,7 +3,9 @@ package errors
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
 // AppError represents a custom application error
@@ -13,6 +15,8 @@ type AppError struct {
 	StatusCode int                    `json:"-"`
 	Details    map[string]interface{} `json:"details,omitempty"`
 	Err        error                  `json:"-"`
+	// RetryAfter tells the client when to try again, for throttling errors
+	RetryAfter time.Duration `json:"-"`
 }
 
 // Error implements the error interface
@@ -63,6 +67,9 @@ const (
 	CodeTokenMissing       = "TOKEN_MISSING"
 	CodeInvalidCredentials = "INVALID_CREDENTIALS"
 	CodePermissionDenied   = "PERMISSION_DENIED"
+	CodeTooManyAttempts    = "TOO_MANY_ATTEMPTS"
+	CodeAccountLocked      = "ACCOUNT_LOCKED"
+	CodeRateLimited        = "RATE_LIMITED"
 
 	// Validation
 	CodeValidationFailed     = "VALIDATION_FAILED"
@@ -134,6 +141,18 @@ var (
 		http.StatusUnauthorized,
 	)
 
+	ErrRefreshTokenReused = NewAppError(
+		CodeTokenInvalid,
+		"Refresh token has already been used; all sessions from this login have been revoked",
+		http.StatusUnauthorized,
+	)
+
+	ErrSessionNotFound = NewAppError(
+		CodeNotFound,
+		"Session not found",
+		http.StatusNotFound,
+	)
+
 	ErrInvalidCredentials = NewAppError(
 		CodeInvalidCredentials,
 		"Invalid email or password",
@@ -232,6 +251,207 @@ var (
 		"Collateral with this asset already exists",
 		http.StatusConflict,
 	)
+
+	ErrCollateralTxAlreadyUsed = NewAppError(
+		CodeConflict,
+		"Transaction is already attached to another collateral",
+		http.StatusConflict,
+	)
+
+	ErrCollateralNotFinal = NewAppError(
+		CodeCollateralLocked,
+		"Collateral deposit has not reached finality",
+		http.StatusConflict,
+	)
+
+	ErrCollateralInvalidated = NewAppError(
+		CodeInvalidOperation,
+		"Collateral deposit was invalidated by a chain reorganisation",
+		http.StatusConflict,
+	)
+
+	ErrCollateralNotAwaitingRelease = NewAppError(
+		CodeInvalidOperation,
+		"Collateral has no pending release request",
+		http.StatusConflict,
+	)
+
+	ErrCollateralDepositUnverified = NewAppError(
+		CodeInvalidOperation,
+		"Deposit transaction could not be verified",
+		http.StatusUnprocessableEntity,
+	)
+
+	ErrStablecoinDepegged = NewAppError(
+		CodeServiceUnavailable,
+		"New loans against this stablecoin are suspended while it trades off its peg",
+		http.StatusServiceUnavailable,
+	)
+)
+
+// Withdrawal Address Errors
+var (
+	ErrWithdrawalAddressNotFound = NewAppError(
+		CodeNotFound,
+		"Withdrawal address not found",
+		http.StatusNotFound,
+	)
+
+	ErrWithdrawalAddressInvalid = NewAppError(
+		CodeInvalidFormat,
+		"Withdrawal address is not valid for this asset and network",
+		http.StatusBadRequest,
+	)
+
+	ErrWithdrawalAddressExists = NewAppError(
+		CodeAlreadyExists,
+		"Withdrawal address is already in the address book",
+		http.StatusConflict,
+	)
+
+	ErrWithdrawalAddressConfirmation = NewAppError(
+		CodeTokenInvalid,
+		"Withdrawal address confirmation is invalid or expired",
+		http.StatusBadRequest,
+	)
+
+	ErrWithdrawalAddressNotUsable = NewAppError(
+		CodeInvalidOperation,
+		"Withdrawal address is not confirmed or is still in its cooling-off period",
+		http.StatusConflict,
+	)
+
+	ErrWithdrawalAddressMismatch = NewAppError(
+		CodeInvalidOperation,
+		"Withdrawal address does not match the collateral asset or network",
+		http.StatusBadRequest,
+	)
+)
+
+// Two-Factor Authentication Errors
+var (
+	ErrMFAUnavailable = NewAppError(
+		CodeServiceUnavailable,
+		"Two-factor authentication is not configured on this server",
+		http.StatusServiceUnavailable,
+	)
+
+	ErrMFAAlreadyEnabled = NewAppError(
+		CodeAlreadyExists,
+		"Two-factor authentication is already enabled",
+		http.StatusConflict,
+	)
+
+	ErrMFANotEnrolled = NewAppError(
+		CodeInvalidOperation,
+		"Two-factor authentication has not been set up",
+		http.StatusBadRequest,
+	)
+
+	ErrMFACodeInvalid = NewAppError(
+		CodeInvalidCredentials,
+		"Invalid two-factor authentication code",
+		http.StatusUnauthorized,
+	)
+
+	ErrStepUpRequired = NewAppError(
+		CodeForbidden,
+		"This action requires recent two-factor verification",
+		http.StatusForbidden,
+	)
+)
+
+// Access Control Errors
+var (
+	ErrRoleNotFound = NewAppError(
+		CodeNotFound,
+		"Role not found",
+		http.StatusNotFound,
+	)
+
+	ErrRoleAlreadyAssigned = NewAppError(
+		CodeAlreadyExists,
+		"User already has this role",
+		http.StatusConflict,
+	)
+
+	ErrRoleChanged = NewAppError(
+		CodeConflict,
+		"User's role changed in the meantime, please retry",
+		http.StatusConflict,
+	)
+
+	ErrSelfRoleAssignment = NewAppError(
+		CodeForbidden,
+		"You cannot change your own role",
+		http.StatusForbidden,
+	)
+)
+
+// Dual Control Errors
+var (
+	ErrApprovalNotFound = NewAppError(
+		CodeNotFound,
+		"Approval not found",
+		http.StatusNotFound,
+	)
+
+	ErrApprovalPending = NewAppError(
+		CodeAlreadyExists,
+		"An approval for this action is already pending",
+		http.StatusConflict,
+	)
+
+	ErrApprovalNotPending = NewAppError(
+		CodeInvalidOperation,
+		"Approval has already been decided",
+		http.StatusConflict,
+	)
+
+	ErrApprovalExpired = NewAppError(
+		CodeInvalidOperation,
+		"Approval has expired, propose the action again",
+		http.StatusConflict,
+	)
+
+	ErrSelfApproval = NewAppError(
+		CodeForbidden,
+		"A different administrator must approve this action",
+		http.StatusForbidden,
+	)
+)
+
+// API Key Errors
+var (
+	ErrAPIKeyInvalid = NewAppError(
+		CodeUnauthorized,
+		"API key is invalid, revoked or expired",
+		http.StatusUnauthorized,
+	)
+
+	ErrAPIKeyIPNotAllowed = NewAppError(
+		CodeForbidden,
+		"API key may not be used from this IP address",
+		http.StatusForbidden,
+	)
+
+	ErrAPIKeyNotFound = NewAppError(
+		CodeNotFound,
+		"API key not found",
+		http.StatusNotFound,
+	)
+
+	ErrAPIKeyScopeNotAllowed = NewAppError(
+		CodePermissionDenied,
+		"API key scope is unknown or not granted to your role",
+		http.StatusForbidden,
+	)
+
+	ErrAPIKeyLimitReached = NewAppError(
+		CodeInvalidOperation,
+		"Maximum number of API keys reached, revoke one first",
+		http.StatusConflict,
+	)
 )
 
 // Loan Errors
@@ -265,6 +485,12 @@ var (
 		"Loan is not active",
 		http.StatusBadRequest,
 	)
+
+	ErrLoanNotApproved = NewAppError(
+		CodeInvalidOperation,
+		"Loan must be approved before it is disbursed",
+		http.StatusConflict,
+	)
 )
 
 // Payment Errors
@@ -463,6 +689,28 @@ func NewInternalError(message string, err error) *AppError {
 	}
 }
 
+// NewTooManyAttemptsError throttles a client until retryAfter has passed
+func NewTooManyAttemptsError(retryAfter time.Duration) *AppError {
+	return newRetryError(CodeTooManyAttempts, "Too many attempts, please try again later", retryAfter)
+}
+
+// NewAccountLockedError reports an account locked out after repeated
+// failures; it unlocks after retryAfter or from the emailed unlock link
+func NewAccountLockedError(retryAfter time.Duration) *AppError {
+	return newRetryError(CodeAccountLocked, "Account temporarily locked after too many failed attempts", retryAfter)
+}
+
+// NewRateLimitedError reports a client over its request rate limit
+func NewRateLimitedError(retryAfter time.Duration) *AppError {
+	return newRetryError(CodeRateLimited, "Rate limit exceeded, please slow down", retryAfter)
+}
+
+func newRetryError(code, message string, retryAfter time.Duration) *AppError {
+	appErr := NewAppError(code, message, http.StatusTooManyRequests)
+	appErr.RetryAfter = retryAfter
+	return appErr.WithDetail("retry_after_seconds", int(math.Ceil(retryAfter.Seconds())))
+}
+
 // WrapError wraps a standard error into an AppError
 func WrapError(err error, code, message string, statusCode int) *AppError {
 	return &AppError{
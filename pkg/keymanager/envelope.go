@@ -0,0 +1,85 @@
+package keymanager
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+const dataKeySize = 32
+
+// Envelope is a secret encrypted under its own data key, with the data key
+// wrapped by a KeyManager master key. Rotating master keys only re-wraps the
+// data key; the ciphertext itself is untouched.
+type Envelope struct {
+	Ciphertext []byte
+	WrappedKey []byte
+	KeyID      string
+}
+
+// Seal encrypts plaintext under a fresh data key. additionalData binds the
+// ciphertext to its owner (a wallet address, say) so it cannot be swapped
+// onto another record.
+func Seal(ctx context.Context, keys KeyManager, plaintext Secret, additionalData []byte) (*Envelope, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	defer zero(dataKey)
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(aead, plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped, keyID, err := keys.Wrap(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return &Envelope{Ciphertext: ciphertext, WrappedKey: wrapped, KeyID: keyID}, nil
+}
+
+// Open decrypts an envelope. The caller should Zero the secret once used.
+func Open(ctx context.Context, keys KeyManager, env *Envelope, additionalData []byte) (Secret, error) {
+	dataKey, err := keys.Unwrap(ctx, env.KeyID, env.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	defer zero(dataKey)
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, env.Ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return Secret(plaintext), nil
+}
+
+// Rewrap moves an envelope's data key under the active master key. It
+// reports false when the envelope already uses it.
+func Rewrap(ctx context.Context, keys KeyManager, env *Envelope) (bool, error) {
+	if env.KeyID == keys.ActiveKeyID() {
+		return false, nil
+	}
+
+	dataKey, err := keys.Unwrap(ctx, env.KeyID, env.WrappedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	defer zero(dataKey)
+
+	wrapped, keyID, err := keys.Wrap(ctx, dataKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	env.WrappedKey = wrapped
+	env.KeyID = keyID
+	return true, nil
+}
@@ -0,0 +1,66 @@
+package keymanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownKey is returned when a data key was wrapped under a master key
+// the manager does not hold.
+var ErrUnknownKey = errors.New("unknown master key")
+
+// KeyManager wraps and unwraps data keys under master keys it never exposes.
+// The local implementation keeps master keys in memory; external KMS backends
+// plug in through Register.
+type KeyManager interface {
+	// ActiveKeyID names the master key Wrap encrypts under.
+	ActiveKeyID() string
+	// Wrap encrypts a data key under the active master key.
+	Wrap(ctx context.Context, dataKey []byte) (wrapped []byte, keyID string, err error)
+	// Unwrap decrypts a data key previously wrapped under keyID.
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Factory builds a KeyManager for an external provider.
+type Factory func(ctx context.Context) (KeyManager, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Factory)
+)
+
+// Register makes an external KMS available under name, typically from an
+// init function in the package that talks to it.
+func Register(name string, factory Factory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	providers[name] = factory
+}
+
+// Connect builds the KeyManager registered under name.
+func Connect(ctx context.Context, name string) (KeyManager, error) {
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("key management provider %q is not registered (available: %v)", name, Providers())
+	}
+	return factory(ctx)
+}
+
+// Providers lists the registered external providers.
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
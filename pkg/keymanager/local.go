@@ -0,0 +1,104 @@
+package keymanager
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// LocalKeyManager wraps data keys with AES-256-GCM master keys held in
+// memory. Retired master keys stay loaded so rows wrapped under them can
+// still be read until rotation re-wraps them.
+type LocalKeyManager struct {
+	activeKeyID string
+	keys        map[string]cipher.AEAD
+}
+
+// NewLocal builds a local manager from base64-encoded 32-byte master keys
+// keyed by id. activeKeyID must name one of them.
+func NewLocal(activeKeyID string, masterKeys map[string]string) (*LocalKeyManager, error) {
+	if _, ok := masterKeys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active master key %q is not configured", activeKeyID)
+	}
+
+	keys := make(map[string]cipher.AEAD, len(masterKeys))
+	for id, encoded := range masterKeys {
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("master key %q is not valid base64: %w", id, err)
+		}
+		if len(raw) != 32 {
+			return nil, fmt.Errorf("master key %q must be 32 bytes, got %d", id, len(raw))
+		}
+		aead, err := newGCM(raw)
+		zero(raw)
+		if err != nil {
+			return nil, err
+		}
+		keys[id] = aead
+	}
+
+	return &LocalKeyManager{activeKeyID: activeKeyID, keys: keys}, nil
+}
+
+func (m *LocalKeyManager) ActiveKeyID() string {
+	return m.activeKeyID
+}
+
+// Wrap implements KeyManager. The key id is bound as associated data so a
+// wrapped key cannot be replayed under a different id.
+func (m *LocalKeyManager) Wrap(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	wrapped, err := seal(m.keys[m.activeKeyID], dataKey, []byte(m.activeKeyID))
+	if err != nil {
+		return nil, "", err
+	}
+	return wrapped, m.activeKeyID, nil
+}
+
+// Unwrap implements KeyManager.
+func (m *LocalKeyManager) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := m.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return open(aead, wrapped, []byte(keyID))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext and prefixes the random nonce.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
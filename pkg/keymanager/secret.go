@@ -0,0 +1,24 @@
+package keymanager
+
+import "fmt"
+
+const redacted = "[REDACTED]"
+
+// Secret holds plaintext key material. It redacts itself wherever it is
+// formatted, logged or marshalled, so a key cannot leak through a stray log
+// field or API response.
+type Secret []byte
+
+func (s Secret) String() string { return redacted }
+
+func (s Secret) GoString() string { return redacted }
+
+// Format covers every fmt verb, including %x and %v.
+func (s Secret) Format(f fmt.State, verb rune) { _, _ = f.Write([]byte(redacted)) }
+
+func (s Secret) MarshalJSON() ([]byte, error) { return []byte(`"` + redacted + `"`), nil }
+
+func (s Secret) MarshalText() ([]byte, error) { return []byte(redacted), nil }
+
+// Zero overwrites the key material in place.
+func (s Secret) Zero() { zero(s) }
@@ -0,0 +1,72 @@
+// Package ratelimit caps request rates with a token bucket, computed as a
+// generic cell rate algorithm (GCRA) so each key needs a single timestamp.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit allows Requests per Window, all of which may arrive as one burst;
+// the bucket then refills evenly across the window.
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// interval is the time one request's worth of capacity takes to refill.
+func (l Limit) interval() time.Duration {
+	return l.Window / time.Duration(l.Requests)
+}
+
+// Result describes a key's bucket after a request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long a refused client must wait, zero when allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter takes one request from key's bucket, if it has one to give.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// allow applies GCRA to a bucket whose theoretical arrival time is tat,
+// returning the result and the new tat to store when the request is allowed.
+func allow(limit Limit, tat, now time.Time) (Result, time.Time) {
+	interval := limit.interval()
+	if tat.Before(now) {
+		tat = now
+	}
+
+	next := tat.Add(interval)
+	allowAt := next.Add(-limit.Window)
+	if now.Before(allowAt) {
+		return Result{
+			Limit:      limit.Requests,
+			Reset:      tat.Sub(now),
+			RetryAfter: allowAt.Sub(now),
+		}, tat
+	}
+
+	return fromReset(limit, next.Sub(now)), next
+}
+
+// fromReset builds an allowed result from the time left until the bucket
+// refills.
+func fromReset(limit Limit, reset time.Duration) Result {
+	remaining := int((limit.Window - reset) / limit.interval())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return Result{
+		Allowed:   true,
+		Limit:     limit.Requests,
+		Remaining: remaining,
+		Reset:     reset,
+	}
+}
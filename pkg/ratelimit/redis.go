@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript is allow() run atomically in Redis, in microseconds. It reads
+// the clock from Redis so instances with skewed clocks share one timeline;
+// that needs Redis 5 or later, which replicate script effects rather than
+// the script.
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local next = tat + interval
+local allow_at = next - window
+if now < allow_at then
+	return {0, tat - now, allow_at - now}
+end
+
+redis.call("SET", KEYS[1], next, "PX", math.ceil((next - now) / 1000))
+return {1, next - now, 0}
+`)
+
+// RedisLimiter shares buckets between every instance of the API.
+type RedisLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisLimiter(client *redis.Client) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: "ratelimit:",
+	}
+}
+
+func (r *RedisLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	values, err := gcraScript.Run(ctx, r.client, []string{r.prefix + key},
+		limit.interval().Microseconds(),
+		limit.Window.Microseconds(),
+	).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to apply rate limit: %w", err)
+	}
+
+	reset := time.Duration(values[1]) * time.Microsecond
+	if values[0] == 1 {
+		return fromReset(limit, reset), nil
+	}
+	return Result{
+		Limit:      limit.Requests,
+		Reset:      reset,
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+	}, nil
+}
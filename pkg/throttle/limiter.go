@@ -0,0 +1,189 @@
+package throttle
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	e "github.com/thoraf20/loanee/pkg/error"
+)
+
+// Rule bounds failures for one kind of subject, an account or an IP.
+type Rule struct {
+	// MaxFailures within Window lock the subject out for Lockout.
+	MaxFailures int
+	Window      time.Duration
+	Lockout     time.Duration
+}
+
+type Options struct {
+	Account Rule
+	IP      Rule
+	// FreeFailures are tolerated before delays start; each failure after
+	// that doubles the wait before the next attempt, from BaseDelay up to
+	// MaxDelay.
+	FreeFailures int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+}
+
+// Attempt names what a guess is counted against. Scope separates endpoints,
+// so failed logins do not lock out email verification. Either subject may
+// be empty.
+type Attempt struct {
+	Scope   string
+	Account string
+	IP      string
+}
+
+// Limiter counts failed attempts per account and per IP, slowing them down
+// progressively and locking the subject out once a rule is exceeded. Store
+// errors are logged and let the attempt through, so an outage of the store
+// does not lock everyone out.
+type Limiter struct {
+	store  Store
+	opts   Options
+	logger zerolog.Logger
+}
+
+func NewLimiter(store Store, opts Options, logger zerolog.Logger) *Limiter {
+	return &Limiter{
+		store:  store,
+		opts:   opts,
+		logger: logger.With().Str("component", "throttle").Logger(),
+	}
+}
+
+// Check refuses an attempt while its account is locked or either subject is
+// waiting out a delay or lockout.
+func (l *Limiter) Check(ctx context.Context, a Attempt) error {
+	if key := a.accountKey(); key != "" {
+		if remaining := l.blocked(ctx, lockKey(key)); remaining > 0 {
+			return e.NewAccountLockedError(remaining)
+		}
+		if remaining := l.blocked(ctx, key); remaining > 0 {
+			return e.NewTooManyAttemptsError(remaining)
+		}
+	}
+	if key := a.ipKey(); key != "" {
+		if remaining := l.blocked(ctx, lockKey(key)); remaining > 0 {
+			return e.NewTooManyAttemptsError(remaining)
+		}
+		if remaining := l.blocked(ctx, key); remaining > 0 {
+			return e.NewTooManyAttemptsError(remaining)
+		}
+	}
+	return nil
+}
+
+// Fail records a failed attempt. It reports true when this failure locked
+// the account out.
+func (l *Limiter) Fail(ctx context.Context, a Attempt) bool {
+	locked := false
+	if key := a.accountKey(); key != "" {
+		locked = l.fail(ctx, key, l.opts.Account)
+	}
+	if key := a.ipKey(); key != "" {
+		if l.fail(ctx, key, l.opts.IP) {
+			l.logger.Warn().Str("scope", a.Scope).Str("ip", a.IP).Msg("IP locked out after repeated failures")
+		}
+	}
+	if locked {
+		l.logger.Warn().Str("scope", a.Scope).Str("account", a.Account).Msg("Account locked out after repeated failures")
+	}
+	return locked
+}
+
+// Succeed clears the account's failures. The IP's are kept, so one valid
+// account cannot be used to reset an attacker's counter.
+func (l *Limiter) Succeed(ctx context.Context, a Attempt) {
+	if key := a.accountKey(); key != "" {
+		l.reset(ctx, key)
+	}
+}
+
+// Unlock lifts an account's lockout in scope and clears its failures.
+func (l *Limiter) Unlock(ctx context.Context, scope, account string) {
+	l.Succeed(ctx, Attempt{Scope: scope, Account: account})
+}
+
+func (l *Limiter) fail(ctx context.Context, key string, rule Rule) bool {
+	if rule.MaxFailures <= 0 {
+		return false
+	}
+
+	count, err := l.store.Increment(ctx, key, rule.Window)
+	if err != nil {
+		l.logger.Warn().Err(err).Str("key", key).Msg("Failed to record failed attempt")
+		return false
+	}
+
+	if count >= int64(rule.MaxFailures) {
+		if err := l.store.Block(ctx, lockKey(key), rule.Lockout); err != nil {
+			l.logger.Warn().Err(err).Str("key", key).Msg("Failed to lock out")
+			return false
+		}
+		return true
+	}
+
+	if delay := l.delay(count); delay > 0 {
+		if err := l.store.Block(ctx, key, delay); err != nil {
+			l.logger.Warn().Err(err).Str("key", key).Msg("Failed to apply delay")
+		}
+	}
+	return false
+}
+
+// delay is the wait imposed after the count-th failure.
+func (l *Limiter) delay(count int64) time.Duration {
+	excess := count - int64(l.opts.FreeFailures)
+	if excess <= 0 || l.opts.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := l.opts.BaseDelay
+	for i := int64(1); i < excess; i++ {
+		delay *= 2
+		if l.opts.MaxDelay > 0 && delay >= l.opts.MaxDelay {
+			return l.opts.MaxDelay
+		}
+	}
+	return delay
+}
+
+func (l *Limiter) blocked(ctx context.Context, key string) time.Duration {
+	remaining, err := l.store.Blocked(ctx, key)
+	if err != nil {
+		l.logger.Warn().Err(err).Str("key", key).Msg("Failed to check attempt limit")
+		return 0
+	}
+	return remaining
+}
+
+func (l *Limiter) reset(ctx context.Context, key string) {
+	for _, k := range []string{key, lockKey(key)} {
+		if err := l.store.Reset(ctx, k); err != nil {
+			l.logger.Warn().Err(err).Str("key", k).Msg("Failed to reset attempt counter")
+		}
+	}
+}
+
+func (a Attempt) accountKey() string {
+	account := strings.ToLower(strings.TrimSpace(a.Account))
+	if account == "" {
+		return ""
+	}
+	return a.Scope + ":account:" + account
+}
+
+func (a Attempt) ipKey() string {
+	if a.IP == "" {
+		return ""
+	}
+	return a.Scope + ":ip:" + a.IP
+}
+
+func lockKey(key string) string {
+	return key + ":lock"
+}
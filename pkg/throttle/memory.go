@@ -0,0 +1,102 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type counter struct {
+	count        int64
+	expiresAt    time.Time
+	blockedUntil time.Time
+}
+
+// MemoryStore is a single-process Store, used when Redis is unavailable.
+// Counters are not shared between instances.
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]*counter
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		counters: make(map[string]*counter),
+		now:      time.Now,
+	}
+}
+
+func (m *MemoryStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	c := m.entry(key)
+	if !now.Before(c.expiresAt) {
+		c.count = 0
+		c.expiresAt = now.Add(ttl)
+	}
+	c.count++
+	return c.count, nil
+}
+
+func (m *MemoryStore) Block(ctx context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := m.entry(key)
+	if until := m.now().Add(ttl); until.After(c.blockedUntil) {
+		c.blockedUntil = until
+	}
+	return nil
+}
+
+func (m *MemoryStore) Blocked(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.counters[key]
+	if !ok {
+		return 0, nil
+	}
+	if remaining := c.blockedUntil.Sub(m.now()); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+func (m *MemoryStore) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.counters, key)
+	return nil
+}
+
+func (m *MemoryStore) entry(key string) *counter {
+	c, ok := m.counters[key]
+	if !ok {
+		c = &counter{}
+		m.counters[key] = c
+	}
+	return c
+}
+
+// sweep drops entries whose window and block have both lapsed, at most once
+// a minute.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+
+	for key, c := range m.counters {
+		if now.After(c.expiresAt) && now.After(c.blockedUntil) {
+			delete(m.counters, key)
+		}
+	}
+}
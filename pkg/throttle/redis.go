@@ -0,0 +1,71 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore shares counters between every instance of the API.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: "throttle:",
+	}
+}
+
+func (r *RedisStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	countKey := r.prefix + key
+
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, countKey)
+	// NX leaves the window of an existing counter alone
+	pipe.ExpireNX(ctx, countKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to increment attempt counter: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+func (r *RedisStore) Block(ctx context.Context, key string, ttl time.Duration) error {
+	blockKey := r.prefix + key + ":blocked"
+
+	remaining, err := r.client.PTTL(ctx, blockKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read block: %w", err)
+	}
+	if remaining >= ttl {
+		return nil
+	}
+
+	if err := r.client.Set(ctx, blockKey, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to block: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStore) Blocked(ctx context.Context, key string) (time.Duration, error) {
+	remaining, err := r.client.PTTL(ctx, r.prefix+key+":blocked").Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read block: %w", err)
+	}
+	// PTTL is negative when the key is missing or has no expiry
+	if remaining < 0 {
+		return 0, nil
+	}
+	return remaining, nil
+}
+
+func (r *RedisStore) Reset(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, r.prefix+key, r.prefix+key+":blocked").Err(); err != nil {
+		return fmt.Errorf("failed to reset attempt counter: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps failure counters and blocks. Keys are opaque; the Limiter
+// namespaces them.
+type Store interface {
+	// Increment bumps key's counter and returns the new count. The first
+	// increment starts a window of ttl after which the counter resets.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Block bars key for ttl, extending any shorter block already in place.
+	Block(ctx context.Context, key string, ttl time.Duration) error
+	// Blocked returns how long key remains barred, zero when it is not.
+	Blocked(ctx context.Context, key string) (time.Duration, error)
+	// Reset clears key's counter and block.
+	Reset(ctx context.Context, key string) error
+}
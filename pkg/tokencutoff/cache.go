@@ -0,0 +1,19 @@
+package tokencutoff
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cache holds each user's token cutoff for a short while so authenticating
+// a request doesn't read the user row. A cached nil means the user has never
+// logged out everywhere.
+type Cache interface {
+	// Get reports whether a cutoff is cached for the user
+	Get(ctx context.Context, userID uuid.UUID) (cutoff *time.Time, found bool, err error)
+	Set(ctx context.Context, userID uuid.UUID, cutoff *time.Time) error
+	// Invalidate drops the cached cutoff once it has moved
+	Invalidate(ctx context.Context, userID uuid.UUID) error
+}
@@ -0,0 +1,60 @@
+package tokencutoff
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type memoryEntry struct {
+	cutoff    *time.Time
+	expiresAt time.Time
+}
+
+// MemoryCache is only seen by this instance, so a log out everywhere served
+// by another instance takes up to the TTL to apply here.
+type MemoryCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[uuid.UUID]memoryEntry
+}
+
+func NewMemoryCache(ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		ttl:     ttl,
+		entries: make(map[uuid.UUID]memoryEntry),
+	}
+}
+
+func (m *MemoryCache) Get(ctx context.Context, userID uuid.UUID) (*time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[userID]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(m.entries, userID)
+		return nil, false, nil
+	}
+	return entry.cutoff, true, nil
+}
+
+func (m *MemoryCache) Set(ctx context.Context, userID uuid.UUID, cutoff *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries[userID] = memoryEntry{cutoff: cutoff, expiresAt: time.Now().Add(m.ttl)}
+	return nil
+}
+
+func (m *MemoryCache) Invalidate(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, userID)
+	return nil
+}
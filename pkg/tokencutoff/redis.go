@@ -0,0 +1,67 @@
+package tokencutoff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// none marks a user who has never logged out everywhere
+const none = "none"
+
+// RedisCache shares cutoffs between every instance of the API, so a log out
+// everywhere applies to all of them as soon as it is invalidated.
+type RedisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+	prefix string
+}
+
+func NewRedisCache(client *redis.Client, ttl time.Duration) *RedisCache {
+	return &RedisCache{
+		client: client,
+		ttl:    ttl,
+		prefix: "token_cutoff:",
+	}
+}
+
+func (r *RedisCache) Get(ctx context.Context, userID uuid.UUID) (*time.Time, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix+userID.String()).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read token cutoff: %w", err)
+	}
+	if value == none {
+		return nil, true, nil
+	}
+
+	cutoff, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse token cutoff: %w", err)
+	}
+	return &cutoff, true, nil
+}
+
+func (r *RedisCache) Set(ctx context.Context, userID uuid.UUID, cutoff *time.Time) error {
+	value := none
+	if cutoff != nil {
+		value = cutoff.UTC().Format(time.RFC3339Nano)
+	}
+	if err := r.client.Set(ctx, r.prefix+userID.String(), value, r.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to cache token cutoff: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisCache) Invalidate(ctx context.Context, userID uuid.UUID) error {
+	if err := r.client.Del(ctx, r.prefix+userID.String()).Err(); err != nil {
+		return fmt.Errorf("failed to invalidate token cutoff: %w", err)
+	}
+	return nil
+}